@@ -1,186 +1,178 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/CrissAlvarezH/fundart-api/internal/common"
+	"github.com/CrissAlvarezH/fundart-api/internal/config"
+	"github.com/CrissAlvarezH/fundart-api/internal/docs"
+	"github.com/CrissAlvarezH/fundart-api/internal/fixtures"
+	"github.com/CrissAlvarezH/fundart-api/internal/health"
+	"github.com/CrissAlvarezH/fundart-api/internal/i18n"
+	"github.com/CrissAlvarezH/fundart-api/internal/logging"
+	"github.com/CrissAlvarezH/fundart-api/internal/metrics"
+	"github.com/CrissAlvarezH/fundart-api/internal/migrations"
 	services2 "github.com/CrissAlvarezH/fundart-api/internal/products/application/services"
 	handler2 "github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/handler"
 	memoryrepo2 "github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/memoryrepo"
+	"github.com/CrissAlvarezH/fundart-api/internal/snapshot"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/application/services"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/handler"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/memoryrepo"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/notifications"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/storage"
 	"github.com/gin-gonic/gin"
-	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"syscall"
 	"time"
 )
 import "net/http"
 
+// readinessTimeout is the time the components have to answer a readiness check
+const readinessTimeout = 2 * time.Second
+
 func main() {
-	app := gin.Default()
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("invalid config", err)
+	}
+	logger := logging.New(os.Stdout, cfg.Log.SlogLevel())
+	// the packages that log without a request use the default logger
+	slog.SetDefault(logger)
+	logger.Info("config loaded", "config", cfg.String())
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		Stdout:      os.Stdout,
+	})
+	if err != nil {
+		fatal("error setting up tracing", err)
+	}
+
+	appMetrics := metrics.New()
+
+	app := gin.New()
+	app.Use(
+		gin.Recovery(), common.RequestID(logger), tracing.Middleware(), common.AccessLog(), appMetrics.Middleware(),
+	)
+	app.Use(common.CORS(cfg.HTTP.CORSOrigins))
 
 	app.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "pong"})
 	})
+	// the components add their checks below, as they are created
+	readiness := health.NewReadiness(readinessTimeout)
+	app.GET("/healthz", health.Liveness)
+	app.GET("/readyz", readiness.Handler)
+	app.GET("/metrics", appMetrics.Handler())
+	docs.AddRoutes(app)
 	app.POST("/upload", func(c *gin.Context) {
 		form, _ := c.MultipartForm()
 		files := form.File["images"]
-		dst := "./images/"
+		dst := cfg.Uploads.ImagesDir
+		logger := logging.FromContext(c.Request.Context())
 
 		for _, file := range files {
-			log.Println(file.Filename)
-
 			// Upload the file to specific dst.
 			err := c.SaveUploadedFile(file, dst)
 			if err != nil {
-				log.Println("error on save image ", file.Filename)
+				logger.Error("error saving uploaded image", "file", file.Filename, "dst", dst, "error", err)
 			}
 		}
 		c.String(http.StatusOK, fmt.Sprintf("%d files uploaded!", len(files)))
 	})
 
 	// USERS [INIT]
-	userData := []memoryrepo.MemoryUser{
-		{
-			ID:        1,
-			Name:      "Cristian",
-			Email:     "cristian@email.com",
-			Password:  "23456_encrypt",
-			Phone:     "320684398",
-			IsActive:  true,
-			CreatedAt: time.Time{},
-			Addresses: nil,
-			Scopes:    nil,
-		},
-		{
-			ID:        2,
-			Name:      "Yulisa",
-			Email:     "yuli@email.com",
-			Password:  "ddd_encrypt",
-			Phone:     "442546536",
-			IsActive:  true,
-			CreatedAt: time.Time{},
-			Addresses: nil,
-			Scopes:    nil,
-		},
-		{
-			ID:        3,
-			Name:      "Andrea",
-			Email:     "andrea@email.com",
-			Password:  "444444",
-			Phone:     "320684398",
-			IsActive:  true,
-			CreatedAt: time.Time{},
-			Addresses: nil,
-			Scopes:    nil,
-		},
-		{
-			ID:        4,
-			Name:      "Gabriel",
-			Email:     "gabriel@email.com",
-			Password:  "oooooo",
-			Phone:     "53462345",
-			IsActive:  true,
-			CreatedAt: time.Time{},
-			Addresses: nil,
-			Scopes:    nil,
-		},
-		{
-			ID:        5,
-			Name:      "Matias",
-			Email:     "matias@email.com",
-			Password:  "tttttt",
-			Phone:     "542534345",
-			IsActive:  true,
-			CreatedAt: time.Time{},
-			Addresses: nil,
-			Scopes:    nil,
-		},
-		{
-			ID:        6,
-			Name:      "Manuel",
-			Email:     "manuel@email.com",
-			Password:  "555555",
-			Phone:     "623452345",
-			IsActive:  true,
-			CreatedAt: time.Time{},
-			Addresses: nil,
-			Scopes:    nil,
-		},
-		{
-			ID:        7,
-			Name:      "Camilo",
-			Email:     "camilo@email.com",
-			Password:  "rrrrrr",
-			Phone:     "320684398",
-			IsActive:  true,
-			CreatedAt: time.Time{},
-			Addresses: nil,
-			Scopes:    nil,
-		},
-		{
-			ID:        8,
-			Name:      "Laura",
-			Email:     "laura@email.com",
-			Password:  "mmmmmm",
-			Phone:     "320684398",
-			IsActive:  true,
-			CreatedAt: time.Time{},
-			Addresses: nil,
-			Scopes:    nil,
-		},
-		{
-			ID:        8,
-			Name:      "Laura",
-			Email:     "laura@email.com",
-			Password:  "22222",
-			Phone:     "5345636456",
-			IsActive:  true,
-			CreatedAt: time.Time{},
-			Addresses: nil,
-			Scopes:    nil,
-		},
-		{
-			ID:        9,
-			Name:      "Carolina",
-			Email:     "carolina@email.com",
-			Password:  "22222",
-			Phone:     "320684398",
-			IsActive:  true,
-			CreatedAt: time.Time{},
-			Addresses: nil,
-			Scopes:    nil,
-		},
-		{
-			ID:        10,
-			Name:      "Martin",
-			Email:     "martin@email.com",
-			Password:  "iriririr",
-			Phone:     "42512345234",
-			IsActive:  true,
-			CreatedAt: time.Time{},
-			Addresses: nil,
-			Scopes:    nil,
-		},
-	}
-	userMemoRepo := memoryrepo.NewMemoryUserRepository(userData)
-	mockJWTManager := infrastructure.NewMockJWTManager(userMemoRepo)
-
-	app.Use(common.Auth(mockJWTManager))
+	useSQL := !cfg.Database.IsMemory()
+	var sqlRepos sqlRepositories
+	if useSQL {
+		var err error
+		sqlRepos, err = newSQLRepositories(cfg.Database.Backend, cfg.Database.URL)
+		if errors.Is(err, migrations.SchemaBehind) {
+			fatal("apply the migrations with: go run ./cmd/migrate up", err)
+		}
+		if err != nil {
+			fatal("error opening database", err)
+		}
+		readiness.Add("database", health.CheckFunc(sqlRepos.db.PingContext))
+	} else {
+		readiness.Add("database", health.CheckFunc(func(ctx context.Context) error { return nil }))
+	}
+
+	memoUserRepo := memoryrepo.NewMemoryUserRepository(nil)
+	memoAddressRepo := memoryrepo.NewMemoryAddressRepository(make([]memoryrepo.MemoryAddress, 0))
+	memoAuditRepo := memoryrepo.NewMemoryAuditLogRepository(make([]users.AuditEvent, 0))
+	userRepos := userRepositories{
+		users:      memoUserRepo,
+		addresses:  memoAddressRepo,
+		audit:      memoAuditRepo,
+		unitOfWork: memoryrepo.NewMemoryUnitOfWork(memoUserRepo, memoAddressRepo, memoAuditRepo),
+	}
+	if useSQL {
+		userRepos = sqlRepos.users
+	}
+	userRepos = userRepos.traced()
+	var jwtManager ports.JWTManager = infrastructure.NewMockJWTManager(userRepos.users)
+	if cfg.JWT.Secret != "" {
+		jwtManager = infrastructure.NewHMACJWTManager(
+			userRepos.users, cfg.JWT.Secret, cfg.JWT.AccessTTL, cfg.JWT.RefreshTTL,
+		)
+	} else {
+		// config.Validate only allows it in dev with the memory backend
+		logger.Warn("JWT_SECRET is not set, using mock tokens")
+	}
+
+	app.Use(common.Auth(jwtManager))
 
 	apiV1Routes := app.Group("/api/v1")
 
-	addressMemoRepo := memoryrepo.NewMemoryAddressRepository(make([]memoryrepo.MemoryAddress, 0))
-	mockPassManager := infrastructure.NewMockPasswordManager()
-	mockVerifyCode := notifications.NewMockVerificationCodeManager()
+	var passManager ports.PasswordManager = infrastructure.NewBcryptPasswordManager(cfg.Passwords.BcryptCost)
+	if cfg.Passwords.Hasher == "mock" {
+		passManager = infrastructure.NewMockPasswordManager()
+		logger.Warn("using mock password hashes")
+	}
+	var verifyCode ports.VerificationCodeManager
+	if cfg.SMTP.Host != "" {
+		smtpVerifyCode := notifications.NewSMTPVerificationCodeManager(
+			cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From,
+		)
+		verifyCode = smtpVerifyCode
+		readiness.Add("mail", smtpVerifyCode)
+	} else {
+		mockVerifyCode := notifications.NewMockVerificationCodeManager()
+		verifyCode = mockVerifyCode
+		readiness.Add("mail", mockVerifyCode)
+	}
+	avatarStorage := storage.NewLocalAvatarStorage(
+		filepath.Join(cfg.Uploads.MediaDir, "avatars"), path.Join(cfg.Uploads.MediaURL, "avatars"),
+	)
+	readiness.Add("storage", avatarStorage)
+	app.Static(cfg.Uploads.MediaURL, cfg.Uploads.MediaDir)
 
 	userService := services.NewUserService(
-		userMemoRepo, addressMemoRepo, mockVerifyCode,
-		mockPassManager, mockJWTManager,
+		userRepos.users, userRepos.addresses, verifyCode,
+		passManager, jwtManager, avatarStorage, userRepos.audit,
+		userRepos.unitOfWork, appMetrics,
 	)
 
 	userHandler := handler.NewUserHandler(userService)
+	// the problems are written last, so the middlewares above see their status
+	apiV1Routes.Use(userHandler.AuditImpersonatedRequests(), i18n.Middleware(), common.Problems())
 	userHandler.AddRoutes(apiV1Routes)
 	// USERS [FIN]
 
@@ -194,17 +186,119 @@ func main() {
 	)
 	memoBrandRepo := memoryrepo2.NewMemoryPhoneBrandRepository(store)
 	memoCaseTypeRepo := memoryrepo2.NewMemoryCaseTypeRepository(store)
-	memoCaseRepo := memoryrepo2.NewMemoryPhoneCaseRepository(store, &memoBrandRepo, &memoCaseTypeRepo)
+	memoCaseRepo := memoryrepo2.NewMemoryPhoneCaseRepository(store)
+	productRepos := productRepositories{
+		cases:     &memoCaseRepo,
+		brands:    &memoBrandRepo,
+		caseTypes: &memoCaseTypeRepo,
+	}
+	if useSQL {
+		productRepos = sqlRepos.products
+	} else {
+		appMetrics.AddRepositorySize("users", memoUserRepo.Len)
+		appMetrics.AddRepositorySize("addresses", memoAddressRepo.Len)
+		appMetrics.AddRepositorySize("audit_events", memoAuditRepo.Len)
+		for table := range store.Sizes() {
+			table := table
+			appMetrics.AddRepositorySize(table, func() int { return store.Sizes()[table] })
+		}
+	}
+	productRepos = productRepos.traced()
 	phoneCaseService := services2.NewPhoneCaseService(
-		&memoCaseRepo, &memoBrandRepo, &memoCaseTypeRepo,
+		productRepos.cases, productRepos.brands, productRepos.caseTypes, appMetrics,
 	)
 
 	phoneCaseHandler := handler2.NewPhoneCaseHandler(phoneCaseService)
 	phoneCaseHandler.AddRoutes(apiV1Routes)
+	discountHandler := handler2.NewDiscountHandler(phoneCaseService)
+	discountHandler.AddRoutes(apiV1Routes)
+	caseTypeHandler := handler2.NewCaseTypeHandler(phoneCaseService)
+	caseTypeHandler.AddRoutes(apiV1Routes)
 	// PHONE CASES [FIN]
 
-	err := app.Run(":8000")
-	if err != nil {
-		log.Fatal("Server error", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// the snapshot is saved every interval and on shutdown
+	var snapshots *snapshot.Snapshotter
+	restored := false
+	if cfg.Snapshot.Dir != "" {
+		snapshots = snapshot.New(cfg.Snapshot.Dir)
+		// in the order the unit of work locks them
+		snapshots.Register("users", memoUserRepo)
+		snapshots.Register("addresses", memoAddressRepo)
+		snapshots.Register("audit", memoAuditRepo)
+		snapshots.Register("products", store)
+
+		var err error
+		restored, err = snapshots.Load()
+		if errors.Is(err, snapshot.Corrupted) {
+			fatal("restore a backup or remove the snapshot to start from the seed data", err)
+		}
+		if err != nil {
+			fatal("error loading snapshot", err)
+		}
+		if restored {
+			logger.Info("memory backend restored", "path", snapshots.Path())
+		}
+	}
+
+	if len(cfg.Fixtures) > 0 && !restored {
+		loader := fixtures.NewLoader(fixtures.Repositories{
+			Users:      userRepos.users,
+			Addresses:  userRepos.addresses,
+			PhoneCases: productRepos.cases,
+			Brands:     productRepos.brands,
+			CaseTypes:  productRepos.caseTypes,
+		}, passManager)
+		if err := loader.LoadFiles(ctx, cfg.Fixtures...); err != nil {
+			fatal("error loading fixtures", err)
+		}
 	}
+
+	server := &http.Server{Addr: cfg.HTTP.Addr, Handler: app, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("server error", err)
+		}
+	}()
+
+	// the last snapshot is saved after the requests are drained, so it has
+	// their changes
+	snapshotCtx, stopSnapshots := context.WithCancel(context.Background())
+	snapshotsDone := make(chan struct{})
+	go func() {
+		defer close(snapshotsDone)
+		if snapshots != nil {
+			snapshots.Run(snapshotCtx, cfg.Snapshot.Interval)
+		}
+	}()
+
+	<-ctx.Done()
+	// a second signal stops the process without waiting
+	stop()
+	logger.Info("shutting down, draining requests", "timeout", cfg.HTTP.ShutdownTimeout.String())
+	readiness.ShuttingDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("requests not drained", "error", err)
+	}
+
+	stopSnapshots()
+	<-snapshotsDone
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Warn("spans not flushed", "error", err)
+	}
+	if sqlRepos.db != nil {
+		sqlRepos.db.Close()
+	}
+	logger.Info("server stopped")
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"github.com/CrissAlvarezH/fundart-api/internal/migrations"
+	productports "github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	productsqlrepo "github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/sqlrepo"
+	producttracedrepo "github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/tracedrepo"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/sqlrepo"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/tracedrepo"
+)
+
+type userRepositories struct {
+	users     ports.UserRepository
+	addresses ports.AddressRepository
+	audit     ports.AuditLogRepository
+	// unitOfWork runs calls of the repositories above in one transaction
+	unitOfWork ports.UnitOfWork
+}
+
+// traced wraps every repository so its calls create spans
+func (r userRepositories) traced() userRepositories {
+	return userRepositories{
+		users:      tracedrepo.NewTracedUserRepository(r.users),
+		addresses:  tracedrepo.NewTracedAddressRepository(r.addresses),
+		audit:      tracedrepo.NewTracedAuditLogRepository(r.audit),
+		unitOfWork: tracedrepo.NewTracedUnitOfWork(r.unitOfWork),
+	}
+}
+
+type productRepositories struct {
+	cases     productports.PhoneCaseRepository
+	brands    productports.PhoneBrandRepository
+	caseTypes productports.CaseTypeRepository
+}
+
+func (r productRepositories) traced() productRepositories {
+	return productRepositories{
+		cases:     producttracedrepo.NewTracedPhoneCaseRepository(r.cases),
+		brands:    producttracedrepo.NewTracedPhoneBrandRepository(r.brands),
+		caseTypes: producttracedrepo.NewTracedCaseTypeRepository(r.caseTypes),
+	}
+}
+
+type sqlRepositories struct {
+	// db is shared by every repository, it's closed on shutdown
+	db       *sqldb.DB
+	users    userRepositories
+	products productRepositories
+}
+
+// newSQLRepositories opens the database of the backend ("postgres" or
+// "sqlite"), checks its schema is up to date and prepares every repository on it
+func newSQLRepositories(backend string, dsn string) (sqlRepositories, error) {
+	db, err := sqldb.Open(migrations.Dialect(backend), dsn)
+	if err != nil {
+		return sqlRepositories{}, err
+	}
+	migrator, err := migrations.NewMigrator(db.DB, db.Dialect.Name())
+	if err != nil {
+		return sqlRepositories{}, err
+	}
+	if err := migrator.Check(); err != nil {
+		return sqlRepositories{}, err
+	}
+
+	repos := sqlRepositories{db: db}
+	if repos.users, err = newSQLUserRepositories(db); err != nil {
+		return sqlRepositories{}, err
+	}
+	repos.products, err = newSQLProductRepositories(db)
+	return repos, err
+}
+
+func newSQLUserRepositories(db *sqldb.DB) (userRepositories, error) {
+	userRepo, err := sqlrepo.NewSQLUserRepository(db)
+	if err != nil {
+		return userRepositories{}, err
+	}
+	addressRepo, err := sqlrepo.NewSQLAddressRepository(db)
+	if err != nil {
+		return userRepositories{}, err
+	}
+	auditRepo, err := sqlrepo.NewSQLAuditLogRepository(db)
+	if err != nil {
+		return userRepositories{}, err
+	}
+
+	return userRepositories{
+		users:      userRepo,
+		addresses:  addressRepo,
+		audit:      auditRepo,
+		unitOfWork: sqlrepo.NewSQLUnitOfWork(db, userRepo, addressRepo, auditRepo),
+	}, nil
+}
+
+func newSQLProductRepositories(db *sqldb.DB) (productRepositories, error) {
+	caseRepo, err := productsqlrepo.NewSQLPhoneCaseRepository(db)
+	if err != nil {
+		return productRepositories{}, err
+	}
+	brandRepo, err := productsqlrepo.NewSQLPhoneBrandRepository(db)
+	if err != nil {
+		return productRepositories{}, err
+	}
+	caseTypeRepo, err := productsqlrepo.NewSQLCaseTypeRepository(db)
+	if err != nil {
+		return productRepositories{}, err
+	}
+
+	return productRepositories{cases: caseRepo, brands: brandRepo, caseTypes: caseTypeRepo}, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/CrissAlvarezH/fundart-api/internal/migrations"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const usage = `usage: migrate [flags] <command>
+
+commands:
+  up             apply every pending migration
+  down N         revert the last N applied migrations
+  status         list migrations and whether they are applied
+  create NAME    add empty up/down files for a new migration to every dialect
+
+flags:
+`
+
+func main() {
+	backend := flag.String("backend", os.Getenv("DATABASE_BACKEND"), "database backend, postgres or sqlite")
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "database url, or the file path for sqlite")
+	dir := flag.String("dir", "internal/migrations", "migrations directory used by create")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			log.Fatal("create needs the migration name")
+		}
+		created, err := migrations.Create(*dir, args[1])
+		for _, path := range created {
+			fmt.Println("created", path)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	migrator := newMigrator(*backend, *dsn)
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		if len(args) != 2 {
+			log.Fatal("down needs the number of migrations to revert")
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			log.Fatal("down needs a positive number of migrations to revert")
+		}
+		reverted, err := migrator.Down(n)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+		if err != nil {
+			log.Fatal(err)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func newMigrator(backend string, dsn string) *migrations.Migrator {
+	if backend != "postgres" && backend != "sqlite" {
+		log.Fatalf("unknown database backend %q, use -backend postgres or sqlite", backend)
+	}
+	db, err := sqldb.Open(migrations.Dialect(backend), dsn)
+	if err != nil {
+		log.Fatal("error opening database: ", err)
+	}
+
+	migrator, err := migrations.NewMigrator(db.DB, db.Dialect.Name())
+	if err != nil {
+		log.Fatal(err)
+	}
+	return migrator
+}
@@ -0,0 +1,87 @@
+package apperror
+
+import (
+	"errors"
+)
+
+// Kind groups the errors by the way the client must handle them, the http
+// layer maps each kind to a status
+type Kind string
+
+const (
+	Invalid              Kind = "invalid"
+	Unauthorized         Kind = "unauthorized"
+	Forbidden            Kind = "forbidden"
+	NotFound             Kind = "not_found"
+	Conflict             Kind = "conflict"
+	PreconditionFailed   Kind = "precondition_failed"
+	PreconditionRequired Kind = "precondition_required"
+	TooLarge             Kind = "too_large"
+	UnsupportedMedia     Kind = "unsupported_media"
+	Internal             Kind = "internal"
+)
+
+// ValidationFailed is the code of the Invalid errors with invalid fields
+const ValidationFailed = "validation_failed"
+
+// Error is an expected error with a stable Code the clients can rely on, the
+// Message is in English and the clients get it translated by the Code. The
+// errors are compared by identity, so the sentinels declared with New keep
+// working with errors.Is
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Fields are the invalid fields of the request, only for Invalid errors
+	Fields []FieldError
+}
+
+// FieldError is a field of the request that failed a rule, like required or
+// oneof, Param is the argument of the rule
+type FieldError struct {
+	Field   string
+	Rule    string
+	Param   string
+	Message string
+}
+
+func New(kind Kind, code string, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Validation is the error of a request with invalid fields
+func Validation(fields ...FieldError) *Error {
+	return &Error{
+		Kind: Invalid, Code: ValidationFailed, Message: "the request has invalid fields", Fields: fields,
+	}
+}
+
+func InvalidField(field string, rule string, message string) *Error {
+	return Validation(FieldError{Field: field, Rule: rule, Message: message})
+}
+
+// InvalidNumber is the error of a path parameter or query that must be a number
+func InvalidNumber(field string) *Error {
+	return InvalidField(field, "number", field+" is not a valid number")
+}
+
+// AsField reports err as an error of the field, for the errors of the domain
+// caused by a single field of the body, the code of err is the rule
+func AsField(field string, err error) *Error {
+	rule := "invalid"
+	if appErr, ok := From(err); ok {
+		rule = appErr.Code
+	}
+	return InvalidField(field, rule, err.Error())
+}
+
+// From returns the Error in the chain of err, ok is false for unexpected
+// errors
+func From(err error) (appErr *Error, ok bool) {
+	ok = errors.As(err, &appErr)
+	return appErr, ok
+}
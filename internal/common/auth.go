@@ -1,10 +1,10 @@
 package common
 
 import (
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
 	"github.com/gin-gonic/gin"
-	"net/http"
 	"strings"
 )
 
@@ -23,16 +23,21 @@ func Auth(jwt ports.JWTManager) gin.HandlerFunc {
 
 		token := authHeaderSplit[1]
 
-		user, err := jwt.Verify(token)
+		user, err := jwt.Verify(c.Request.Context(), token)
 		if err != nil {
 			c.Next()
 			return
 		}
 
 		c.Set("user", user)
+		withUserLogger(c, user)
 	}
 }
 
+// AuthenticationRequired is the error of the anonymous requests to the routes
+// that need a user
+var AuthenticationRequired = apperror.New(apperror.Unauthorized, "authentication_required", "authentication required")
+
 type ScopeValidatorFunc func(user users.User, isAnonymous bool, c *gin.Context) (bool, string)
 
 func extractUser(c *gin.Context) (users.User, bool) {
@@ -56,8 +61,7 @@ func ValidOr(functions ...ScopeValidatorFunc) gin.HandlerFunc {
 				return
 			}
 		}
-		c.JSON(http.StatusForbidden, gin.H{"error": validationMsg})
-		c.Abort()
+		Deny(c, !ok, validationMsg)
 	}
 }
 
@@ -67,10 +71,27 @@ func Valid(function ScopeValidatorFunc) gin.HandlerFunc {
 		result, msg := function(user, !ok, c)
 
 		if !result {
-			c.JSON(http.StatusForbidden, gin.H{"error": msg})
-			c.Abort()
+			Deny(c, !ok, msg)
 		}
 
 		c.Next()
 	}
 }
+
+// Deny aborts the request that failed a validator, anonymous users get a 401
+// so they know they have to log in and the others a 403
+func Deny(c *gin.Context, isAnonymous bool, msg string) {
+	if isAnonymous {
+		c.Error(AuthenticationRequired)
+		c.Abort()
+		return
+	}
+	Forbid(c, msg)
+}
+
+// Forbid aborts the request with a 403 problem, msg is the reason given by the
+// validator
+func Forbid(c *gin.Context, msg string) {
+	c.Error(apperror.New(apperror.Forbidden, "permission_denied", msg))
+	c.Abort()
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+)
+
+var (
+	MissingIfMatch = apperror.New(
+		apperror.PreconditionRequired, "missing_if_match", "If-Match header is required, send the ETag of the resource",
+	)
+	InvalidIfMatch = apperror.New(
+		apperror.Invalid, "invalid_if_match", "If-Match header must be a single ETag returned by the api or '*'",
+	)
+)
+
+// ETag is a strong validator made from the version of a resource
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func SetETag(c *gin.Context, version int) {
+	c.Header("ETag", ETag(version))
+}
+
+// IfMatch returns the version sent in the If-Match header, '*' returns 0 which
+// is the AnyVersion of the repositories. Weak ETags are rejected because
+// If-Match requires a strong comparison
+func IfMatch(c *gin.Context) (int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return 0, MissingIfMatch
+	}
+	if header == "*" {
+		return 0, nil
+	}
+
+	if len(header) < 3 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, InvalidIfMatch
+	}
+	version, err := strconv.Atoi(header[1 : len(header)-1])
+	if err != nil || version < 1 {
+		return 0, InvalidIfMatch
+	}
+	return version, nil
+}
+
+// RequireIfMatch adds the error of the header to the context, it's responded
+// as 428 when the header is missing and 400 when it's not valid, the handler
+// must return when ok is false
+func RequireIfMatch(c *gin.Context) (version int, ok bool) {
+	version, err := IfMatch(c)
+	if err != nil {
+		c.Error(err)
+		return 0, false
+	}
+	return version, true
+}
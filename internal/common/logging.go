@@ -0,0 +1,97 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/CrissAlvarezH/fundart-api/internal/logging"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	maxRequestIDLen = 128
+)
+
+// RequestID reuses the X-Request-ID sent by the client or a proxy so the logs
+// can be followed across services, otherwise a random one is created. The id
+// is sent back in the response and added to the logger of the request context
+func RequestID(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+
+		ctx := logging.WithLogger(c.Request.Context(), logger.With("request_id", id))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// validRequestID rejects ids that could break the log lines or fill them
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog logs every request once it's handled, it must run after RequestID.
+// The route is the template, like /api/v1/users/:id, so the lines can be
+// grouped by endpoint
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", c.Writer.Size(),
+			"ip", c.ClientIP(),
+		}
+		if user, ok := extractUser(c); ok {
+			attrs = append(attrs, "user_id", user.ID)
+			if user.ImpersonatorID != 0 {
+				attrs = append(attrs, "impersonator_id", user.ImpersonatorID)
+			}
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logging.FromContext(c.Request.Context()).Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// withUserLogger adds the authenticated user to the logger of the request
+func withUserLogger(c *gin.Context, user users.User) {
+	args := []any{"user_id", user.ID}
+	if user.ImpersonatorID != 0 {
+		args = append(args, "impersonator_id", user.ImpersonatorID)
+	}
+	c.Request = c.Request.WithContext(logging.With(c.Request.Context(), args...))
+}
@@ -0,0 +1,127 @@
+package common_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/CrissAlvarezH/fundart-api/internal/common"
+	"github.com/CrissAlvarezH/fundart-api/internal/logging"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeJWT only verifies, the other methods are not used by Auth
+type fakeJWT struct {
+	ports.JWTManager
+}
+
+func (fakeJWT) Verify(ctx context.Context, token string) (users.User, error) {
+	return users.User{ID: 7, ImpersonatorID: 3}, nil
+}
+
+func serveLogged(header string) (*httptest.ResponseRecorder, []map[string]any) {
+	var out bytes.Buffer
+	router := gin.New()
+	router.Use(common.RequestID(logging.New(&out, slog.LevelInfo)), common.AccessLog())
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			c.Set("user", users.User{ID: 7, ImpersonatorID: 3})
+		}
+	})
+	router.GET("/users/:id", func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Info("from the service")
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/users/7", nil)
+	if header != "" {
+		req.Header.Set(common.RequestIDHeader, header)
+	}
+	req.Header.Set("Authorization", "Bearer token")
+	router.ServeHTTP(w, req)
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var entry map[string]any
+		json.Unmarshal([]byte(line), &entry)
+		lines = append(lines, entry)
+	}
+	return w, lines
+}
+
+func TestRequestID(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		reused bool
+	}{
+		{"sent by the client", "abc-123", true},
+		{"missing", "", false},
+		{"with spaces", "abc 123", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w, lines := serveLogged(c.header)
+			id := w.Header().Get(common.RequestIDHeader)
+			if c.reused && id != c.header {
+				t.Errorf("expected the request id %q, got %q", c.header, id)
+			}
+			if !c.reused && (id == "" || id == c.header) {
+				t.Errorf("expected a new request id, got %q", id)
+			}
+			for _, line := range lines {
+				if line["request_id"] != id {
+					t.Errorf("expected every line with the request id %q, got %v", id, line)
+				}
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	_, lines := serveLogged("abc-123")
+	if len(lines) != 2 || lines[0]["msg"] != "from the service" {
+		t.Fatalf("expected the handler line and the access line, got %v", lines)
+	}
+
+	access := lines[1]
+	expected := map[string]any{
+		"msg": "request", "method": "GET", "route": "/users/:id", "status": float64(http.StatusNoContent),
+		"user_id": float64(7), "impersonator_id": float64(3),
+	}
+	for key, value := range expected {
+		if access[key] != value {
+			t.Errorf("expected %s %v, got %v", key, value, access[key])
+		}
+	}
+	if _, ok := access["latency_ms"]; !ok {
+		t.Errorf("expected the latency, got %v", access)
+	}
+}
+
+func TestAuth_AddsUserToLogger(t *testing.T) {
+	var out bytes.Buffer
+	router := gin.New()
+	router.Use(common.RequestID(logging.New(&out, slog.LevelInfo)), common.Auth(fakeJWT{}))
+	router.GET("/me", func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Info("from the service")
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]any
+	json.Unmarshal(out.Bytes(), &line)
+	if line["user_id"] != float64(7) || line["impersonator_id"] != float64(3) {
+		t.Errorf("expected the user in the service logs, got %v", line)
+	}
+}
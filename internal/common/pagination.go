@@ -1,7 +1,7 @@
 package common
 
 import (
-	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
 	"github.com/gin-gonic/gin"
 	"math"
 	"net/url"
@@ -16,6 +16,8 @@ type PaginationData struct {
 	Offset   int
 }
 
+// GetPaginationParams fails with a validation error when page or page_size
+// aren't valid
 func GetPaginationParams(query url.Values) (PaginationData, error) {
 	pageSize := 10
 	if query.Has("page_size") {
@@ -23,10 +25,12 @@ func GetPaginationParams(query url.Values) (PaginationData, error) {
 		pageSize, err = strconv.Atoi(query.Get("page_size"))
 		query.Del("page_size")
 		if err != nil {
-			return PaginationData{}, errors.New("error to get 'page_size' query")
+			return PaginationData{}, apperror.InvalidNumber("page_size")
 		}
 		if pageSize < 1 {
-			return PaginationData{}, errors.New("'page_size' can't be lower than 1")
+			return PaginationData{}, apperror.Validation(apperror.FieldError{
+				Field: "page_size", Rule: "min", Param: "1", Message: "'page_size' can't be lower than 1",
+			})
 		}
 	}
 
@@ -36,10 +40,12 @@ func GetPaginationParams(query url.Values) (PaginationData, error) {
 		page, err = strconv.Atoi(query.Get("page"))
 		query.Del("page")
 		if err != nil {
-			return PaginationData{}, errors.New("error to get 'page' query")
+			return PaginationData{}, apperror.InvalidNumber("page")
 		}
 		if page < 1 {
-			return PaginationData{}, errors.New("'page' can't be lower than 1")
+			return PaginationData{}, apperror.Validation(apperror.FieldError{
+				Field: "page", Rule: "min", Param: "1", Message: "'page' can't be lower than 1",
+			})
 		}
 	}
 
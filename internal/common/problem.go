@@ -0,0 +1,124 @@
+package common
+
+import (
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
+	"github.com/CrissAlvarezH/fundart-api/internal/i18n"
+	"github.com/CrissAlvarezH/fundart-api/internal/logging"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 body of the error responses, Code is stable so the
+// clients can handle the errors without parsing Detail
+type Problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail"`
+	Instance  string         `json:"instance"`
+	Code      string         `json:"code"`
+	TraceID   string         `json:"trace_id,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Errors    []FieldProblem `json:"errors,omitempty"`
+}
+
+type FieldProblem struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Param  string `json:"param,omitempty"`
+	Detail string `json:"detail"`
+}
+
+var statuses = map[apperror.Kind]int{
+	apperror.Invalid:              http.StatusBadRequest,
+	apperror.Unauthorized:         http.StatusUnauthorized,
+	apperror.Forbidden:            http.StatusForbidden,
+	apperror.NotFound:             http.StatusNotFound,
+	apperror.Conflict:             http.StatusConflict,
+	apperror.PreconditionFailed:   http.StatusPreconditionFailed,
+	apperror.PreconditionRequired: http.StatusPreconditionRequired,
+	apperror.TooLarge:             http.StatusRequestEntityTooLarge,
+	apperror.UnsupportedMedia:     http.StatusUnsupportedMediaType,
+	apperror.Internal:             http.StatusInternalServerError,
+}
+
+var internalError = apperror.New(apperror.Internal, "internal_error", "unexpected error, try again later")
+
+func init() {
+	// the validation errors name the fields as they are sent in the body
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonName)
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" || name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// Problems responds the last error added with c.Error as a problem in the
+// language of the request, the handlers add the error and return. The errors
+// of the binding must have the gin.ErrorTypeBind type. Unexpected errors are
+// logged and hidden behind a generic 500, it must be registered after the
+// middlewares that read the status of the response
+func Problems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		p := NewProblem(c, c.Errors.Last())
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(p.Status, p)
+	}
+}
+
+func NewProblem(c *gin.Context, ginErr *gin.Error) Problem {
+	err := problemError(c, ginErr)
+
+	status, ok := statuses[err.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	p := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    err.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      err.Code,
+		TraceID:   tracing.TraceID(c.Request.Context()),
+		RequestID: c.GetString("request_id"),
+	}
+	for _, f := range err.Fields {
+		p.Errors = append(p.Errors, FieldProblem{Field: f.Field, Code: f.Rule, Param: f.Param, Detail: f.Message})
+	}
+	return p
+}
+
+// problemError returns the error localized to the language of the request
+func problemError(c *gin.Context, ginErr *gin.Error) *apperror.Error {
+	trans := i18n.Translator(c)
+	if ginErr.IsType(gin.ErrorTypeBind) {
+		if validationErrs, ok := ginErr.Err.(validator.ValidationErrors); ok {
+			return i18n.ValidationError(trans, validationErrs)
+		}
+		return i18n.Localize(trans, apperror.New(apperror.Invalid, "invalid_body", ginErr.Err.Error()))
+	}
+	if err, ok := apperror.From(ginErr.Err); ok {
+		return i18n.Localize(trans, err)
+	}
+	logging.FromContext(c.Request.Context()).Error("unexpected error", "error", ginErr.Err)
+	return i18n.Localize(trans, internalError)
+}
@@ -0,0 +1,155 @@
+package common_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
+	"github.com/CrissAlvarezH/fundart-api/internal/common"
+	"github.com/CrissAlvarezH/fundart-api/internal/logging"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var bookNotFound = apperror.New(apperror.NotFound, "book_not_found", "book does not exists")
+
+type bookDTO struct {
+	Title  string   `json:"title" binding:"required"`
+	Pages  int      `json:"pages" binding:"gte=1"`
+	Format string   `json:"format" binding:"oneof=pdf epub"`
+	Tags   []string `json:"tags" binding:"min=1"`
+}
+
+func serveProblem(handler gin.HandlerFunc, body string) (*httptest.ResponseRecorder, common.Problem, string) {
+	var out bytes.Buffer
+	router := gin.New()
+	router.Use(common.RequestID(logging.New(&out, slog.LevelInfo)), common.Problems())
+	router.POST("/books/:id", handler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/books/7", strings.NewReader(body))
+	req.Header.Set(common.RequestIDHeader, "abc-123")
+	router.ServeHTTP(w, req)
+
+	var problem common.Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	return w, problem, out.String()
+}
+
+func bindBook(c *gin.Context) {
+	var body bookDTO
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func TestProblems_AppError(t *testing.T) {
+	w, problem, _ := serveProblem(func(c *gin.Context) {
+		c.Error(fmt.Errorf("loading book: %w", bookNotFound))
+	}, "")
+
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != common.ProblemContentType {
+		t.Fatalf("expected a 404 problem, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	expected := common.Problem{
+		Type: "about:blank", Title: "Not Found", Status: http.StatusNotFound, Detail: "book does not exists",
+		Instance: "/books/7", Code: "book_not_found", RequestID: "abc-123",
+	}
+	if problem.Type != expected.Type || problem.Title != expected.Title || problem.Status != expected.Status ||
+		problem.Detail != expected.Detail || problem.Instance != expected.Instance ||
+		problem.Code != expected.Code || problem.RequestID != expected.RequestID {
+		t.Errorf("expected %+v, got %+v", expected, problem)
+	}
+}
+
+func TestProblems_ValidationErrors(t *testing.T) {
+	w, problem, _ := serveProblem(bindBook, `{"pages": 0, "format": "doc", "tags": []}`)
+
+	if w.Code != http.StatusBadRequest || problem.Code != apperror.ValidationFailed {
+		t.Fatalf("expected a validation problem, got %d %+v", w.Code, problem)
+	}
+	expected := map[string]common.FieldProblem{
+		"title":  {Field: "title", Code: "required"},
+		"pages":  {Field: "pages", Code: "gte", Param: "1"},
+		"format": {Field: "format", Code: "oneof", Param: "pdf epub"},
+		"tags":   {Field: "tags", Code: "min", Param: "1"},
+	}
+	if len(problem.Errors) != len(expected) {
+		t.Fatalf("expected %d field errors, got %+v", len(expected), problem.Errors)
+	}
+	for _, f := range problem.Errors {
+		e, ok := expected[f.Field]
+		if !ok || f.Code != e.Code || f.Param != e.Param || f.Detail == "" {
+			t.Errorf("unexpected field error %+v", f)
+		}
+	}
+}
+
+func TestProblems_InvalidBody(t *testing.T) {
+	w, problem, _ := serveProblem(bindBook, `{"title": `)
+
+	if w.Code != http.StatusBadRequest || problem.Code != "invalid_body" || len(problem.Errors) != 0 {
+		t.Errorf("expected an invalid body problem, got %d %+v", w.Code, problem)
+	}
+}
+
+func TestProblems_UnexpectedError(t *testing.T) {
+	w, problem, logs := serveProblem(func(c *gin.Context) {
+		c.Error(errors.New("connection refused"))
+	}, "")
+
+	if w.Code != http.StatusInternalServerError || problem.Code != "internal_error" {
+		t.Fatalf("expected an internal error problem, got %d %+v", w.Code, problem)
+	}
+	if strings.Contains(problem.Detail, "connection refused") {
+		t.Errorf("the detail of unexpected errors must be hidden, got %q", problem.Detail)
+	}
+	if !strings.Contains(logs, "connection refused") {
+		t.Errorf("expected the error in the logs, got %s", logs)
+	}
+}
+
+func TestProblems_KeepsWrittenResponses(t *testing.T) {
+	w, _, _ := serveProblem(func(c *gin.Context) {
+		c.Error(bookNotFound)
+		c.String(http.StatusAccepted, "already written")
+	}, "")
+
+	if w.Code != http.StatusAccepted || w.Body.String() != "already written" {
+		t.Errorf("expected the response of the handler, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestProblems_Localized(t *testing.T) {
+	router := gin.New()
+	router.Use(common.Problems())
+	router.GET("/users/:id", func(c *gin.Context) {
+		c.Error(ports.UserDoesNotExists)
+	})
+
+	cases := map[string]string{
+		"":               "el usuario no existe",
+		"es-CO":          "el usuario no existe",
+		"en-US,en;q=0.9": "user does not exists",
+	}
+	for header, expected := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/users/7", nil)
+		req.Header.Set("Accept-Language", header)
+		router.ServeHTTP(w, req)
+
+		var problem common.Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		if problem.Detail != expected || problem.Code != "user_not_found" {
+			t.Errorf("%q: expected %q, got %+v", header, expected, problem)
+		}
+	}
+}
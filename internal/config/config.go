@@ -0,0 +1,454 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const redacted = "[redacted]"
+
+type Config struct {
+	// Env is dev or prod, the mock tokens and passwords are only allowed in dev
+	Env      string         `yaml:"env"`
+	HTTP     HTTPConfig     `yaml:"http"`
+	Database DatabaseConfig `yaml:"database"`
+	Snapshot SnapshotConfig `yaml:"snapshot"`
+	// Fixtures are yaml or json files loaded on an empty backend, the memory
+	// backend loads fixtures/dev.yaml when none is set
+	Fixtures  []string       `yaml:"fixtures"`
+	JWT       JWTConfig      `yaml:"jwt"`
+	Passwords PasswordConfig `yaml:"passwords"`
+	SMTP      SMTPConfig     `yaml:"smtp"`
+	Uploads   UploadConfig   `yaml:"uploads"`
+	Log       LogConfig      `yaml:"log"`
+	Tracing   TracingConfig  `yaml:"tracing"`
+}
+
+type HTTPConfig struct {
+	Addr string `yaml:"addr"`
+	// CORSOrigins are the origins allowed to call the api from a browser, "*"
+	// allows any origin
+	CORSOrigins []string `yaml:"cors_origins"`
+	// ShutdownTimeout is how long the in-flight requests are waited for on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type DatabaseConfig struct {
+	// Backend is memory, postgres or sqlite
+	Backend string `yaml:"backend"`
+	// URL is the postgres url, or the path of the database file for sqlite
+	URL string `yaml:"url"`
+}
+
+func (d DatabaseConfig) IsMemory() bool {
+	return d.Backend == "memory"
+}
+
+// SnapshotConfig keeps the memory backend across restarts when Dir is set
+type SnapshotConfig struct {
+	Dir      string        `yaml:"dir"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// JWTConfig uses mock tokens when Secret is empty, only in dev with the memory
+// backend
+type JWTConfig struct {
+	Secret     string        `yaml:"secret"`
+	AccessTTL  time.Duration `yaml:"access_ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+}
+
+// PasswordConfig selects how the passwords are hashed, the mock hasher keeps
+// them readable and is only allowed in dev with the memory backend
+type PasswordConfig struct {
+	// Hasher is bcrypt or mock
+	Hasher     string `yaml:"hasher"`
+	BcryptCost int    `yaml:"bcrypt_cost"`
+}
+
+// SMTPConfig logs the emails instead of sending them when Host is empty
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+type UploadConfig struct {
+	// MediaDir is served on MediaURL, the avatars are stored inside it
+	MediaDir string `yaml:"media_dir"`
+	MediaURL string `yaml:"media_url"`
+	// ImagesDir receives the images of the upload endpoint
+	ImagesDir string `yaml:"images_dir"`
+}
+
+// LogConfig sets the minimum level of the json logs, debug, info, warn or error
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+// SlogLevel is info when the level is not valid, Validate reports it
+func (l LogConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// TracingConfig sends the spans to an otlp http collector or to stdout, with
+// none the trace ids are still added to the logs
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the otlp collector url, like http://localhost:4318
+	Endpoint string `yaml:"endpoint"`
+	// SampleRatio is the fraction of the new traces that are kept, from 0 to 1
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+func Default() Config {
+	return Config{
+		Env:       "dev",
+		HTTP:      HTTPConfig{Addr: ":8000", ShutdownTimeout: 15 * time.Second},
+		Database:  DatabaseConfig{Backend: "memory"},
+		Snapshot:  SnapshotConfig{Interval: time.Minute},
+		JWT:       JWTConfig{AccessTTL: 15 * time.Minute, RefreshTTL: 7 * 24 * time.Hour},
+		Passwords: PasswordConfig{Hasher: "bcrypt", BcryptCost: 10},
+		SMTP:      SMTPConfig{Port: 587},
+		Uploads:   UploadConfig{MediaDir: "./media", MediaURL: "/media", ImagesDir: "./images/"},
+		Log:       LogConfig{Level: "info"},
+		Tracing:   TracingConfig{Exporter: "none", SampleRatio: 1},
+	}
+}
+
+// Load reads the config from the defaults, then the yaml file, then the env
+// vars and last the flags, each source overrides the previous ones. The file
+// is given with the -config flag or the CONFIG_FILE env var
+func Load(args []string, getenv func(string) string) (Config, error) {
+	c := Default()
+
+	path := getenv("CONFIG_FILE")
+	if fromArgs, ok := configFlag(args); ok {
+		path = fromArgs
+	}
+	if path != "" {
+		if err := c.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range c.settings() {
+		value := getenv(s.env)
+		if value == "" {
+			continue
+		}
+		if err := s.value.Set(value); err != nil {
+			return Config{}, fmt.Errorf("invalid %s %q: %w", s.env, value, err)
+		}
+	}
+
+	flags := flag.NewFlagSet("api", flag.ContinueOnError)
+	flags.String("config", path, "yaml file with the config, env vars and flags override it")
+	for _, s := range c.settings() {
+		value := s.value
+		if s.redact != nil {
+			// the usage shows the current values as defaults
+			value = &redactedValue{Value: s.value, redact: s.redact}
+		}
+		flags.Var(value, s.flag, s.usage+" (env "+s.env+")")
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if c.Database.IsMemory() && len(c.Fixtures) == 0 {
+		c.Fixtures = []string{"fixtures/dev.yaml"}
+	}
+	return c, c.Validate()
+}
+
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	defer file.Close()
+
+	// unknown keys are rejected so a typo doesn't silently keep the default
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// configFlag finds the -config flag before the flags are parsed, the file must
+// be loaded first so the other flags can override it
+func configFlag(args []string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// Validate returns every invalid setting at once
+func (c Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Env != "dev" && c.Env != "prod" {
+		add("unknown env %q, use dev or prod", c.Env)
+	}
+	// the mocks are only for local runs, a sql database would keep their
+	// readable passwords and accept their forgeable tokens
+	allowMocks := c.Env == "dev" && c.Database.IsMemory()
+
+	if c.HTTP.Addr == "" {
+		add("http addr is required")
+	}
+	if c.HTTP.ShutdownTimeout <= 0 {
+		add("shutdown timeout must be positive")
+	}
+	for _, origin := range c.HTTP.CORSOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			add("cors origin %q must be '*' or a scheme and host like https://fundart.com", origin)
+		}
+	}
+
+	switch c.Database.Backend {
+	case "memory":
+	case "postgres", "sqlite":
+		if c.Database.URL == "" {
+			add("database url is required for the %s backend", c.Database.Backend)
+		}
+		if c.Snapshot.Dir != "" {
+			add("snapshot dir only works with the memory backend")
+		}
+	default:
+		add("unknown database backend %q, use memory, postgres or sqlite", c.Database.Backend)
+	}
+	if c.Snapshot.Interval <= 0 {
+		add("snapshot interval must be positive")
+	}
+
+	if c.JWT.Secret == "" && !allowMocks {
+		add("jwt secret is required, mock tokens are only allowed in dev with the memory backend")
+	}
+	if c.JWT.Secret != "" && len(c.JWT.Secret) < 32 {
+		add("jwt secret must have at least 32 characters")
+	}
+	if c.JWT.AccessTTL <= 0 || c.JWT.RefreshTTL <= 0 {
+		add("jwt ttls must be positive")
+	}
+
+	switch c.Passwords.Hasher {
+	case "bcrypt":
+		// the range accepted by bcrypt
+		if c.Passwords.BcryptCost < 4 || c.Passwords.BcryptCost > 31 {
+			add("bcrypt cost must be between 4 and 31")
+		}
+	case "mock":
+		if !allowMocks {
+			add("mock password hasher is only allowed in dev with the memory backend")
+		}
+	default:
+		add("unknown password hasher %q, use bcrypt or mock", c.Passwords.Hasher)
+	}
+
+	if c.SMTP.Host != "" {
+		if c.SMTP.Port < 1 || c.SMTP.Port > 65535 {
+			add("smtp port %d is not valid", c.SMTP.Port)
+		}
+		if c.SMTP.From == "" {
+			add("smtp from is required to send emails")
+		}
+	}
+
+	if c.Uploads.MediaDir == "" || c.Uploads.MediaURL == "" || c.Uploads.ImagesDir == "" {
+		add("upload paths are required")
+	}
+	if c.Uploads.MediaURL != "" && !strings.HasPrefix(c.Uploads.MediaURL, "/") {
+		add("media url must start with '/'")
+	}
+
+	if err := new(slog.Level).UnmarshalText([]byte(c.Log.Level)); err != nil {
+		add("log level %q must be debug, info, warn or error", c.Log.Level)
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		u, err := url.Parse(c.Tracing.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("tracing endpoint %q must be an http url like http://localhost:4318", c.Tracing.Endpoint)
+		}
+	default:
+		add("unknown tracing exporter %q, use none, stdout or otlp", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing sample ratio must be between 0 and 1")
+	}
+
+	return errors.Join(errs...)
+}
+
+// String lists every setting with its env var, the secrets are redacted so the
+// config can be logged
+func (c Config) String() string {
+	var b strings.Builder
+	for _, s := range c.settings() {
+		value := s.value.String()
+		if s.redact != nil {
+			value = s.redact(value)
+		}
+		fmt.Fprintf(&b, "%s=%s\n", s.env, value)
+	}
+	return b.String()
+}
+
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	value flag.Value
+	// redact hides the secret parts of the value when the config is logged
+	redact func(string) string
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"APP_ENV", "env", "dev or prod", (*stringValue)(&c.Env), nil},
+		{"HTTP_ADDR", "addr", "address the server listens on", (*stringValue)(&c.HTTP.Addr), nil},
+		{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "time to drain the requests on shutdown", (*durationValue)(&c.HTTP.ShutdownTimeout), nil},
+		{"CORS_ORIGINS", "cors-origins", "comma separated origins allowed by CORS", (*listValue)(&c.HTTP.CORSOrigins), nil},
+		{"DATABASE_BACKEND", "database-backend", "memory, postgres or sqlite", (*stringValue)(&c.Database.Backend), nil},
+		{"DATABASE_URL", "database-url", "database url, or the file path for sqlite", (*stringValue)(&c.Database.URL), redactURL},
+		{"SNAPSHOT_DIR", "snapshot-dir", "directory of the memory backend snapshot", (*stringValue)(&c.Snapshot.Dir), nil},
+		{"SNAPSHOT_INTERVAL", "snapshot-interval", "time between snapshots", (*durationValue)(&c.Snapshot.Interval), nil},
+		{"FIXTURES", "fixtures", "comma separated fixture files", (*listValue)(&c.Fixtures), nil},
+		{"JWT_SECRET", "jwt-secret", "key that signs the tokens", (*stringValue)(&c.JWT.Secret), redactSecret},
+		{"JWT_ACCESS_TTL", "jwt-access-ttl", "lifetime of the access tokens", (*durationValue)(&c.JWT.AccessTTL), nil},
+		{"JWT_REFRESH_TTL", "jwt-refresh-ttl", "lifetime of the refresh tokens", (*durationValue)(&c.JWT.RefreshTTL), nil},
+		{"PASSWORD_HASHER", "password-hasher", "bcrypt or mock", (*stringValue)(&c.Passwords.Hasher), nil},
+		{"BCRYPT_COST", "bcrypt-cost", "cost of the bcrypt password hashes", (*intValue)(&c.Passwords.BcryptCost), nil},
+		{"SMTP_HOST", "smtp-host", "smtp server, emails are logged when empty", (*stringValue)(&c.SMTP.Host), nil},
+		{"SMTP_PORT", "smtp-port", "smtp server port", (*intValue)(&c.SMTP.Port), nil},
+		{"SMTP_USERNAME", "smtp-username", "smtp user", (*stringValue)(&c.SMTP.Username), nil},
+		{"SMTP_PASSWORD", "smtp-password", "smtp password", (*stringValue)(&c.SMTP.Password), redactSecret},
+		{"SMTP_FROM", "smtp-from", "sender address of the emails", (*stringValue)(&c.SMTP.From), nil},
+		{"MEDIA_DIR", "media-dir", "directory of the uploaded media", (*stringValue)(&c.Uploads.MediaDir), nil},
+		{"MEDIA_URL", "media-url", "url path the media is served on", (*stringValue)(&c.Uploads.MediaURL), nil},
+		{"IMAGES_DIR", "images-dir", "directory of the uploaded images", (*stringValue)(&c.Uploads.ImagesDir), nil},
+		{"LOG_LEVEL", "log-level", "debug, info, warn or error", (*stringValue)(&c.Log.Level), nil},
+		{"TRACING_EXPORTER", "tracing-exporter", "none, stdout or otlp", (*stringValue)(&c.Tracing.Exporter), nil},
+		{"OTLP_ENDPOINT", "otlp-endpoint", "url of the otlp http collector", (*stringValue)(&c.Tracing.Endpoint), nil},
+		{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "fraction of the new traces that are kept", (*floatValue)(&c.Tracing.SampleRatio), nil},
+	}
+}
+
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL only hides the password, the rest of the url helps to debug
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return redacted
+	}
+	if _, ok := u.User.Password(); !ok {
+		return value
+	}
+	return u.Redacted()
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string     { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	i, err := strconv.Atoi(s)
+	*v = intValue(i)
+	return err
+}
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	*v = floatValue(f)
+	return err
+}
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	*v = durationValue(d)
+	return err
+}
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+// listValue is a comma separated list, empty items are dropped
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	*v = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*v = append(*v, item)
+		}
+	}
+	return nil
+}
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+
+type redactedValue struct {
+	flag.Value
+	redact func(string) string
+}
+
+func (v *redactedValue) String() string {
+	if v == nil || v.Value == nil {
+		return ""
+	}
+	return v.redact(v.Value.String())
+}
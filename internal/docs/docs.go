@@ -0,0 +1,46 @@
+package docs
+
+import (
+	_ "embed"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Spec is the OpenAPI document of the routes of the user and phone case
+// handlers, a test checks every registered route is in it
+//
+//go:embed openapi.yaml
+var Spec []byte
+
+const SpecPath = "/openapi.yaml"
+
+// the swagger ui assets are loaded from a cdn, so they aren't vendored here
+const uiPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Fundart API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "` + SpecPath + `", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>`
+
+// AddRoutes serves the document on /openapi.yaml and the Swagger UI on /docs
+func AddRoutes(g gin.IRoutes) {
+	g.GET(SpecPath, ServeSpec)
+	g.GET("/docs", ServeUI)
+}
+
+func ServeSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/yaml", Spec)
+}
+
+func ServeUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(uiPage))
+}
@@ -0,0 +1,180 @@
+package docs_test
+
+import (
+	"github.com/CrissAlvarezH/fundart-api/internal/docs"
+	productservices "github.com/CrissAlvarezH/fundart-api/internal/products/application/services"
+	producthandler "github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/handler"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/services"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/handler"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+type operation struct {
+	Parameters []map[string]any `yaml:"parameters"`
+}
+
+type spec struct {
+	Servers []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	Paths      map[string]map[string]operation `yaml:"paths"`
+	Components struct {
+		Parameters map[string]map[string]any `yaml:"parameters"`
+	} `yaml:"components"`
+}
+
+func loadSpec(t *testing.T) spec {
+	t.Helper()
+	var s spec
+	if err := yaml.Unmarshal(docs.Spec, &s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Servers) != 1 {
+		t.Fatalf("expected one server, got %d", len(s.Servers))
+	}
+	return s
+}
+
+// routes returns the routes of the handlers documented by the spec as
+// "METHOD /path/{param}", relative to the server url
+func routes(base string) map[string]bool {
+	router := gin.New()
+	g := router.Group(base)
+	userHandler := handler.NewUserHandler(services.UserService{})
+	userHandler.AddRoutes(g)
+	phoneCaseHandler := producthandler.NewPhoneCaseHandler(productservices.PhoneCaseService{})
+	phoneCaseHandler.AddRoutes(g)
+	discountHandler := producthandler.NewDiscountHandler(productservices.PhoneCaseService{})
+	discountHandler.AddRoutes(g)
+	caseTypeHandler := producthandler.NewCaseTypeHandler(productservices.PhoneCaseService{})
+	caseTypeHandler.AddRoutes(g)
+
+	result := make(map[string]bool)
+	for _, r := range router.Routes() {
+		path := pathParam.ReplaceAllString(strings.TrimPrefix(r.Path, base), "{$1}")
+		result[r.Method+" "+path] = true
+	}
+	return result
+}
+
+func TestSpec_CoversEveryRoute(t *testing.T) {
+	s := loadSpec(t)
+	registered := routes(s.Servers[0].URL)
+
+	documented := make(map[string]bool)
+	for path, operations := range s.Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	for route := range registered {
+		if !documented[route] {
+			t.Errorf("route %s is missing from the spec", route)
+		}
+	}
+	for route := range documented {
+		if !registered[route] {
+			t.Errorf("the spec documents %s but it's not registered", route)
+		}
+	}
+}
+
+func TestSpec_DeclaresPathParameters(t *testing.T) {
+	s := loadSpec(t)
+	braces := regexp.MustCompile(`\{(\w+)\}`)
+
+	for path, operations := range s.Paths {
+		for method, op := range operations {
+			declared := make(map[string]bool)
+			for _, p := range op.Parameters {
+				if ref, ok := p["$ref"].(string); ok {
+					p = s.Components.Parameters[strings.TrimPrefix(ref, "#/components/parameters/")]
+				}
+				if p["in"] == "path" {
+					declared[p["name"].(string)] = true
+				}
+			}
+			for _, m := range braces.FindAllStringSubmatch(path, -1) {
+				if !declared[m[1]] {
+					t.Errorf("%s %s doesn't declare the path parameter %s", method, path, m[1])
+				}
+			}
+		}
+	}
+}
+
+func TestSpec_RefsResolve(t *testing.T) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(docs.Spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var walk func(node any)
+	walk = func(node any) {
+		switch n := node.(type) {
+		case map[string]any:
+			if ref, ok := n["$ref"].(string); ok && !resolves(doc, ref) {
+				t.Errorf("$ref %s does not resolve", ref)
+			}
+			for _, v := range n {
+				walk(v)
+			}
+		case []any:
+			for _, v := range n {
+				walk(v)
+			}
+		}
+	}
+	walk(doc)
+}
+
+func resolves(doc map[string]any, ref string) bool {
+	var node any = doc
+	for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return false
+		}
+		if node, ok = m[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAddRoutes(t *testing.T) {
+	router := gin.New()
+	docs.AddRoutes(router)
+
+	cases := []struct {
+		path        string
+		contentType string
+		contains    string
+	}{
+		{docs.SpecPath, "application/yaml", "openapi: 3"},
+		{"/docs", "text/html", `url: "` + docs.SpecPath + `"`},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, c.path, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), c.contentType) {
+				t.Errorf("unexpected response %d %s", w.Code, w.Header().Get("Content-Type"))
+			}
+			if !strings.Contains(w.Body.String(), c.contains) {
+				t.Errorf("expected %q in the body", c.contains)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	UnknownFormat    = errors.New("unknown fixtures format, use .yaml, .yml or .json")
+	UnknownReference = errors.New("fixture references an unknown record")
+)
+
+// Fixtures rows reference each other by natural key instead of ID: users by
+// email, brand references by brand and name, case types and discounts by name
+type Fixtures struct {
+	Users      []User      `yaml:"users" json:"users"`
+	Brands     []Brand     `yaml:"brands" json:"brands"`
+	CaseTypes  []CaseType  `yaml:"case_types" json:"case_types"`
+	Discounts  []Discount  `yaml:"discounts" json:"discounts"`
+	PhoneCases []PhoneCase `yaml:"phone_cases" json:"phone_cases"`
+}
+
+// User is active unless is_active is false
+type User struct {
+	Name      string    `yaml:"name" json:"name"`
+	Email     string    `yaml:"email" json:"email"`
+	Password  string    `yaml:"password" json:"password"`
+	Phone     string    `yaml:"phone" json:"phone"`
+	IsActive  *bool     `yaml:"is_active" json:"is_active"`
+	Scopes    []string  `yaml:"scopes" json:"scopes"`
+	Addresses []Address `yaml:"addresses" json:"addresses"`
+}
+
+type Address struct {
+	Department    string `yaml:"department" json:"department"`
+	City          string `yaml:"city" json:"city"`
+	Address       string `yaml:"address" json:"address"`
+	ReceiverPhone string `yaml:"receiver_phone" json:"receiver_phone"`
+	ReceiverName  string `yaml:"receiver_name" json:"receiver_name"`
+	IsDefault     bool   `yaml:"is_default" json:"is_default"`
+}
+
+type Brand struct {
+	Name       string   `yaml:"name" json:"name"`
+	References []string `yaml:"references" json:"references"`
+}
+
+type CaseType struct {
+	Name   string          `yaml:"name" json:"name"`
+	Icon   string          `yaml:"icon" json:"icon"`
+	Images []CaseTypeImage `yaml:"images" json:"images"`
+}
+
+type CaseTypeImage struct {
+	Path          string `yaml:"path" json:"path"`
+	OrderPriority int    `yaml:"order_priority" json:"order_priority"`
+}
+
+type Discount struct {
+	Name       string    `yaml:"name" json:"name"`
+	Rate       int       `yaml:"rate" json:"rate"`
+	ValidUntil time.Time `yaml:"valid_until" json:"valid_until"`
+	CreatedBy  string    `yaml:"created_by" json:"created_by"`
+}
+
+// PhoneCase price is in the minor unit of the currency, COP when not given
+type PhoneCase struct {
+	Price           int64  `yaml:"price" json:"price"`
+	Currency        string `yaml:"currency" json:"currency"`
+	ScaffoldImg     string `yaml:"scaffold_img" json:"scaffold_img"`
+	InventoryStatus string `yaml:"inventory_status" json:"inventory_status"`
+	Brand           string `yaml:"brand" json:"brand"`
+	Reference       string `yaml:"reference" json:"reference"`
+	CaseType        string `yaml:"case_type" json:"case_type"`
+	Discount        string `yaml:"discount" json:"discount"`
+	CreatedBy       string `yaml:"created_by" json:"created_by"`
+}
+
+// Read parses a fixtures file, the format is chosen by its extension and
+// unknown fields are rejected so typos do not go unnoticed
+func Read(path string) (Fixtures, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Fixtures{}, err
+	}
+
+	var f Fixtures
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(&f); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&f)
+	default:
+		return Fixtures{}, fmt.Errorf("%w: %s", UnknownFormat, path)
+	}
+	if err != nil {
+		return Fixtures{}, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ReadAll merges the given files in order
+func ReadAll(paths ...string) (Fixtures, error) {
+	var all Fixtures
+	for _, path := range paths {
+		f, err := Read(path)
+		if err != nil {
+			return Fixtures{}, err
+		}
+		all.Users = append(all.Users, f.Users...)
+		all.Brands = append(all.Brands, f.Brands...)
+		all.CaseTypes = append(all.CaseTypes, f.CaseTypes...)
+		all.Discounts = append(all.Discounts, f.Discounts...)
+		all.PhoneCases = append(all.PhoneCases, f.PhoneCases...)
+	}
+	return all, nil
+}
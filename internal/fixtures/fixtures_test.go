@@ -0,0 +1,153 @@
+package fixtures_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/fixtures"
+	"github.com/CrissAlvarezH/fundart-api/internal/migrations"
+	productsmemoryrepo "github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/memoryrepo"
+	productssqlrepo "github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/sqlrepo"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb/sqldbtest"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/memoryrepo"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/sqlrepo"
+)
+
+const devFixtures = "../../fixtures/dev.yaml"
+
+func memoryRepositories(t *testing.T) fixtures.Repositories {
+	store := productsmemoryrepo.NewMemoryStore(nil, nil, nil, nil, nil)
+	cases := productsmemoryrepo.NewMemoryPhoneCaseRepository(store)
+	brands := productsmemoryrepo.NewMemoryPhoneBrandRepository(store)
+	caseTypes := productsmemoryrepo.NewMemoryCaseTypeRepository(store)
+	return fixtures.Repositories{
+		Users:      memoryrepo.NewMemoryUserRepository(nil),
+		Addresses:  memoryrepo.NewMemoryAddressRepository(nil),
+		PhoneCases: &cases,
+		Brands:     &brands,
+		CaseTypes:  &caseTypes,
+	}
+}
+
+func sqliteRepositories(t *testing.T) fixtures.Repositories {
+	db := sqldbtest.Open(t, migrations.SQLite)
+
+	var repos fixtures.Repositories
+	var errs [5]error
+	repos.Users, errs[0] = sqlrepo.NewSQLUserRepository(db)
+	repos.Addresses, errs[1] = sqlrepo.NewSQLAddressRepository(db)
+	repos.PhoneCases, errs[2] = productssqlrepo.NewSQLPhoneCaseRepository(db)
+	repos.Brands, errs[3] = productssqlrepo.NewSQLPhoneBrandRepository(db)
+	repos.CaseTypes, errs[4] = productssqlrepo.NewSQLCaseTypeRepository(db)
+	if err := errors.Join(errs[:]...); err != nil {
+		t.Fatal(err)
+	}
+	return repos
+}
+
+func TestLoader_DevFixtures(t *testing.T) {
+	ctx := context.Background()
+	backends := map[string]func(t *testing.T) fixtures.Repositories{
+		"memory": memoryRepositories,
+		"sqlite": sqliteRepositories,
+	}
+	for name, newRepos := range backends {
+		t.Run(name, func(t *testing.T) {
+			repos := newRepos(t)
+			loader := fixtures.NewLoader(repos, infrastructure.NewMockPasswordManager())
+
+			// loading twice must not duplicate records
+			for i := 0; i < 2; i++ {
+				if err := loader.LoadFiles(ctx, devFixtures); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, total := repos.Users.List(ctx, map[string]string{}, 100, 0); total != 10 {
+				t.Errorf("expected 10 users, got %d", total)
+			}
+			user, ok := repos.Users.GetByEmail(ctx, "cristian@email.com")
+			if !ok {
+				t.Fatal("user cristian@email.com not loaded")
+			}
+			if password, _ := repos.Users.GetPassword(ctx, user.ID); password != "23456_encrypt" {
+				t.Errorf("expected the password to be hashed, got %q", password)
+			}
+			if !user.IsActive {
+				t.Error("expected users to be active by default")
+			}
+			if address, ok := repos.Addresses.GetDefault(ctx, user.ID); !ok || address.Address != "Cll 41 # 12-30" {
+				t.Errorf("unexpected default address %+v", address)
+			}
+
+			if refs := repos.Brands.ListBrandReferences(ctx, "Apple"); len(refs) != 3 {
+				t.Errorf("expected 3 Apple references, got %d", len(refs))
+			}
+			cases, total := repos.PhoneCases.ListPhoneCases(ctx, map[string]string{}, 100, 0)
+			if total != 3 {
+				t.Fatalf("expected 3 phone cases, got %d", total)
+			}
+			first := cases[0]
+			if first.PhoneBrandReference.Name != "iPhone 15" || first.CaseType.Name != "Silicone" {
+				t.Errorf("phone case relations not resolved, got %+v", first)
+			}
+			if first.Discount.Name != "launch" {
+				t.Errorf("expected the launch discount, got %+v", first.Discount)
+			}
+			if len(first.CaseType.Images) != 2 {
+				t.Errorf("expected 2 case type images, got %d", len(first.CaseType.Images))
+			}
+			if first.CreatedBy.ID != user.ID {
+				t.Errorf("expected phone case created by %d, got %d", user.ID, first.CreatedBy.ID)
+			}
+		})
+	}
+}
+
+func TestLoader_UnknownReference(t *testing.T) {
+	ctx := context.Background()
+	loader := fixtures.NewLoader(memoryRepositories(t), infrastructure.NewMockPasswordManager())
+
+	err := loader.Load(ctx, fixtures.Fixtures{
+		PhoneCases: []fixtures.PhoneCase{
+			{Price: 100, Brand: "Apple", Reference: "iPhone 15", CaseType: "Silicone"},
+		},
+	})
+	if !errors.Is(err, fixtures.UnknownReference) {
+		t.Errorf("expected %v, got %v", fixtures.UnknownReference, err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	f, err := fixtures.ReadAll(
+		write("brands.json", `{"brands": [{"name": "Apple", "references": ["iPhone 15"]}]}`),
+		write("empty.yaml", ``),
+		write("users.yml", "users:\n  - name: Laura\n    email: laura@email.com\n    is_active: false\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Brands) != 1 || len(f.Users) != 1 || *f.Users[0].IsActive {
+		t.Errorf("unexpected fixtures %+v", f)
+	}
+
+	if _, err := fixtures.Read(write("typo.yaml", "user:\n  - name: Laura\n")); err == nil {
+		t.Error("expected unknown fields to be rejected")
+	}
+	if _, err := fixtures.Read(write("users.toml", "")); !errors.Is(err, fixtures.UnknownFormat) {
+		t.Errorf("expected %v, got %v", fixtures.UnknownFormat, err)
+	}
+}
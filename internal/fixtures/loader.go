@@ -0,0 +1,287 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	productsPorts "github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	products "github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/Rhymond/go-money"
+	"math"
+	"strings"
+)
+
+type Repositories struct {
+	Users      ports.UserRepository
+	Addresses  ports.AddressRepository
+	PhoneCases productsPorts.PhoneCaseRepository
+	Brands     productsPorts.PhoneBrandRepository
+	CaseTypes  productsPorts.CaseTypeRepository
+}
+
+// Loader writes fixtures through the repository ports, so it works with every
+// backend. Records that already exist, matched by natural key, are reused, so
+// loading the same fixtures twice does not duplicate them. Soft deleted records
+// exist too, a reload doesn't bring them back
+type Loader struct {
+	repos           Repositories
+	passwordManager ports.PasswordManager
+}
+
+func NewLoader(repos Repositories, passwordManager ports.PasswordManager) *Loader {
+	return &Loader{repos: repos, passwordManager: passwordManager}
+}
+
+func (l *Loader) LoadFiles(ctx context.Context, paths ...string) error {
+	f, err := ReadAll(paths...)
+	if err != nil {
+		return err
+	}
+	return l.Load(ctx, f)
+}
+
+func (l *Loader) Load(ctx context.Context, f Fixtures) error {
+	for _, u := range f.Users {
+		if err := l.loadUser(ctx, u); err != nil {
+			return fmt.Errorf("user %s: %w", u.Email, err)
+		}
+	}
+	for _, b := range f.Brands {
+		if err := l.loadBrand(ctx, b); err != nil {
+			return fmt.Errorf("brand %s: %w", b.Name, err)
+		}
+	}
+	for _, c := range f.CaseTypes {
+		if err := l.loadCaseType(ctx, c); err != nil {
+			return fmt.Errorf("case type %s: %w", c.Name, err)
+		}
+	}
+	for _, d := range f.Discounts {
+		if err := l.loadDiscount(ctx, d); err != nil {
+			return fmt.Errorf("discount %s: %w", d.Name, err)
+		}
+	}
+	for i, p := range f.PhoneCases {
+		if err := l.loadPhoneCase(ctx, p); err != nil {
+			return fmt.Errorf("phone case %d (%s %s): %w", i+1, p.Reference, p.CaseType, err)
+		}
+	}
+	return nil
+}
+
+func (l *Loader) loadUser(ctx context.Context, u User) error {
+	user, ok := l.repos.Users.GetByEmail(ctx, u.Email)
+	if !ok {
+		password, err := l.passwordManager.Encrypt(u.Password)
+		if err != nil {
+			return err
+		}
+		scopes := make([]users.ScopeName, 0, len(u.Scopes))
+		for _, s := range u.Scopes {
+			scopes = append(scopes, users.ScopeName(s))
+		}
+		isActive := u.IsActive == nil || *u.IsActive
+
+		user, err = l.repos.Users.Add(ctx, u.Name, u.Email, password, u.Phone, isActive, scopes)
+		if err != nil {
+			return err
+		}
+	}
+
+	existing := l.repos.Addresses.List(ctx, user.ID)
+	for _, a := range u.Addresses {
+		address, ok := findAddress(existing, a)
+		if !ok {
+			var err error
+			address, err = l.repos.Addresses.Add(
+				ctx, user.ID, a.Department, a.City, a.Address, a.ReceiverPhone, a.ReceiverName,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		if a.IsDefault && !address.IsDefault {
+			if _, err := l.repos.Addresses.SetDefault(ctx, user.ID, address.ID, address.Version); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Loader) loadBrand(ctx context.Context, b Brand) error {
+	brand := products.PhoneBrand(b.Name)
+	if !containsBrand(l.repos.Brands.ListAllBrands(ctx), brand) {
+		if err := l.repos.Brands.CreateBrand(ctx, brand); err != nil {
+			return err
+		}
+	}
+
+	existing := l.repos.Brands.ListBrandReferences(ctx, brand)
+	for _, name := range b.References {
+		if _, ok := findBrandReference(existing, name); ok {
+			continue
+		}
+		if _, err := l.repos.Brands.CreateBrandReference(ctx, name, brand); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Loader) loadCaseType(ctx context.Context, c CaseType) error {
+	caseType, ok := l.findCaseType(ctx, c.Name)
+	if !ok {
+		var err error
+		caseType, err = l.repos.CaseTypes.CreateCaseType(ctx, c.Name, c.Icon)
+		if err != nil {
+			return err
+		}
+	}
+
+	existing := l.repos.CaseTypes.ListCaseTypeImages(ctx, caseType.ID, true)
+	for _, img := range c.Images {
+		if containsImage(existing, img.Path) {
+			continue
+		}
+		if _, err := l.repos.CaseTypes.CreateCaseTypeImage(ctx, caseType.ID, img.Path, img.OrderPriority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Loader) loadDiscount(ctx context.Context, d Discount) error {
+	if _, ok := l.findDiscount(ctx, d.Name); ok {
+		return nil
+	}
+	createdBy, err := l.userID(ctx, d.CreatedBy)
+	if err != nil {
+		return err
+	}
+	_, err = l.repos.PhoneCases.CreateDiscount(ctx, d.Name, d.Rate, d.ValidUntil, createdBy)
+	return err
+}
+
+func (l *Loader) loadPhoneCase(ctx context.Context, p PhoneCase) error {
+	ref, ok := findBrandReference(l.repos.Brands.ListBrandReferences(ctx, products.PhoneBrand(p.Brand)), p.Reference)
+	if !ok {
+		return fmt.Errorf("%w: brand reference %s of %s", UnknownReference, p.Reference, p.Brand)
+	}
+	caseType, ok := l.findCaseType(ctx, p.CaseType)
+	if !ok {
+		return fmt.Errorf("%w: case type %s", UnknownReference, p.CaseType)
+	}
+	createdBy, err := l.userID(ctx, p.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	phoneCase, ok := l.findPhoneCase(ctx, ref.ID, caseType.ID, p.ScaffoldImg)
+	if !ok {
+		currency := p.Currency
+		if currency == "" {
+			currency = money.COP
+		}
+		status := products.InventoryStatus(p.InventoryStatus)
+		if status == "" {
+			status = products.PhoneCaseAvailable
+		}
+
+		phoneCase, err = l.repos.PhoneCases.CreatePhoneCase(
+			ctx, *money.New(p.Price, currency), p.ScaffoldImg, status, ref.ID, caseType.ID, createdBy,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.Discount == "" || phoneCase.IsDeleted() {
+		return nil
+	}
+	discount, ok := l.findDiscount(ctx, p.Discount)
+	if !ok {
+		return fmt.Errorf("%w: discount %s", UnknownReference, p.Discount)
+	}
+	if discount.IsDeleted() {
+		return nil
+	}
+	return l.repos.PhoneCases.AttachDiscount(ctx, phoneCase.ID, discount.ID)
+}
+
+func (l *Loader) userID(ctx context.Context, email string) (users.UserID, error) {
+	user, ok := l.repos.Users.GetByEmail(ctx, email)
+	if !ok {
+		return 0, fmt.Errorf("%w: user %s", UnknownReference, email)
+	}
+	return user.ID, nil
+}
+
+func (l *Loader) findCaseType(ctx context.Context, name string) (products.CaseType, bool) {
+	for _, c := range l.repos.CaseTypes.ListCaseTypes(ctx) {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return products.CaseType{}, false
+}
+
+func (l *Loader) findDiscount(ctx context.Context, name string) (products.Discount, bool) {
+	for _, d := range l.repos.PhoneCases.ListAllDiscounts(ctx, true) {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return products.Discount{}, false
+}
+
+// a phone case is identified by its brand reference, case type and scaffold
+func (l *Loader) findPhoneCase(
+	ctx context.Context, refID products.PhoneBrandReferenceID, typeID products.CaseTypeID, scaffoldImg string,
+) (products.PhoneCase, bool) {
+	filters := map[string]string{productsPorts.IncludeDeleted: "true"}
+	cases, _ := l.repos.PhoneCases.ListPhoneCases(ctx, filters, math.MaxInt32, 0)
+	for _, c := range cases {
+		if c.PhoneBrandReference.ID == refID && c.CaseType.ID == typeID && c.CaseScaffoldImgPah == scaffoldImg {
+			return c, true
+		}
+	}
+	return products.PhoneCase{}, false
+}
+
+func findAddress(addresses []users.Address, a Address) (users.Address, bool) {
+	for _, e := range addresses {
+		if e.Department == a.Department && e.City == a.City && strings.EqualFold(e.Address, a.Address) {
+			return e, true
+		}
+	}
+	return users.Address{}, false
+}
+
+func findBrandReference(refs []products.PhoneBrandReference, name string) (products.PhoneBrandReference, bool) {
+	for _, r := range refs {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return products.PhoneBrandReference{}, false
+}
+
+func containsBrand(brands []products.PhoneBrand, brand products.PhoneBrand) bool {
+	for _, b := range brands {
+		if b == brand {
+			return true
+		}
+	}
+	return false
+}
+
+func containsImage(images []products.CaseTypeImage, path string) bool {
+	for _, img := range images {
+		if img.Path == path {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Checker is implemented by the adapters the api can't work without, Check
+// must return before the context is done
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+type CheckFunc func(ctx context.Context) error
+
+func (f CheckFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type ComponentStatus struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+type component struct {
+	name    string
+	checker Checker
+}
+
+// Readiness runs the checks of every component on each request, the api is
+// ready when all of them pass
+type Readiness struct {
+	timeout      time.Duration
+	components   []component
+	shuttingDown atomic.Bool
+}
+
+// NewReadiness fails the checks that take longer than timeout
+func NewReadiness(timeout time.Duration) *Readiness {
+	return &Readiness{timeout: timeout}
+}
+
+// Add must be called before serving requests, it panics when name was already
+// added because the report has one status per name
+func (r *Readiness) Add(name string, checker Checker) {
+	for _, c := range r.components {
+		if c.name == name {
+			panic("health: component " + name + " added twice")
+		}
+	}
+	r.components = append(r.components, component{name: name, checker: checker})
+}
+
+// ShuttingDown makes the api not ready so the load balancer stops sending
+// requests while the in-flight ones are drained
+func (r *Readiness) ShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Check runs the checks concurrently
+func (r *Readiness) Check(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Components: make(map[string]ComponentStatus, len(r.components))}
+	if r.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+		return report
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range r.components {
+		wg.Add(1)
+		go func(c component) {
+			defer wg.Done()
+			status := check(ctx, c.checker)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[c.name] = status
+			if status.Status != StatusOK {
+				report.Status = StatusUnavailable
+			}
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+// check doesn't wait for a checker that ignores the context
+func check(ctx context.Context, checker Checker) ComponentStatus {
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() { result <- checker.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	status := ComponentStatus{Status: StatusOK, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		status.Status = StatusUnavailable
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// Handler responds 503 while any component is unavailable
+func (r *Readiness) Handler(c *gin.Context) {
+	report := r.Check(c.Request.Context())
+	if report.Status != StatusOK {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// Liveness only tells the process is serving requests, it doesn't check the
+// components so a broken database doesn't get the process restarted
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+}
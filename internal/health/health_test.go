@@ -0,0 +1,109 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/health"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var ok = health.CheckFunc(func(ctx context.Context) error { return nil })
+
+func serve(readiness *health.Readiness, path string) (*httptest.ResponseRecorder, health.Report) {
+	router := gin.New()
+	router.GET("/healthz", health.Liveness)
+	router.GET("/readyz", readiness.Handler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	router.ServeHTTP(w, req)
+
+	var report health.Report
+	json.Unmarshal(w.Body.Bytes(), &report)
+	return w, report
+}
+
+func TestReadiness(t *testing.T) {
+	broken := health.CheckFunc(func(ctx context.Context) error { return errors.New("connection refused") })
+	// hangs ignores the context, the readiness must not wait for it
+	hangs := health.CheckFunc(func(ctx context.Context) error { time.Sleep(time.Second); return nil })
+
+	cases := []struct {
+		name           string
+		checks         map[string]health.Checker
+		expectedCode   int
+		expectedStatus map[string]string
+	}{
+		{"no components", nil, http.StatusOK, map[string]string{}},
+		{"every component ok", map[string]health.Checker{"database": ok, "mail": ok}, http.StatusOK,
+			map[string]string{"database": health.StatusOK, "mail": health.StatusOK}},
+		{"broken component", map[string]health.Checker{"database": broken, "mail": ok}, http.StatusServiceUnavailable,
+			map[string]string{"database": health.StatusUnavailable, "mail": health.StatusOK}},
+		{"slow component", map[string]health.Checker{"storage": hangs}, http.StatusServiceUnavailable,
+			map[string]string{"storage": health.StatusUnavailable}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			readiness := health.NewReadiness(50 * time.Millisecond)
+			for name, checker := range c.checks {
+				readiness.Add(name, checker)
+			}
+
+			start := time.Now()
+			w, report := serve(readiness, "/readyz")
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("readiness took %s", elapsed)
+			}
+			if w.Code != c.expectedCode {
+				t.Errorf("expected status %d, got %d: %s", c.expectedCode, w.Code, w.Body)
+			}
+			if len(report.Components) != len(c.expectedStatus) {
+				t.Errorf("expected %d components, got %+v", len(c.expectedStatus), report.Components)
+			}
+			for name, status := range c.expectedStatus {
+				if report.Components[name].Status != status {
+					t.Errorf("expected %s %s, got %+v", name, status, report.Components[name])
+				}
+			}
+		})
+	}
+
+	t.Run("errors are reported", func(t *testing.T) {
+		readiness := health.NewReadiness(time.Second)
+		readiness.Add("mail", broken)
+		if _, report := serve(readiness, "/readyz"); report.Components["mail"].Error != "connection refused" {
+			t.Errorf("expected the error of the component, got %+v", report.Components["mail"])
+		}
+	})
+}
+
+func TestReadiness_ShuttingDown(t *testing.T) {
+	readiness := health.NewReadiness(time.Second)
+	readiness.Add("database", ok)
+	readiness.ShuttingDown()
+
+	w, report := serve(readiness, "/readyz")
+	if w.Code != http.StatusServiceUnavailable || report.Status != health.StatusShuttingDown {
+		t.Errorf("expected 503 while shutting down, got %d: %s", w.Code, w.Body)
+	}
+	if w, _ := serve(readiness, "/healthz"); w.Code != http.StatusOK {
+		t.Errorf("the process is still alive while shutting down, got %d", w.Code)
+	}
+}
+
+func TestReadiness_DuplicatedComponent(t *testing.T) {
+	readiness := health.NewReadiness(time.Second)
+	readiness.Add("mail", ok)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic when a component is added twice")
+		}
+	}()
+	readiness.Add("mail", ok)
+}
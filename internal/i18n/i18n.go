@@ -0,0 +1,170 @@
+package i18n
+
+import (
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	estranslations "github.com/go-playground/validator/v10/translations/es"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Language string
+
+const (
+	Spanish Language = "es"
+	English Language = "en"
+
+	// DefaultLanguage is used when the client doesn't accept any supported
+	// language, it's the default language of the users too
+	DefaultLanguage = Spanish
+)
+
+const translatorKey = "translator"
+
+var universal = ut.New(es.New(), es.New(), en.New())
+
+func init() {
+	for lang, messages := range catalog {
+		trans, _ := universal.GetTranslator(string(lang))
+		for key, text := range messages {
+			if err := trans.Add(key, text, false); err != nil {
+				panic("i18n: " + string(lang) + " " + key + ": " + err.Error())
+			}
+		}
+	}
+
+	// the validation errors of the bodies bound by gin
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	esTrans, _ := universal.GetTranslator(string(Spanish))
+	enTrans, _ := universal.GetTranslator(string(English))
+	if err := estranslations.RegisterDefaultTranslations(v, esTrans); err != nil {
+		panic("i18n: validator es: " + err.Error())
+	}
+	if err := entranslations.RegisterDefaultTranslations(v, enTrans); err != nil {
+		panic("i18n: validator en: " + err.Error())
+	}
+}
+
+// Negotiate returns the translator of the supported language the client
+// prefers in the Accept-Language header, regions like es-CO use the language
+func Negotiate(acceptLanguage string) ut.Translator {
+	trans, _ := universal.FindTranslator(preferred(acceptLanguage)...)
+	return trans
+}
+
+type weighted struct {
+	lang string
+	q    float64
+}
+
+// preferred returns the languages of the header sorted by their q, the ones
+// with q=0 are not acceptable
+func preferred(acceptLanguage string) []string {
+	var ranges []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+		if lang == "" || lang == "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			ranges = append(ranges, weighted{lang: lang, q: q})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	langs := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		langs = append(langs, r.lang)
+	}
+	return langs
+}
+
+// Middleware negotiates the language of the request, the responses have the
+// Content-Language header and vary by Accept-Language
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trans := Negotiate(c.GetHeader("Accept-Language"))
+		c.Set(translatorKey, trans)
+		c.Header("Content-Language", trans.Locale())
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// Translator returns the translator of the request, it's negotiated when the
+// middleware is not registered
+func Translator(c *gin.Context) ut.Translator {
+	if trans, ok := c.Get(translatorKey); ok {
+		return trans.(ut.Translator)
+	}
+	return Negotiate(c.GetHeader("Accept-Language"))
+}
+
+// T translates the message of the catalog with the language of the request
+func T(c *gin.Context, key string, params ...string) string {
+	return Translate(Translator(c), key, key, params...)
+}
+
+// Translate returns fallback when the key is not in the catalog
+func Translate(trans ut.Translator, key string, fallback string, params ...string) string {
+	text, err := trans.T(key, params...)
+	if err != nil {
+		return fallback
+	}
+	return text
+}
+
+// Localize returns a copy of err with the message and the fields translated
+// by their code, the codes without translation keep their message
+func Localize(trans ut.Translator, err *apperror.Error) *apperror.Error {
+	localized := *err
+	localized.Message = Translate(trans, err.Code, err.Message)
+	localized.Fields = make([]apperror.FieldError, 0, len(err.Fields))
+	for _, f := range err.Fields {
+		f.Message = fieldMessage(trans, f)
+		localized.Fields = append(localized.Fields, f)
+	}
+	return &localized
+}
+
+func fieldMessage(trans ut.Translator, f apperror.FieldError) string {
+	if text, err := trans.T("field_"+f.Rule, f.Field, f.Param); err == nil {
+		return text
+	}
+	// the rules of apperror.AsField are the codes of the domain errors
+	return Translate(trans, f.Rule, f.Message)
+}
+
+// ValidationError translates the errors of the validator, the fields are
+// named as in the body
+func ValidationError(trans ut.Translator, errs validator.ValidationErrors) *apperror.Error {
+	fields := make([]apperror.FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, apperror.FieldError{
+			Field: fe.Field(), Rule: fe.Tag(), Param: fe.Param(), Message: fe.Translate(trans),
+		})
+	}
+	localized := Localize(trans, apperror.Validation())
+	localized.Fields = fields
+	return localized
+}
@@ -0,0 +1,129 @@
+package i18n_test
+
+import (
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
+	"github.com/CrissAlvarezH/fundart-api/internal/common"
+	"github.com/CrissAlvarezH/fundart-api/internal/i18n"
+	productports "github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		header   string
+		expected i18n.Language
+	}{
+		{"", i18n.DefaultLanguage},
+		{"en", i18n.English},
+		{"en-US,en;q=0.9", i18n.English},
+		{"es-CO,es;q=0.9,en;q=0.8", i18n.Spanish},
+		{"fr, en;q=0.5", i18n.English},
+		{"es;q=0.2, en;q=0.8", i18n.English},
+		{"en;q=0, es", i18n.Spanish},
+		{"fr, *", i18n.DefaultLanguage},
+		{"en;q=abc", i18n.DefaultLanguage},
+	}
+	for _, c := range cases {
+		t.Run(c.header, func(t *testing.T) {
+			if locale := i18n.Negotiate(c.header).Locale(); locale != string(c.expected) {
+				t.Errorf("expected %s, got %s", c.expected, locale)
+			}
+		})
+	}
+}
+
+// every error the handlers can respond must be translated to both languages
+func TestCatalog_TranslatesErrorCodes(t *testing.T) {
+	errs := []*apperror.Error{
+		ports.UserDoesNotExists, ports.EmailAlreadyExists, ports.AddressDoesNotExists, ports.InvalidCredentials,
+		ports.VersionMismatch, ports.InvalidLanguage, ports.InvalidNotificationChannel, ports.InvalidValidationCode,
+		ports.InvalidAvatarType, ports.AvatarTooLarge, ports.InvalidToken, ports.ImpersonationNotAllowed,
+		ports.ForbiddenWhileImpersonating, ports.PasswordTooLong, ports.ScopesChangeNotAllowed,
+		ports.UserAlreadyActive,
+		productports.PhoneCaseDoesNotExists, productports.DiscountDoesNotExists, productports.PhoneBrandDoesNotExists,
+		productports.PhoneBrandReferenceDoesNotExists, productports.BrandAlreadyExists,
+		productports.CaseTypeDoesNotExists, productports.CaseTypeImageDoesNotExists, productports.CaseTypeInUse, productports.NotDeleted,
+		common.MissingIfMatch, common.InvalidIfMatch, common.AuthenticationRequired, apperror.Validation(),
+	}
+	for _, lang := range []i18n.Language{i18n.Spanish, i18n.English} {
+		trans := i18n.Negotiate(string(lang))
+		for _, err := range errs {
+			if _, tErr := trans.T(err.Code); tErr != nil {
+				t.Errorf("%s: %s is not translated", lang, err.Code)
+			}
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	err := apperror.AsField("language", ports.InvalidLanguage)
+
+	localized := i18n.Localize(i18n.Negotiate("es"), err)
+	if localized.Message != "la solicitud tiene campos inválidos" {
+		t.Errorf("unexpected message %q", localized.Message)
+	}
+	if len(localized.Fields) != 1 || localized.Fields[0].Message != "idioma inválido" {
+		t.Errorf("expected the field translated by the code of the domain error, got %+v", localized.Fields)
+	}
+	if err.Message != "the request has invalid fields" || err.Fields[0].Message != "invalid language" {
+		t.Errorf("the error must not be modified, got %+v", err)
+	}
+
+	number := i18n.Localize(i18n.Negotiate("es"), apperror.InvalidNumber("page"))
+	if number.Fields[0].Message != "page no es un número válido" {
+		t.Errorf("expected the rule translated with the field, got %+v", number.Fields)
+	}
+
+	unknown := apperror.New(apperror.Conflict, "unknown_code", "keep me")
+	if localized := i18n.Localize(i18n.Negotiate("es"), unknown); localized.Message != "keep me" {
+		t.Errorf("expected the message of an unknown code, got %q", localized.Message)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	type body struct {
+		Password string `json:"password" binding:"required"`
+	}
+	err := binding.Validator.ValidateStruct(body{})
+	validationErrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	cases := map[string]string{
+		"es": "password es un campo requerido",
+		"en": "password is a required field",
+	}
+	for lang, expected := range cases {
+		localized := i18n.ValidationError(i18n.Negotiate(lang), validationErrs)
+		if len(localized.Fields) != 1 || localized.Fields[0].Message != expected {
+			t.Errorf("%s: expected %q, got %+v", lang, expected, localized.Fields)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(i18n.Middleware())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, i18n.T(c, "password_changed"))
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "en-GB")
+	router.ServeHTTP(w, req)
+
+	if w.Body.String() != "password changed successfully" {
+		t.Errorf("unexpected body %q", w.Body)
+	}
+	if w.Header().Get("Content-Language") != "en" || w.Header().Get("Vary") != "Accept-Language" {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+}
@@ -0,0 +1,109 @@
+package i18n
+
+// catalog has the messages of the api by key, the keys of the errors are their
+// apperror codes and the ones of the field errors are field_ and the rule, with
+// the field as {0} and the param of the rule as {1}
+var catalog = map[Language]map[string]string{
+	English: {
+		// users
+		"user_not_found":                "user does not exists",
+		"email_already_exists":          "email already exists",
+		"address_not_found":             "address does not exists",
+		"invalid_credentials":           "invalid credentials",
+		"invalid_language":              "invalid language",
+		"invalid_notification_channel":  "invalid notification channel",
+		"invalid_verification_code":     "invalid validation code",
+		"user_already_active":           "user is already active",
+		"invalid_avatar_type":           "avatar must be a jpeg or png image",
+		"avatar_too_large":              "avatar exceeds the max size allowed",
+		"invalid_token":                 "invalid token",
+		"impersonation_not_allowed":     "impersonation not allowed",
+		"forbidden_while_impersonating": "action not allowed while impersonating a user",
+		"password_too_long":             "password is too long",
+		"scopes_change_not_allowed":     "only admins can change the scopes of a user",
+
+		// products
+		"phone_case_not_found":            "phone case does not exists",
+		"discount_not_found":              "discount does not exists",
+		"phone_brand_not_found":           "phone brand does not exists",
+		"phone_brand_reference_not_found": "phone brand reference does not exists",
+		"brand_already_exists":            "brand already exists",
+		"case_type_not_found":             "case type does not exists",
+		"case_type_image_not_found":       "case type image does not exists",
+		"case_type_in_use":                "case type is used by phone cases",
+		"not_deleted":                     "resource is not deleted",
+
+		// requests
+		"version_mismatch":        "resource was modified by another request",
+		"missing_if_match":        "If-Match header is required, send the ETag of the resource",
+		"invalid_if_match":        "If-Match header must be a single ETag returned by the api or '*'",
+		"authentication_required": "authentication required, send the access token of the login",
+		"permission_denied":       "you don't have permission to perform this action",
+		"validation_failed":       "the request has invalid fields",
+		"invalid_body":            "the body of the request is not valid",
+		"internal_error":          "unexpected error, try again later",
+
+		"field_required": "{0} is required",
+		"field_number":   "{0} is not a valid number",
+		"field_min":      "'{0}' can't be lower than {1}",
+		"field_rfc3339":  "'{0}' must be a RFC3339 date",
+		"field_file":     "error reading '{0}' file",
+
+		"user_activated":     "User was activated successfully",
+		"recovery_code_sent": "message sent successfully",
+		"verification_sent":  "verification code sent successfully",
+		"password_recovered": "recovery password successfully",
+		"password_changed":   "password changed successfully",
+	},
+	Spanish: {
+		// users
+		"user_not_found":                "el usuario no existe",
+		"email_already_exists":          "el correo ya está registrado",
+		"address_not_found":             "la dirección no existe",
+		"invalid_credentials":           "credenciales inválidas",
+		"invalid_language":              "idioma inválido",
+		"invalid_notification_channel":  "canal de notificación inválido",
+		"invalid_verification_code":     "código de verificación inválido",
+		"user_already_active":           "el usuario ya está activo",
+		"invalid_avatar_type":           "el avatar debe ser una imagen jpeg o png",
+		"avatar_too_large":              "el avatar supera el tamaño máximo permitido",
+		"invalid_token":                 "token inválido",
+		"impersonation_not_allowed":     "no está permitido suplantar a este usuario",
+		"forbidden_while_impersonating": "acción no permitida mientras se suplanta a un usuario",
+		"password_too_long":             "la contraseña es demasiado larga",
+		"scopes_change_not_allowed":     "solo los administradores pueden cambiar los permisos de un usuario",
+
+		// products
+		"phone_case_not_found":            "la funda no existe",
+		"discount_not_found":              "el descuento no existe",
+		"phone_brand_not_found":           "la marca de celular no existe",
+		"phone_brand_reference_not_found": "la referencia de celular no existe",
+		"brand_already_exists":            "la marca ya existe",
+		"case_type_not_found":             "el tipo de funda no existe",
+		"case_type_image_not_found":       "la imagen del tipo de funda no existe",
+		"case_type_in_use":                "hay fundas que usan este tipo de funda",
+		"not_deleted":                     "el recurso no está eliminado",
+
+		// requests
+		"version_mismatch":        "el recurso fue modificado por otra solicitud",
+		"missing_if_match":        "el encabezado If-Match es obligatorio, envía el ETag del recurso",
+		"invalid_if_match":        "el encabezado If-Match debe ser un único ETag devuelto por la api o '*'",
+		"authentication_required": "debes autenticarte, envía el token de acceso del inicio de sesión",
+		"permission_denied":       "no tienes permiso para realizar esta acción",
+		"validation_failed":       "la solicitud tiene campos inválidos",
+		"invalid_body":            "el cuerpo de la solicitud no es válido",
+		"internal_error":          "error inesperado, inténtalo de nuevo más tarde",
+
+		"field_required": "{0} es obligatorio",
+		"field_number":   "{0} no es un número válido",
+		"field_min":      "'{0}' no puede ser menor que {1}",
+		"field_rfc3339":  "'{0}' debe ser una fecha RFC3339",
+		"field_file":     "error leyendo el archivo '{0}'",
+
+		"user_activated":     "El usuario fue activado correctamente",
+		"recovery_code_sent": "mensaje enviado correctamente",
+		"verification_sent":  "código de verificación enviado correctamente",
+		"password_recovered": "contraseña recuperada correctamente",
+		"password_changed":   "contraseña cambiada correctamente",
+	},
+}
@@ -0,0 +1,31 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/CrissAlvarezH/fundart-api/internal/logging"
+	"log/slog"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	var out bytes.Buffer
+	ctx := logging.WithLogger(context.Background(), logging.New(&out, slog.LevelInfo))
+	ctx = logging.With(ctx, "request_id", "abc")
+
+	logging.FromContext(ctx).Debug("hidden")
+	logging.FromContext(ctx).Info("saved", "user_id", 7)
+
+	var line map[string]any
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("expected a single json line, got %q: %v", out.String(), err)
+	}
+	if line["msg"] != "saved" || line["request_id"] != "abc" || line["user_id"] != float64(7) {
+		t.Errorf("unexpected line %v", line)
+	}
+
+	if logging.FromContext(context.Background()) != slog.Default() {
+		t.Error("expected the default logger without a logger in the context")
+	}
+}
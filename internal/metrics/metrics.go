@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	productports "github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	userports "github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"strconv"
+	"time"
+)
+
+const namespace = "fundart"
+
+// unmatchedRoute groups the requests that match no route, so scanners can't
+// create a series per path
+const unmatchedRoute = "unmatched"
+
+var (
+	_ userports.Metrics    = (*Metrics)(nil)
+	_ productports.Metrics = (*Metrics)(nil)
+)
+
+// Metrics is the prometheus adapter of the metrics ports, each instance has
+// its own registry so the tests don't share the counters
+type Metrics struct {
+	registry          *prometheus.Registry
+	requests          *prometheus.HistogramVec
+	registrations     prometheus.Counter
+	logins            *prometheus.CounterVec
+	verificationCodes *prometheus.CounterVec
+	phoneCases        prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of the http requests by route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "user_registrations_total",
+			Help:      "Users registered.",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by outcome.",
+		}, []string{"outcome"}),
+		verificationCodes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "verification_codes_sent_total",
+			Help:      "Verification codes sent by email, by purpose.",
+		}, []string{"purpose"}),
+		phoneCases: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "phone_cases_created_total",
+			Help:      "Phone cases created.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.registrations, m.logins, m.verificationCodes, m.phoneCases,
+	)
+
+	// the series start at zero so rate() works from the first failed login
+	for _, outcome := range userports.LoginOutcomes {
+		m.logins.WithLabelValues(string(outcome))
+	}
+	for _, purpose := range userports.VerificationCodePurposes {
+		m.verificationCodes.WithLabelValues(string(purpose))
+	}
+	return m
+}
+
+// Middleware observes every request by its route template, like
+// /api/v1/users/:id, never by its path
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.requests.WithLabelValues(
+			c.Request.Method, route, strconv.Itoa(c.Writer.Status()),
+		).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the metrics in the prometheus text format
+func (m *Metrics) Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// AddRepositorySize exports the number of items of a memory repository, size
+// is called on every scrape so it must be cheap and safe for concurrent use
+func (m *Metrics) AddRepositorySize(repository string, size func() int) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "memory_repository_items",
+		Help:        "Items stored in the memory repositories.",
+		ConstLabels: prometheus.Labels{"repository": repository},
+	}, func() float64 { return float64(size()) }))
+}
+
+func (m *Metrics) UserRegistered() {
+	m.registrations.Inc()
+}
+
+func (m *Metrics) LoginAttempted(outcome userports.LoginOutcome) {
+	m.logins.WithLabelValues(string(outcome)).Inc()
+}
+
+func (m *Metrics) VerificationCodeSent(purpose userports.VerificationCodePurpose) {
+	m.verificationCodes.WithLabelValues(string(purpose)).Inc()
+}
+
+func (m *Metrics) PhoneCaseCreated() {
+	m.phoneCases.Inc()
+}
@@ -0,0 +1,63 @@
+package metrics_test
+
+import (
+	"github.com/CrissAlvarezH/fundart-api/internal/metrics"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/metrics", m.Handler())
+	router.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	get("/users/1")
+	get("/users/2")
+	get("/wp-admin")
+
+	m.UserRegistered()
+	m.LoginAttempted(ports.LoginSucceeded)
+	m.LoginAttempted(ports.LoginInvalidPassword)
+	m.LoginAttempted(ports.LoginInvalidPassword)
+	m.VerificationCodeSent(ports.VerifyAccountCode)
+	m.PhoneCaseCreated()
+	m.AddRepositorySize("users", func() int { return 3 })
+
+	w := get("/metrics")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	expected := []string{
+		`fundart_http_request_duration_seconds_count{method="GET",route="/users/:id",status="404"} 2`,
+		`fundart_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+		`fundart_user_registrations_total 1`,
+		`fundart_logins_total{outcome="success"} 1`,
+		`fundart_logins_total{outcome="invalid_password"} 2`,
+		`fundart_logins_total{outcome="unknown_email"} 0`,
+		`fundart_verification_codes_sent_total{purpose="verify_account"} 1`,
+		`fundart_verification_codes_sent_total{purpose="recover_password"} 0`,
+		`fundart_phone_cases_created_total 1`,
+		`fundart_memory_repository_items{repository="users"} 3`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected %q in the metrics", line)
+		}
+	}
+	if strings.Contains(body, "/users/1") || strings.Contains(body, "/wp-admin") {
+		t.Error("the paths must not be used as labels")
+	}
+}
@@ -0,0 +1,388 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// every dialect has the same numbered set of migrations, written in its own sql
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+var Dialects = []Dialect{Postgres, SQLite}
+
+var (
+	SchemaBehind     = errors.New("database schema is behind, run the pending migrations")
+	ChecksumMismatch = errors.New("applied migration was modified")
+	UnknownVersion   = errors.New("applied migration does not exists")
+)
+
+// lockKey identifies the postgres advisory lock taken while migrating
+const lockKey = 5_830_117
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+
+	hasUp   bool
+	hasDown bool
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, dialect Dialect) (*Migrator, error) {
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Load returns the embedded migrations of the dialect sorted by version
+func Load(dialect Dialect) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, string(dialect))
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q", dialect)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := files.ReadFile(string(dialect) + "/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+			m.hasUp = true
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+			m.hasDown = true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if !m.hasUp || !m.hasDown {
+			return nil, fmt.Errorf("migration %04d_%s needs an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Apply runs the pending migrations of the dialect
+func Apply(db *sql.DB, dialect Dialect) error {
+	migrator, err := NewMigrator(db, dialect)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up()
+	return err
+}
+
+// Up applies every pending migration and returns the applied ones
+func (m *Migrator) Up() ([]Migration, error) {
+	applied := make([]Migration, 0)
+	err := m.withLock(func(tx execer) error {
+		done, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(done); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := m.run(tx, migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last n applied migrations and returns the reverted ones
+func (m *Migrator) Down(n int) ([]Migration, error) {
+	reverted := make([]Migration, 0, n)
+	err := m.withLock(func(tx execer) error {
+		done, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < n; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := m.run(tx, migration, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration with the time it was applied, applied
+// migrations whose file changed since then are reported as an error
+func (m *Migrator) Status() ([]Status, error) {
+	var done map[int]appliedMigration
+	err := m.withLock(func(tx execer) error {
+		var err error
+		done, err = m.applied(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if a, ok := done[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = a.appliedAt
+		}
+		result = append(result, status)
+	}
+	return result, m.verify(done)
+}
+
+// Check returns SchemaBehind when there are pending migrations
+func (m *Migrator) Check() error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			return fmt.Errorf("%w: %04d_%s is pending", SchemaBehind, s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// withLock runs fn so only one process migrates at a time. Postgres uses an
+// advisory lock, sqlite takes the database write lock with the transaction
+func (m *Migrator) withLock(fn func(tx execer) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect == Postgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockKey)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.createTable(tx); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) createTable(e execer) error {
+	_, err := e.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			checksum   TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		)`)
+	return err
+}
+
+func (m *Migrator) applied(e execer) (map[int]appliedMigration, error) {
+	rows, err := e.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var checksum, appliedAt string
+		if err := rows.Scan(&version, &checksum, &appliedAt); err != nil {
+			return nil, err
+		}
+		t, _ := time.Parse(time.RFC3339, appliedAt)
+		applied[version] = appliedMigration{checksum: checksum, appliedAt: t}
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, a := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: version %d", UnknownVersion, version)
+		}
+		if migration.Checksum != a.checksum {
+			return fmt.Errorf("%w: %04d_%s", ChecksumMismatch, version, migration.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) run(tx execer, migration Migration, up bool) error {
+	if up {
+		if _, err := tx.Exec(migration.Up); err != nil {
+			return fmt.Errorf("migration %04d_%s up: %w", migration.Version, migration.Name, err)
+		}
+		_, err := tx.Exec(
+			m.placeholders(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`),
+			migration.Version, migration.Name, migration.Checksum, time.Now().UTC().Format(time.RFC3339),
+		)
+		return err
+	}
+
+	if _, err := tx.Exec(migration.Down); err != nil {
+		return fmt.Errorf("migration %04d_%s down: %w", migration.Version, migration.Name, err)
+	}
+	_, err := tx.Exec(m.placeholders(`DELETE FROM schema_migrations WHERE version = ?`), migration.Version)
+	return err
+}
+
+// placeholders rewrites ? placeholders to the $n form used by postgres
+func (m *Migrator) placeholders(query string) string {
+	if m.dialect != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Create adds empty up and down files for a new migration to every dialect
+// directory inside dir, numbered after the last existing migration
+func Create(dir string, name string) ([]string, error) {
+	if !regexp.MustCompile(`^\w+$`).MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q, use letters, numbers and underscores", name)
+	}
+
+	last := 0
+	for _, dialect := range Dialects {
+		entries, err := os.ReadDir(filepath.Join(dir, string(dialect)))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			match := fileNamePattern.FindStringSubmatch(entry.Name())
+			if match == nil {
+				continue
+			}
+			if version, _ := strconv.Atoi(match[1]); version > last {
+				last = version
+			}
+		}
+	}
+
+	created := make([]string, 0, 2*len(Dialects))
+	for _, dialect := range Dialects {
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, string(dialect), fmt.Sprintf("%04d_%s.%s.sql", last+1, name, direction))
+			if err := os.WriteFile(path, []byte{}, 0o644); err != nil {
+				return created, err
+			}
+			created = append(created, path)
+		}
+	}
+	return created, nil
+}
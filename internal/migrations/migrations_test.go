@@ -0,0 +1,166 @@
+package migrations_test
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/migrations"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb"
+)
+
+func openTestDB(t *testing.T, path string) *sql.DB {
+	db, err := sqldb.Open(migrations.SQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db.DB
+}
+
+func TestMigrations_SameSetForEveryDialect(t *testing.T) {
+	postgres, err := migrations.Load(migrations.Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := migrations.Load(migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("postgres has %d migrations and sqlite %d", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+			t.Errorf("migration %d differs: %04d_%s and %04d_%s", i,
+				postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+	}
+}
+
+func TestMigrator_Up_Down_Status(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	migrator, err := migrations.NewMigrator(db, migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, _ := migrations.Load(migrations.SQLite)
+
+	if err := migrator.Check(); !errors.Is(err, migrations.SchemaBehind) {
+		t.Errorf("expected SchemaBehind on an empty database, got %v", err)
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(all) {
+		t.Errorf("expected %d applied migrations, got %d", len(all), len(applied))
+	}
+	if err := migrator.Check(); err != nil {
+		t.Errorf("expected an up to date schema, got %v", err)
+	}
+	if applied, _ := migrator.Up(); len(applied) != 0 {
+		t.Errorf("second up should not apply anything, applied %d", len(applied))
+	}
+
+	reverted, err := migrator.Down(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != all[len(all)-1].Version {
+		t.Fatalf("expected the last migration to be reverted, got %+v", reverted)
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range statuses {
+		pending := i == len(statuses)-1
+		if s.Applied == pending {
+			t.Errorf("migration %04d_%s applied = %v", s.Version, s.Name, s.Applied)
+		}
+	}
+	if _, err := db.Exec(`SELECT deleted_at FROM phone_cases`); err == nil {
+		t.Error("reverted migration columns should not exist")
+	}
+}
+
+func TestMigrator_ChecksumMismatch(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	if err := migrations.Apply(db, migrations.SQLite); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec(`UPDATE schema_migrations SET checksum = 'changed' WHERE version = 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, _ := migrations.NewMigrator(db, migrations.SQLite)
+	if err := migrator.Check(); !errors.Is(err, migrations.ChecksumMismatch) {
+		t.Errorf("expected ChecksumMismatch, got %v", err)
+	}
+	if _, err := migrator.Up(); !errors.Is(err, migrations.ChecksumMismatch) {
+		t.Errorf("expected up to refuse a modified migration, got %v", err)
+	}
+}
+
+func TestMigrator_ConcurrentUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	all, _ := migrations.Load(migrations.SQLite)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	total := 0
+	for i := 0; i < 4; i++ {
+		migrator, err := migrations.NewMigrator(openTestDB(t, path), migrations.SQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied, err := migrator.Up()
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			total += len(applied)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if total != len(all) {
+		t.Errorf("each migration should be applied once, applied %d of %d", total, len(all))
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	for _, dialect := range migrations.Dialects {
+		os.MkdirAll(filepath.Join(dir, string(dialect)), 0o755)
+		os.WriteFile(filepath.Join(dir, string(dialect), "0007_existing.up.sql"), nil, 0o644)
+	}
+
+	created, err := migrations.Create(dir, "add_orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2*len(migrations.Dialects) {
+		t.Fatalf("expected up and down files for every dialect, got %v", created)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sqlite", "0008_add_orders.down.sql")); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := migrations.Create(dir, "bad name"); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+}
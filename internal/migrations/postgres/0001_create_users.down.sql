@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_events CASCADE;
+DROP TABLE IF EXISTS addresses CASCADE;
+DROP TABLE IF EXISTS users CASCADE;
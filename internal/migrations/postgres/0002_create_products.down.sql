@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS phone_cases CASCADE;
+DROP TABLE IF EXISTS discounts CASCADE;
+DROP TABLE IF EXISTS case_type_images CASCADE;
+DROP TABLE IF EXISTS case_types CASCADE;
+DROP TABLE IF EXISTS phone_brand_references CASCADE;
+DROP TABLE IF EXISTS phone_brands CASCADE;
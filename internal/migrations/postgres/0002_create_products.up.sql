@@ -0,0 +1,64 @@
+CREATE TABLE IF NOT EXISTS phone_brands (
+    name TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS phone_brand_references (
+    id    SERIAL PRIMARY KEY,
+    brand TEXT NOT NULL,
+    name  TEXT NOT NULL,
+    CONSTRAINT phone_brand_references_brand_fkey FOREIGN KEY (brand)
+        REFERENCES phone_brands (name) ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS phone_brand_references_brand_idx ON phone_brand_references (brand);
+
+CREATE TABLE IF NOT EXISTS case_types (
+    id            SERIAL PRIMARY KEY,
+    name          TEXT NOT NULL,
+    icon_img_path TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS case_type_images (
+    id             SERIAL PRIMARY KEY,
+    case_type_id   INTEGER NOT NULL,
+    path           TEXT    NOT NULL,
+    order_priority INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT case_type_images_case_type_id_fkey FOREIGN KEY (case_type_id)
+        REFERENCES case_types (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS case_type_images_case_type_id_idx ON case_type_images (case_type_id, order_priority);
+
+CREATE TABLE IF NOT EXISTS discounts (
+    id          SERIAL PRIMARY KEY,
+    name        TEXT        NOT NULL,
+    rate        INTEGER     NOT NULL CHECK (rate BETWEEN 0 AND 100),
+    valid_until TIMESTAMPTZ NOT NULL,
+    created_by  INTEGER     NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- prices are stored in the currency minor unit, as go-money does
+CREATE TABLE IF NOT EXISTS phone_cases (
+    id                       SERIAL PRIMARY KEY,
+    price_amount             BIGINT      NOT NULL CHECK (price_amount >= 0),
+    price_currency           CHAR(3)     NOT NULL,
+    case_scaffold_img_path   TEXT        NOT NULL DEFAULT '',
+    inventory_status         TEXT        NOT NULL,
+    discount_id              INTEGER,
+    phone_brand_reference_id INTEGER     NOT NULL,
+    case_type_id             INTEGER     NOT NULL,
+    created_by               INTEGER     NOT NULL DEFAULT 0,
+    created_at               TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT phone_cases_discount_id_fkey FOREIGN KEY (discount_id)
+        REFERENCES discounts (id) ON DELETE SET NULL,
+    CONSTRAINT phone_cases_phone_brand_reference_id_fkey FOREIGN KEY (phone_brand_reference_id)
+        REFERENCES phone_brand_references (id),
+    CONSTRAINT phone_cases_case_type_id_fkey FOREIGN KEY (case_type_id)
+        REFERENCES case_types (id)
+);
+
+CREATE INDEX IF NOT EXISTS phone_cases_discount_id_idx ON phone_cases (discount_id);
+CREATE INDEX IF NOT EXISTS phone_cases_phone_brand_reference_id_idx ON phone_cases (phone_brand_reference_id);
+CREATE INDEX IF NOT EXISTS phone_cases_case_type_id_idx ON phone_cases (case_type_id);
+CREATE INDEX IF NOT EXISTS phone_cases_inventory_status_idx ON phone_cases (inventory_status);
@@ -0,0 +1,7 @@
+-- version is increased on every change of a row, writes that expect another
+-- version are rejected so concurrent changes are not lost
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE addresses ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE case_types ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE discounts ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE phone_cases ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
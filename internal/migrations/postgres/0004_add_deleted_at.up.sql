@@ -0,0 +1,5 @@
+-- deleted_at is null while the row is not deleted, deleted rows are kept so
+-- they can still be referenced and restored
+ALTER TABLE discounts ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE phone_cases ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE case_type_images ADD COLUMN deleted_at TIMESTAMPTZ;
@@ -0,0 +1,62 @@
+CREATE TABLE IF NOT EXISTS users (
+    id                         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name                       TEXT     NOT NULL,
+    email                      TEXT     NOT NULL,
+    password                   TEXT     NOT NULL,
+    phone                      TEXT     NOT NULL DEFAULT '',
+    avatar_url                 TEXT     NOT NULL DEFAULT '',
+    is_active                  BOOLEAN  NOT NULL DEFAULT FALSE,
+    scopes                     TEXT     NOT NULL DEFAULT '[]',
+    created_at                 DATETIME NOT NULL,
+    verification_code          TEXT     NOT NULL DEFAULT '',
+    recovery_password_code     TEXT     NOT NULL DEFAULT '',
+    language                   TEXT     NOT NULL DEFAULT 'es',
+    marketing_email_consent    BOOLEAN  NOT NULL DEFAULT FALSE,
+    marketing_email_consent_at DATETIME,
+    marketing_sms_consent      BOOLEAN  NOT NULL DEFAULT FALSE,
+    marketing_sms_consent_at   DATETIME,
+    notification_channels      TEXT     NOT NULL DEFAULT '["email"]'
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (lower(email));
+CREATE INDEX IF NOT EXISTS users_phone_idx ON users (phone);
+
+CREATE TABLE IF NOT EXISTS addresses (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id        INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    department     TEXT    NOT NULL,
+    city           TEXT    NOT NULL,
+    address        TEXT    NOT NULL,
+    receiver_phone TEXT    NOT NULL DEFAULT '',
+    receiver_name  TEXT    NOT NULL DEFAULT '',
+    is_default     BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS addresses_user_id_idx ON addresses (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS addresses_default_key ON addresses (user_id) WHERE is_default;
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    type            TEXT     NOT NULL,
+    actor_id        INTEGER  NOT NULL DEFAULT 0,
+    impersonator_id INTEGER  NOT NULL DEFAULT 0,
+    target_id       INTEGER  NOT NULL DEFAULT 0,
+    ip              TEXT     NOT NULL DEFAULT '',
+    user_agent      TEXT     NOT NULL DEFAULT '',
+    details         TEXT     NOT NULL DEFAULT '{}',
+    created_at      DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_type_idx ON audit_events (type, created_at);
+CREATE INDEX IF NOT EXISTS audit_events_actor_id_idx ON audit_events (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_events_target_id_idx ON audit_events (target_id, created_at);
+
+-- audit events are append-only
+CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit events are append-only');
+END;
+CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit events are append-only');
+END;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS phone_cases;
+DROP TABLE IF EXISTS discounts;
+DROP TABLE IF EXISTS case_type_images;
+DROP TABLE IF EXISTS case_types;
+DROP TABLE IF EXISTS phone_brand_references;
+DROP TABLE IF EXISTS phone_brands;
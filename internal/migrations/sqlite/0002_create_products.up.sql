@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS phone_brands (
+    name TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS phone_brand_references (
+    id    INTEGER PRIMARY KEY AUTOINCREMENT,
+    brand TEXT NOT NULL REFERENCES phone_brands (name) ON UPDATE CASCADE,
+    name  TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS phone_brand_references_brand_idx ON phone_brand_references (brand);
+
+CREATE TABLE IF NOT EXISTS case_types (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    name          TEXT NOT NULL,
+    icon_img_path TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS case_type_images (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    case_type_id   INTEGER NOT NULL REFERENCES case_types (id) ON DELETE CASCADE,
+    path           TEXT    NOT NULL,
+    order_priority INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS case_type_images_case_type_id_idx ON case_type_images (case_type_id, order_priority);
+
+CREATE TABLE IF NOT EXISTS discounts (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    name        TEXT     NOT NULL,
+    rate        INTEGER  NOT NULL CHECK (rate BETWEEN 0 AND 100),
+    valid_until DATETIME NOT NULL,
+    created_by  INTEGER  NOT NULL DEFAULT 0,
+    created_at  DATETIME NOT NULL
+);
+
+-- prices are stored in the currency minor unit, as go-money does
+CREATE TABLE IF NOT EXISTS phone_cases (
+    id                       INTEGER PRIMARY KEY AUTOINCREMENT,
+    price_amount             INTEGER  NOT NULL CHECK (price_amount >= 0),
+    price_currency           TEXT     NOT NULL,
+    case_scaffold_img_path   TEXT     NOT NULL DEFAULT '',
+    inventory_status         TEXT     NOT NULL,
+    discount_id              INTEGER REFERENCES discounts (id) ON DELETE SET NULL,
+    phone_brand_reference_id INTEGER  NOT NULL REFERENCES phone_brand_references (id),
+    case_type_id             INTEGER  NOT NULL REFERENCES case_types (id),
+    created_by               INTEGER  NOT NULL DEFAULT 0,
+    created_at               DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS phone_cases_discount_id_idx ON phone_cases (discount_id);
+CREATE INDEX IF NOT EXISTS phone_cases_phone_brand_reference_id_idx ON phone_cases (phone_brand_reference_id);
+CREATE INDEX IF NOT EXISTS phone_cases_case_type_id_idx ON phone_cases (case_type_id);
+CREATE INDEX IF NOT EXISTS phone_cases_inventory_status_idx ON phone_cases (inventory_status);
@@ -0,0 +1,5 @@
+ALTER TABLE phone_cases DROP COLUMN version;
+ALTER TABLE discounts DROP COLUMN version;
+ALTER TABLE case_types DROP COLUMN version;
+ALTER TABLE addresses DROP COLUMN version;
+ALTER TABLE users DROP COLUMN version;
@@ -0,0 +1,3 @@
+ALTER TABLE case_type_images DROP COLUMN deleted_at;
+ALTER TABLE phone_cases DROP COLUMN deleted_at;
+ALTER TABLE discounts DROP COLUMN deleted_at;
@@ -0,0 +1,7 @@
+package ports
+
+// Metrics counts the business events of the products, the implementations
+// must be safe for concurrent use
+type Metrics interface {
+	PhoneCaseCreated()
+}
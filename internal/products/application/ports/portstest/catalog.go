@@ -0,0 +1,271 @@
+package portstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+)
+
+const missingID = 9999
+
+func PhoneBrandRepository(t *testing.T, newBackend NewBackend) {
+	ctx := context.Background()
+	t.Run("brands", func(t *testing.T) {
+		repo := newBackend(t).Brands
+
+		if brands := repo.ListAllBrands(ctx); len(brands) != 0 {
+			t.Errorf("expected no brands, got %v", brands)
+		}
+		for _, brand := range []domain.PhoneBrand{"Samsung", "Apple"} {
+			if err := repo.CreateBrand(ctx, brand); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.CreateBrand(ctx, "Apple"); !errors.Is(err, ports.BrandAlreadyExists) {
+			t.Errorf("expected BrandAlreadyExists, got %v", err)
+		}
+		if brands := repo.ListAllBrands(ctx); len(brands) != 2 || !hasBrand(brands, "Apple") || !hasBrand(brands, "Samsung") {
+			t.Errorf("unexpected brands %v", brands)
+		}
+
+		if err := repo.UpdateBrand(ctx, "Xiaomi", "Redmi"); !errors.Is(err, ports.PhoneBrandDoesNotExists) {
+			t.Errorf("expected PhoneBrandDoesNotExists, got %v", err)
+		}
+		if err := repo.UpdateBrand(ctx, "Samsung", "Apple"); !errors.Is(err, ports.BrandAlreadyExists) {
+			t.Errorf("expected BrandAlreadyExists, got %v", err)
+		}
+		if err := repo.UpdateBrand(ctx, "Samsung", "Samsung"); err != nil {
+			t.Errorf("renaming a brand to its own name, got %v", err)
+		}
+		if err := repo.UpdateBrand(ctx, "Samsung", "Galaxy"); err != nil {
+			t.Fatal(err)
+		}
+		if brands := repo.ListAllBrands(ctx); hasBrand(brands, "Samsung") || !hasBrand(brands, "Galaxy") {
+			t.Errorf("brand not renamed, got %v", brands)
+		}
+	})
+
+	t.Run("brand references", func(t *testing.T) {
+		repo := newBackend(t).Brands
+		repo.CreateBrand(ctx, "Apple")
+		repo.CreateBrand(ctx, "Samsung")
+
+		if _, err := repo.CreateBrandReference(ctx, "Pixel 8", "Google"); !errors.Is(err, ports.PhoneBrandDoesNotExists) {
+			t.Errorf("expected PhoneBrandDoesNotExists, got %v", err)
+		}
+		first, err := repo.CreateBrandReference(ctx, "iPhone 14", "Apple")
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, _ := repo.CreateBrandReference(ctx, "iPhone 15", "Apple")
+		repo.CreateBrandReference(ctx, "Galaxy S23", "Samsung")
+		if first.ID == 0 || first.Brand != "Apple" || first.Name != "iPhone 14" {
+			t.Errorf("unexpected reference %+v", first)
+		}
+
+		refs := repo.ListBrandReferences(ctx, "Apple")
+		if len(refs) != 2 || refs[0].ID != first.ID || refs[1].ID != second.ID {
+			t.Errorf("expected the references of the brand in order, got %+v", refs)
+		}
+		if refs := repo.ListBrandReferences(ctx, "Google"); len(refs) != 0 {
+			t.Errorf("expected no references, got %+v", refs)
+		}
+
+		if found, ok := repo.GetBrandReferenceByID(ctx, second.ID); !ok || found.Name != "iPhone 15" {
+			t.Errorf("unexpected reference %+v", found)
+		}
+		if _, ok := repo.GetBrandReferenceByID(ctx, missingID); ok {
+			t.Error("GetBrandReferenceByID of a missing reference")
+		}
+
+		updated, err := repo.UpdateBrandReference(ctx, first.ID, "iPhone 14 Pro")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Name != "iPhone 14 Pro" || updated.Brand != "Apple" {
+			t.Errorf("unexpected updated reference %+v", updated)
+		}
+		if _, err := repo.UpdateBrandReference(ctx, missingID, "x"); !errors.Is(err, ports.PhoneBrandReferenceDoesNotExists) {
+			t.Errorf("expected PhoneBrandReferenceDoesNotExists, got %v", err)
+		}
+
+		// references follow a renamed brand
+		if err := repo.UpdateBrand(ctx, "Apple", "Apple Inc"); err != nil {
+			t.Fatal(err)
+		}
+		if refs := repo.ListBrandReferences(ctx, "Apple Inc"); len(refs) != 2 {
+			t.Errorf("expected 2 references of the renamed brand, got %+v", refs)
+		}
+		if found, _ := repo.GetBrandReferenceByID(ctx, first.ID); found.Brand != "Apple Inc" {
+			t.Errorf("expected the renamed brand, got %q", found.Brand)
+		}
+	})
+}
+
+func hasBrand(brands []domain.PhoneBrand, brand domain.PhoneBrand) bool {
+	for _, b := range brands {
+		if b == brand {
+			return true
+		}
+	}
+	return false
+}
+
+func CaseTypeRepository(t *testing.T, newBackend NewBackend) {
+	ctx := context.Background()
+	t.Run("case types", func(t *testing.T) {
+		repo := newBackend(t).CaseTypes
+
+		silicone, err := repo.CreateCaseType(ctx, "Silicone", "/icons/silicone.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		leather, _ := repo.CreateCaseType(ctx, "Leather", "")
+		if silicone.ID == 0 || silicone.Version != 1 || silicone.IconImgPath != "/icons/silicone.png" {
+			t.Errorf("unexpected case type %+v", silicone)
+		}
+
+		list := repo.ListCaseTypes(ctx)
+		if len(list) != 2 || list[0].ID != silicone.ID || list[1].ID != leather.ID {
+			t.Errorf("expected the case types in order, got %+v", list)
+		}
+		if _, ok := repo.GetCaseTypeByID(ctx, missingID); ok {
+			t.Error("GetCaseTypeByID of a missing case type")
+		}
+
+		updated, err := repo.UpdateCaseType(ctx, silicone.ID, silicone.Version, "Soft silicone")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Name != "Soft silicone" || updated.IconImgPath != "/icons/silicone.png" || updated.Version != 2 {
+			t.Errorf("unexpected updated case type %+v", updated)
+		}
+		if _, err := repo.UpdateCaseType(ctx, silicone.ID, silicone.Version, "Stale"); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if _, err := repo.UpdateCaseType(ctx, missingID, ports.AnyVersion, "x"); !errors.Is(err, ports.CaseTypeDoesNotExists) {
+			t.Errorf("expected CaseTypeDoesNotExists, got %v", err)
+		}
+	})
+
+	t.Run("delete case types", func(t *testing.T) {
+		b := newBackend(t)
+		c := seedCatalog(ctx, t, b)
+		unused, _ := b.CaseTypes.CreateCaseType(ctx, "Leather", "")
+		image, _ := b.CaseTypes.CreateCaseTypeImage(ctx, unused.ID, "/leather.png", 1)
+		b.CaseTypes.DeleteCaseTypeImage(ctx, image.ID)
+		b.CaseTypes.CreateCaseTypeImage(ctx, unused.ID, "/leather-back.png", 2)
+
+		// deleted phone cases are kept, so they still use their case type
+		phoneCase := createPhoneCase(ctx, t, b, c, 45000, domain.PhoneCaseAvailable)
+		if err := b.PhoneCases.DeletePhoneCase(ctx, phoneCase.ID, ports.AnyVersion); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.CaseTypes.DeleteCaseType(ctx, c.caseType.ID, ports.AnyVersion); !errors.Is(err, ports.CaseTypeInUse) {
+			t.Errorf("expected CaseTypeInUse, got %v", err)
+		}
+
+		if err := b.CaseTypes.DeleteCaseType(ctx, unused.ID, unused.Version+1); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if err := b.CaseTypes.DeleteCaseType(ctx, missingID, ports.AnyVersion); !errors.Is(err, ports.CaseTypeDoesNotExists) {
+			t.Errorf("expected CaseTypeDoesNotExists, got %v", err)
+		}
+		if err := b.CaseTypes.DeleteCaseType(ctx, unused.ID, unused.Version); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := b.CaseTypes.GetCaseTypeByID(ctx, unused.ID); ok {
+			t.Error("the case type was not deleted")
+		}
+		if images := b.CaseTypes.ListCaseTypeImages(ctx, unused.ID, true); len(images) != 0 {
+			t.Errorf("the images must be deleted with their case type, got %+v", images)
+		}
+		if list := b.CaseTypes.ListCaseTypes(ctx); len(list) != 1 || list[0].ID != c.caseType.ID {
+			t.Errorf("expected only the used case type, got %+v", list)
+		}
+	})
+
+	t.Run("images", func(t *testing.T) {
+		repo := newBackend(t).CaseTypes
+		caseType, _ := repo.CreateCaseType(ctx, "Silicone", "")
+		other, _ := repo.CreateCaseType(ctx, "Leather", "")
+
+		if _, err := repo.CreateCaseTypeImage(ctx, missingID, "/img.png", 1); !errors.Is(err, ports.CaseTypeDoesNotExists) {
+			t.Errorf("expected CaseTypeDoesNotExists, got %v", err)
+		}
+		last, err := repo.CreateCaseTypeImage(ctx, caseType.ID, "/last.png", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first, _ := repo.CreateCaseTypeImage(ctx, caseType.ID, "/first.png", 1)
+		second, _ := repo.CreateCaseTypeImage(ctx, caseType.ID, "/second.png", 1)
+		repo.CreateCaseTypeImage(ctx, other.ID, "/other.png", 0)
+
+		// images are sorted by their priority, then by creation
+		images := repo.ListCaseTypeImages(ctx, caseType.ID, false)
+		expected := []domain.CaseTypeImageID{first.ID, second.ID, last.ID}
+		if len(images) != len(expected) {
+			t.Fatalf("expected %d images, got %+v", len(expected), images)
+		}
+		for i, ID := range expected {
+			if images[i].ID != ID {
+				t.Errorf("expected images %v, got %+v", expected, images)
+				break
+			}
+		}
+		if found, _ := repo.GetCaseTypeByID(ctx, caseType.ID); len(found.Images) != 3 || found.Images[0].ID != first.ID {
+			t.Errorf("case type must load its sorted images, got %+v", found.Images)
+		}
+
+		updated, err := repo.UpdateCaseTypeImage(ctx, last.ID, "/updated.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Path != "/updated.png" || updated.OrderPriority != 2 {
+			t.Errorf("unexpected updated image %+v", updated)
+		}
+		if _, err := repo.UpdateCaseTypeImage(ctx, missingID, "/x.png"); !errors.Is(err, ports.CaseTypeImageDoesNotExists) {
+			t.Errorf("expected CaseTypeImageDoesNotExists, got %v", err)
+		}
+
+		if err := repo.DeleteCaseTypeImage(ctx, first.ID); err != nil {
+			t.Fatal(err)
+		}
+		if images := repo.ListCaseTypeImages(ctx, caseType.ID, false); len(images) != 2 {
+			t.Errorf("expected 2 images, got %+v", images)
+		}
+		if found, _ := repo.GetCaseTypeByID(ctx, caseType.ID); len(found.Images) != 2 {
+			t.Errorf("case type must not load its deleted images, got %+v", found.Images)
+		}
+		images = repo.ListCaseTypeImages(ctx, caseType.ID, true)
+		if len(images) != 3 || images[0].ID != first.ID || !images[0].IsDeleted() {
+			t.Errorf("expected the deleted image, got %+v", images)
+		}
+		if err := repo.DeleteCaseTypeImage(ctx, first.ID); !errors.Is(err, ports.CaseTypeImageDoesNotExists) {
+			t.Errorf("expected CaseTypeImageDoesNotExists, got %v", err)
+		}
+		if _, err := repo.UpdateCaseTypeImage(ctx, first.ID, "/x.png"); !errors.Is(err, ports.CaseTypeImageDoesNotExists) {
+			t.Errorf("expected CaseTypeImageDoesNotExists, got %v", err)
+		}
+
+		if _, err := repo.RestoreCaseTypeImage(ctx, second.ID); !errors.Is(err, ports.NotDeleted) {
+			t.Errorf("expected NotDeleted, got %v", err)
+		}
+		if _, err := repo.RestoreCaseTypeImage(ctx, missingID); !errors.Is(err, ports.CaseTypeImageDoesNotExists) {
+			t.Errorf("expected CaseTypeImageDoesNotExists, got %v", err)
+		}
+		restored, err := repo.RestoreCaseTypeImage(ctx, first.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.IsDeleted() || restored.Path != "/first.png" {
+			t.Errorf("unexpected restored image %+v", restored)
+		}
+		if images := repo.ListCaseTypeImages(ctx, caseType.ID, false); len(images) != 3 {
+			t.Errorf("a restored image is listed again, got %+v", images)
+		}
+	})
+}
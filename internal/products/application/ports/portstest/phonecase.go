@@ -0,0 +1,458 @@
+package portstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/Rhymond/go-money"
+)
+
+type catalog struct {
+	ref      domain.PhoneBrandReference
+	caseType domain.CaseType
+}
+
+func seedCatalog(ctx context.Context, t *testing.T, b Backend) catalog {
+	t.Helper()
+	if err := b.Brands.CreateBrand(ctx, "Apple"); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := b.Brands.CreateBrandReference(ctx, "iPhone 15", "Apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caseType, err := b.CaseTypes.CreateCaseType(ctx, "Silicone", "/icons/silicone.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.CaseTypes.CreateCaseTypeImage(ctx, caseType.ID, "/img/silicone.png", 1); err != nil {
+		t.Fatal(err)
+	}
+	return catalog{ref: ref, caseType: caseType}
+}
+
+func createPhoneCase(ctx context.Context, t *testing.T, b Backend, c catalog, amount int64, status domain.InventoryStatus) domain.PhoneCase {
+	t.Helper()
+	phoneCase, err := b.PhoneCases.CreatePhoneCase(ctx, *money.New(amount, money.COP), "/scaffold.png", status, c.ref.ID, c.caseType.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return phoneCase
+}
+
+func phoneCaseIDs(cases []domain.PhoneCase) []domain.PhoneCaseID {
+	IDs := make([]domain.PhoneCaseID, 0, len(cases))
+	for _, c := range cases {
+		IDs = append(IDs, c.ID)
+	}
+	return IDs
+}
+
+func PhoneCaseRepository(t *testing.T, newBackend NewBackend) {
+	ctx := context.Background()
+	t.Run("create and get", func(t *testing.T) {
+		b := newBackend(t)
+		c := seedCatalog(ctx, t, b)
+
+		created := createPhoneCase(ctx, t, b, c, 45000, domain.PhoneCaseAvailable)
+		if created.ID == 0 || created.Version != 1 || created.CreatedBy.ID != 1 {
+			t.Errorf("unexpected phone case %+v", created)
+		}
+
+		found, ok := b.PhoneCases.GetPhoneCaseByID(ctx, created.ID)
+		if !ok {
+			t.Fatal("created phone case not found")
+		}
+		if found.Price.Amount() != 45000 || found.Price.Currency().Code != money.COP ||
+			found.CaseScaffoldImgPah != "/scaffold.png" || found.InventoryStatus != domain.PhoneCaseAvailable {
+			t.Errorf("unexpected phone case %+v", found)
+		}
+		if found.PhoneBrandReference != c.ref {
+			t.Errorf("expected reference %+v, got %+v", c.ref, found.PhoneBrandReference)
+		}
+		if found.CaseType.ID != c.caseType.ID || found.CaseType.Name != "Silicone" || len(found.CaseType.Images) != 1 {
+			t.Errorf("unexpected case type %+v", found.CaseType)
+		}
+		if found.Discount.ID != 0 {
+			t.Errorf("expected no discount, got %+v", found.Discount)
+		}
+		if _, ok := b.PhoneCases.GetPhoneCaseByID(ctx, missingID); ok {
+			t.Error("GetPhoneCaseByID of a missing phone case")
+		}
+	})
+
+	t.Run("missing references", func(t *testing.T) {
+		b := newBackend(t)
+		c := seedCatalog(ctx, t, b)
+		created := createPhoneCase(ctx, t, b, c, 45000, domain.PhoneCaseAvailable)
+		price := *money.New(1000, money.COP)
+
+		_, unknownRef := b.PhoneCases.CreatePhoneCase(ctx, price, "", domain.PhoneCaseAvailable, missingID, c.caseType.ID, 1)
+		_, unknownType := b.PhoneCases.CreatePhoneCase(ctx, price, "", domain.PhoneCaseAvailable, c.ref.ID, missingID, 1)
+		_, updateUnknownRef := b.PhoneCases.UpdatePhoneCase(
+			ctx, created.ID, ports.AnyVersion, price, "", domain.PhoneCaseAvailable, missingID, c.caseType.ID,
+		)
+		_, updateUnknownType := b.PhoneCases.UpdatePhoneCase(
+			ctx, created.ID, ports.AnyVersion, price, "", domain.PhoneCaseAvailable, c.ref.ID, missingID,
+		)
+		_, updateMissing := b.PhoneCases.UpdatePhoneCase(
+			ctx, missingID, ports.AnyVersion, price, "", domain.PhoneCaseAvailable, c.ref.ID, c.caseType.ID,
+		)
+		cases := []struct {
+			name     string
+			err      error
+			expected error
+		}{
+			{"create with unknown reference", unknownRef, ports.PhoneBrandReferenceDoesNotExists},
+			{"create with unknown case type", unknownType, ports.CaseTypeDoesNotExists},
+			{"update with unknown reference", updateUnknownRef, ports.PhoneBrandReferenceDoesNotExists},
+			{"update with unknown case type", updateUnknownType, ports.CaseTypeDoesNotExists},
+			{"update missing phone case", updateMissing, ports.PhoneCaseDoesNotExists},
+			{"delete missing phone case", b.PhoneCases.DeletePhoneCase(ctx, missingID, ports.AnyVersion), ports.PhoneCaseDoesNotExists},
+			{"attach unknown discount", b.PhoneCases.AttachDiscount(ctx, created.ID, missingID), ports.DiscountDoesNotExists},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				if !errors.Is(c.err, c.expected) {
+					t.Errorf("expected %v, got %v", c.expected, c.err)
+				}
+			})
+		}
+
+		if _, total := b.PhoneCases.ListPhoneCases(ctx, nil, 10, 0); total != 1 {
+			t.Errorf("rejected writes must not be stored, got %d phone cases", total)
+		}
+		if found, _ := b.PhoneCases.GetPhoneCaseByID(ctx, created.ID); found.Version != created.Version || found.Price.Amount() != 45000 {
+			t.Errorf("rejected writes must not change the phone case, got %+v", found)
+		}
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		b := newBackend(t)
+		c := seedCatalog(ctx, t, b)
+		created := createPhoneCase(ctx, t, b, c, 45000, domain.PhoneCaseAvailable)
+		otherType, _ := b.CaseTypes.CreateCaseType(ctx, "Leather", "")
+
+		updated, err := b.PhoneCases.UpdatePhoneCase(
+			ctx, created.ID, created.Version, *money.New(50000, money.COP), "/new.png", domain.PhoneCaseOutOfStock,
+			c.ref.ID, otherType.ID,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Price.Amount() != 50000 || updated.CaseScaffoldImgPah != "/new.png" ||
+			updated.InventoryStatus != domain.PhoneCaseOutOfStock || updated.CaseType.ID != otherType.ID {
+			t.Errorf("unexpected updated phone case %+v", updated)
+		}
+		if updated.Version != created.Version+1 {
+			t.Errorf("expected version %d, got %d", created.Version+1, updated.Version)
+		}
+
+		if err := b.PhoneCases.DeletePhoneCase(ctx, created.ID, created.Version); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if err := b.PhoneCases.DeletePhoneCase(ctx, created.ID, updated.Version); err != nil {
+			t.Fatal(err)
+		}
+		if found, ok := b.PhoneCases.GetPhoneCaseByID(ctx, created.ID); !ok || !found.IsDeleted() {
+			t.Errorf("a deleted phone case is kept as deleted, got %+v", found)
+		}
+	})
+
+	t.Run("soft delete and restore", func(t *testing.T) {
+		b := newBackend(t)
+		c := seedCatalog(ctx, t, b)
+		kept := createPhoneCase(ctx, t, b, c, 45000, domain.PhoneCaseAvailable)
+		deleted := createPhoneCase(ctx, t, b, c, 45000, domain.PhoneCaseAvailable)
+		discount, _ := b.PhoneCases.CreateDiscount(ctx, "Black friday", 20, time.Now().Add(time.Hour), 1)
+		price := *money.New(50000, money.COP)
+
+		if _, err := b.PhoneCases.RestorePhoneCase(ctx, deleted.ID, ports.AnyVersion); !errors.Is(err, ports.NotDeleted) {
+			t.Errorf("expected NotDeleted, got %v", err)
+		}
+		if err := b.PhoneCases.DeletePhoneCase(ctx, deleted.ID, deleted.Version); err != nil {
+			t.Fatal(err)
+		}
+		found, _ := b.PhoneCases.GetPhoneCaseByID(ctx, deleted.ID)
+		if !found.IsDeleted() || found.Version != deleted.Version+1 {
+			t.Errorf("expected a deleted phone case with version %d, got %+v", deleted.Version+1, found)
+		}
+
+		list, total := b.PhoneCases.ListPhoneCases(ctx, nil, 10, 0)
+		if total != 1 {
+			t.Errorf("expected total 1, got %d", total)
+		}
+		assertIDs(t, []domain.PhoneCaseID{kept.ID}, phoneCaseIDs(list))
+		list, total = b.PhoneCases.ListPhoneCases(ctx, map[string]string{ports.IncludeDeleted: "true"}, 10, 0)
+		if total != 2 {
+			t.Errorf("expected total 2, got %d", total)
+		}
+		assertIDs(t, []domain.PhoneCaseID{kept.ID, deleted.ID}, phoneCaseIDs(list))
+
+		_, updateDeleted := b.PhoneCases.UpdatePhoneCase(
+			ctx, deleted.ID, ports.AnyVersion, price, "", domain.PhoneCaseAvailable, c.ref.ID, c.caseType.ID,
+		)
+		_, restoreStale := b.PhoneCases.RestorePhoneCase(ctx, deleted.ID, deleted.Version)
+		_, restoreMissing := b.PhoneCases.RestorePhoneCase(ctx, missingID, ports.AnyVersion)
+		cases := []struct {
+			name     string
+			err      error
+			expected error
+		}{
+			{"update deleted phone case", updateDeleted, ports.PhoneCaseDoesNotExists},
+			{"delete deleted phone case", b.PhoneCases.DeletePhoneCase(ctx, deleted.ID, ports.AnyVersion), ports.PhoneCaseDoesNotExists},
+			{"attach to deleted phone case", b.PhoneCases.AttachDiscount(ctx, deleted.ID, discount.ID), ports.PhoneCaseDoesNotExists},
+			{"restore with stale version", restoreStale, ports.VersionMismatch},
+			{"restore missing phone case", restoreMissing, ports.PhoneCaseDoesNotExists},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				if !errors.Is(c.err, c.expected) {
+					t.Errorf("expected %v, got %v", c.expected, c.err)
+				}
+			})
+		}
+
+		restored, err := b.PhoneCases.RestorePhoneCase(ctx, deleted.ID, found.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.IsDeleted() || restored.Version != found.Version+1 {
+			t.Errorf("expected a restored phone case with version %d, got %+v", found.Version+1, restored)
+		}
+		if _, total := b.PhoneCases.ListPhoneCases(ctx, nil, 10, 0); total != 2 {
+			t.Errorf("a restored phone case is listed again, got total %d", total)
+		}
+	})
+
+	t.Run("list pagination", func(t *testing.T) {
+		b := newBackend(t)
+		c := seedCatalog(ctx, t, b)
+		var added []domain.PhoneCaseID
+		for i := 0; i < 5; i++ {
+			added = append(added, createPhoneCase(ctx, t, b, c, 45000, domain.PhoneCaseAvailable).ID)
+		}
+
+		// limit is the end of the page, not its size
+		cases := []struct {
+			name          string
+			limit, offset int
+			expected      []domain.PhoneCaseID
+		}{
+			{"first page", 2, 0, added[0:2]},
+			{"middle page", 4, 2, added[2:4]},
+			{"last partial page", 6, 4, added[4:]},
+			{"whole list", 5, 0, added},
+			{"page after the end", 10, 5, nil},
+			{"page far after the end", 20, 10, nil},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				list, total := b.PhoneCases.ListPhoneCases(ctx, map[string]string{}, c.limit, c.offset)
+				if total != len(added) {
+					t.Errorf("expected total %d, got %d", len(added), total)
+				}
+				assertIDs(t, c.expected, phoneCaseIDs(list))
+			})
+		}
+	})
+
+	t.Run("list filters", func(t *testing.T) {
+		b := newBackend(t)
+		c := seedCatalog(ctx, t, b)
+		cheap := createPhoneCase(ctx, t, b, c, 30000, domain.PhoneCaseAvailable)
+		expensive := createPhoneCase(ctx, t, b, c, 60000, domain.PhoneCaseAvailable)
+		soldOut := createPhoneCase(ctx, t, b, c, 30000, domain.PhoneCaseOutOfStock)
+
+		cases := []struct {
+			name     string
+			filters  map[string]string
+			expected []domain.PhoneCaseID
+		}{
+			{"no filters", nil, []domain.PhoneCaseID{cheap.ID, expensive.ID, soldOut.ID}},
+			{"price", map[string]string{"price": "30000"}, []domain.PhoneCaseID{cheap.ID, soldOut.ID}},
+			{"inventory status", map[string]string{"inventory_status": "OUT_OF_STOCK"}, []domain.PhoneCaseID{soldOut.ID}},
+			{"every filter must match", map[string]string{"price": "30000", "inventory_status": "AVAILABLE"}, []domain.PhoneCaseID{cheap.ID}},
+			{"empty filters are ignored", map[string]string{"price": "", "inventory_status": "AVAILABLE"}, []domain.PhoneCaseID{cheap.ID, expensive.ID}},
+			{"unknown filters are ignored", map[string]string{"color": "red"}, []domain.PhoneCaseID{cheap.ID, expensive.ID, soldOut.ID}},
+			{"no match", map[string]string{"price": "1"}, nil},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				list, total := b.PhoneCases.ListPhoneCases(ctx, c.filters, 10, 0)
+				if total != len(c.expected) {
+					t.Errorf("expected total %d, got %d", len(c.expected), total)
+				}
+				assertIDs(t, c.expected, phoneCaseIDs(list))
+			})
+		}
+	})
+}
+
+func Discounts(t *testing.T, newBackend NewBackend) {
+	ctx := context.Background()
+	t.Run("create, update and get", func(t *testing.T) {
+		repo := newBackend(t).PhoneCases
+		validUntil := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		if discounts := repo.ListAllDiscounts(ctx, false); len(discounts) != 0 {
+			t.Errorf("expected no discounts, got %+v", discounts)
+		}
+		first, err := repo.CreateDiscount(ctx, "Black friday", 20, validUntil, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, _ := repo.CreateDiscount(ctx, "Christmas", 10, validUntil, 1)
+		if first.ID == 0 || first.Version != 1 || first.Name != "Black friday" || first.Rate != 20 ||
+			!first.ValidUntil.Equal(validUntil) || first.CreatedBy.ID != 1 {
+			t.Errorf("unexpected discount %+v", first)
+		}
+
+		discounts := repo.ListAllDiscounts(ctx, false)
+		if len(discounts) != 2 || discounts[0].ID != first.ID || discounts[1].ID != second.ID {
+			t.Errorf("expected the discounts in order, got %+v", discounts)
+		}
+		if _, ok := repo.GetDiscountByID(ctx, missingID); ok {
+			t.Error("GetDiscountByID of a missing discount")
+		}
+
+		extended := validUntil.AddDate(0, 1, 0)
+		updated, err := repo.UpdateDiscount(ctx, first.ID, first.Version, 30, extended)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Rate != 30 || !updated.ValidUntil.Equal(extended) || updated.Name != "Black friday" || updated.Version != 2 {
+			t.Errorf("unexpected updated discount %+v", updated)
+		}
+		if found, _ := repo.GetDiscountByID(ctx, first.ID); found.Rate != 30 {
+			t.Errorf("expected the updated rate, got %d", found.Rate)
+		}
+		if _, err := repo.UpdateDiscount(ctx, first.ID, first.Version, 40, extended); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if _, err := repo.UpdateDiscount(ctx, missingID, ports.AnyVersion, 40, extended); !errors.Is(err, ports.DiscountDoesNotExists) {
+			t.Errorf("expected DiscountDoesNotExists, got %v", err)
+		}
+	})
+
+	t.Run("attach and delete", func(t *testing.T) {
+		b := newBackend(t)
+		c := seedCatalog(ctx, t, b)
+		phoneCase := createPhoneCase(ctx, t, b, c, 45000, domain.PhoneCaseAvailable)
+		discount, _ := b.PhoneCases.CreateDiscount(ctx, "Black friday", 20, time.Now().Add(time.Hour), 1)
+
+		if err := b.PhoneCases.AttachDiscount(ctx, missingID, discount.ID); !errors.Is(err, ports.PhoneCaseDoesNotExists) {
+			t.Errorf("expected PhoneCaseDoesNotExists, got %v", err)
+		}
+		if err := b.PhoneCases.AttachDiscount(ctx, phoneCase.ID, discount.ID); err != nil {
+			t.Fatal(err)
+		}
+		found, _ := b.PhoneCases.GetPhoneCaseByID(ctx, phoneCase.ID)
+		if found.Discount.ID != discount.ID || found.Discount.Rate != 20 {
+			t.Errorf("expected the attached discount, got %+v", found.Discount)
+		}
+		if found.Version != phoneCase.Version+1 {
+			t.Errorf("attaching a discount changes the phone case, expected version %d, got %d",
+				phoneCase.Version+1, found.Version)
+		}
+
+		if err := b.PhoneCases.DeleteDiscount(ctx, discount.ID, discount.Version+1); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if found, _ := b.PhoneCases.GetPhoneCaseByID(ctx, phoneCase.ID); found.Discount.ID != discount.ID {
+			t.Error("a rejected delete must not hide the discount")
+		}
+
+		if err := b.PhoneCases.DeleteDiscount(ctx, discount.ID, discount.Version); err != nil {
+			t.Fatal(err)
+		}
+		deleted, ok := b.PhoneCases.GetDiscountByID(ctx, discount.ID)
+		if !ok || !deleted.IsDeleted() || deleted.Version != discount.Version+1 {
+			t.Errorf("a deleted discount is kept as deleted, got %+v", deleted)
+		}
+		if discounts := b.PhoneCases.ListAllDiscounts(ctx, false); len(discounts) != 0 {
+			t.Errorf("deleted discounts are not listed, got %+v", discounts)
+		}
+		if discounts := b.PhoneCases.ListAllDiscounts(ctx, true); len(discounts) != 1 {
+			t.Errorf("expected the deleted discount, got %+v", discounts)
+		}
+		hidden, _ := b.PhoneCases.GetPhoneCaseByID(ctx, phoneCase.ID)
+		if hidden.Discount.ID != 0 {
+			t.Error("a deleted discount must not be shown on its phone cases")
+		}
+		if hidden.Version != found.Version+1 {
+			t.Errorf("hiding a discount changes the phone case, expected version %d, got %d",
+				found.Version+1, hidden.Version)
+		}
+		if err := b.PhoneCases.DeleteDiscount(ctx, discount.ID, ports.AnyVersion); !errors.Is(err, ports.DiscountDoesNotExists) {
+			t.Errorf("expected DiscountDoesNotExists, got %v", err)
+		}
+		if _, err := b.PhoneCases.UpdateDiscount(ctx, discount.ID, ports.AnyVersion, 10, time.Now()); !errors.Is(err, ports.DiscountDoesNotExists) {
+			t.Errorf("expected DiscountDoesNotExists, got %v", err)
+		}
+		if err := b.PhoneCases.AttachDiscount(ctx, phoneCase.ID, discount.ID); !errors.Is(err, ports.DiscountDoesNotExists) {
+			t.Errorf("expected DiscountDoesNotExists, got %v", err)
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		b := newBackend(t)
+		repo := b.PhoneCases
+		phoneCase := createPhoneCase(ctx, t, b, seedCatalog(ctx, t, b), 45000, domain.PhoneCaseAvailable)
+		discount, _ := repo.CreateDiscount(ctx, "Black friday", 20, time.Now().Add(time.Hour), 1)
+		if err := repo.AttachDiscount(ctx, phoneCase.ID, discount.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.RestoreDiscount(ctx, discount.ID, ports.AnyVersion); !errors.Is(err, ports.NotDeleted) {
+			t.Errorf("expected NotDeleted, got %v", err)
+		}
+		if err := repo.DeleteDiscount(ctx, discount.ID, discount.Version); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.RestoreDiscount(ctx, discount.ID, discount.Version); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if _, err := repo.RestoreDiscount(ctx, missingID, ports.AnyVersion); !errors.Is(err, ports.DiscountDoesNotExists) {
+			t.Errorf("expected DiscountDoesNotExists, got %v", err)
+		}
+
+		restored, err := repo.RestoreDiscount(ctx, discount.ID, discount.Version+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.IsDeleted() || restored.Version != discount.Version+2 || restored.Rate != 20 {
+			t.Errorf("unexpected restored discount %+v", restored)
+		}
+		if discounts := repo.ListAllDiscounts(ctx, false); len(discounts) != 1 {
+			t.Errorf("a restored discount is listed again, got %+v", discounts)
+		}
+		// the phone cases of the discount show it again
+		found, _ := repo.GetPhoneCaseByID(ctx, phoneCase.ID)
+		if found.Discount.ID != discount.ID {
+			t.Errorf("expected the restored discount, got %+v", found.Discount)
+		}
+		if found.Version != phoneCase.Version+3 {
+			t.Errorf("attaching, hiding and showing the discount change the phone case, expected version %d, got %d",
+				phoneCase.Version+3, found.Version)
+		}
+	})
+}
+
+func assertIDs[ID comparable](t *testing.T, expected []ID, got []ID) {
+	t.Helper()
+	if len(expected) != len(got) {
+		t.Errorf("expected %v, got %v", expected, got)
+		return
+	}
+	for i := range expected {
+		if expected[i] != got[i] {
+			t.Errorf("expected %v, got %v", expected, got)
+			return
+		}
+	}
+}
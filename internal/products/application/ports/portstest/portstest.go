@@ -0,0 +1,28 @@
+package portstest
+
+import (
+	"testing"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+)
+
+// Backend are the repositories of a storage backend, they must share their
+// data, a phone case references the brands and case types of the backend
+type Backend struct {
+	PhoneCases ports.PhoneCaseRepository
+	Brands     ports.PhoneBrandRepository
+	CaseTypes  ports.CaseTypeRepository
+}
+
+// NewBackend returns an empty backend, it's called once per test so the tests
+// don't see the data of each other
+type NewBackend func(t *testing.T) Backend
+
+// Run runs the contract of every repository against the backend, each backend
+// must pass the same tests
+func Run(t *testing.T, newBackend NewBackend) {
+	t.Run("PhoneBrandRepository", func(t *testing.T) { PhoneBrandRepository(t, newBackend) })
+	t.Run("CaseTypeRepository", func(t *testing.T) { CaseTypeRepository(t, newBackend) })
+	t.Run("PhoneCaseRepository", func(t *testing.T) { PhoneCaseRepository(t, newBackend) })
+	t.Run("Discounts", func(t *testing.T) { Discounts(t, newBackend) })
+}
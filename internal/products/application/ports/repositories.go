@@ -1,7 +1,8 @@
 package ports
 
 import (
-	"errors"
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
 	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
 	"github.com/Rhymond/go-money"
@@ -9,57 +10,89 @@ import (
 )
 
 var (
-	PhoneCaseDoesNotExists           = errors.New("phone case does not exists")
-	DiscountDoesNotExists            = errors.New("discount does not exists")
-	PhoneBrandDoesNotExists          = errors.New("phone brand does not exists")
-	PhoneBrandReferenceDoesNotExists = errors.New("phone brand reference does not exists")
-	BrandAlreadyExists               = errors.New("brand already exists")
-	CaseTypeDoesNotExists            = errors.New("case type does not exists")
-	CaseTypeImageDoesNotExists       = errors.New("case type image does not exists")
+	PhoneCaseDoesNotExists           = apperror.New(apperror.NotFound, "phone_case_not_found", "phone case does not exists")
+	DiscountDoesNotExists            = apperror.New(apperror.NotFound, "discount_not_found", "discount does not exists")
+	PhoneBrandDoesNotExists          = apperror.New(apperror.NotFound, "phone_brand_not_found", "phone brand does not exists")
+	PhoneBrandReferenceDoesNotExists = apperror.New(apperror.NotFound, "phone_brand_reference_not_found", "phone brand reference does not exists")
+	BrandAlreadyExists               = apperror.New(apperror.Conflict, "brand_already_exists", "brand already exists")
+	CaseTypeDoesNotExists            = apperror.New(apperror.NotFound, "case_type_not_found", "case type does not exists")
+	CaseTypeImageDoesNotExists       = apperror.New(apperror.NotFound, "case_type_image_not_found", "case type image does not exists")
+	CaseTypeInUse                    = apperror.New(apperror.Conflict, "case_type_in_use", "case type is used by phone cases")
+	VersionMismatch                  = apperror.New(apperror.PreconditionFailed, "version_mismatch", "resource was modified by another request")
+	NotDeleted                       = apperror.New(apperror.Conflict, "not_deleted", "resource is not deleted")
 )
 
+// AnyVersion skips the version check of a write, any other version must match
+// the current one or the write fails with VersionMismatch
+const AnyVersion = 0
+
+// IncludeDeleted is the list filter that adds the soft deleted rows, any
+// value but "true" is ignored
+const IncludeDeleted = "include_deleted"
+
+// Deletes of phone cases, discounts and case type images are soft deletes, the
+// rows are still returned by their ID but hidden from the lists, and they can't
+// be changed until they are restored. Phone cases don't show a deleted discount
+// but keep it, so restoring the discount shows it on them again
 type PhoneCaseRepository interface {
-	ListPhoneCases(filters map[string]string, limit int, offset int) ([]domain.PhoneCase, int)
-	GetPhoneCaseByID(id domain.PhoneCaseID) (domain.PhoneCase, bool)
+	// ListPhoneCases supports the filters 'price', 'inventory_status' and
+	// IncludeDeleted, empty filters are ignored
+	ListPhoneCases(ctx context.Context, filters map[string]string, limit int, offset int) ([]domain.PhoneCase, int)
+	GetPhoneCaseByID(ctx context.Context, id domain.PhoneCaseID) (domain.PhoneCase, bool)
 	CreatePhoneCase(
-		price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus,
-		phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID,
-		createdBy users.UserID,
+		ctx context.Context, price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus,
+		phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID, createdBy users.UserID,
 	) (domain.PhoneCase, error)
 	UpdatePhoneCase(
-		ID domain.PhoneCaseID, price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus,
+		ctx context.Context, ID domain.PhoneCaseID, version int,
+		price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus,
 		phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID,
 	) (domain.PhoneCase, error)
-	DeletePhoneCase(ID domain.PhoneCaseID) error
+	DeletePhoneCase(ctx context.Context, ID domain.PhoneCaseID, version int) error
+	RestorePhoneCase(ctx context.Context, ID domain.PhoneCaseID, version int) (domain.PhoneCase, error)
 
-	AttachDiscount(caseID domain.PhoneCaseID, discountID domain.DiscountID) error
+	AttachDiscount(ctx context.Context, caseID domain.PhoneCaseID, discountID domain.DiscountID) error
 
-	ListAllDiscounts() []domain.Discount
-	GetDiscountByID(ID domain.DiscountID) (domain.Discount, bool)
-	CreateDiscount(name string, rate int, validUntil time.Time, createdBy users.UserID) (domain.Discount, error)
-	UpdateDiscount(ID domain.DiscountID, rate int, validUntil time.Time) (domain.Discount, error)
-	DeleteDiscount(ID domain.DiscountID) error
+	ListAllDiscounts(ctx context.Context, includeDeleted bool) []domain.Discount
+	GetDiscountByID(ctx context.Context, ID domain.DiscountID) (domain.Discount, bool)
+	CreateDiscount(
+		ctx context.Context, name string, rate int, validUntil time.Time, createdBy users.UserID,
+	) (domain.Discount, error)
+	UpdateDiscount(
+		ctx context.Context, ID domain.DiscountID, version int, rate int, validUntil time.Time,
+	) (domain.Discount, error)
+	DeleteDiscount(ctx context.Context, ID domain.DiscountID, version int) error
+	RestoreDiscount(ctx context.Context, ID domain.DiscountID, version int) (domain.Discount, error)
 }
 
 type PhoneBrandRepository interface {
-	ListAllBrands() []domain.PhoneBrand
-	UpdateBrand(brand domain.PhoneBrand, renameTo string) error
-	CreateBrand(brand domain.PhoneBrand) error
+	ListAllBrands(ctx context.Context) []domain.PhoneBrand
+	UpdateBrand(ctx context.Context, brand domain.PhoneBrand, renameTo string) error
+	CreateBrand(ctx context.Context, brand domain.PhoneBrand) error
 
-	ListBrandReferences(brand domain.PhoneBrand) []domain.PhoneBrandReference
-	GetBrandReferenceByID(ID domain.PhoneBrandReferenceID) (domain.PhoneBrandReference, bool)
-	UpdateBrandReference(ID domain.PhoneBrandReferenceID, name string) (domain.PhoneBrandReference, error)
-	CreateBrandReference(name string, brand domain.PhoneBrand) (domain.PhoneBrandReference, error)
+	ListBrandReferences(ctx context.Context, brand domain.PhoneBrand) []domain.PhoneBrandReference
+	GetBrandReferenceByID(ctx context.Context, ID domain.PhoneBrandReferenceID) (domain.PhoneBrandReference, bool)
+	UpdateBrandReference(
+		ctx context.Context, ID domain.PhoneBrandReferenceID, name string,
+	) (domain.PhoneBrandReference, error)
+	CreateBrandReference(ctx context.Context, name string, brand domain.PhoneBrand) (domain.PhoneBrandReference, error)
 }
 
+// the Images of a case type never include the deleted ones
 type CaseTypeRepository interface {
-	ListCaseTypes() []domain.CaseType
-	GetCaseTypeByID(ID domain.CaseTypeID) (domain.CaseType, bool)
-	UpdateCaseType(ID domain.CaseTypeID, name string) (domain.CaseType, error)
-	CreateCaseType(name string, iconPath string) (domain.CaseType, error)
+	ListCaseTypes(ctx context.Context) []domain.CaseType
+	GetCaseTypeByID(ctx context.Context, ID domain.CaseTypeID) (domain.CaseType, bool)
+	UpdateCaseType(ctx context.Context, ID domain.CaseTypeID, version int, name string) (domain.CaseType, error)
+	CreateCaseType(ctx context.Context, name string, iconPath string) (domain.CaseType, error)
+	// DeleteCaseType removes the case type and its images for good, it fails
+	// with CaseTypeInUse while a phone case, even a deleted one, has it
+	DeleteCaseType(ctx context.Context, ID domain.CaseTypeID, version int) error
 
-	ListCaseTypeImages(typeID domain.CaseTypeID) []domain.CaseTypeImage
-	CreateCaseTypeImage(typeID domain.CaseTypeID, path string, orderPriority int) (domain.CaseTypeImage, error)
-	UpdateCaseTypeImage(ID domain.CaseTypeImageID, path string) (domain.CaseTypeImage, error)
-	DeleteCaseTypeImage(ID domain.CaseTypeImageID) error
+	ListCaseTypeImages(ctx context.Context, typeID domain.CaseTypeID, includeDeleted bool) []domain.CaseTypeImage
+	CreateCaseTypeImage(
+		ctx context.Context, typeID domain.CaseTypeID, path string, orderPriority int,
+	) (domain.CaseTypeImage, error)
+	UpdateCaseTypeImage(ctx context.Context, ID domain.CaseTypeImageID, path string) (domain.CaseTypeImage, error)
+	DeleteCaseTypeImage(ctx context.Context, ID domain.CaseTypeImageID) error
+	RestoreCaseTypeImage(ctx context.Context, ID domain.CaseTypeImageID) (domain.CaseTypeImage, error)
 }
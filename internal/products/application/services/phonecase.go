@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
 	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
 	"github.com/Rhymond/go-money"
 	"time"
@@ -12,114 +14,316 @@ type PhoneCaseService struct {
 	caseRepo     ports.PhoneCaseRepository
 	brandRepo    ports.PhoneBrandRepository
 	caseTypeRepo ports.CaseTypeRepository
+	metrics      ports.Metrics
 }
 
 func NewPhoneCaseService(
 	caseRepo ports.PhoneCaseRepository, brandRepo ports.PhoneBrandRepository,
-	caseTypeRepo ports.CaseTypeRepository,
+	caseTypeRepo ports.CaseTypeRepository, metrics ports.Metrics,
 ) PhoneCaseService {
 	return PhoneCaseService{
 		caseRepo:     caseRepo,
 		brandRepo:    brandRepo,
 		caseTypeRepo: caseTypeRepo,
+		metrics:      metrics,
 	}
 }
 
-func (s *PhoneCaseService) ListPhoneCases(filters map[string]string, limit int, offset int) ([]domain.PhoneCase, int) {
-	return s.caseRepo.ListPhoneCases(filters, limit, offset)
+func (s *PhoneCaseService) ListPhoneCases(
+	ctx context.Context, filters map[string]string, limit int, offset int,
+) ([]domain.PhoneCase, int) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.ListPhoneCases")
+	defer span.End()
+
+	return s.caseRepo.ListPhoneCases(ctx, filters, limit, offset)
 }
 
-func (s *PhoneCaseService) GetPhoneCaseByID(ID domain.PhoneCaseID) (domain.PhoneCase, bool) {
-	return s.caseRepo.GetPhoneCaseByID(ID)
+func (s *PhoneCaseService) GetPhoneCaseByID(ctx context.Context, ID domain.PhoneCaseID) (domain.PhoneCase, bool) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.GetPhoneCaseByID")
+	defer span.End()
+
+	return s.caseRepo.GetPhoneCaseByID(ctx, ID)
 }
 
 func (s *PhoneCaseService) CreatePhoneCase(
-	price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus,
-	phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID,
-	createdBy users.UserID,
+	ctx context.Context, price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus,
+	phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID, createdBy users.UserID,
 ) (domain.PhoneCase, error) {
-	return s.caseRepo.CreatePhoneCase(price, scaffoldImgPath, inventoryStatus, phoneBrandRefID, caseTypeID, createdBy)
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.CreatePhoneCase")
+	defer span.End()
+
+	phoneCase, err := s.caseRepo.CreatePhoneCase(
+		ctx, price, scaffoldImgPath, inventoryStatus, phoneBrandRefID, caseTypeID, createdBy,
+	)
+	if err != nil {
+		return domain.PhoneCase{}, err
+	}
+	s.metrics.PhoneCaseCreated()
+	return phoneCase, nil
 }
 
 func (s *PhoneCaseService) UpdatePhoneCase(
-	ID domain.PhoneCaseID, price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus,
-	phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID,
+	ctx context.Context, ID domain.PhoneCaseID, version int, price money.Money, scaffoldImgPath string,
+	inventoryStatus domain.InventoryStatus, phoneBrandRefID domain.PhoneBrandReferenceID,
+	caseTypeID domain.CaseTypeID,
+) (domain.PhoneCase, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.UpdatePhoneCase")
+	defer span.End()
+
+	return s.caseRepo.UpdatePhoneCase(
+		ctx, ID, version, price, scaffoldImgPath, inventoryStatus, phoneBrandRefID, caseTypeID,
+	)
+}
+
+func (s *PhoneCaseService) DeletePhoneCase(ctx context.Context, ID domain.PhoneCaseID, version int) error {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.DeletePhoneCase")
+	defer span.End()
+
+	return s.caseRepo.DeletePhoneCase(ctx, ID, version)
+}
+
+func (s *PhoneCaseService) RestorePhoneCase(
+	ctx context.Context, ID domain.PhoneCaseID, version int,
 ) (domain.PhoneCase, error) {
-	return s.caseRepo.UpdatePhoneCase(ID, price, scaffoldImgPath, inventoryStatus, phoneBrandRefID, caseTypeID)
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.RestorePhoneCase")
+	defer span.End()
+
+	return s.caseRepo.RestorePhoneCase(ctx, ID, version)
+}
+
+func (s *PhoneCaseService) AttachDiscount(
+	ctx context.Context, caseID domain.PhoneCaseID, discountID domain.DiscountID,
+) error {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.AttachDiscount")
+	defer span.End()
+
+	return s.caseRepo.AttachDiscount(ctx, caseID, discountID)
+}
+
+func (s *PhoneCaseService) ListAllDiscounts(ctx context.Context, includeDeleted bool) []domain.Discount {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.ListAllDiscounts")
+	defer span.End()
+
+	return s.caseRepo.ListAllDiscounts(ctx, includeDeleted)
+}
+
+func (s *PhoneCaseService) GetDiscountByID(ctx context.Context, ID domain.DiscountID) (domain.Discount, bool) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.GetDiscountByID")
+	defer span.End()
+
+	return s.caseRepo.GetDiscountByID(ctx, ID)
+}
+
+func (s *PhoneCaseService) CreateDiscount(
+	ctx context.Context, name string, rate int, validUntil time.Time, createdBy users.UserID,
+) (domain.Discount, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.CreateDiscount")
+	defer span.End()
+
+	return s.caseRepo.CreateDiscount(ctx, name, rate, validUntil, createdBy)
+}
+
+func (s *PhoneCaseService) UpdateDiscount(
+	ctx context.Context, ID domain.DiscountID, version int, rate int, validUntil time.Time, user users.User,
+) (domain.Discount, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.UpdateDiscount")
+	defer span.End()
+
+	return s.caseRepo.UpdateDiscount(ctx, ID, version, rate, validUntil)
+}
+
+func (s *PhoneCaseService) DeleteDiscount(ctx context.Context, ID domain.DiscountID, version int) error {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.DeleteDiscount")
+	defer span.End()
+
+	return s.caseRepo.DeleteDiscount(ctx, ID, version)
 }
 
-func (s *PhoneCaseService) DeletePhoneCase(ID domain.PhoneCaseID) error {
-	return s.caseRepo.DeletePhoneCase(ID)
+func (s *PhoneCaseService) RestoreDiscount(
+	ctx context.Context, ID domain.DiscountID, version int,
+) (domain.Discount, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.RestoreDiscount")
+	defer span.End()
+
+	return s.caseRepo.RestoreDiscount(ctx, ID, version)
 }
 
-func (s *PhoneCaseService) AttachDiscount(caseID domain.PhoneCaseID, discountID domain.DiscountID) error {
-	return s.caseRepo.AttachDiscount(caseID, discountID)
+func (s *PhoneCaseService) ListPhoneBrands(ctx context.Context) []domain.PhoneBrand {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.ListPhoneBrands")
+	defer span.End()
+
+	return s.brandRepo.ListAllBrands(ctx)
 }
 
-func (s *PhoneCaseService) ListAllDiscounts() []domain.Discount {
-	return s.caseRepo.ListAllDiscounts()
+func (s *PhoneCaseService) UpdatePhoneBrand(ctx context.Context, brand domain.PhoneBrand, renameTo string) error {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.UpdatePhoneBrand")
+	defer span.End()
+
+	return s.brandRepo.UpdateBrand(ctx, brand, renameTo)
 }
 
-func (s *PhoneCaseService) CreateDiscount(name string, rate int, validUntil time.Time, createdBy users.UserID) (domain.Discount, error) {
-	return s.caseRepo.CreateDiscount(name, rate, validUntil, createdBy)
+func (s *PhoneCaseService) CreatePhoneBrand(ctx context.Context, brand domain.PhoneBrand) error {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.CreatePhoneBrand")
+	defer span.End()
+
+	return s.brandRepo.CreateBrand(ctx, brand)
 }
 
-func (s *PhoneCaseService) UpdateDiscount(ID domain.DiscountID, rate int, validUntil time.Time, user users.User) (domain.Discount, error) {
-	return s.caseRepo.UpdateDiscount(ID, rate, validUntil)
+func (s *PhoneCaseService) ListBrandReferences(
+	ctx context.Context, brand domain.PhoneBrand,
+) []domain.PhoneBrandReference {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.ListBrandReferences")
+	defer span.End()
+
+	return s.brandRepo.ListBrandReferences(ctx, brand)
 }
 
-func (s *PhoneCaseService) DeleteDiscount(ID domain.DiscountID) error {
-	return s.caseRepo.DeleteDiscount(ID)
+func (s *PhoneCaseService) UpdateBrandReference(
+	ctx context.Context, ID domain.PhoneBrandReferenceID, name string,
+) (domain.PhoneBrandReference, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.UpdateBrandReference")
+	defer span.End()
+
+	return s.brandRepo.UpdateBrandReference(ctx, ID, name)
 }
 
-func (s *PhoneCaseService) ListPhoneBrands() []domain.PhoneBrand {
-	return s.brandRepo.ListAllBrands()
+func (s *PhoneCaseService) CreateBrandReference(
+	ctx context.Context, name string, brand domain.PhoneBrand,
+) (domain.PhoneBrandReference, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.CreateBrandReference")
+	defer span.End()
+
+	return s.brandRepo.CreateBrandReference(ctx, name, brand)
 }
 
-func (s *PhoneCaseService) UpdatePhoneBrand(brand domain.PhoneBrand, renameTo string) error {
-	return s.brandRepo.UpdateBrand(brand, renameTo)
+func (s *PhoneCaseService) ListCaseTypes(ctx context.Context) []domain.CaseType {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.ListCaseTypes")
+	defer span.End()
+
+	return s.caseTypeRepo.ListCaseTypes(ctx)
 }
 
-func (s *PhoneCaseService) CreatePhoneBrand(brand domain.PhoneBrand) error {
-	return s.brandRepo.CreateBrand(brand)
+func (s *PhoneCaseService) GetCaseTypeByID(ctx context.Context, ID domain.CaseTypeID) (domain.CaseType, bool) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.GetCaseTypeByID")
+	defer span.End()
+
+	return s.caseTypeRepo.GetCaseTypeByID(ctx, ID)
 }
 
-func (s *PhoneCaseService) ListBrandReferences(brand domain.PhoneBrand) []domain.PhoneBrandReference {
-	return s.brandRepo.ListBrandReferences(brand)
+func (s *PhoneCaseService) UpdateCaseType(
+	ctx context.Context, ID domain.CaseTypeID, version int, name string,
+) (domain.CaseType, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.UpdateCaseType")
+	defer span.End()
+
+	return s.caseTypeRepo.UpdateCaseType(ctx, ID, version, name)
 }
 
-func (s *PhoneCaseService) UpdateBrandReference(ID domain.PhoneBrandReferenceID, name string) (domain.PhoneBrandReference, error) {
-	return s.brandRepo.UpdateBrandReference(ID, name)
+func (s *PhoneCaseService) DeleteCaseType(ctx context.Context, ID domain.CaseTypeID, version int) error {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.DeleteCaseType")
+	defer span.End()
+
+	return s.caseTypeRepo.DeleteCaseType(ctx, ID, version)
 }
 
-func (s *PhoneCaseService) CreateBrandReference(name string, brand domain.PhoneBrand) (domain.PhoneBrandReference, error) {
-	return s.brandRepo.CreateBrandReference(name, brand)
+func (s *PhoneCaseService) CreateCaseType(ctx context.Context, name string, iconPath string) (domain.CaseType, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.CreateCaseType")
+	defer span.End()
+
+	return s.caseTypeRepo.CreateCaseType(ctx, name, iconPath)
 }
 
-func (s *PhoneCaseService) ListCaseTypes() []domain.CaseType {
-	return s.caseTypeRepo.ListCaseTypes()
+func (s *PhoneCaseService) ListCaseTypeImages(
+	ctx context.Context, typeID domain.CaseTypeID, includeDeleted bool,
+) []domain.CaseTypeImage {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.ListCaseTypeImages")
+	defer span.End()
+
+	return s.caseTypeRepo.ListCaseTypeImages(ctx, typeID, includeDeleted)
 }
 
-func (s *PhoneCaseService) UpdateCaseType(ID domain.CaseTypeID, name string) (domain.CaseType, error) {
-	return s.caseTypeRepo.UpdateCaseType(ID, name)
+func (s *PhoneCaseService) CreateCaseTypeImage(
+	ctx context.Context, typeID domain.CaseTypeID, path string, orderPriority int,
+) (domain.CaseTypeImage, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.CreateCaseTypeImage")
+	defer span.End()
+
+	return s.caseTypeRepo.CreateCaseTypeImage(ctx, typeID, path, orderPriority)
 }
 
-func (s *PhoneCaseService) CreateCaseType(name string, iconPath string) (domain.CaseType, error) {
-	return s.caseTypeRepo.CreateCaseType(name, iconPath)
+func (s *PhoneCaseService) UpdateCaseTypeImage(
+	ctx context.Context, ID domain.CaseTypeImageID, path string,
+) (domain.CaseTypeImage, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.UpdateCaseTypeImage")
+	defer span.End()
+
+	return s.caseTypeRepo.UpdateCaseTypeImage(ctx, ID, path)
 }
 
-func (s *PhoneCaseService) ListCaseTypeImages(typeID domain.CaseTypeID) []domain.CaseTypeImage {
-	return s.caseTypeRepo.ListCaseTypeImages(typeID)
+// UpdateCaseTypeImages changes the path of the images, every image must be one
+// of the case type and not deleted, otherwise none is changed
+func (s *PhoneCaseService) UpdateCaseTypeImages(
+	ctx context.Context, typeID domain.CaseTypeID, images []domain.CaseTypeImage,
+) ([]domain.CaseTypeImage, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.UpdateCaseTypeImages")
+	defer span.End()
+
+	if _, ok := s.caseTypeRepo.GetCaseTypeByID(ctx, typeID); !ok {
+		return nil, ports.CaseTypeDoesNotExists
+	}
+	current := make(map[domain.CaseTypeImageID]bool)
+	for _, image := range s.caseTypeRepo.ListCaseTypeImages(ctx, typeID, false) {
+		current[image.ID] = true
+	}
+	for _, image := range images {
+		if !current[image.ID] {
+			return nil, ports.CaseTypeImageDoesNotExists
+		}
+	}
+
+	for _, image := range images {
+		if _, err := s.caseTypeRepo.UpdateCaseTypeImage(ctx, image.ID, image.Path); err != nil {
+			return nil, err
+		}
+	}
+	return s.caseTypeRepo.ListCaseTypeImages(ctx, typeID, false), nil
 }
 
-func (s *PhoneCaseService) CreateCaseTypeImage(typeID domain.CaseTypeID, path string, orderPriority int) (domain.CaseTypeImage, error) {
-	return s.caseTypeRepo.CreateCaseTypeImage(typeID, path, orderPriority)
+// DeleteCaseTypeImage deletes the image only when it belongs to the case type
+func (s *PhoneCaseService) DeleteCaseTypeImage(
+	ctx context.Context, typeID domain.CaseTypeID, ID domain.CaseTypeImageID,
+) error {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.DeleteCaseTypeImage")
+	defer span.End()
+
+	if !s.hasCaseTypeImage(ctx, typeID, ID) {
+		return ports.CaseTypeImageDoesNotExists
+	}
+	return s.caseTypeRepo.DeleteCaseTypeImage(ctx, ID)
 }
 
-func (s *PhoneCaseService) UpdateCaseTypeImage(ID domain.CaseTypeImageID, path string) (domain.CaseTypeImage, error) {
-	return s.caseTypeRepo.UpdateCaseTypeImage(ID, path)
+// RestoreCaseTypeImage restores the image only when it belongs to the case type
+func (s *PhoneCaseService) RestoreCaseTypeImage(
+	ctx context.Context, typeID domain.CaseTypeID, ID domain.CaseTypeImageID,
+) (domain.CaseTypeImage, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseService.RestoreCaseTypeImage")
+	defer span.End()
+
+	if !s.hasCaseTypeImage(ctx, typeID, ID) {
+		return domain.CaseTypeImage{}, ports.CaseTypeImageDoesNotExists
+	}
+	return s.caseTypeRepo.RestoreCaseTypeImage(ctx, ID)
 }
 
-func (s *PhoneCaseService) DeleteCaseTypeImage(ID domain.CaseTypeImageID) error {
-	return s.caseTypeRepo.DeleteCaseTypeImage(ID)
+// hasCaseTypeImage tells if the image, deleted or not, is one of the images of
+// the case type
+func (s *PhoneCaseService) hasCaseTypeImage(
+	ctx context.Context, typeID domain.CaseTypeID, ID domain.CaseTypeImageID,
+) bool {
+	for _, image := range s.caseTypeRepo.ListCaseTypeImages(ctx, typeID, true) {
+		if image.ID == ID {
+			return true
+		}
+	}
+	return false
 }
@@ -25,6 +25,15 @@ type PhoneCase struct {
 	PhoneBrandReference PhoneBrandReference
 	CaseType            CaseType
 	CreatedBy           users.User
+	// Version is increased on every change of the phone case, it starts at 1
+	Version int
+	// DeletedAt is zero while the phone case is not deleted, deleted phone
+	// cases are kept so they can still be referenced
+	DeletedAt time.Time
+}
+
+func (p PhoneCase) IsDeleted() bool {
+	return !p.DeletedAt.IsZero()
 }
 
 type PhoneBrand string
@@ -43,6 +52,7 @@ type CaseType struct {
 	Name        string
 	IconImgPath string
 	Images      []CaseTypeImage
+	Version     int
 }
 
 type CaseTypeImageID int
@@ -51,6 +61,11 @@ type CaseTypeImage struct {
 	ID            CaseTypeImageID
 	Path          string
 	OrderPriority int
+	DeletedAt     time.Time
+}
+
+func (i CaseTypeImage) IsDeleted() bool {
+	return !i.DeletedAt.IsZero()
 }
 
 type DiscountID int
@@ -62,4 +77,10 @@ type Discount struct {
 	ValidUntil time.Time
 	CreatedBy  users.User
 	CreatedAt  time.Time
+	Version    int
+	DeletedAt  time.Time
+}
+
+func (d Discount) IsDeleted() bool {
+	return !d.DeletedAt.IsZero()
 }
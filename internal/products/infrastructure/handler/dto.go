@@ -2,6 +2,7 @@ package handler
 
 import (
 	"github.com/Rhymond/go-money"
+	"time"
 )
 
 type PhoneBrandReferenceListDTO struct {
@@ -14,6 +15,7 @@ type CaseTypeListDTO struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
 	IconImgPath string `json:"icon_img_path"`
+	Version     int    `json:"version"`
 }
 
 type DiscountListDTO struct {
@@ -21,6 +23,15 @@ type DiscountListDTO struct {
 	Name       string `json:"name"`
 	Rate       int    `json:"rate"`
 	ValidUntil string `json:"valid_until"`
+	Version    int    `json:"version"`
+	DeletedAt  string `json:"deleted_at,omitempty"`
+}
+
+type CaseTypeImageListDTO struct {
+	ID            int    `json:"id"`
+	Path          string `json:"path"`
+	OrderPriority int    `json:"order_priority"`
+	DeletedAt     string `json:"deleted_at,omitempty"`
 }
 
 type PhoneCaseListDTO struct {
@@ -33,6 +44,8 @@ type PhoneCaseListDTO struct {
 	PhoneBrandReference PhoneBrandReferenceListDTO `json:"phone_brand_reference"`
 	CaseType            CaseTypeListDTO            `json:"case_type"`
 	CreatedBy           string                     `json:"created_by"`
+	Version             int                        `json:"version"`
+	DeletedAt           string                     `json:"deleted_at,omitempty"`
 }
 
 type PhoneCaseUpdateDTO struct {
@@ -50,3 +63,21 @@ type PhoneCaseCreateDTO struct {
 	PhoneBrandRefID int         `json:"phone_brand_ref_id"`
 	CaseTypeID      int         `json:"case_type_id"`
 }
+
+type DiscountUpdateDTO struct {
+	Rate       int       `json:"rate" binding:"gte=0,lte=100"`
+	ValidUntil time.Time `json:"valid_until" binding:"required"`
+}
+
+type CaseTypeUpdateDTO struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type CaseTypeImagesUpdateDTO struct {
+	Images []CaseTypeImageUpdateDTO `json:"images" binding:"required,dive"`
+}
+
+type CaseTypeImageUpdateDTO struct {
+	ID   int    `json:"id" binding:"required"`
+	Path string `json:"path" binding:"required"`
+}
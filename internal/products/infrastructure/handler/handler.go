@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
 	"github.com/CrissAlvarezH/fundart-api/internal/common"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
 	"github.com/CrissAlvarezH/fundart-api/internal/products/application/services"
 	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
@@ -22,28 +25,29 @@ func NewPhoneCaseHandler(service services.PhoneCaseService) PhoneCaseHandler {
 
 func (h *PhoneCaseHandler) AddRoutes(g *gin.RouterGroup) {
 	g.GET("/cases", h.List)
-	g.POST("/cases/", h.Create)
+	g.POST("/cases/", common.Valid(ScopeProductsAdmin), h.Create)
 	g.GET("/cases/:id", h.GetByID)
-	g.PUT("/cases/:id/", h.Update)
-	g.DELETE("/cases/:id/", h.Delete)
+	g.PUT("/cases/:id/", common.Valid(ScopeProductsAdmin), h.Update)
+	g.DELETE("/cases/:id/", common.Valid(ScopeProductsAdmin), h.Delete)
+	g.POST("/cases/:id/restore/", common.Valid(ScopeProductsAdmin), h.Restore)
 }
 
 func (h *PhoneCaseHandler) Create(c *gin.Context) {
 	var body PhoneCaseCreateDTO
-	if err := c.Bind(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	authU, ok := c.Get("user")
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "auth error"})
+		c.Error(errors.New("auth error"))
 		return
 	}
 	user := authU.(users.User)
 
 	phoneCase, err := h.service.CreatePhoneCase(
-		body.Price,
+		c.Request.Context(), body.Price,
 		body.ScaffoldImgPath,
 		domain.InventoryStatus(body.InventoryStatus),
 		domain.PhoneBrandReferenceID(body.PhoneBrandRefID),
@@ -52,28 +56,39 @@ func (h *PhoneCaseHandler) Create(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(referenceError(err))
 		return
 	}
 
+	common.SetETag(c, phoneCase.Version)
 	c.JSON(http.StatusCreated, mapToPhoneCaseListDTO(phoneCase))
 }
 
 func (h *PhoneCaseHandler) List(c *gin.Context) {
 	pageParams, err := common.GetPaginationParams(c.Request.URL.Query())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	include, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+	if include {
+		pageParams.Filters[ports.IncludeDeleted] = "true"
+	} else {
+		delete(pageParams.Filters, ports.IncludeDeleted)
+	}
+
 	phoneCases, count := h.service.ListPhoneCases(
-		pageParams.Filters, pageParams.Limit, pageParams.Offset,
+		c.Request.Context(), pageParams.Filters, pageParams.Limit, pageParams.Offset,
 	)
 
 	phoneCasesDTO := mapToPhoneCasesListDTO(phoneCases)
 
 	c.JSON(http.StatusOK, gin.H{
-		"pagination": common.PaginationJson(count, pageParams.Page, pageParams.Page),
+		"pagination": common.PaginationJson(count, pageParams.Page, pageParams.PageSize),
 		"result":     phoneCasesDTO,
 	})
 }
@@ -81,34 +96,46 @@ func (h *PhoneCaseHandler) List(c *gin.Context) {
 func (h *PhoneCaseHandler) GetByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not valid number"})
+		c.Error(apperror.InvalidNumber("id"))
 		return
 	}
 
-	phoneCase, ok := h.service.GetPhoneCaseByID(domain.PhoneCaseID(id))
+	include, ok := includeDeleted(c)
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "phone case does not exists"})
 		return
 	}
 
+	phoneCase, ok := h.service.GetPhoneCaseByID(c.Request.Context(), domain.PhoneCaseID(id))
+	if !ok || (phoneCase.IsDeleted() && !include) {
+		c.Error(ports.PhoneCaseDoesNotExists)
+		return
+	}
+
+	common.SetETag(c, phoneCase.Version)
 	c.JSON(http.StatusOK, mapToPhoneCaseListDTO(phoneCase))
 }
 
 func (h *PhoneCaseHandler) Update(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not valid number"})
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
 		return
 	}
 
 	var body PhoneCaseUpdateDTO
-	if err := c.Bind(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	phoneCase, err := h.service.UpdatePhoneCase(
-		domain.PhoneCaseID(id),
+		c.Request.Context(), domain.PhoneCaseID(id),
+		version,
 		body.Price,
 		body.ScaffoldImgPath,
 		domain.InventoryStatus(body.InventoryStatus),
@@ -117,53 +144,195 @@ func (h *PhoneCaseHandler) Update(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(referenceError(err))
 		return
 	}
 
+	common.SetETag(c, phoneCase.Version)
 	c.JSON(http.StatusOK, mapToPhoneCaseListDTO(phoneCase))
 }
 
 func (h *PhoneCaseHandler) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not valid number"})
+		c.Error(apperror.InvalidNumber("id"))
 		return
 	}
 
-	if err := h.service.DeletePhoneCase(domain.PhoneCaseID(id)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	err = h.service.DeletePhoneCase(c.Request.Context(), domain.PhoneCaseID(id), version)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+func (h *PhoneCaseHandler) Restore(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	phoneCase, err := h.service.RestorePhoneCase(c.Request.Context(), domain.PhoneCaseID(id), version)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	common.SetETag(c, phoneCase.Version)
+	c.JSON(http.StatusOK, mapToPhoneCaseListDTO(phoneCase))
+}
+
+// referenceError reports the references of the body that don't exist as
+// invalid fields, so they aren't confused with a missing phone case
+func referenceError(err error) error {
+	switch {
+	case errors.Is(err, ports.PhoneBrandReferenceDoesNotExists):
+		return apperror.AsField("phone_brand_ref_id", err)
+	case errors.Is(err, ports.CaseTypeDoesNotExists):
+		return apperror.AsField("case_type_id", err)
+	}
+	return err
+}
+
 type DiscountHandler struct {
 	service services.PhoneCaseService
 }
 
+func NewDiscountHandler(service services.PhoneCaseService) DiscountHandler {
+	return DiscountHandler{
+		service: service,
+	}
+}
+
 func (h *DiscountHandler) AddRoutes(g *gin.RouterGroup) {
 	g.GET("/discounts", h.List)
 	g.GET("/discounts/:id", h.GetByID)
-	g.PUT("/discounts/:id/", h.Update)
-	g.DELETE("/discounts/:id/", h.Delete)
+	g.PUT("/discounts/:id/", common.Valid(ScopeProductsAdmin), h.Update)
+	g.DELETE("/discounts/:id/", common.Valid(ScopeProductsAdmin), h.Delete)
+	g.POST("/discounts/:id/restore/", common.Valid(ScopeProductsAdmin), h.Restore)
 }
 
 func (h *DiscountHandler) List(c *gin.Context) {
+	include, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+
+	discounts := h.service.ListAllDiscounts(c.Request.Context(), include)
 
+	discountsDTO := make([]DiscountListDTO, 0, len(discounts))
+	for _, d := range discounts {
+		discountsDTO = append(discountsDTO, mapToDiscountListDTO(d))
+	}
+	c.JSON(http.StatusOK, gin.H{"result": discountsDTO})
 }
 
 func (h *DiscountHandler) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	include, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+
+	discount, ok := h.service.GetDiscountByID(c.Request.Context(), domain.DiscountID(id))
+	if !ok || (discount.IsDeleted() && !include) {
+		c.Error(ports.DiscountDoesNotExists)
+		return
+	}
 
+	common.SetETag(c, discount.Version)
+	c.JSON(http.StatusOK, mapToDiscountListDTO(discount))
 }
 
 func (h *DiscountHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var body DiscountUpdateDTO
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	discount, err := h.service.UpdateDiscount(
+		c.Request.Context(), domain.DiscountID(id), version, body.Rate, body.ValidUntil, c.MustGet("user").(users.User),
+	)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
+	common.SetETag(c, discount.Version)
+	c.JSON(http.StatusOK, mapToDiscountListDTO(discount))
 }
 
 func (h *DiscountHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	err = h.service.DeleteDiscount(c.Request.Context(), domain.DiscountID(id), version)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
+	c.Status(http.StatusNoContent)
+}
+
+func (h *DiscountHandler) Restore(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	discount, err := h.service.RestoreDiscount(c.Request.Context(), domain.DiscountID(id), version)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	common.SetETag(c, discount.Version)
+	c.JSON(http.StatusOK, mapToDiscountListDTO(discount))
 }
 
 type PhoneBrandHandler struct {
@@ -223,36 +392,199 @@ type CaseTypeHandler struct {
 	service services.PhoneCaseService
 }
 
+func NewCaseTypeHandler(service services.PhoneCaseService) CaseTypeHandler {
+	return CaseTypeHandler{
+		service: service,
+	}
+}
+
 func (h *CaseTypeHandler) AddRoutes(g *gin.RouterGroup) {
 	g.GET("/case-types", h.List)
 	g.GET("/case-types/:id", h.GetByID)
-	g.PUT("/case-types/:id/", h.Update)
-	g.DELETE("/case-types/:id/", h.Delete)
+	g.PUT("/case-types/:id/", common.Valid(ScopeProductsAdmin), h.Update)
+	g.DELETE("/case-types/:id/", common.Valid(ScopeProductsAdmin), h.Delete)
 
-	g.PUT("/case-types/:id/images", h.UpdateImages)
-	g.DELETE("/case-types/:id/images/:img_id", h.DeleteImages)
+	g.GET("/case-types/:id/images", h.ListImages)
+	g.PUT("/case-types/:id/images/", common.Valid(ScopeProductsAdmin), h.UpdateImages)
+	g.DELETE("/case-types/:id/images/:img_id/", common.Valid(ScopeProductsAdmin), h.DeleteImage)
+	g.POST("/case-types/:id/images/:img_id/restore/", common.Valid(ScopeProductsAdmin), h.RestoreImage)
 }
 
 func (h *CaseTypeHandler) List(c *gin.Context) {
+	caseTypes := h.service.ListCaseTypes(c.Request.Context())
 
+	caseTypesDTO := make([]CaseTypeListDTO, 0, len(caseTypes))
+	for _, caseType := range caseTypes {
+		caseTypesDTO = append(caseTypesDTO, mapToCaseTypeListDTO(caseType))
+	}
+	c.JSON(http.StatusOK, gin.H{"result": caseTypesDTO})
 }
 
 func (h *CaseTypeHandler) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	caseType, ok := h.service.GetCaseTypeByID(c.Request.Context(), domain.CaseTypeID(id))
+	if !ok {
+		c.Error(ports.CaseTypeDoesNotExists)
+		return
+	}
 
+	common.SetETag(c, caseType.Version)
+	c.JSON(http.StatusOK, mapToCaseTypeListDTO(caseType))
 }
 
 func (h *CaseTypeHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var body CaseTypeUpdateDTO
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	caseType, err := h.service.UpdateCaseType(c.Request.Context(), domain.CaseTypeID(id), version, body.Name)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
+	common.SetETag(c, caseType.Version)
+	c.JSON(http.StatusOK, mapToCaseTypeListDTO(caseType))
 }
 
 func (h *CaseTypeHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	err = h.service.DeleteCaseType(c.Request.Context(), domain.CaseTypeID(id), version)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *CaseTypeHandler) ListImages(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	include, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := h.service.GetCaseTypeByID(c.Request.Context(), domain.CaseTypeID(id)); !ok {
+		c.Error(ports.CaseTypeDoesNotExists)
+		return
+	}
+	images := h.service.ListCaseTypeImages(c.Request.Context(), domain.CaseTypeID(id), include)
 
+	imagesDTO := make([]CaseTypeImageListDTO, 0, len(images))
+	for _, image := range images {
+		imagesDTO = append(imagesDTO, mapToCaseTypeImageListDTO(image))
+	}
+	c.JSON(http.StatusOK, gin.H{"result": imagesDTO})
 }
 
+// UpdateImages doesn't need If-Match because the images are not versioned
 func (h *CaseTypeHandler) UpdateImages(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	var body CaseTypeImagesUpdateDTO
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	changes := make([]domain.CaseTypeImage, 0, len(body.Images))
+	for _, image := range body.Images {
+		changes = append(changes, domain.CaseTypeImage{ID: domain.CaseTypeImageID(image.ID), Path: image.Path})
+	}
+	images, err := h.service.UpdateCaseTypeImages(c.Request.Context(), domain.CaseTypeID(id), changes)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
+	imagesDTO := make([]CaseTypeImageListDTO, 0, len(images))
+	for _, image := range images {
+		imagesDTO = append(imagesDTO, mapToCaseTypeImageListDTO(image))
+	}
+	c.JSON(http.StatusOK, gin.H{"result": imagesDTO})
+}
+
+// DeleteImage doesn't need If-Match because the images are not versioned
+func (h *CaseTypeHandler) DeleteImage(c *gin.Context) {
+	typeID, imageID, ok := imageParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCaseTypeImage(c.Request.Context(), typeID, imageID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
-func (h *CaseTypeHandler) DeleteImages(c *gin.Context) {
+// RestoreImage doesn't need If-Match because the images are not versioned
+func (h *CaseTypeHandler) RestoreImage(c *gin.Context) {
+	typeID, imageID, ok := imageParams(c)
+	if !ok {
+		return
+	}
+
+	image, err := h.service.RestoreCaseTypeImage(c.Request.Context(), typeID, imageID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToCaseTypeImageListDTO(image))
+}
 
+// imageParams parses the case type and the image of the path, the handler must
+// return when ok is false
+func imageParams(c *gin.Context) (domain.CaseTypeID, domain.CaseTypeImageID, bool) {
+	typeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return 0, 0, false
+	}
+	imageID, err := strconv.Atoi(c.Param("img_id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("img_id"))
+		return 0, 0, false
+	}
+	return domain.CaseTypeID(typeID), domain.CaseTypeImageID(imageID), true
 }
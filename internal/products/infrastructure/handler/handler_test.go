@@ -0,0 +1,500 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/CrissAlvarezH/fundart-api/internal/common"
+	"github.com/CrissAlvarezH/fundart-api/internal/metrics"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/services"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/handler"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/memoryrepo"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/Rhymond/go-money"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	admin    = users.User{ID: 1, Scopes: []users.ScopeName{users.PRODUCTS_ADMIN}}
+	customer = users.User{ID: 2}
+)
+
+type testAPI struct {
+	cases      *memoryrepo.MemoryPhoneCaseRepository
+	caseTypes  *memoryrepo.MemoryCaseTypeRepository
+	kept       domain.PhoneCase
+	deleted    domain.PhoneCase
+	discount   domain.Discount
+	caseType   domain.CaseType
+	image      domain.CaseTypeImage
+	otherType  domain.CaseType
+	otherImage domain.CaseTypeImage
+}
+
+// newTestAPI seeds a phone case with a discount, a deleted phone case and two
+// case types with an image each
+func newTestAPI(t *testing.T) testAPI {
+	ctx := context.Background()
+	store := memoryrepo.NewMemoryStore(nil, nil, nil, nil, nil)
+	brands := memoryrepo.NewMemoryPhoneBrandRepository(store)
+	caseTypes := memoryrepo.NewMemoryCaseTypeRepository(store)
+	cases := memoryrepo.NewMemoryPhoneCaseRepository(store)
+
+	brands.CreateBrand(ctx, "Apple")
+	ref, _ := brands.CreateBrandReference(ctx, "iPhone 15", "Apple")
+	caseType, _ := caseTypes.CreateCaseType(ctx, "Silicone", "")
+	create := func() domain.PhoneCase {
+		phoneCase, err := cases.CreatePhoneCase(
+			ctx, *money.New(45000, money.COP), "", domain.PhoneCaseAvailable, ref.ID, caseType.ID, admin.ID,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return phoneCase
+	}
+
+	api := testAPI{cases: &cases, caseTypes: &caseTypes, kept: create(), deleted: create(), caseType: caseType}
+	if err := cases.DeletePhoneCase(ctx, api.deleted.ID, ports.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	api.deleted, _ = cases.GetPhoneCaseByID(ctx, api.deleted.ID)
+
+	var err error
+	if api.discount, err = cases.CreateDiscount(ctx, "Black friday", 20, time.Now().AddDate(0, 1, 0), admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := cases.AttachDiscount(ctx, api.kept.ID, api.discount.ID); err != nil {
+		t.Fatal(err)
+	}
+	api.kept, _ = cases.GetPhoneCaseByID(ctx, api.kept.ID)
+
+	if api.image, err = caseTypes.CreateCaseTypeImage(ctx, caseType.ID, "/silicone.png", 1); err != nil {
+		t.Fatal(err)
+	}
+	api.otherType, _ = caseTypes.CreateCaseType(ctx, "Leather", "")
+	if api.otherImage, err = caseTypes.CreateCaseTypeImage(ctx, api.otherType.ID, "/leather.png", 1); err != nil {
+		t.Fatal(err)
+	}
+	return api
+}
+
+func (a testAPI) serve(user *users.User, method string, path string, ifMatch string) *httptest.ResponseRecorder {
+	return a.serveBody(user, method, path, ifMatch, "")
+}
+
+func (a testAPI) serveBody(user *users.User, method string, path string, ifMatch string, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user != nil {
+			c.Set("user", *user)
+		}
+	})
+	service := services.NewPhoneCaseService(a.cases, nil, a.caseTypes, metrics.New())
+	g := router.Group("", common.Problems())
+	phoneCaseHandler := handler.NewPhoneCaseHandler(service)
+	phoneCaseHandler.AddRoutes(g)
+	discountHandler := handler.NewDiscountHandler(service)
+	discountHandler.AddRoutes(g)
+	caseTypeHandler := handler.NewCaseTypeHandler(service)
+	caseTypeHandler.AddRoutes(g)
+
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func casePath(ID domain.PhoneCaseID) string {
+	return "/cases/" + strconv.Itoa(int(ID))
+}
+
+func discountPath(ID domain.DiscountID) string {
+	return "/discounts/" + strconv.Itoa(int(ID))
+}
+
+func imagePath(typeID domain.CaseTypeID, ID domain.CaseTypeImageID) string {
+	return "/case-types/" + strconv.Itoa(int(typeID)) + "/images/" + strconv.Itoa(int(ID))
+}
+
+func TestPhoneCaseHandler_ListIncludeDeleted(t *testing.T) {
+	api := newTestAPI(t)
+
+	cases := []struct {
+		name           string
+		user           *users.User
+		query          string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{"deleted cases are hidden", nil, "", http.StatusOK, 1},
+		{"anonymous can't include deleted", nil, "?include_deleted=true", http.StatusUnauthorized, 0},
+		{"customer can't include deleted", &customer, "?include_deleted=true", http.StatusForbidden, 0},
+		{"admin includes deleted", &admin, "?include_deleted=true", http.StatusOK, 2},
+		{"admin without the filter", &admin, "", http.StatusOK, 1},
+		{"only true includes deleted", &admin, "?include_deleted=yes", http.StatusOK, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := api.serve(c.user, http.MethodGet, "/cases"+c.query, "")
+			if w.Code != c.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", c.expectedStatus, w.Code, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Result []handler.PhoneCaseListDTO `json:"result"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Result) != c.expectedCount {
+				t.Errorf("expected %d phone cases, got %d", c.expectedCount, len(body.Result))
+			}
+		})
+	}
+}
+
+func TestPhoneCaseHandler_ListPagination(t *testing.T) {
+	api := newTestAPI(t)
+
+	w := api.serve(&admin, http.MethodGet, "/cases?include_deleted=true&page=2&page_size=1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	var body struct {
+		Pagination struct {
+			Total      int `json:"total"`
+			Page       int `json:"page"`
+			PageSize   int `json:"page_size"`
+			TotalPages int `json:"total_pages"`
+		} `json:"pagination"`
+		Result []handler.PhoneCaseListDTO `json:"result"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if p := body.Pagination; p.Total != 2 || p.Page != 2 || p.PageSize != 1 || p.TotalPages != 2 {
+		t.Errorf("unexpected pagination %+v", p)
+	}
+	if len(body.Result) != 1 {
+		t.Errorf("expected 1 phone case, got %d", len(body.Result))
+	}
+}
+
+func TestPhoneCaseHandler_GetDeleted(t *testing.T) {
+	api := newTestAPI(t)
+
+	if w := api.serve(&customer, http.MethodGet, casePath(api.deleted.ID), ""); w.Code != http.StatusNotFound {
+		t.Errorf("deleted phone case must be hidden, got %d", w.Code)
+	}
+
+	w := api.serve(&admin, http.MethodGet, casePath(api.deleted.ID)+"?include_deleted=true", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var body handler.PhoneCaseListDTO
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.DeletedAt == "" {
+		t.Error("expected the deleted_at of the phone case")
+	}
+}
+
+func TestPhoneCaseHandler_WritesNeedAdmin(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t)
+	path := casePath(api.kept.ID) + "/"
+
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/cases/"},
+		{http.MethodPut, path},
+		{http.MethodDelete, path},
+	}
+	for _, r := range routes {
+		t.Run(r.method, func(t *testing.T) {
+			if w := api.serve(nil, r.method, r.path, "*"); w.Code != http.StatusUnauthorized {
+				t.Errorf("anonymous: expected status 401, got %d: %s", w.Code, w.Body)
+			}
+			if w := api.serve(&customer, r.method, r.path, "*"); w.Code != http.StatusForbidden {
+				t.Errorf("customer: expected status 403, got %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+	if phoneCase, _ := api.cases.GetPhoneCaseByID(ctx, api.kept.ID); phoneCase.IsDeleted() || phoneCase.Version != api.kept.Version {
+		t.Fatal("the phone case was changed without permissions")
+	}
+
+	if w := api.serve(&admin, http.MethodDelete, path, "*"); w.Code != http.StatusNoContent {
+		t.Errorf("admin: expected status 204, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestPhoneCaseHandler_Restore(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t)
+	restorePath := casePath(api.deleted.ID) + "/restore/"
+	staleETag := common.ETag(api.deleted.Version - 1)
+
+	cases := []struct {
+		name           string
+		user           *users.User
+		path           string
+		ifMatch        string
+		expectedStatus int
+	}{
+		{"anonymous", nil, restorePath, "*", http.StatusUnauthorized},
+		{"customer", &customer, restorePath, "*", http.StatusForbidden},
+		{"missing If-Match", &admin, restorePath, "", http.StatusPreconditionRequired},
+		{"stale version", &admin, restorePath, staleETag, http.StatusPreconditionFailed},
+		{"missing phone case", &admin, casePath(9999) + "/restore/", "*", http.StatusNotFound},
+		{"not deleted", &admin, casePath(api.kept.ID) + "/restore/", "*", http.StatusConflict},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := api.serve(c.user, http.MethodPost, c.path, c.ifMatch)
+			if w.Code != c.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", c.expectedStatus, w.Code, w.Body)
+			}
+		})
+	}
+
+	w := api.serve(&admin, http.MethodPost, restorePath, common.ETag(api.deleted.Version))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if etag := w.Header().Get("ETag"); etag != common.ETag(api.deleted.Version+1) {
+		t.Errorf("expected ETag %s, got %s", common.ETag(api.deleted.Version+1), etag)
+	}
+	if phoneCase, _ := api.cases.GetPhoneCaseByID(ctx, api.deleted.ID); phoneCase.IsDeleted() {
+		t.Error("phone case was not restored")
+	}
+}
+
+func TestDiscountHandler_DeleteAndRestore(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t)
+	path := discountPath(api.discount.ID) + "/"
+	restorePath := discountPath(api.discount.ID) + "/restore/"
+	etag := common.ETag(api.discount.Version)
+
+	cases := []struct {
+		name           string
+		user           *users.User
+		method         string
+		path           string
+		ifMatch        string
+		expectedStatus int
+	}{
+		{"anonymous deletes", nil, http.MethodDelete, path, "*", http.StatusUnauthorized},
+		{"customer deletes", &customer, http.MethodDelete, path, "*", http.StatusForbidden},
+		{"delete without If-Match", &admin, http.MethodDelete, path, "", http.StatusPreconditionRequired},
+		{"delete a stale version", &admin, http.MethodDelete, path, common.ETag(api.discount.Version + 1), http.StatusPreconditionFailed},
+		{"delete a missing discount", &admin, http.MethodDelete, discountPath(9999) + "/", "*", http.StatusNotFound},
+		{"restore a discount not deleted", &admin, http.MethodPost, restorePath, "*", http.StatusConflict},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := api.serve(c.user, c.method, c.path, c.ifMatch)
+			if w.Code != c.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", c.expectedStatus, w.Code, w.Body)
+			}
+		})
+	}
+
+	if w := api.serve(&admin, http.MethodDelete, path, etag); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", w.Code, w.Body)
+	}
+	if w := api.serve(&customer, http.MethodGet, discountPath(api.discount.ID), ""); w.Code != http.StatusNotFound {
+		t.Errorf("deleted discount must be hidden, got %d", w.Code)
+	}
+	if phoneCase, _ := api.cases.GetPhoneCaseByID(ctx, api.kept.ID); phoneCase.Discount.ID != 0 {
+		t.Error("the phone case kept the deleted discount")
+	}
+
+	deleted, _ := api.cases.GetDiscountByID(ctx, api.discount.ID)
+	if w := api.serve(nil, http.MethodPost, restorePath, "*"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", w.Code)
+	}
+	if w := api.serve(&customer, http.MethodPost, restorePath, "*"); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", w.Code)
+	}
+	w := api.serve(&admin, http.MethodPost, restorePath, common.ETag(deleted.Version))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if etag := w.Header().Get("ETag"); etag != common.ETag(deleted.Version+1) {
+		t.Errorf("expected ETag %s, got %s", common.ETag(deleted.Version+1), etag)
+	}
+	if phoneCase, _ := api.cases.GetPhoneCaseByID(ctx, api.kept.ID); phoneCase.Discount.ID != api.discount.ID {
+		t.Error("the phone case didn't get its discount back")
+	}
+}
+
+func TestCaseTypeHandler_DeleteAndRestoreImage(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t)
+	path := imagePath(api.caseType.ID, api.image.ID) + "/"
+	restorePath := imagePath(api.caseType.ID, api.image.ID) + "/restore/"
+	// the image exists but it's of another case type
+	otherPath := imagePath(api.caseType.ID, api.otherImage.ID)
+
+	cases := []struct {
+		name           string
+		user           *users.User
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"anonymous deletes", nil, http.MethodDelete, path, http.StatusUnauthorized},
+		{"customer deletes", &customer, http.MethodDelete, path, http.StatusForbidden},
+		{"delete an image of another case type", &admin, http.MethodDelete, otherPath + "/", http.StatusNotFound},
+		{"delete a missing image", &admin, http.MethodDelete, imagePath(api.caseType.ID, 9999) + "/", http.StatusNotFound},
+		{"delete an invalid image", &admin, http.MethodDelete, "/case-types/1/images/x/", http.StatusBadRequest},
+		{"restore an image not deleted", &admin, http.MethodPost, restorePath, http.StatusConflict},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := api.serve(c.user, c.method, c.path, "")
+			if w.Code != c.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", c.expectedStatus, w.Code, w.Body)
+			}
+		})
+	}
+	if images := api.caseTypes.ListCaseTypeImages(ctx, api.otherType.ID, false); len(images) != 1 {
+		t.Fatal("the image of the other case type was deleted")
+	}
+
+	if err := api.caseTypes.DeleteCaseTypeImage(ctx, api.otherImage.ID); err != nil {
+		t.Fatal(err)
+	}
+	if w := api.serve(&admin, http.MethodPost, otherPath+"/restore/", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 restoring an image of another case type, got %d", w.Code)
+	}
+	if images := api.caseTypes.ListCaseTypeImages(ctx, api.otherType.ID, false); len(images) != 0 {
+		t.Fatal("the image of the other case type was restored")
+	}
+
+	if w := api.serve(&admin, http.MethodDelete, path, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", w.Code, w.Body)
+	}
+	if images := api.caseTypes.ListCaseTypeImages(ctx, api.caseType.ID, false); len(images) != 0 {
+		t.Errorf("expected the image to be deleted, got %d images", len(images))
+	}
+
+	if w := api.serve(&customer, http.MethodPost, restorePath, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", w.Code)
+	}
+	w := api.serve(&admin, http.MethodPost, restorePath, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var body handler.CaseTypeImageListDTO
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ID != int(api.image.ID) || body.DeletedAt != "" {
+		t.Errorf("unexpected restored image %+v", body)
+	}
+}
+
+func TestCaseTypeHandler_Delete(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t)
+	path := "/case-types/" + strconv.Itoa(int(api.otherType.ID)) + "/"
+
+	cases := []struct {
+		name           string
+		user           *users.User
+		path           string
+		ifMatch        string
+		expectedStatus int
+	}{
+		{"anonymous", nil, path, "*", http.StatusUnauthorized},
+		{"customer", &customer, path, "*", http.StatusForbidden},
+		{"missing If-Match", &admin, path, "", http.StatusPreconditionRequired},
+		{"stale version", &admin, path, common.ETag(api.otherType.Version + 1), http.StatusPreconditionFailed},
+		{"missing case type", &admin, "/case-types/9999/", "*", http.StatusNotFound},
+		{"used by phone cases", &admin, "/case-types/" + strconv.Itoa(int(api.caseType.ID)) + "/", "*", http.StatusConflict},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := api.serve(c.user, http.MethodDelete, c.path, c.ifMatch)
+			if w.Code != c.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", c.expectedStatus, w.Code, w.Body)
+			}
+		})
+	}
+
+	if w := api.serve(&admin, http.MethodDelete, path, common.ETag(api.otherType.Version)); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", w.Code, w.Body)
+	}
+	if _, ok := api.caseTypes.GetCaseTypeByID(ctx, api.otherType.ID); ok {
+		t.Error("the case type was not deleted")
+	}
+}
+
+func TestCaseTypeHandler_UpdateImages(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t)
+	path := "/case-types/" + strconv.Itoa(int(api.caseType.ID)) + "/images/"
+	imageBody := func(ID domain.CaseTypeImageID) string {
+		return `{"images": [{"id": ` + strconv.Itoa(int(ID)) + `, "path": "/updated.png"}]}`
+	}
+
+	cases := []struct {
+		name           string
+		user           *users.User
+		body           string
+		expectedStatus int
+	}{
+		{"anonymous", nil, imageBody(api.image.ID), http.StatusUnauthorized},
+		{"customer", &customer, imageBody(api.image.ID), http.StatusForbidden},
+		{"without images", &admin, `{}`, http.StatusBadRequest},
+		{"without path", &admin, `{"images": [{"id": 1}]}`, http.StatusBadRequest},
+		{"image of another case type", &admin, imageBody(api.otherImage.ID), http.StatusNotFound},
+		{"missing image", &admin, imageBody(9999), http.StatusNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := api.serveBody(c.user, http.MethodPut, path, "", c.body)
+			if w.Code != c.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", c.expectedStatus, w.Code, w.Body)
+			}
+		})
+	}
+	if images := api.caseTypes.ListCaseTypeImages(ctx, api.otherType.ID, false); images[0].Path != api.otherImage.Path {
+		t.Fatal("the image of the other case type was changed")
+	}
+
+	w := api.serveBody(&admin, http.MethodPut, path, "", imageBody(api.image.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Result []handler.CaseTypeImageListDTO `json:"result"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Result) != 1 || body.Result[0].Path != "/updated.png" {
+		t.Errorf("expected the updated image, got %+v", body.Result)
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"time"
 )
 
 func mapToPhoneCasesListDTO(l []domain.PhoneCase) []PhoneCaseListDTO {
@@ -27,6 +28,8 @@ func mapToPhoneCaseListDTO(p domain.PhoneCase) PhoneCaseListDTO {
 		PhoneBrandReference: phoneBrandRefDTO,
 		CaseType:            caseTypeDTO,
 		CreatedBy:           p.CreatedBy.Email,
+		Version:             p.Version,
+		DeletedAt:           formatDeletedAt(p.DeletedAt),
 	}
 }
 
@@ -36,6 +39,8 @@ func mapToDiscountListDTO(d domain.Discount) DiscountListDTO {
 		Name:       d.Name,
 		Rate:       d.Rate,
 		ValidUntil: d.ValidUntil.Format("2006-01-02 15:04:05"),
+		Version:    d.Version,
+		DeletedAt:  formatDeletedAt(d.DeletedAt),
 	}
 }
 
@@ -47,10 +52,29 @@ func mapToPhoneBrandRefListDTO(r domain.PhoneBrandReference) PhoneBrandReference
 	}
 }
 
+func mapToCaseTypeImageListDTO(i domain.CaseTypeImage) CaseTypeImageListDTO {
+	return CaseTypeImageListDTO{
+		ID:            int(i.ID),
+		Path:          i.Path,
+		OrderPriority: i.OrderPriority,
+		DeletedAt:     formatDeletedAt(i.DeletedAt),
+	}
+}
+
+// formatDeletedAt returns an empty string for the resources that are not
+// deleted, so the field is left out of the response
+func formatDeletedAt(deletedAt time.Time) string {
+	if deletedAt.IsZero() {
+		return ""
+	}
+	return deletedAt.Format("2006-01-02 15:04:05")
+}
+
 func mapToCaseTypeListDTO(c domain.CaseType) CaseTypeListDTO {
 	return CaseTypeListDTO{
 		ID:          int(c.ID),
 		Name:        c.Name,
 		IconImgPath: c.IconImgPath,
+		Version:     c.Version,
 	}
 }
\ No newline at end of file
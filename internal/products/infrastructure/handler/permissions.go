@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"github.com/CrissAlvarezH/fundart-api/internal/common"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func ScopeProductsAdmin(user users.User, isAnonymous bool, c *gin.Context) (bool, string) {
+	if isAnonymous {
+		return false, "user is anonymous"
+	}
+	if !user.HasScope(users.PRODUCTS_ADMIN) {
+		return false, "doesn't have permissions"
+	}
+	return true, ""
+}
+
+// includeDeleted tells if the request asks for the deleted resources with the
+// include_deleted query, only admins can ask for them so the request of other
+// users is denied, the handler must return when ok is false
+func includeDeleted(c *gin.Context) (include bool, ok bool) {
+	if c.Query(ports.IncludeDeleted) != "true" {
+		return false, true
+	}
+
+	user, authenticated := c.Get("user")
+	var u users.User
+	if authenticated {
+		u = user.(users.User)
+	}
+	if allowed, msg := ScopeProductsAdmin(u, !authenticated, c); !allowed {
+		common.Deny(c, !authenticated, msg)
+		return false, false
+	}
+	return true, true
+}
@@ -1,126 +1,175 @@
 package memoryrepo
 
 import (
+	"context"
 	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
 	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"time"
 )
 
 type MemoryCaseTypeRepository struct {
-	store MemoryStore
+	store *MemoryStore
 }
 
-func NewMemoryCaseTypeRepository(store MemoryStore) MemoryCaseTypeRepository {
+func NewMemoryCaseTypeRepository(store *MemoryStore) MemoryCaseTypeRepository {
 	return MemoryCaseTypeRepository{
 		store: store,
 	}
 }
 
-func (m *MemoryCaseTypeRepository) ListCaseTypes() []domain.CaseType {
-	l := make([]domain.CaseType, 0, len(m.store.CaseTypes))
-	for _, e := range m.store.CaseTypes {
-		l = append(l, mapToCaseType(e))
+func (m *MemoryCaseTypeRepository) ListCaseTypes(ctx context.Context) []domain.CaseType {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	l := make([]domain.CaseType, 0, len(m.store.caseTypes))
+	for _, ID := range sortedIDs(m.store.caseTypes) {
+		l = append(l, m.store.caseType(ID))
 	}
 	return l
 }
 
-func (m *MemoryCaseTypeRepository) GetCaseTypeByID(ID domain.CaseTypeID) (domain.CaseType, bool) {
-	for _, e := range m.store.CaseTypes {
-		if e.ID == int(ID) {
-			caseType := mapToCaseType(e)
-			caseType.Images = m.ListCaseTypeImages(ID)
-			return caseType, true
-		}
+func (m *MemoryCaseTypeRepository) GetCaseTypeByID(ctx context.Context, ID domain.CaseTypeID) (domain.CaseType, bool) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	if _, ok := m.store.caseTypes[int(ID)]; !ok {
+		return domain.CaseType{}, false
 	}
-	return domain.CaseType{}, false
+	return m.store.caseType(int(ID)), true
 }
 
-func (m *MemoryCaseTypeRepository) UpdateCaseType(ID domain.CaseTypeID, name string) (domain.CaseType, error) {
-	for i, e := range m.store.CaseTypes {
-		if e.ID == int(ID) {
-			m.store.CaseTypes[i].Name = name
-			return mapToCaseType(m.store.CaseTypes[i]), nil
-		}
-	}
+func (m *MemoryCaseTypeRepository) UpdateCaseType(
+	ctx context.Context, ID domain.CaseTypeID, version int, name string,
+) (domain.CaseType, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
 
-	return domain.CaseType{}, ports.CaseTypeDoesNotExists
+	e, ok := m.store.caseTypes[int(ID)]
+	if !ok {
+		return domain.CaseType{}, ports.CaseTypeDoesNotExists
+	}
+	if !matchVersion(e.Version, version) {
+		return domain.CaseType{}, ports.VersionMismatch
+	}
+	e.Name = name
+	e.Version++
+	return m.store.caseType(int(ID)), nil
 }
 
-func (m *MemoryCaseTypeRepository) CreateCaseType(name string, iconPath string) (domain.CaseType, error) {
-	lastID := 0
-	if len(m.store.CaseTypes) > 0 {
-		lastID = m.store.CaseTypes[len(m.store.CaseTypes)-1].ID
-	}
+func (m *MemoryCaseTypeRepository) CreateCaseType(
+	ctx context.Context, name string, iconPath string,
+) (domain.CaseType, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
 
+	m.store.lastCaseTypeID++
 	mC := MemoryCaseType{
-		ID:          lastID + 1,
+		ID:          m.store.lastCaseTypeID,
 		Name:        name,
 		IconImgPath: iconPath,
-		Images:      []MemoryCaseTypeImage{},
+		Version:     1,
 	}
 
-	m.store.CaseTypes = append(m.store.CaseTypes, mC)
+	m.store.caseTypes[mC.ID] = &mC
 	return mapToCaseType(mC), nil
 }
 
-func (m *MemoryCaseTypeRepository) ListCaseTypeImages(typeID domain.CaseTypeID) []domain.CaseTypeImage {
-	l := make([]domain.CaseTypeImage, 0, len(m.store.CaseTypeImage))
-	for _, e := range m.store.CaseTypeImage {
-		if e.CaseTypeID == int(typeID) {
-			l = append(l, mapToCaseTypeImage(e))
+func (m *MemoryCaseTypeRepository) DeleteCaseType(ctx context.Context, ID domain.CaseTypeID, version int) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	e, ok := m.store.caseTypes[int(ID)]
+	if !ok {
+		return ports.CaseTypeDoesNotExists
+	}
+	if !matchVersion(e.Version, version) {
+		return ports.VersionMismatch
+	}
+	for _, p := range m.store.phoneCases {
+		if p.CaseTypeID == int(ID) {
+			return ports.CaseTypeInUse
 		}
 	}
-	return l
+
+	for imgID, img := range m.store.caseTypeImages {
+		if img.CaseTypeID == int(ID) {
+			delete(m.store.caseTypeImages, imgID)
+		}
+	}
+	delete(m.store.caseTypes, int(ID))
+	return nil
+}
+
+func (m *MemoryCaseTypeRepository) ListCaseTypeImages(
+	ctx context.Context, typeID domain.CaseTypeID, includeDeleted bool,
+) []domain.CaseTypeImage {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	return mapToCaseTypeImages(m.store.imagesOf(int(typeID), includeDeleted))
 }
 
 func (m *MemoryCaseTypeRepository) CreateCaseTypeImage(
-	typeID domain.CaseTypeID, path string, orderPriority int,
+	ctx context.Context, typeID domain.CaseTypeID, path string, orderPriority int,
 ) (domain.CaseTypeImage, error) {
-	_, ok := m.GetCaseTypeByID(typeID)
-	if !ok {
-		return domain.CaseTypeImage{}, ports.CaseTypeDoesNotExists
-	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
 
-	lastID := 0
-	if len(m.store.CaseTypeImage) > 0 {
-		lastID = int(m.store.CaseTypeImage[len(m.store.CaseTypeImage)-1].ID)
+	if _, ok := m.store.caseTypes[int(typeID)]; !ok {
+		return domain.CaseTypeImage{}, ports.CaseTypeDoesNotExists
 	}
 
+	m.store.lastCaseTypeImageID++
 	caseImg := MemoryCaseTypeImage{
-		ID:            lastID + 1,
+		ID:            m.store.lastCaseTypeImageID,
 		Path:          path,
 		OrderPriority: orderPriority,
 		CaseTypeID:    int(typeID),
 	}
-	m.store.CaseTypeImage = append(m.store.CaseTypeImage, caseImg)
+	m.store.caseTypeImages[caseImg.ID] = &caseImg
 
 	return mapToCaseTypeImage(caseImg), nil
 }
 
 func (m *MemoryCaseTypeRepository) UpdateCaseTypeImage(
-	ID domain.CaseTypeImageID, path string,
+	ctx context.Context, ID domain.CaseTypeImageID, path string,
 ) (domain.CaseTypeImage, error) {
-	for i, e := range m.store.CaseTypeImage {
-		if e.ID == int(ID) {
-			m.store.CaseTypeImage[i].Path = path
-			return mapToCaseTypeImage(e), nil
-		}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	e, ok := m.store.caseTypeImages[int(ID)]
+	if !ok || !e.DeletedAt.IsZero() {
+		return domain.CaseTypeImage{}, ports.CaseTypeImageDoesNotExists
 	}
-	return domain.CaseTypeImage{}, ports.CaseTypeImageDoesNotExists
+	e.Path = path
+	return mapToCaseTypeImage(*e), nil
 }
 
-func (m *MemoryCaseTypeRepository) DeleteCaseTypeImage(ID domain.CaseTypeImageID) error {
-	l := make([]MemoryCaseTypeImage, 0)
-	found := false
-	for _, e := range m.store.CaseTypeImage {
-		if e.ID != int(ID) {
-			l = append(l, e)
-			found = true
-		}
-	}
-	if !found {
+func (m *MemoryCaseTypeRepository) DeleteCaseTypeImage(ctx context.Context, ID domain.CaseTypeImageID) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	e, ok := m.store.caseTypeImages[int(ID)]
+	if !ok || !e.DeletedAt.IsZero() {
 		return ports.CaseTypeImageDoesNotExists
 	}
-
-	m.store.CaseTypeImage = l
+	e.DeletedAt = time.Now().UTC()
 	return nil
 }
+
+func (m *MemoryCaseTypeRepository) RestoreCaseTypeImage(
+	ctx context.Context, ID domain.CaseTypeImageID,
+) (domain.CaseTypeImage, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	e, ok := m.store.caseTypeImages[int(ID)]
+	if !ok {
+		return domain.CaseTypeImage{}, ports.CaseTypeImageDoesNotExists
+	}
+	if e.DeletedAt.IsZero() {
+		return domain.CaseTypeImage{}, ports.NotDeleted
+	}
+	e.DeletedAt = time.Time{}
+	return mapToCaseTypeImage(*e), nil
+}
@@ -21,7 +21,9 @@ func mapToPhoneCase(
 		CreatedAt:           m.CreatedAt,
 		PhoneBrandReference: brandRef,
 		CaseType:            caseType,
-		CreatedBy:           users.User{},
+		CreatedBy:           m.CreatedBy,
+		Version:             m.Version,
+		DeletedAt:           m.DeletedAt,
 	}
 }
 
@@ -39,8 +41,10 @@ func mapToDiscount(m MemoryDiscount) domain.Discount {
 		Name:       m.Name,
 		Rate:       m.Rate,
 		ValidUntil: m.ValidUntil,
-		CreatedBy:  users.User{},
+		CreatedBy:  users.User{ID: users.UserID(m.CreateByID)},
 		CreatedAt:  m.CreatedAt,
+		Version:    m.Version,
+		DeletedAt:  m.DeletedAt,
 	}
 }
 
@@ -82,6 +86,7 @@ func mapToCaseType(m MemoryCaseType) domain.CaseType {
 		Name:        m.Name,
 		IconImgPath: m.IconImgPath,
 		Images:      mapToCaseTypeImages(m.Images),
+		Version:     m.Version,
 	}
 }
 
@@ -98,5 +103,6 @@ func mapToCaseTypeImage(m MemoryCaseTypeImage) domain.CaseTypeImage {
 		ID:            domain.CaseTypeImageID(m.ID),
 		Path:          m.Path,
 		OrderPriority: m.OrderPriority,
+		DeletedAt:     m.DeletedAt,
 	}
 }
@@ -0,0 +1,83 @@
+package memoryrepo_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports/portstest"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/memoryrepo"
+	"github.com/Rhymond/go-money"
+)
+
+const workers = 50
+
+// run with go test -race, the stress test only checks invariants, the race
+// detector reports unsynchronized access
+func TestMemoryStore_ConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	store := memoryrepo.NewMemoryStore(nil, nil, nil, nil, nil)
+	brandRepo := memoryrepo.NewMemoryPhoneBrandRepository(store)
+	caseTypeRepo := memoryrepo.NewMemoryCaseTypeRepository(store)
+	caseRepo := memoryrepo.NewMemoryPhoneCaseRepository(store)
+
+	brandRepo.CreateBrand(ctx, "Apple")
+	ref, _ := brandRepo.CreateBrandReference(ctx, "iPhone 15", "Apple")
+	caseType, _ := caseTypeRepo.CreateCaseType(ctx, "Silicone", "")
+
+	var wg sync.WaitGroup
+	IDs := make(chan domain.PhoneCaseID, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			price := *money.New(45000, money.COP)
+			phoneCase, err := caseRepo.CreatePhoneCase(ctx, price, "", domain.PhoneCaseAvailable, ref.ID, caseType.ID, 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			IDs <- phoneCase.ID
+
+			discount, _ := caseRepo.CreateDiscount(ctx, "sale", 10, time.Now(), 1)
+			caseRepo.AttachDiscount(ctx, phoneCase.ID, discount.ID)
+			caseTypeRepo.CreateCaseTypeImage(ctx, caseType.ID, "/img.png", 1)
+			caseRepo.ListPhoneCases(ctx, map[string]string{}, 10, 0)
+			brandRepo.ListBrandReferences(ctx, "Apple")
+			caseTypeRepo.ListCaseTypes(ctx)
+		}()
+	}
+	wg.Wait()
+	close(IDs)
+
+	seen := make(map[domain.PhoneCaseID]bool)
+	for ID := range IDs {
+		if seen[ID] {
+			t.Errorf("ID %d was assigned twice", ID)
+		}
+		seen[ID] = true
+	}
+	if _, total := caseRepo.ListPhoneCases(ctx, map[string]string{}, workers, 0); total != workers {
+		t.Errorf("expected %d phone cases, got %d", workers, total)
+	}
+
+	// IDs are not reused after deleting the last phone case
+	caseRepo.DeletePhoneCase(ctx, domain.PhoneCaseID(workers), ports.AnyVersion)
+	created, _ := caseRepo.CreatePhoneCase(ctx, *money.New(1, money.COP), "", domain.PhoneCaseAvailable, ref.ID, caseType.ID, 1)
+	if created.ID != domain.PhoneCaseID(workers+1) {
+		t.Errorf("expected ID %d, got %d", workers+1, created.ID)
+	}
+}
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	portstest.Run(t, func(t *testing.T) portstest.Backend {
+		store := memoryrepo.NewMemoryStore(nil, nil, nil, nil, nil)
+		cases := memoryrepo.NewMemoryPhoneCaseRepository(store)
+		brands := memoryrepo.NewMemoryPhoneBrandRepository(store)
+		caseTypes := memoryrepo.NewMemoryCaseTypeRepository(store)
+		return portstest.Backend{PhoneCases: &cases, Brands: &brands, CaseTypes: &caseTypes}
+	})
+}
@@ -1,92 +1,121 @@
 package memoryrepo
 
 import (
+	"context"
 	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
 	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
 )
 
 type MemoryPhoneBrandRepository struct {
-	store MemoryStore
+	store *MemoryStore
 }
 
-func NewMemoryPhoneBrandRepository(store MemoryStore) MemoryPhoneBrandRepository {
+func NewMemoryPhoneBrandRepository(store *MemoryStore) MemoryPhoneBrandRepository {
 	return MemoryPhoneBrandRepository{
 		store: store,
 	}
 }
 
-func (m *MemoryPhoneBrandRepository) ListAllBrands() []domain.PhoneBrand {
-	return mapToPhoneBrands(m.store.Brands)
+func (m *MemoryPhoneBrandRepository) ListAllBrands(ctx context.Context) []domain.PhoneBrand {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	return mapToPhoneBrands(m.store.brands)
 }
 
-func (m *MemoryPhoneBrandRepository) UpdateBrand(brand domain.PhoneBrand, renameTo string) error {
-	for i, b := range m.store.Brands {
-		if b == MemoryBrand(brand) {
-			m.store.Brands[i] = MemoryBrand(renameTo)
-			return nil
+func (m *MemoryPhoneBrandRepository) UpdateBrand(ctx context.Context, brand domain.PhoneBrand, renameTo string) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	i, ok := m.store.brandIndex(MemoryBrand(brand))
+	if !ok {
+		return ports.PhoneBrandDoesNotExists
+	}
+	if _, taken := m.store.brandIndex(MemoryBrand(renameTo)); taken && renameTo != string(brand) {
+		return ports.BrandAlreadyExists
+	}
+
+	m.store.brands[i] = MemoryBrand(renameTo)
+	// references follow the renamed brand
+	for _, ref := range m.store.brandReferences {
+		if ref.Brand == MemoryBrand(brand) {
+			ref.Brand = MemoryBrand(renameTo)
 		}
 	}
-	return ports.PhoneBrandDoesNotExists
+	return nil
 }
 
-func (m *MemoryPhoneBrandRepository) CreateBrand(brand domain.PhoneBrand) error {
-	for _, e := range m.store.Brands {
-		if e == MemoryBrand(brand) {
-			return ports.BrandAlreadyExists
-		}
+func (m *MemoryPhoneBrandRepository) CreateBrand(ctx context.Context, brand domain.PhoneBrand) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if _, ok := m.store.brandIndex(MemoryBrand(brand)); ok {
+		return ports.BrandAlreadyExists
 	}
 
-	m.store.Brands = append(m.store.Brands, MemoryBrand(brand))
+	m.store.brands = append(m.store.brands, MemoryBrand(brand))
 	return nil
 }
 
-func (m *MemoryPhoneBrandRepository) ListBrandReferences(brand domain.PhoneBrand) []domain.PhoneBrandReference {
+func (m *MemoryPhoneBrandRepository) ListBrandReferences(
+	ctx context.Context, brand domain.PhoneBrand,
+) []domain.PhoneBrandReference {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
 	r := make([]MemoryPhoneBrandReference, 0)
-	for _, e := range m.store.BrandReferences {
-		if e.Brand == MemoryBrand(brand) {
-			r = append(r, e)
+	for _, ID := range sortedIDs(m.store.brandReferences) {
+		if e := m.store.brandReferences[ID]; e.Brand == MemoryBrand(brand) {
+			r = append(r, *e)
 		}
 	}
 	return mapToPhoneBrandRefs(r)
 }
 
 func (m *MemoryPhoneBrandRepository) GetBrandReferenceByID(
-	ID domain.PhoneBrandReferenceID,
+	ctx context.Context, ID domain.PhoneBrandReferenceID,
 ) (domain.PhoneBrandReference, bool) {
-	for _, e := range m.store.BrandReferences {
-		if e.ID == int(ID) {
-			return mapToPhoneBrandRef(e), true
-		}
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	e, ok := m.store.brandReferences[int(ID)]
+	if !ok {
+		return domain.PhoneBrandReference{}, false
 	}
-	return domain.PhoneBrandReference{}, false
+	return mapToPhoneBrandRef(*e), true
 }
 
 func (m *MemoryPhoneBrandRepository) UpdateBrandReference(
-	ID domain.PhoneBrandReferenceID, name string,
+	ctx context.Context, ID domain.PhoneBrandReferenceID, name string,
 ) (domain.PhoneBrandReference, error) {
-	for i, e := range m.store.BrandReferences {
-		if e.ID == int(ID) {
-			m.store.BrandReferences[i].Name = name
-			return mapToPhoneBrandRef(m.store.BrandReferences[i]), nil
-		}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	e, ok := m.store.brandReferences[int(ID)]
+	if !ok {
+		return domain.PhoneBrandReference{}, ports.PhoneBrandReferenceDoesNotExists
 	}
-	return domain.PhoneBrandReference{}, ports.PhoneBrandReferenceDoesNotExists
+	e.Name = name
+	return mapToPhoneBrandRef(*e), nil
 }
 
 func (m *MemoryPhoneBrandRepository) CreateBrandReference(
-	name string, brand domain.PhoneBrand,
+	ctx context.Context, name string, brand domain.PhoneBrand,
 ) (domain.PhoneBrandReference, error) {
-	lastID := 0
-	if len(m.store.BrandReferences) > 0 {
-		lastID = m.store.BrandReferences[len(m.store.BrandReferences)-1].ID
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if _, ok := m.store.brandIndex(MemoryBrand(brand)); !ok {
+		return domain.PhoneBrandReference{}, ports.PhoneBrandDoesNotExists
 	}
 
+	m.store.lastBrandRefID++
 	mR := MemoryPhoneBrandReference{
-		ID:    lastID + 1,
+		ID:    m.store.lastBrandRefID,
 		Brand: MemoryBrand(brand),
 		Name:  name,
 	}
 
-	m.store.BrandReferences = append(m.store.BrandReferences, mR)
+	m.store.brandReferences[mR.ID] = &mR
 	return mapToPhoneBrandRef(mR), nil
 }
@@ -1,6 +1,7 @@
 package memoryrepo
 
 import (
+	"context"
 	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
 	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
@@ -10,214 +11,283 @@ import (
 )
 
 type MemoryPhoneCaseRepository struct {
-	store     MemoryStore
-	brandRepo ports.PhoneBrandRepository
-	caseType  ports.CaseTypeRepository
+	store *MemoryStore
 }
 
-func NewMemoryPhoneCaseRepository(
-	store MemoryStore, brandRepo ports.PhoneBrandRepository, caseTypeRepo ports.CaseTypeRepository,
-) MemoryPhoneCaseRepository {
+func NewMemoryPhoneCaseRepository(store *MemoryStore) MemoryPhoneCaseRepository {
 	return MemoryPhoneCaseRepository{
-		store:     store,
-		brandRepo: brandRepo,
-		caseType:  caseTypeRepo,
+		store: store,
 	}
 }
 
 func (r *MemoryPhoneCaseRepository) ListPhoneCases(
-	filters map[string]string, limit int, offset int,
+	ctx context.Context, filters map[string]string, limit int, offset int,
 ) ([]domain.PhoneCase, int) {
-	filtered := make([]MemoryPhoneCase, 0, len(r.store.PhoneCases))
-
-	if len(filters) == 0 {
-		filtered = r.store.PhoneCases
-	} else {
-		for _, v := range r.store.PhoneCases {
-			price, ok := filters["price"]
-			if ok && v.Price == price {
-				filtered = append(filtered, v)
-			}
-
-			status, ok := filters["inventory_status"]
-			if ok && v.InventoryStatus == status {
-				filtered = append(filtered, v)
-			}
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	filtered := make([]*MemoryPhoneCase, 0, len(r.store.phoneCases))
+	includeDeleted := filters[ports.IncludeDeleted] == "true"
+	for _, ID := range sortedIDs(r.store.phoneCases) {
+		p := r.store.phoneCases[ID]
+		if (includeDeleted || p.DeletedAt.IsZero()) && matchPhoneCaseFilters(p, filters) {
+			filtered = append(filtered, p)
 		}
 	}
 
-	data := make([]MemoryPhoneCase, 0, len(filtered))
+	data := make([]*MemoryPhoneCase, 0, len(filtered))
 	if len(filtered) >= limit && len(filtered) >= offset {
 		data = filtered[offset:limit]
 	} else if len(filtered) >= offset && len(filtered) < limit {
 		data = filtered[offset:]
 	}
 
-	result := make([]domain.PhoneCase, 0, len(filtered))
+	result := make([]domain.PhoneCase, 0, len(data))
 	for _, p := range data {
-		discount, _ := r.GetDiscountByID(domain.DiscountID(p.DiscountID))
-		brandRef, _ := r.brandRepo.GetBrandReferenceByID(domain.PhoneBrandReferenceID(p.PhoneBrandReferenceID))
-		caseType, _ := r.caseType.GetCaseTypeByID(domain.CaseTypeID(p.CaseTypeID))
-		phoneCase := mapToPhoneCase(p, discount, brandRef, caseType)
-		result = append(result, phoneCase)
+		result = append(result, r.store.phoneCase(p))
 	}
 
 	return result, len(filtered)
 }
 
-func (r *MemoryPhoneCaseRepository) GetPhoneCaseByID(ID domain.PhoneCaseID) (domain.PhoneCase, bool) {
-	for _, p := range r.store.PhoneCases {
-		if p.ID == int(ID) {
-			discount, _ := r.GetDiscountByID(domain.DiscountID(p.DiscountID))
-			brandRef, _ := r.brandRepo.GetBrandReferenceByID(domain.PhoneBrandReferenceID(p.PhoneBrandReferenceID))
-			caseType, _ := r.caseType.GetCaseTypeByID(domain.CaseTypeID(p.CaseTypeID))
-			phoneCase := mapToPhoneCase(p, discount, brandRef, caseType)
-			return phoneCase, true
-		}
+func (r *MemoryPhoneCaseRepository) GetPhoneCaseByID(
+	ctx context.Context, ID domain.PhoneCaseID,
+) (domain.PhoneCase, bool) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	p, ok := r.store.phoneCases[int(ID)]
+	if !ok {
+		return domain.PhoneCase{}, false
 	}
-	return domain.PhoneCase{}, false
+	return r.store.phoneCase(p), true
 }
 
 func (r *MemoryPhoneCaseRepository) CreatePhoneCase(
-	price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus,
-	phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID,
-	createdBy users.UserID,
+	ctx context.Context, price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus,
+	phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID, createdBy users.UserID,
 ) (domain.PhoneCase, error) {
-	lastID := 0
-	if len(r.store.PhoneCases) > 0 {
-		lastID = r.store.PhoneCases[len(r.store.PhoneCases)-1].ID
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.store.checkPhoneCaseReferences(int(phoneBrandRefID), int(caseTypeID)); err != nil {
+		return domain.PhoneCase{}, err
 	}
+
+	r.store.lastPhoneCaseID++
 	mP := MemoryPhoneCase{
-		ID:                    lastID + 1,
+		ID:                    r.store.lastPhoneCaseID,
 		Price:                 strconv.Itoa(int(price.Amount())),
 		CaseScaffoldImgPath:   scaffoldImgPath,
 		InventoryStatus:       string(inventoryStatus),
 		DiscountID:            0,
-		CreatedAt:             time.Time{},
+		CreatedAt:             time.Now().UTC(),
 		PhoneBrandReferenceID: int(phoneBrandRefID),
 		CaseTypeID:            int(caseTypeID),
-		CreatedBy:             users.User{},
+		CreatedBy:             users.User{ID: createdBy},
+		Version:               1,
 	}
+	r.store.phoneCases[mP.ID] = &mP
 
-	discount := domain.Discount{}
-	brandRef, _ := r.brandRepo.GetBrandReferenceByID(phoneBrandRefID)
-	caseType, _ := r.caseType.GetCaseTypeByID(caseTypeID)
-	phoneCase := mapToPhoneCase(mP, discount, brandRef, caseType)
-	return phoneCase, nil
+	return r.store.phoneCase(&mP), nil
 }
 
 func (r *MemoryPhoneCaseRepository) UpdatePhoneCase(
-	ID domain.PhoneCaseID, price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus,
-	phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID,
+	ctx context.Context, ID domain.PhoneCaseID, version int, price money.Money, scaffoldImgPath string,
+	inventoryStatus domain.InventoryStatus, phoneBrandRefID domain.PhoneBrandReferenceID,
+	caseTypeID domain.CaseTypeID,
 ) (domain.PhoneCase, error) {
-	for i, p := range r.store.PhoneCases {
-		if p.ID == int(ID) {
-			r.store.PhoneCases[i].Price = strconv.Itoa(int(price.Amount()))
-			r.store.PhoneCases[i].CaseScaffoldImgPath = scaffoldImgPath
-			r.store.PhoneCases[i].InventoryStatus = string(inventoryStatus)
-			r.store.PhoneCases[i].PhoneBrandReferenceID = int(phoneBrandRefID)
-
-			discount, _ := r.GetDiscountByID(domain.DiscountID(p.DiscountID))
-			brandRef, _ := r.brandRepo.GetBrandReferenceByID(domain.PhoneBrandReferenceID(p.PhoneBrandReferenceID))
-			caseType, _ := r.caseType.GetCaseTypeByID(domain.CaseTypeID(p.CaseTypeID))
-			phoneCase := mapToPhoneCase(p, discount, brandRef, caseType)
-			return phoneCase, nil
-		}
-	}
-	return domain.PhoneCase{}, ports.PhoneCaseDoesNotExists
-}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
 
-func (r *MemoryPhoneCaseRepository) DeletePhoneCase(ID domain.PhoneCaseID) error {
-	result := make([]MemoryPhoneCase, 0, len(r.store.PhoneCases))
-	found := false
+	p, ok := r.store.phoneCases[int(ID)]
+	if !ok || !p.DeletedAt.IsZero() {
+		return domain.PhoneCase{}, ports.PhoneCaseDoesNotExists
+	}
+	if !matchVersion(p.Version, version) {
+		return domain.PhoneCase{}, ports.VersionMismatch
+	}
+	if err := r.store.checkPhoneCaseReferences(int(phoneBrandRefID), int(caseTypeID)); err != nil {
+		return domain.PhoneCase{}, err
+	}
+	p.Price = strconv.Itoa(int(price.Amount()))
+	p.CaseScaffoldImgPath = scaffoldImgPath
+	p.InventoryStatus = string(inventoryStatus)
+	p.PhoneBrandReferenceID = int(phoneBrandRefID)
+	p.CaseTypeID = int(caseTypeID)
+	p.Version++
 
-	for _, p := range r.store.PhoneCases {
-		if p.ID != int(ID) {
-			result = append(result, p)
-			found = true
-		}
+	return r.store.phoneCase(p), nil
+}
 
-	}
+func (r *MemoryPhoneCaseRepository) DeletePhoneCase(ctx context.Context, ID domain.PhoneCaseID, version int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
 
-	if !found {
+	p, ok := r.store.phoneCases[int(ID)]
+	if !ok || !p.DeletedAt.IsZero() {
 		return ports.PhoneCaseDoesNotExists
 	}
+	if !matchVersion(p.Version, version) {
+		return ports.VersionMismatch
+	}
+	p.DeletedAt = time.Now().UTC()
+	p.Version++
 	return nil
 }
 
-func (r *MemoryPhoneCaseRepository) AttachDiscount(caseID domain.PhoneCaseID, discountID domain.DiscountID) error {
-	_, ok := r.GetDiscountByID(discountID)
+func (r *MemoryPhoneCaseRepository) RestorePhoneCase(
+	ctx context.Context, ID domain.PhoneCaseID, version int,
+) (domain.PhoneCase, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.phoneCases[int(ID)]
 	if !ok {
-		return ports.DiscountDoesNotExists
+		return domain.PhoneCase{}, ports.PhoneCaseDoesNotExists
+	}
+	if p.DeletedAt.IsZero() {
+		return domain.PhoneCase{}, ports.NotDeleted
+	}
+	if !matchVersion(p.Version, version) {
+		return domain.PhoneCase{}, ports.VersionMismatch
 	}
+	p.DeletedAt = time.Time{}
+	p.Version++
+	return r.store.phoneCase(p), nil
+}
 
-	for i, p := range r.store.PhoneCases {
-		if p.ID == int(caseID) {
-			r.store.PhoneCases[i].DiscountID = int(discountID)
-			return nil
-		}
+func (r *MemoryPhoneCaseRepository) AttachDiscount(
+	ctx context.Context, caseID domain.PhoneCaseID, discountID domain.DiscountID,
+) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if d, ok := r.store.discounts[int(discountID)]; !ok || !d.DeletedAt.IsZero() {
+		return ports.DiscountDoesNotExists
 	}
 
-	return ports.PhoneCaseDoesNotExists
+	p, ok := r.store.phoneCases[int(caseID)]
+	if !ok || !p.DeletedAt.IsZero() {
+		return ports.PhoneCaseDoesNotExists
+	}
+	p.DiscountID = int(discountID)
+	p.Version++
+	return nil
 }
 
-func (r *MemoryPhoneCaseRepository) ListAllDiscounts() []domain.Discount {
-	return mapToDiscounts(r.store.Discounts)
-}
+func (r *MemoryPhoneCaseRepository) ListAllDiscounts(ctx context.Context, includeDeleted bool) []domain.Discount {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
 
-func (r *MemoryPhoneCaseRepository) GetDiscountByID(ID domain.DiscountID) (domain.Discount, bool) {
-	for _, d := range r.store.Discounts {
-		if d.ID == int(ID) {
-			return mapToDiscount(d), true
+	discounts := make([]MemoryDiscount, 0, len(r.store.discounts))
+	for _, ID := range sortedIDs(r.store.discounts) {
+		if d := r.store.discounts[ID]; includeDeleted || d.DeletedAt.IsZero() {
+			discounts = append(discounts, *d)
 		}
 	}
-	return domain.Discount{}, false
+	return mapToDiscounts(discounts)
+}
+
+func (r *MemoryPhoneCaseRepository) GetDiscountByID(ctx context.Context, ID domain.DiscountID) (domain.Discount, bool) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	d, ok := r.store.discounts[int(ID)]
+	if !ok {
+		return domain.Discount{}, false
+	}
+	return mapToDiscount(*d), true
 }
 
 func (r *MemoryPhoneCaseRepository) CreateDiscount(
-	name string, rate int, validUntil time.Time, createdBy users.UserID,
+	ctx context.Context, name string, rate int, validUntil time.Time, createdBy users.UserID,
 ) (domain.Discount, error) {
-	lastID := 0
-	if len(r.store.Discounts) > 0 {
-		lastID = r.store.Discounts[len(r.store.Discounts)-1].ID
-	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.lastDiscountID++
 	mD := MemoryDiscount{
-		ID:         lastID + 1,
+		ID:         r.store.lastDiscountID,
 		Name:       name,
 		Rate:       rate,
 		ValidUntil: validUntil,
 		CreateByID: int(createdBy),
-		CreatedAt:  time.Time{},
+		CreatedAt:  time.Now().UTC(),
+		Version:    1,
 	}
-	r.store.Discounts = append(r.store.Discounts, mD)
+	r.store.discounts[mD.ID] = &mD
 	return mapToDiscount(mD), nil
 }
 
 func (r *MemoryPhoneCaseRepository) UpdateDiscount(
-	ID domain.DiscountID, rate int, validUntil time.Time,
+	ctx context.Context, ID domain.DiscountID, version int, rate int, validUntil time.Time,
 ) (domain.Discount, error) {
-	for i, d := range r.store.Discounts {
-		if d.ID == int(ID) {
-			r.store.Discounts[i].Rate = rate
-			r.store.Discounts[i].ValidUntil = validUntil
-			return mapToDiscount(d), nil
-		}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	d, ok := r.store.discounts[int(ID)]
+	if !ok || !d.DeletedAt.IsZero() {
+		return domain.Discount{}, ports.DiscountDoesNotExists
+	}
+	if !matchVersion(d.Version, version) {
+		return domain.Discount{}, ports.VersionMismatch
 	}
-	return domain.Discount{}, ports.DiscountDoesNotExists
+	d.Rate = rate
+	d.ValidUntil = validUntil
+	d.Version++
+	return mapToDiscount(*d), nil
 }
 
-func (r *MemoryPhoneCaseRepository) DeleteDiscount(ID domain.DiscountID) error {
-	result := make([]MemoryDiscount, 0, len(r.store.Discounts))
-	found := false
-	for _, d := range r.store.Discounts {
-		if d.ID == int(ID) {
-			result = append(result, d)
-			found = true
-		}
-	}
-	if !found {
+func (r *MemoryPhoneCaseRepository) DeleteDiscount(ctx context.Context, ID domain.DiscountID, version int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	d, ok := r.store.discounts[int(ID)]
+	if !ok || !d.DeletedAt.IsZero() {
 		return ports.DiscountDoesNotExists
 	}
-	r.store.Discounts = result
+	if !matchVersion(d.Version, version) {
+		return ports.VersionMismatch
+	}
+	d.DeletedAt = time.Now().UTC()
+	d.Version++
+	r.store.touchPhoneCasesWithDiscount(d.ID)
 	return nil
 }
+
+// RestoreDiscount shows the discount again on the phone cases it had when it
+// was deleted
+func (r *MemoryPhoneCaseRepository) RestoreDiscount(
+	ctx context.Context, ID domain.DiscountID, version int,
+) (domain.Discount, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	d, ok := r.store.discounts[int(ID)]
+	if !ok {
+		return domain.Discount{}, ports.DiscountDoesNotExists
+	}
+	if d.DeletedAt.IsZero() {
+		return domain.Discount{}, ports.NotDeleted
+	}
+	if !matchVersion(d.Version, version) {
+		return domain.Discount{}, ports.VersionMismatch
+	}
+	d.DeletedAt = time.Time{}
+	d.Version++
+	r.store.touchPhoneCasesWithDiscount(d.ID)
+	return mapToDiscount(*d), nil
+}
+
+// every given filter must match, empty filters are ignored like in the sql
+// repositories
+func matchPhoneCaseFilters(p *MemoryPhoneCase, filters map[string]string) bool {
+	if price := filters["price"]; price != "" && p.Price != price {
+		return false
+	}
+	if status := filters["inventory_status"]; status != "" && p.InventoryStatus != status {
+		return false
+	}
+	return true
+}
@@ -15,6 +15,8 @@ type MemoryPhoneCase struct {
 	PhoneBrandReferenceID int
 	CaseTypeID            int
 	CreatedBy             users.User
+	Version               int
+	DeletedAt             time.Time
 }
 
 type MemoryDiscount struct {
@@ -24,6 +26,8 @@ type MemoryDiscount struct {
 	ValidUntil time.Time
 	CreateByID int
 	CreatedAt  time.Time
+	Version    int
+	DeletedAt  time.Time
 }
 
 type MemoryBrand string
@@ -39,6 +43,7 @@ type MemoryCaseType struct {
 	Name        string
 	IconImgPath string
 	Images      []MemoryCaseTypeImage
+	Version     int
 }
 
 type MemoryCaseTypeImage struct {
@@ -46,4 +51,5 @@ type MemoryCaseTypeImage struct {
 	Path          string
 	OrderPriority int
 	CaseTypeID    int
+	DeletedAt     time.Time
 }
@@ -0,0 +1,88 @@
+package memoryrepo
+
+import "encoding/json"
+
+// storeSnapshot keeps every table and ID sequence of the store, so IDs of
+// deleted rows are not reused after a restore
+type storeSnapshot struct {
+	PhoneCases      []MemoryPhoneCase
+	Discounts       []MemoryDiscount
+	Brands          []MemoryBrand
+	BrandReferences []MemoryPhoneBrandReference
+	CaseTypes       []MemoryCaseType
+	CaseTypeImages  []MemoryCaseTypeImage
+
+	LastPhoneCaseID     int
+	LastDiscountID      int
+	LastBrandRefID      int
+	LastCaseTypeID      int
+	LastCaseTypeImageID int
+}
+
+func (s *MemoryStore) RLock() {
+	s.mu.RLock()
+}
+
+func (s *MemoryStore) RUnlock() {
+	s.mu.RUnlock()
+}
+
+func (s *MemoryStore) MarshalSnapshot() ([]byte, error) {
+	return json.Marshal(storeSnapshot{
+		PhoneCases:      values(s.phoneCases),
+		Discounts:       values(s.discounts),
+		Brands:          s.brands,
+		BrandReferences: values(s.brandReferences),
+		CaseTypes:       values(s.caseTypes),
+		CaseTypeImages:  values(s.caseTypeImages),
+
+		LastPhoneCaseID:     s.lastPhoneCaseID,
+		LastDiscountID:      s.lastDiscountID,
+		LastBrandRefID:      s.lastBrandRefID,
+		LastCaseTypeID:      s.lastCaseTypeID,
+		LastCaseTypeImageID: s.lastCaseTypeImageID,
+	})
+}
+
+// UnmarshalSnapshot replaces the content of the store
+func (s *MemoryStore) UnmarshalSnapshot(data []byte) error {
+	var snapshot storeSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+
+	restored := NewMemoryStore(
+		snapshot.PhoneCases, snapshot.Discounts, snapshot.Brands,
+		snapshot.BrandReferences, snapshot.CaseTypes,
+	)
+	for _, img := range snapshot.CaseTypeImages {
+		img := img
+		restored.caseTypeImages[img.ID] = &img
+		restored.lastCaseTypeImageID = max(restored.lastCaseTypeImageID, img.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.phoneCases = restored.phoneCases
+	s.discounts = restored.discounts
+	s.brands = restored.brands
+	s.brandReferences = restored.brandReferences
+	s.caseTypes = restored.caseTypes
+	s.caseTypeImages = restored.caseTypeImages
+	s.lastPhoneCaseID = max(restored.lastPhoneCaseID, snapshot.LastPhoneCaseID)
+	s.lastDiscountID = max(restored.lastDiscountID, snapshot.LastDiscountID)
+	s.lastBrandRefID = max(restored.lastBrandRefID, snapshot.LastBrandRefID)
+	s.lastCaseTypeID = max(restored.lastCaseTypeID, snapshot.LastCaseTypeID)
+	s.lastCaseTypeImageID = max(restored.lastCaseTypeImageID, snapshot.LastCaseTypeImageID)
+	return nil
+}
+
+// values returns the rows of a table ordered by ID
+func values[T any](table map[int]*T) []T {
+	rows := make([]T, 0, len(table))
+	for _, ID := range sortedIDs(table) {
+		rows = append(rows, *table[ID])
+	}
+	return rows
+}
@@ -1,23 +1,180 @@
 package memoryrepo
 
+import (
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"sort"
+	"sync"
+)
+
+// MemoryStore holds the products data shared by the memory repositories, it
+// must be passed by pointer. Rows are indexed by ID and every table has its own
+// ID sequence so IDs are never reused after a delete
 type MemoryStore struct {
-	PhoneCases      []MemoryPhoneCase
-	Discounts       []MemoryDiscount
-	Brands          []MemoryBrand
-	BrandReferences []MemoryPhoneBrandReference
-	CaseTypes       []MemoryCaseType
-	CaseTypeImage   []MemoryCaseTypeImage
+	mu              sync.RWMutex
+	phoneCases      map[int]*MemoryPhoneCase
+	discounts       map[int]*MemoryDiscount
+	brands          []MemoryBrand
+	brandReferences map[int]*MemoryPhoneBrandReference
+	caseTypes       map[int]*MemoryCaseType
+	caseTypeImages  map[int]*MemoryCaseTypeImage
+
+	lastPhoneCaseID     int
+	lastDiscountID      int
+	lastBrandRefID      int
+	lastCaseTypeID      int
+	lastCaseTypeImageID int
 }
 
 func NewMemoryStore(
 	phoneCases []MemoryPhoneCase, discounts []MemoryDiscount, brands []MemoryBrand,
 	brandRefs []MemoryPhoneBrandReference, caseTypes []MemoryCaseType,
-) MemoryStore {
-	return MemoryStore{
-		PhoneCases:      phoneCases,
-		Discounts:       discounts,
-		Brands:          brands,
-		BrandReferences: brandRefs,
-		CaseTypes:       caseTypes,
+) *MemoryStore {
+	s := &MemoryStore{
+		phoneCases:      make(map[int]*MemoryPhoneCase, len(phoneCases)),
+		discounts:       make(map[int]*MemoryDiscount, len(discounts)),
+		brands:          append([]MemoryBrand{}, brands...),
+		brandReferences: make(map[int]*MemoryPhoneBrandReference, len(brandRefs)),
+		caseTypes:       make(map[int]*MemoryCaseType, len(caseTypes)),
+		caseTypeImages:  make(map[int]*MemoryCaseTypeImage),
+	}
+
+	// rows without a version start at the first one
+	for _, p := range phoneCases {
+		p := p
+		p.Version = max(p.Version, 1)
+		s.phoneCases[p.ID] = &p
+		s.lastPhoneCaseID = max(s.lastPhoneCaseID, p.ID)
+	}
+	for _, d := range discounts {
+		d := d
+		d.Version = max(d.Version, 1)
+		s.discounts[d.ID] = &d
+		s.lastDiscountID = max(s.lastDiscountID, d.ID)
+	}
+	for _, r := range brandRefs {
+		r := r
+		s.brandReferences[r.ID] = &r
+		s.lastBrandRefID = max(s.lastBrandRefID, r.ID)
+	}
+	for _, c := range caseTypes {
+		c := c
+		c.Version = max(c.Version, 1)
+		// images are kept in their own table, the case type field is only
+		// used to seed them
+		for _, img := range c.Images {
+			img := img
+			img.CaseTypeID = c.ID
+			s.caseTypeImages[img.ID] = &img
+			s.lastCaseTypeImageID = max(s.lastCaseTypeImageID, img.ID)
+		}
+		c.Images = nil
+		s.caseTypes[c.ID] = &c
+		s.lastCaseTypeID = max(s.lastCaseTypeID, c.ID)
+	}
+	return s
+}
+
+// Sizes returns the number of rows of each table, soft deleted rows included
+func (s *MemoryStore) Sizes() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return map[string]int{
+		"phone_cases":      len(s.phoneCases),
+		"discounts":        len(s.discounts),
+		"brand_references": len(s.brandReferences),
+		"case_types":       len(s.caseTypes),
+		"case_type_images": len(s.caseTypeImages),
+	}
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// sortedIDs returns the keys of a table in ascending order, so lists keep the
+// creation order
+func sortedIDs[T any](table map[int]*T) []int {
+	IDs := make([]int, 0, len(table))
+	for ID := range table {
+		IDs = append(IDs, ID)
+	}
+	sort.Ints(IDs)
+	return IDs
+}
+
+func matchVersion(current int, expected int) bool {
+	return expected == ports.AnyVersion || current == expected
+}
+
+// the methods below read related rows and must be called with the lock held
+
+func (s *MemoryStore) brandIndex(brand MemoryBrand) (int, bool) {
+	for i, b := range s.brands {
+		if b == brand {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// checkPhoneCaseReferences enforces the same rules as the foreign keys of the
+// sql backends
+func (s *MemoryStore) checkPhoneCaseReferences(brandRefID int, caseTypeID int) error {
+	if _, ok := s.brandReferences[brandRefID]; !ok {
+		return ports.PhoneBrandReferenceDoesNotExists
+	}
+	if _, ok := s.caseTypes[caseTypeID]; !ok {
+		return ports.CaseTypeDoesNotExists
+	}
+	return nil
+}
+
+// touchPhoneCasesWithDiscount changes the version of the phone cases that show
+// or stop showing the discount
+func (s *MemoryStore) touchPhoneCasesWithDiscount(discountID int) {
+	for _, p := range s.phoneCases {
+		if p.DiscountID == discountID {
+			p.Version++
+		}
+	}
+}
+
+// phone cases keep the reference to a deleted discount so restoring it brings
+// it back, but they don't show it
+func (s *MemoryStore) phoneCase(p *MemoryPhoneCase) domain.PhoneCase {
+	discount := domain.Discount{}
+	if d, ok := s.discounts[p.DiscountID]; ok && d.DeletedAt.IsZero() {
+		discount = mapToDiscount(*d)
+	}
+	brandRef := domain.PhoneBrandReference{}
+	if r, ok := s.brandReferences[p.PhoneBrandReferenceID]; ok {
+		brandRef = mapToPhoneBrandRef(*r)
+	}
+	caseType := domain.CaseType{}
+	if _, ok := s.caseTypes[p.CaseTypeID]; ok {
+		caseType = s.caseType(p.CaseTypeID)
+	}
+	return mapToPhoneCase(*p, discount, brandRef, caseType)
+}
+
+func (s *MemoryStore) caseType(ID int) domain.CaseType {
+	caseType := mapToCaseType(*s.caseTypes[ID])
+	caseType.Images = mapToCaseTypeImages(s.imagesOf(ID, false))
+	return caseType
+}
+
+func (s *MemoryStore) imagesOf(typeID int, includeDeleted bool) []MemoryCaseTypeImage {
+	images := make([]MemoryCaseTypeImage, 0)
+	for _, ID := range sortedIDs(s.caseTypeImages) {
+		img := s.caseTypeImages[ID]
+		if img.CaseTypeID == typeID && (includeDeleted || img.DeletedAt.IsZero()) {
+			images = append(images, *img)
+		}
 	}
+	sort.SliceStable(images, func(i, j int) bool { return images[i].OrderPriority < images[j].OrderPriority })
+	return images
 }
@@ -0,0 +1,244 @@
+package memoryrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/memoryrepo"
+	"github.com/Rhymond/go-money"
+)
+
+type sharedRepos struct {
+	brands    memoryrepo.MemoryPhoneBrandRepository
+	caseTypes memoryrepo.MemoryCaseTypeRepository
+	cases     memoryrepo.MemoryPhoneCaseRepository
+}
+
+func newSharedRepos() sharedRepos {
+	store := memoryrepo.NewMemoryStore(nil, nil, nil, nil, nil)
+	return sharedRepos{
+		brands:    memoryrepo.NewMemoryPhoneBrandRepository(store),
+		caseTypes: memoryrepo.NewMemoryCaseTypeRepository(store),
+		cases:     memoryrepo.NewMemoryPhoneCaseRepository(store),
+	}
+}
+
+func (r sharedRepos) seedPhoneCase(t *testing.T) domain.PhoneCase {
+	ctx := context.Background()
+	t.Helper()
+	if err := r.brands.CreateBrand(ctx, "Apple"); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := r.brands.CreateBrandReference(ctx, "iPhone 15", "Apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caseType, err := r.caseTypes.CreateCaseType(ctx, "Silicone", "/icon.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phoneCase, err := r.cases.CreatePhoneCase(
+		ctx, *money.New(45000, money.COP), "/scaffold.png", domain.PhoneCaseAvailable, ref.ID, caseType.ID, 1,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return phoneCase
+}
+
+func TestSharedStore_WritesAreVisibleAcrossRepositories(t *testing.T) {
+	ctx := context.Background()
+	t.Run("created phone case joins brand reference and case type", func(t *testing.T) {
+		repos := newSharedRepos()
+		created := repos.seedPhoneCase(t)
+
+		phoneCase, ok := repos.cases.GetPhoneCaseByID(ctx, created.ID)
+		if !ok {
+			t.Fatal("created phone case not found")
+		}
+		if phoneCase.PhoneBrandReference.Name != "iPhone 15" || phoneCase.PhoneBrandReference.Brand != "Apple" {
+			t.Errorf("unexpected brand reference %+v", phoneCase.PhoneBrandReference)
+		}
+		if phoneCase.CaseType.Name != "Silicone" {
+			t.Errorf("unexpected case type %+v", phoneCase.CaseType)
+		}
+	})
+
+	t.Run("case type changes show up in phone cases", func(t *testing.T) {
+		repos := newSharedRepos()
+		created := repos.seedPhoneCase(t)
+
+		repos.caseTypes.UpdateCaseType(ctx, created.CaseType.ID, ports.AnyVersion, "Leather")
+		repos.caseTypes.CreateCaseTypeImage(ctx, created.CaseType.ID, "/leather.png", 1)
+
+		phoneCase, _ := repos.cases.GetPhoneCaseByID(ctx, created.ID)
+		if phoneCase.CaseType.Name != "Leather" {
+			t.Errorf("expected case type Leather, got %s", phoneCase.CaseType.Name)
+		}
+		if len(phoneCase.CaseType.Images) != 1 || phoneCase.CaseType.Images[0].Path != "/leather.png" {
+			t.Errorf("unexpected case type images %+v", phoneCase.CaseType.Images)
+		}
+	})
+
+	t.Run("brand rename cascades to references", func(t *testing.T) {
+		repos := newSharedRepos()
+		created := repos.seedPhoneCase(t)
+
+		if err := repos.brands.UpdateBrand(ctx, "Apple", "Apple Inc"); err != nil {
+			t.Fatal(err)
+		}
+		if refs := repos.brands.ListBrandReferences(ctx, "Apple Inc"); len(refs) != 1 {
+			t.Errorf("expected 1 reference under the new name, got %d", len(refs))
+		}
+		phoneCase, _ := repos.cases.GetPhoneCaseByID(ctx, created.ID)
+		if phoneCase.PhoneBrandReference.Brand != "Apple Inc" {
+			t.Errorf("expected brand Apple Inc, got %s", phoneCase.PhoneBrandReference.Brand)
+		}
+	})
+
+	t.Run("deleted discount is hidden from phone cases", func(t *testing.T) {
+		repos := newSharedRepos()
+		created := repos.seedPhoneCase(t)
+
+		discount, _ := repos.cases.CreateDiscount(ctx, "sale", 10, time.Now().Add(time.Hour), 1)
+		if err := repos.cases.AttachDiscount(ctx, created.ID, discount.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.cases.DeleteDiscount(ctx, discount.ID, ports.AnyVersion); err != nil {
+			t.Fatal(err)
+		}
+		phoneCase, _ := repos.cases.GetPhoneCaseByID(ctx, created.ID)
+		if phoneCase.Discount.ID != 0 {
+			t.Errorf("expected no discount, got %+v", phoneCase.Discount)
+		}
+	})
+
+	t.Run("deleted phone case is hidden", func(t *testing.T) {
+		repos := newSharedRepos()
+		created := repos.seedPhoneCase(t)
+
+		if err := repos.cases.DeletePhoneCase(ctx, created.ID, ports.AnyVersion); err != nil {
+			t.Fatal(err)
+		}
+		if phoneCase, ok := repos.cases.GetPhoneCaseByID(ctx, created.ID); !ok || !phoneCase.IsDeleted() {
+			t.Error("deleted phone case must be kept as deleted")
+		}
+		if _, total := repos.cases.ListPhoneCases(ctx, map[string]string{}, 10, 0); total != 0 {
+			t.Errorf("expected 0 phone cases, got %d", total)
+		}
+	})
+}
+
+func TestSharedStore_RelationalIntegrity(t *testing.T) {
+	ctx := context.Background()
+	repos := newSharedRepos()
+	created := repos.seedPhoneCase(t)
+	price := *money.New(45000, money.COP)
+
+	cases := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name: "brand reference of unknown brand",
+			err: func() error {
+				_, err := repos.brands.CreateBrandReference(ctx, "Galaxy S24", "Samsung")
+				return err
+			}(),
+			expected: ports.PhoneBrandDoesNotExists,
+		},
+		{
+			name: "rename to existing brand",
+			err: func() error {
+				repos.brands.CreateBrand(ctx, "Samsung")
+				return repos.brands.UpdateBrand(ctx, "Apple", "Samsung")
+			}(),
+			expected: ports.BrandAlreadyExists,
+		},
+		{
+			name: "phone case with unknown brand reference",
+			err: func() error {
+				_, err := repos.cases.CreatePhoneCase(ctx, price, "", domain.PhoneCaseAvailable, 99, created.CaseType.ID, 1)
+				return err
+			}(),
+			expected: ports.PhoneBrandReferenceDoesNotExists,
+		},
+		{
+			name: "phone case with unknown case type",
+			err: func() error {
+				_, err := repos.cases.CreatePhoneCase(ctx, price, "", domain.PhoneCaseAvailable, created.PhoneBrandReference.ID, 99, 1)
+				return err
+			}(),
+			expected: ports.CaseTypeDoesNotExists,
+		},
+		{
+			name: "update phone case with unknown case type",
+			err: func() error {
+				_, err := repos.cases.UpdatePhoneCase(ctx, created.ID, ports.AnyVersion, price, "", domain.PhoneCaseAvailable, created.PhoneBrandReference.ID, 99)
+				return err
+			}(),
+			expected: ports.CaseTypeDoesNotExists,
+		},
+		{
+			name:     "attach unknown discount",
+			err:      repos.cases.AttachDiscount(ctx, created.ID, 99),
+			expected: ports.DiscountDoesNotExists,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if !errors.Is(c.err, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, c.err)
+			}
+		})
+	}
+
+	if _, total := repos.cases.ListPhoneCases(ctx, map[string]string{}, 10, 0); total != 1 {
+		t.Errorf("rejected writes must not be stored, got %d phone cases", total)
+	}
+}
+
+func TestSharedStore_Versions(t *testing.T) {
+	ctx := context.Background()
+	repos := newSharedRepos()
+	created := repos.seedPhoneCase(t)
+	price := *money.New(50000, money.COP)
+
+	updated, err := repos.cases.UpdatePhoneCase(
+		ctx, created.ID, created.Version, price, "", domain.PhoneCaseAvailable, created.PhoneBrandReference.ID, created.CaseType.ID,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != created.Version+1 {
+		t.Errorf("expected version %d, got %d", created.Version+1, updated.Version)
+	}
+	_, err = repos.cases.UpdatePhoneCase(
+		ctx, created.ID, created.Version, price, "", domain.PhoneCaseAvailable, created.PhoneBrandReference.ID, created.CaseType.ID,
+	)
+	if !errors.Is(err, ports.VersionMismatch) {
+		t.Errorf("expected VersionMismatch, got %v", err)
+	}
+
+	discount, _ := repos.cases.CreateDiscount(ctx, "sale", 10, time.Now().Add(time.Hour), 1)
+	repos.cases.AttachDiscount(ctx, created.ID, discount.ID)
+	// attaching a discount changes the phone case
+	if err := repos.cases.DeletePhoneCase(ctx, created.ID, updated.Version); !errors.Is(err, ports.VersionMismatch) {
+		t.Errorf("expected VersionMismatch, got %v", err)
+	}
+	if _, err := repos.cases.UpdateDiscount(ctx, discount.ID, discount.Version+1, 20, time.Now()); !errors.Is(err, ports.VersionMismatch) {
+		t.Errorf("expected VersionMismatch, got %v", err)
+	}
+	if _, err := repos.caseTypes.UpdateCaseType(ctx, created.CaseType.ID, created.CaseType.Version+1, "Leather"); !errors.Is(err, ports.VersionMismatch) {
+		t.Errorf("expected VersionMismatch, got %v", err)
+	}
+	if err := repos.cases.DeletePhoneCase(ctx, 99, 1); !errors.Is(err, ports.PhoneCaseDoesNotExists) {
+		t.Errorf("expected PhoneCaseDoesNotExists, got %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb"
+	"time"
+)
+
+const (
+	caseTypeColumns      = `id, name, icon_img_path, version`
+	caseTypeImageColumns = `id, path, order_priority, case_type_id, deleted_at`
+)
+
+const (
+	listCaseTypesQuery = `SELECT ` + caseTypeColumns + ` FROM case_types ORDER BY id`
+	getCaseTypeQuery   = `SELECT ` + caseTypeColumns + ` FROM case_types WHERE id = $1`
+	// a version of 0 matches any version, see ports.AnyVersion
+	updateCaseTypeQuery = `
+		UPDATE case_types SET name = $2, version = version + 1
+		WHERE id = $1 AND ($3 = 0 OR version = $3)
+		RETURNING ` + caseTypeColumns
+	// the images are removed by the cascade of their foreign key
+	deleteCaseTypeQuery = `DELETE FROM case_types WHERE id = $1 AND ($2 = 0 OR version = $2)`
+	createCaseTypeQuery = `
+		INSERT INTO case_types (name, icon_img_path) VALUES ($1, $2)
+		RETURNING ` + caseTypeColumns
+	createCaseTypeImageQuery = `
+		INSERT INTO case_type_images (case_type_id, path, order_priority) VALUES ($1, $2, $3)
+		RETURNING ` + caseTypeImageColumns
+	updateCaseTypeImageQuery = `
+		UPDATE case_type_images SET path = $2 WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + caseTypeImageColumns
+	deleteCaseTypeImageQuery = `
+		UPDATE case_type_images SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`
+	restoreCaseTypeImageQuery = `
+		UPDATE case_type_images SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + caseTypeImageColumns
+	caseTypeImageExistsQuery = `SELECT count(*) FROM case_type_images WHERE id = $1`
+)
+
+// images of several case types are loaded at once to avoid a query per case
+// type, the IDs are bound as a list of the dialect
+func listCaseTypeImagesQuery(dialect sqldb.Dialect) string {
+	return `
+		SELECT ` + caseTypeImageColumns + ` FROM case_type_images
+		WHERE ` + dialect.InList("case_type_id", "$1") + ` AND ($2 OR deleted_at IS NULL)
+		ORDER BY order_priority, id`
+}
+
+type SQLCaseTypeRepository struct {
+	dialect      sqldb.Dialect
+	list         *sql.Stmt
+	get          *sql.Stmt
+	update       *sql.Stmt
+	create       *sql.Stmt
+	delete       *sql.Stmt
+	listImages   *sql.Stmt
+	createImage  *sql.Stmt
+	updateImage  *sql.Stmt
+	deleteImage  *sql.Stmt
+	restoreImage *sql.Stmt
+	imageExists  *sql.Stmt
+}
+
+func NewSQLCaseTypeRepository(db *sqldb.DB) (*SQLCaseTypeRepository, error) {
+	r := &SQLCaseTypeRepository{dialect: db.Dialect}
+	err := sqldb.PrepareStatements(db.DB, map[**sql.Stmt]string{
+		&r.list:         listCaseTypesQuery,
+		&r.get:          getCaseTypeQuery,
+		&r.update:       updateCaseTypeQuery,
+		&r.create:       createCaseTypeQuery,
+		&r.delete:       deleteCaseTypeQuery,
+		&r.listImages:   listCaseTypeImagesQuery(db.Dialect),
+		&r.createImage:  createCaseTypeImageQuery,
+		&r.updateImage:  updateCaseTypeImageQuery,
+		&r.deleteImage:  deleteCaseTypeImageQuery,
+		&r.restoreImage: restoreCaseTypeImageQuery,
+		&r.imageExists:  caseTypeImageExistsQuery,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *SQLCaseTypeRepository) ListCaseTypes(ctx context.Context) []domain.CaseType {
+	caseTypes := make([]domain.CaseType, 0)
+
+	rows, err := r.list.QueryContext(ctx)
+	if err != nil {
+		return caseTypes
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		caseType, err := scanCaseType(rows)
+		if err != nil {
+			return caseTypes
+		}
+		caseTypes = append(caseTypes, caseType)
+	}
+
+	r.loadImages(ctx, caseTypes)
+	return caseTypes
+}
+
+func (r *SQLCaseTypeRepository) GetCaseTypeByID(ctx context.Context, ID domain.CaseTypeID) (domain.CaseType, bool) {
+	caseType, err := scanCaseType(r.get.QueryRowContext(ctx, ID))
+	if err != nil {
+		return domain.CaseType{}, false
+	}
+	caseType.Images = r.ListCaseTypeImages(ctx, ID, false)
+	return caseType, true
+}
+
+func (r *SQLCaseTypeRepository) UpdateCaseType(
+	ctx context.Context, ID domain.CaseTypeID, version int, name string,
+) (domain.CaseType, error) {
+	caseType, err := scanCaseType(r.update.QueryRowContext(ctx, ID, name, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, ok := r.GetCaseTypeByID(ctx, ID); ok {
+			return domain.CaseType{}, ports.VersionMismatch
+		}
+		return domain.CaseType{}, ports.CaseTypeDoesNotExists
+	}
+	if err != nil {
+		return domain.CaseType{}, err
+	}
+	caseType.Images = r.ListCaseTypeImages(ctx, ID, false)
+	return caseType, nil
+}
+
+func (r *SQLCaseTypeRepository) CreateCaseType(
+	ctx context.Context, name string, iconPath string,
+) (domain.CaseType, error) {
+	return scanCaseType(r.create.QueryRowContext(ctx, name, iconPath))
+}
+
+func (r *SQLCaseTypeRepository) DeleteCaseType(ctx context.Context, ID domain.CaseTypeID, version int) error {
+	result, err := r.delete.ExecContext(ctx, ID, version)
+	if r.dialect.IsForeignKeyViolation(err) {
+		return ports.CaseTypeInUse
+	}
+	err = rowsAffected(result, err, ports.CaseTypeDoesNotExists)
+	if errors.Is(err, ports.CaseTypeDoesNotExists) {
+		if _, ok := r.GetCaseTypeByID(ctx, ID); ok {
+			return ports.VersionMismatch
+		}
+	}
+	return err
+}
+
+func (r *SQLCaseTypeRepository) ListCaseTypeImages(
+	ctx context.Context, typeID domain.CaseTypeID, includeDeleted bool,
+) []domain.CaseTypeImage {
+	return listCaseTypeImages(ctx, r.dialect, r.listImages, []domain.CaseTypeID{typeID}, includeDeleted)[typeID]
+}
+
+func (r *SQLCaseTypeRepository) CreateCaseTypeImage(
+	ctx context.Context, typeID domain.CaseTypeID, path string, orderPriority int,
+) (domain.CaseTypeImage, error) {
+	image, _, err := scanCaseTypeImage(r.createImage.QueryRowContext(ctx, typeID, path, orderPriority))
+	if r.dialect.IsForeignKeyViolation(err) {
+		return domain.CaseTypeImage{}, ports.CaseTypeDoesNotExists
+	}
+	return image, err
+}
+
+func (r *SQLCaseTypeRepository) UpdateCaseTypeImage(
+	ctx context.Context, ID domain.CaseTypeImageID, path string,
+) (domain.CaseTypeImage, error) {
+	image, _, err := scanCaseTypeImage(r.updateImage.QueryRowContext(ctx, ID, path))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.CaseTypeImage{}, ports.CaseTypeImageDoesNotExists
+	}
+	return image, err
+}
+
+func (r *SQLCaseTypeRepository) DeleteCaseTypeImage(ctx context.Context, ID domain.CaseTypeImageID) error {
+	result, err := r.deleteImage.ExecContext(ctx, ID, time.Now().UTC())
+	return rowsAffected(result, err, ports.CaseTypeImageDoesNotExists)
+}
+
+func (r *SQLCaseTypeRepository) RestoreCaseTypeImage(
+	ctx context.Context, ID domain.CaseTypeImageID,
+) (domain.CaseTypeImage, error) {
+	image, _, err := scanCaseTypeImage(r.restoreImage.QueryRowContext(ctx, ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		var count int
+		if err := r.imageExists.QueryRowContext(ctx, ID).Scan(&count); err != nil {
+			return domain.CaseTypeImage{}, err
+		}
+		if count == 0 {
+			return domain.CaseTypeImage{}, ports.CaseTypeImageDoesNotExists
+		}
+		return domain.CaseTypeImage{}, ports.NotDeleted
+	}
+	return image, err
+}
+
+func (r *SQLCaseTypeRepository) loadImages(ctx context.Context, caseTypes []domain.CaseType) {
+	IDs := make([]domain.CaseTypeID, 0, len(caseTypes))
+	for _, c := range caseTypes {
+		IDs = append(IDs, c.ID)
+	}
+
+	images := listCaseTypeImages(ctx, r.dialect, r.listImages, IDs, false)
+	for i := range caseTypes {
+		caseTypes[i].Images = images[caseTypes[i].ID]
+	}
+}
+
+// listCaseTypeImages returns the images of the given case types grouped by
+// case type, every case type gets a non nil slice
+func listCaseTypeImages(
+	ctx context.Context, dialect sqldb.Dialect, stmt *sql.Stmt, typeIDs []domain.CaseTypeID, includeDeleted bool,
+) map[domain.CaseTypeID][]domain.CaseTypeImage {
+	images := make(map[domain.CaseTypeID][]domain.CaseTypeImage, len(typeIDs))
+	IDs := make([]int64, 0, len(typeIDs))
+	for _, ID := range typeIDs {
+		images[ID] = make([]domain.CaseTypeImage, 0)
+		IDs = append(IDs, int64(ID))
+	}
+
+	rows, err := stmt.QueryContext(ctx, dialect.List(IDs), includeDeleted)
+	if err != nil {
+		return images
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		image, typeID, err := scanCaseTypeImage(rows)
+		if err != nil {
+			return images
+		}
+		images[typeID] = append(images[typeID], image)
+	}
+	return images
+}
+
+func scanCaseType(row sqldb.RowScanner) (domain.CaseType, error) {
+	var c domain.CaseType
+	if err := row.Scan(&c.ID, &c.Name, &c.IconImgPath, &c.Version); err != nil {
+		return domain.CaseType{}, err
+	}
+	c.Images = make([]domain.CaseTypeImage, 0)
+	return c, nil
+}
+
+func scanCaseTypeImage(row sqldb.RowScanner) (domain.CaseTypeImage, domain.CaseTypeID, error) {
+	var image domain.CaseTypeImage
+	var typeID domain.CaseTypeID
+	var deletedAt sql.NullTime
+	err := row.Scan(&image.ID, &image.Path, &image.OrderPriority, &typeID, &deletedAt)
+	image.DeletedAt = deletedAt.Time
+	return image, typeID, err
+}
@@ -0,0 +1,150 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb"
+)
+
+const brandReferenceColumns = `id, brand, name`
+
+const (
+	listBrandsQuery           = `SELECT name FROM phone_brands ORDER BY name`
+	updateBrandQuery          = `UPDATE phone_brands SET name = $2 WHERE name = $1`
+	createBrandQuery          = `INSERT INTO phone_brands (name) VALUES ($1)`
+	listBrandReferencesQuery  = `SELECT ` + brandReferenceColumns + ` FROM phone_brand_references WHERE brand = $1 ORDER BY id`
+	getBrandReferenceQuery    = `SELECT ` + brandReferenceColumns + ` FROM phone_brand_references WHERE id = $1`
+	updateBrandReferenceQuery = `
+		UPDATE phone_brand_references SET name = $2 WHERE id = $1
+		RETURNING ` + brandReferenceColumns
+	createBrandReferenceQuery = `
+		INSERT INTO phone_brand_references (name, brand) VALUES ($1, $2)
+		RETURNING ` + brandReferenceColumns
+)
+
+type SQLPhoneBrandRepository struct {
+	dialect              sqldb.Dialect
+	listBrands           *sql.Stmt
+	updateBrand          *sql.Stmt
+	createBrand          *sql.Stmt
+	listBrandReferences  *sql.Stmt
+	getBrandReference    *sql.Stmt
+	updateBrandReference *sql.Stmt
+	createBrandReference *sql.Stmt
+}
+
+func NewSQLPhoneBrandRepository(db *sqldb.DB) (*SQLPhoneBrandRepository, error) {
+	r := &SQLPhoneBrandRepository{dialect: db.Dialect}
+	err := sqldb.PrepareStatements(db.DB, map[**sql.Stmt]string{
+		&r.listBrands:           listBrandsQuery,
+		&r.updateBrand:          updateBrandQuery,
+		&r.createBrand:          createBrandQuery,
+		&r.listBrandReferences:  listBrandReferencesQuery,
+		&r.getBrandReference:    getBrandReferenceQuery,
+		&r.updateBrandReference: updateBrandReferenceQuery,
+		&r.createBrandReference: createBrandReferenceQuery,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *SQLPhoneBrandRepository) ListAllBrands(ctx context.Context) []domain.PhoneBrand {
+	brands := make([]domain.PhoneBrand, 0)
+
+	rows, err := r.listBrands.QueryContext(ctx)
+	if err != nil {
+		return brands
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var brand string
+		if err := rows.Scan(&brand); err != nil {
+			return brands
+		}
+		brands = append(brands, domain.PhoneBrand(brand))
+	}
+	return brands
+}
+
+func (r *SQLPhoneBrandRepository) UpdateBrand(ctx context.Context, brand domain.PhoneBrand, renameTo string) error {
+	result, err := r.updateBrand.ExecContext(ctx, string(brand), renameTo)
+	if r.dialect.IsUniqueViolation(err) {
+		return ports.BrandAlreadyExists
+	}
+	return rowsAffected(result, err, ports.PhoneBrandDoesNotExists)
+}
+
+func (r *SQLPhoneBrandRepository) CreateBrand(ctx context.Context, brand domain.PhoneBrand) error {
+	_, err := r.createBrand.ExecContext(ctx, string(brand))
+	if r.dialect.IsUniqueViolation(err) {
+		return ports.BrandAlreadyExists
+	}
+	return err
+}
+
+func (r *SQLPhoneBrandRepository) ListBrandReferences(
+	ctx context.Context, brand domain.PhoneBrand,
+) []domain.PhoneBrandReference {
+	refs := make([]domain.PhoneBrandReference, 0)
+
+	rows, err := r.listBrandReferences.QueryContext(ctx, string(brand))
+	if err != nil {
+		return refs
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		ref, err := scanBrandReference(rows)
+		if err != nil {
+			return refs
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+func (r *SQLPhoneBrandRepository) GetBrandReferenceByID(
+	ctx context.Context, ID domain.PhoneBrandReferenceID,
+) (domain.PhoneBrandReference, bool) {
+	ref, err := scanBrandReference(r.getBrandReference.QueryRowContext(ctx, ID))
+	if err != nil {
+		return domain.PhoneBrandReference{}, false
+	}
+	return ref, true
+}
+
+func (r *SQLPhoneBrandRepository) UpdateBrandReference(
+	ctx context.Context, ID domain.PhoneBrandReferenceID, name string,
+) (domain.PhoneBrandReference, error) {
+	ref, err := scanBrandReference(r.updateBrandReference.QueryRowContext(ctx, ID, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.PhoneBrandReference{}, ports.PhoneBrandReferenceDoesNotExists
+	}
+	return ref, err
+}
+
+func (r *SQLPhoneBrandRepository) CreateBrandReference(
+	ctx context.Context, name string, brand domain.PhoneBrand,
+) (domain.PhoneBrandReference, error) {
+	ref, err := scanBrandReference(r.createBrandReference.QueryRowContext(ctx, name, string(brand)))
+	if r.dialect.IsForeignKeyViolation(err) {
+		return domain.PhoneBrandReference{}, ports.PhoneBrandDoesNotExists
+	}
+	return ref, err
+}
+
+func scanBrandReference(row sqldb.RowScanner) (domain.PhoneBrandReference, error) {
+	var ref domain.PhoneBrandReference
+	var brand string
+	if err := row.Scan(&ref.ID, &brand, &ref.Name); err != nil {
+		return domain.PhoneBrandReference{}, err
+	}
+	ref.Brand = domain.PhoneBrand(brand)
+	return ref, nil
+}
@@ -0,0 +1,458 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/Rhymond/go-money"
+	"time"
+)
+
+const (
+	discountColumns = `id, name, rate, valid_until, created_by, created_at, version, deleted_at`
+	// related rows are joined so listing doesn't need a query per phone case
+	phoneCaseSelect = `
+		SELECT
+			pc.id, pc.price_amount, pc.price_currency, pc.case_scaffold_img_path, pc.inventory_status,
+			pc.created_by, pc.created_at, pc.version, pc.deleted_at,
+			d.id, d.name, d.rate, d.valid_until, d.created_by, d.created_at, d.version,
+			br.id, br.brand, br.name,
+			ct.id, ct.name, ct.icon_img_path, ct.version
+		FROM phone_cases pc
+		JOIN phone_brand_references br ON br.id = pc.phone_brand_reference_id
+		JOIN case_types ct ON ct.id = pc.case_type_id
+		LEFT JOIN discounts d ON d.id = pc.discount_id AND d.deleted_at IS NULL`
+	// empty filters are ignored, so the list queries can be prepared
+	phoneCaseFiltersCondition = `
+		($1 = '' OR CAST(pc.price_amount AS TEXT) = $1)
+		AND ($2 = '' OR pc.inventory_status = $2)
+		AND ($3 OR pc.deleted_at IS NULL)`
+)
+
+const (
+	listPhoneCasesQuery = phoneCaseSelect + ` WHERE ` + phoneCaseFiltersCondition +
+		` ORDER BY pc.id LIMIT $4 OFFSET $5`
+	countPhoneCasesQuery = `SELECT count(*) FROM phone_cases pc WHERE ` + phoneCaseFiltersCondition
+	getPhoneCaseQuery    = phoneCaseSelect + ` WHERE pc.id = $1`
+	createPhoneCaseQuery = `
+		INSERT INTO phone_cases (
+			price_amount, price_currency, case_scaffold_img_path, inventory_status,
+			phone_brand_reference_id, case_type_id, created_by, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`
+	// a version of 0 matches any version, see ports.AnyVersion
+	updatePhoneCaseQuery = `
+		UPDATE phone_cases SET
+			price_amount = $2, price_currency = $3, case_scaffold_img_path = $4, inventory_status = $5,
+			phone_brand_reference_id = $6, case_type_id = $7, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($8 = 0 OR version = $8)`
+	deletePhoneCaseQuery = `
+		UPDATE phone_cases SET deleted_at = $3, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)`
+	restorePhoneCaseQuery = `
+		UPDATE phone_cases SET deleted_at = NULL, version = version + 1
+		WHERE id = $1 AND deleted_at IS NOT NULL AND ($2 = 0 OR version = $2)`
+	phoneCaseExistsQuery = `SELECT count(*) FROM phone_cases WHERE id = $1 AND deleted_at IS NULL`
+	attachDiscountQuery  = `
+		UPDATE phone_cases SET discount_id = $2, version = version + 1 WHERE id = $1 AND deleted_at IS NULL`
+	listDiscountsQuery  = `SELECT ` + discountColumns + ` FROM discounts WHERE ($1 OR deleted_at IS NULL) ORDER BY id`
+	getDiscountQuery    = `SELECT ` + discountColumns + ` FROM discounts WHERE id = $1`
+	createDiscountQuery = `
+		INSERT INTO discounts (name, rate, valid_until, created_by, created_at) VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + discountColumns
+	updateDiscountQuery = `
+		UPDATE discounts SET rate = $2, valid_until = $3, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($4 = 0 OR version = $4)
+		RETURNING ` + discountColumns
+	// phone cases keep a deleted discount so restoring it brings it back, they
+	// only stop showing it, which changes their version
+	touchPhoneCasesWithDiscountQuery = `
+		UPDATE phone_cases SET version = version + 1 WHERE discount_id = $1`
+	deleteDiscountQuery = `
+		UPDATE discounts SET deleted_at = $3, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)`
+	restoreDiscountQuery = `
+		UPDATE discounts SET deleted_at = NULL, version = version + 1
+		WHERE id = $1 AND deleted_at IS NOT NULL AND ($2 = 0 OR version = $2)
+		RETURNING ` + discountColumns
+	brandReferenceExistsQuery = `SELECT count(*) FROM phone_brand_references WHERE id = $1`
+)
+
+type SQLPhoneCaseRepository struct {
+	db              *sqldb.DB
+	list            *sql.Stmt
+	count           *sql.Stmt
+	get             *sql.Stmt
+	create          *sql.Stmt
+	update          *sql.Stmt
+	delete          *sql.Stmt
+	restore         *sql.Stmt
+	exists          *sql.Stmt
+	attachDiscount  *sql.Stmt
+	listImages      *sql.Stmt
+	listDiscounts   *sql.Stmt
+	getDiscount     *sql.Stmt
+	createDiscount  *sql.Stmt
+	updateDiscount  *sql.Stmt
+	touchDiscounted *sql.Stmt
+	deleteDiscount  *sql.Stmt
+	restoreDiscount *sql.Stmt
+	brandRefExists  *sql.Stmt
+}
+
+func NewSQLPhoneCaseRepository(db *sqldb.DB) (*SQLPhoneCaseRepository, error) {
+	r := &SQLPhoneCaseRepository{db: db}
+	err := sqldb.PrepareStatements(db.DB, map[**sql.Stmt]string{
+		&r.list:            listPhoneCasesQuery,
+		&r.count:           countPhoneCasesQuery,
+		&r.get:             getPhoneCaseQuery,
+		&r.create:          createPhoneCaseQuery,
+		&r.update:          updatePhoneCaseQuery,
+		&r.delete:          deletePhoneCaseQuery,
+		&r.restore:         restorePhoneCaseQuery,
+		&r.exists:          phoneCaseExistsQuery,
+		&r.attachDiscount:  attachDiscountQuery,
+		&r.listImages:      listCaseTypeImagesQuery(db.Dialect),
+		&r.listDiscounts:   listDiscountsQuery,
+		&r.getDiscount:     getDiscountQuery,
+		&r.createDiscount:  createDiscountQuery,
+		&r.updateDiscount:  updateDiscountQuery,
+		&r.touchDiscounted: touchPhoneCasesWithDiscountQuery,
+		&r.deleteDiscount:  deleteDiscountQuery,
+		&r.restoreDiscount: restoreDiscountQuery,
+		&r.brandRefExists:  brandReferenceExistsQuery,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *SQLPhoneCaseRepository) ListPhoneCases(
+	ctx context.Context, filters map[string]string, limit int, offset int,
+) ([]domain.PhoneCase, int) {
+	result := make([]domain.PhoneCase, 0)
+
+	includeDeleted := filters[ports.IncludeDeleted] == "true"
+	var total int
+	err := r.count.QueryRowContext(ctx, filters["price"], filters["inventory_status"], includeDeleted).Scan(&total)
+	if err != nil {
+		return result, 0
+	}
+
+	rows, err := r.list.QueryContext(ctx, filters["price"], filters["inventory_status"], includeDeleted, limit-offset, offset)
+	if err != nil {
+		return result, total
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		phoneCase, err := scanPhoneCase(rows)
+		if err != nil {
+			return result, total
+		}
+		result = append(result, phoneCase)
+	}
+
+	r.loadCaseTypeImages(ctx, result)
+	return result, total
+}
+
+func (r *SQLPhoneCaseRepository) GetPhoneCaseByID(
+	ctx context.Context, ID domain.PhoneCaseID,
+) (domain.PhoneCase, bool) {
+	phoneCase, err := scanPhoneCase(r.get.QueryRowContext(ctx, ID))
+	if err != nil {
+		return domain.PhoneCase{}, false
+	}
+
+	cases := []domain.PhoneCase{phoneCase}
+	r.loadCaseTypeImages(ctx, cases)
+	return cases[0], true
+}
+
+func (r *SQLPhoneCaseRepository) CreatePhoneCase(
+	ctx context.Context, price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus,
+	phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID, createdBy users.UserID,
+) (domain.PhoneCase, error) {
+	var ID domain.PhoneCaseID
+	err := r.create.QueryRowContext(ctx,
+		price.Amount(), price.Currency().Code, scaffoldImgPath, string(inventoryStatus),
+		phoneBrandRefID, caseTypeID, createdBy, time.Now().UTC(),
+	).Scan(&ID)
+	if err != nil {
+		return domain.PhoneCase{}, r.mapReferenceError(ctx, err, phoneBrandRefID)
+	}
+
+	phoneCase, _ := r.GetPhoneCaseByID(ctx, ID)
+	return phoneCase, nil
+}
+
+func (r *SQLPhoneCaseRepository) UpdatePhoneCase(
+	ctx context.Context, ID domain.PhoneCaseID, version int, price money.Money, scaffoldImgPath string,
+	inventoryStatus domain.InventoryStatus, phoneBrandRefID domain.PhoneBrandReferenceID,
+	caseTypeID domain.CaseTypeID,
+) (domain.PhoneCase, error) {
+	result, err := r.update.ExecContext(ctx,
+		ID, price.Amount(), price.Currency().Code, scaffoldImgPath, string(inventoryStatus),
+		phoneBrandRefID, caseTypeID, version,
+	)
+	err = rowsAffected(result, r.mapReferenceError(ctx, err, phoneBrandRefID), ports.PhoneCaseDoesNotExists)
+	if errors.Is(err, ports.PhoneCaseDoesNotExists) {
+		return domain.PhoneCase{}, r.phoneCaseNotChanged(ctx, ID)
+	}
+	if err != nil {
+		return domain.PhoneCase{}, err
+	}
+
+	phoneCase, _ := r.GetPhoneCaseByID(ctx, ID)
+	return phoneCase, nil
+}
+
+func (r *SQLPhoneCaseRepository) DeletePhoneCase(ctx context.Context, ID domain.PhoneCaseID, version int) error {
+	result, err := r.delete.ExecContext(ctx, ID, version, time.Now().UTC())
+	err = rowsAffected(result, err, ports.PhoneCaseDoesNotExists)
+	if errors.Is(err, ports.PhoneCaseDoesNotExists) {
+		return r.phoneCaseNotChanged(ctx, ID)
+	}
+	return err
+}
+
+func (r *SQLPhoneCaseRepository) RestorePhoneCase(
+	ctx context.Context, ID domain.PhoneCaseID, version int,
+) (domain.PhoneCase, error) {
+	result, err := r.restore.ExecContext(ctx, ID, version)
+	err = rowsAffected(result, err, ports.PhoneCaseDoesNotExists)
+	if errors.Is(err, ports.PhoneCaseDoesNotExists) {
+		return domain.PhoneCase{}, r.phoneCaseNotRestored(ctx, ID)
+	}
+	if err != nil {
+		return domain.PhoneCase{}, err
+	}
+
+	phoneCase, _ := r.GetPhoneCaseByID(ctx, ID)
+	return phoneCase, nil
+}
+
+func (r *SQLPhoneCaseRepository) AttachDiscount(
+	ctx context.Context, caseID domain.PhoneCaseID, discountID domain.DiscountID,
+) error {
+	if discount, ok := r.GetDiscountByID(ctx, discountID); !ok || discount.IsDeleted() {
+		return ports.DiscountDoesNotExists
+	}
+	result, err := r.attachDiscount.ExecContext(ctx, caseID, discountID)
+	if r.db.Dialect.IsForeignKeyViolation(err) {
+		return ports.DiscountDoesNotExists
+	}
+	return rowsAffected(result, err, ports.PhoneCaseDoesNotExists)
+}
+
+func (r *SQLPhoneCaseRepository) ListAllDiscounts(ctx context.Context, includeDeleted bool) []domain.Discount {
+	discounts := make([]domain.Discount, 0)
+
+	rows, err := r.listDiscounts.QueryContext(ctx, includeDeleted)
+	if err != nil {
+		return discounts
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		discount, err := scanDiscount(rows)
+		if err != nil {
+			return discounts
+		}
+		discounts = append(discounts, discount)
+	}
+	return discounts
+}
+
+func (r *SQLPhoneCaseRepository) GetDiscountByID(ctx context.Context, ID domain.DiscountID) (domain.Discount, bool) {
+	discount, err := scanDiscount(r.getDiscount.QueryRowContext(ctx, ID))
+	if err != nil {
+		return domain.Discount{}, false
+	}
+	return discount, true
+}
+
+func (r *SQLPhoneCaseRepository) CreateDiscount(
+	ctx context.Context, name string, rate int, validUntil time.Time, createdBy users.UserID,
+) (domain.Discount, error) {
+	return scanDiscount(r.createDiscount.QueryRowContext(ctx, name, rate, validUntil.UTC(), createdBy, time.Now().UTC()))
+}
+
+func (r *SQLPhoneCaseRepository) UpdateDiscount(
+	ctx context.Context, ID domain.DiscountID, version int, rate int, validUntil time.Time,
+) (domain.Discount, error) {
+	discount, err := scanDiscount(r.updateDiscount.QueryRowContext(ctx, ID, rate, validUntil.UTC(), version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Discount{}, r.discountNotChanged(ctx, ID)
+	}
+	return discount, err
+}
+
+func (r *SQLPhoneCaseRepository) DeleteDiscount(ctx context.Context, ID domain.DiscountID, version int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Stmt(r.touchDiscounted).ExecContext(ctx, ID); err != nil {
+		return err
+	}
+	result, err := tx.Stmt(r.deleteDiscount).ExecContext(ctx, ID, version, time.Now().UTC())
+	err = rowsAffected(result, err, ports.DiscountDoesNotExists)
+	if errors.Is(err, ports.DiscountDoesNotExists) {
+		tx.Rollback()
+		return r.discountNotChanged(ctx, ID)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLPhoneCaseRepository) RestoreDiscount(
+	ctx context.Context, ID domain.DiscountID, version int,
+) (domain.Discount, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Discount{}, err
+	}
+	defer tx.Rollback()
+
+	discount, err := scanDiscount(tx.Stmt(r.restoreDiscount).QueryRowContext(ctx, ID, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		return domain.Discount{}, r.discountNotRestored(ctx, ID)
+	}
+	if err != nil {
+		return domain.Discount{}, err
+	}
+	if _, err := tx.Stmt(r.touchDiscounted).ExecContext(ctx, ID); err != nil {
+		return domain.Discount{}, err
+	}
+	return discount, tx.Commit()
+}
+
+// phoneCaseNotChanged tells why a write guarded by a version did not change the phone case
+func (r *SQLPhoneCaseRepository) phoneCaseNotChanged(ctx context.Context, ID domain.PhoneCaseID) error {
+	var count int
+	if err := r.exists.QueryRowContext(ctx, ID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ports.PhoneCaseDoesNotExists
+	}
+	return ports.VersionMismatch
+}
+
+// phoneCaseNotRestored tells why a restore guarded by a version did not change the phone case
+func (r *SQLPhoneCaseRepository) phoneCaseNotRestored(ctx context.Context, ID domain.PhoneCaseID) error {
+	phoneCase, ok := r.GetPhoneCaseByID(ctx, ID)
+	if !ok {
+		return ports.PhoneCaseDoesNotExists
+	}
+	if !phoneCase.IsDeleted() {
+		return ports.NotDeleted
+	}
+	return ports.VersionMismatch
+}
+
+func (r *SQLPhoneCaseRepository) discountNotChanged(ctx context.Context, ID domain.DiscountID) error {
+	if discount, ok := r.GetDiscountByID(ctx, ID); !ok || discount.IsDeleted() {
+		return ports.DiscountDoesNotExists
+	}
+	return ports.VersionMismatch
+}
+
+func (r *SQLPhoneCaseRepository) discountNotRestored(ctx context.Context, ID domain.DiscountID) error {
+	discount, ok := r.GetDiscountByID(ctx, ID)
+	if !ok {
+		return ports.DiscountDoesNotExists
+	}
+	if !discount.IsDeleted() {
+		return ports.NotDeleted
+	}
+	return ports.VersionMismatch
+}
+
+func (r *SQLPhoneCaseRepository) loadCaseTypeImages(ctx context.Context, cases []domain.PhoneCase) {
+	typeIDs := make([]domain.CaseTypeID, 0, len(cases))
+	for _, c := range cases {
+		typeIDs = append(typeIDs, c.CaseType.ID)
+	}
+
+	images := listCaseTypeImages(ctx, r.db.Dialect, r.listImages, typeIDs, false)
+	for i := range cases {
+		cases[i].CaseType.Images = images[cases[i].CaseType.ID]
+	}
+}
+
+// sqlite doesn't report which foreign key failed, a phone case only references
+// a brand reference and a case type so checking the first one is enough, the
+// same check is used for postgres to keep one implementation
+func (r *SQLPhoneCaseRepository) mapReferenceError(
+	ctx context.Context, err error, brandRefID domain.PhoneBrandReferenceID,
+) error {
+	if !r.db.Dialect.IsForeignKeyViolation(err) {
+		return err
+	}
+
+	var count int
+	if err := r.brandRefExists.QueryRowContext(ctx, brandRefID).Scan(&count); err == nil && count == 0 {
+		return ports.PhoneBrandReferenceDoesNotExists
+	}
+	return ports.CaseTypeDoesNotExists
+}
+
+func scanPhoneCase(row sqldb.RowScanner) (domain.PhoneCase, error) {
+	var p domain.PhoneCase
+	var amount int64
+	var currency, status, brand string
+	var discountID, discountRate, discountCreatedBy, discountVersion sql.NullInt64
+	var discountName sql.NullString
+	var discountValidUntil, discountCreatedAt, deletedAt sql.NullTime
+
+	err := row.Scan(
+		&p.ID, &amount, &currency, &p.CaseScaffoldImgPah, &status, &p.CreatedBy.ID, &p.CreatedAt, &p.Version,
+		&deletedAt,
+		&discountID, &discountName, &discountRate, &discountValidUntil, &discountCreatedBy, &discountCreatedAt,
+		&discountVersion,
+		&p.PhoneBrandReference.ID, &brand, &p.PhoneBrandReference.Name,
+		&p.CaseType.ID, &p.CaseType.Name, &p.CaseType.IconImgPath, &p.CaseType.Version,
+	)
+	if err != nil {
+		return domain.PhoneCase{}, err
+	}
+
+	p.Price = *money.New(amount, currency)
+	p.InventoryStatus = domain.InventoryStatus(status)
+	p.PhoneBrandReference.Brand = domain.PhoneBrand(brand)
+	p.DeletedAt = deletedAt.Time
+	if discountID.Valid {
+		p.Discount = domain.Discount{
+			ID:         domain.DiscountID(discountID.Int64),
+			Name:       discountName.String,
+			Rate:       int(discountRate.Int64),
+			ValidUntil: discountValidUntil.Time,
+			CreatedBy:  users.User{ID: users.UserID(discountCreatedBy.Int64)},
+			CreatedAt:  discountCreatedAt.Time,
+			Version:    int(discountVersion.Int64),
+		}
+	}
+	return p, nil
+}
+
+func scanDiscount(row sqldb.RowScanner) (domain.Discount, error) {
+	var d domain.Discount
+	var deletedAt sql.NullTime
+	err := row.Scan(&d.ID, &d.Name, &d.Rate, &d.ValidUntil, &d.CreatedBy.ID, &d.CreatedAt, &d.Version, &deletedAt)
+	d.DeletedAt = deletedAt.Time
+	return d, err
+}
@@ -0,0 +1,175 @@
+package sqlrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/migrations"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports/portstest"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/sqlrepo"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb/sqldbtest"
+	"github.com/Rhymond/go-money"
+)
+
+func TestSQLProductRepositories(t *testing.T) {
+	sqldbtest.Run(t, func(t *testing.T, dialect migrations.Dialect) {
+		ctx := context.Background()
+		db := sqldbtest.Open(t, dialect)
+		brandRepo, err := sqlrepo.NewSQLPhoneBrandRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		caseTypeRepo, err := sqlrepo.NewSQLCaseTypeRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		caseRepo, err := sqlrepo.NewSQLPhoneCaseRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := brandRepo.CreateBrand(ctx, "Apple"); err != nil {
+			t.Fatal(err)
+		}
+		if err := brandRepo.CreateBrand(ctx, "Apple"); !errors.Is(err, ports.BrandAlreadyExists) {
+			t.Errorf("expected BrandAlreadyExists, got %v", err)
+		}
+		if _, err := brandRepo.CreateBrandReference(ctx, "Galaxy S23", "Samsung"); !errors.Is(err, ports.PhoneBrandDoesNotExists) {
+			t.Errorf("expected PhoneBrandDoesNotExists, got %v", err)
+		}
+		ref, err := brandRepo.CreateBrandReference(ctx, "iPhone 15", "Apple")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		caseType, err := caseTypeRepo.CreateCaseType(ctx, "Silicone", "/icons/silicone.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := caseTypeRepo.CreateCaseTypeImage(ctx, caseType.ID, "/img/1.png", 1); err != nil {
+			t.Fatal(err)
+		}
+
+		price := *money.New(45000, money.COP)
+		if _, err := caseRepo.CreatePhoneCase(ctx, price, "", domain.PhoneCaseAvailable, ref.ID, 9999, 1); !errors.Is(err, ports.CaseTypeDoesNotExists) {
+			t.Errorf("expected CaseTypeDoesNotExists, got %v", err)
+		}
+		phoneCase, err := caseRepo.CreatePhoneCase(ctx, price, "/scaffold.png", domain.PhoneCaseAvailable, ref.ID, caseType.ID, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if phoneCase.Price.Amount() != 45000 || phoneCase.PhoneBrandReference.Name != "iPhone 15" {
+			t.Errorf("unexpected phone case %+v", phoneCase)
+		}
+
+		discount, err := caseRepo.CreateDiscount(ctx, "Black friday", 20, time.Now().Add(time.Hour), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := caseRepo.AttachDiscount(ctx, phoneCase.ID, discount.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		cases, total := caseRepo.ListPhoneCases(ctx, map[string]string{"inventory_status": "AVAILABLE"}, 10, 0)
+		if total != 1 || len(cases) != 1 {
+			t.Fatalf("expected 1 phone case, got %d (total %d)", len(cases), total)
+		}
+		if cases[0].Discount.ID != discount.ID || len(cases[0].CaseType.Images) != 1 {
+			t.Errorf("related rows were not loaded: %+v", cases[0])
+		}
+
+		if err := caseRepo.DeleteDiscount(ctx, discount.ID, ports.AnyVersion); err != nil {
+			t.Fatal(err)
+		}
+		if found, _ := caseRepo.GetPhoneCaseByID(ctx, phoneCase.ID); found.Discount.ID != 0 {
+			t.Error("deleted discount should not be shown on the phone case")
+		}
+
+		if err := caseRepo.DeletePhoneCase(ctx, phoneCase.ID, ports.AnyVersion); err != nil {
+			t.Fatal(err)
+		}
+		if err := caseRepo.DeletePhoneCase(ctx, phoneCase.ID, ports.AnyVersion); !errors.Is(err, ports.PhoneCaseDoesNotExists) {
+			t.Errorf("expected PhoneCaseDoesNotExists, got %v", err)
+		}
+	})
+}
+
+func TestSQLProductRepositories_Versions(t *testing.T) {
+	sqldbtest.Run(t, func(t *testing.T, dialect migrations.Dialect) {
+		ctx := context.Background()
+		db := sqldbtest.Open(t, dialect)
+		brandRepo, _ := sqlrepo.NewSQLPhoneBrandRepository(db)
+		caseTypeRepo, _ := sqlrepo.NewSQLCaseTypeRepository(db)
+		caseRepo, err := sqlrepo.NewSQLPhoneCaseRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		brandRepo.CreateBrand(ctx, "Apple")
+		ref, _ := brandRepo.CreateBrandReference(ctx, "iPhone 15", "Apple")
+		caseType, _ := caseTypeRepo.CreateCaseType(ctx, "Silicone", "")
+		price := *money.New(45000, money.COP)
+		phoneCase, err := caseRepo.CreatePhoneCase(ctx, price, "", domain.PhoneCaseAvailable, ref.ID, caseType.ID, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if phoneCase.Version != 1 {
+			t.Errorf("expected version 1, got %d", phoneCase.Version)
+		}
+
+		updated, err := caseRepo.UpdatePhoneCase(ctx, phoneCase.ID, phoneCase.Version, price, "", domain.PhoneCaseOutOfStock, ref.ID, caseType.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Version != 2 {
+			t.Errorf("expected version 2, got %d", updated.Version)
+		}
+		_, err = caseRepo.UpdatePhoneCase(ctx, phoneCase.ID, phoneCase.Version, price, "", domain.PhoneCaseAvailable, ref.ID, caseType.ID)
+		if !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+
+		discount, _ := caseRepo.CreateDiscount(ctx, "sale", 10, time.Now().Add(time.Hour), 1)
+		if err := caseRepo.DeleteDiscount(ctx, discount.ID, discount.Version+1); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if err := caseRepo.DeleteDiscount(ctx, 9999, 1); !errors.Is(err, ports.DiscountDoesNotExists) {
+			t.Errorf("expected DiscountDoesNotExists, got %v", err)
+		}
+		if _, err := caseTypeRepo.UpdateCaseType(ctx, caseType.ID, caseType.Version+1, "Leather"); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+
+		if err := caseRepo.DeletePhoneCase(ctx, phoneCase.ID, phoneCase.Version); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if err := caseRepo.DeletePhoneCase(ctx, phoneCase.ID, updated.Version); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestSQLRepositoryContract(t *testing.T) {
+	sqldbtest.Run(t, func(t *testing.T, dialect migrations.Dialect) {
+		portstest.Run(t, func(t *testing.T) portstest.Backend {
+			db := sqldbtest.Open(t, dialect)
+			cases, err := sqlrepo.NewSQLPhoneCaseRepository(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			brands, err := sqlrepo.NewSQLPhoneBrandRepository(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			caseTypes, err := sqlrepo.NewSQLCaseTypeRepository(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return portstest.Backend{PhoneCases: cases, Brands: brands, CaseTypes: caseTypes}
+		})
+	})
+}
@@ -0,0 +1,95 @@
+package tracedrepo
+
+import (
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
+)
+
+type TracedCaseTypeRepository struct {
+	repo ports.CaseTypeRepository
+}
+
+func NewTracedCaseTypeRepository(repo ports.CaseTypeRepository) *TracedCaseTypeRepository {
+	return &TracedCaseTypeRepository{repo: repo}
+}
+
+func (r *TracedCaseTypeRepository) ListCaseTypes(ctx context.Context) []domain.CaseType {
+	ctx, span := tracing.Start(ctx, "CaseTypeRepository.ListCaseTypes")
+	defer span.End()
+	return r.repo.ListCaseTypes(ctx)
+}
+
+func (r *TracedCaseTypeRepository) GetCaseTypeByID(ctx context.Context, ID domain.CaseTypeID) (domain.CaseType, bool) {
+	ctx, span := tracing.Start(ctx, "CaseTypeRepository.GetCaseTypeByID")
+	defer span.End()
+	return r.repo.GetCaseTypeByID(ctx, ID)
+}
+
+func (r *TracedCaseTypeRepository) UpdateCaseType(
+	ctx context.Context, ID domain.CaseTypeID, version int, name string,
+) (domain.CaseType, error) {
+	ctx, span := tracing.Start(ctx, "CaseTypeRepository.UpdateCaseType")
+	result, err := r.repo.UpdateCaseType(ctx, ID, version, name)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedCaseTypeRepository) CreateCaseType(
+	ctx context.Context, name string, iconPath string,
+) (domain.CaseType, error) {
+	ctx, span := tracing.Start(ctx, "CaseTypeRepository.CreateCaseType")
+	result, err := r.repo.CreateCaseType(ctx, name, iconPath)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedCaseTypeRepository) DeleteCaseType(ctx context.Context, ID domain.CaseTypeID, version int) error {
+	ctx, span := tracing.Start(ctx, "CaseTypeRepository.DeleteCaseType")
+	err := r.repo.DeleteCaseType(ctx, ID, version)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedCaseTypeRepository) ListCaseTypeImages(
+	ctx context.Context, typeID domain.CaseTypeID, includeDeleted bool,
+) []domain.CaseTypeImage {
+	ctx, span := tracing.Start(ctx, "CaseTypeRepository.ListCaseTypeImages")
+	defer span.End()
+	return r.repo.ListCaseTypeImages(ctx, typeID, includeDeleted)
+}
+
+func (r *TracedCaseTypeRepository) CreateCaseTypeImage(
+	ctx context.Context, typeID domain.CaseTypeID, path string, orderPriority int,
+) (domain.CaseTypeImage, error) {
+	ctx, span := tracing.Start(ctx, "CaseTypeRepository.CreateCaseTypeImage")
+	result, err := r.repo.CreateCaseTypeImage(ctx, typeID, path, orderPriority)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedCaseTypeRepository) UpdateCaseTypeImage(
+	ctx context.Context, ID domain.CaseTypeImageID, path string,
+) (domain.CaseTypeImage, error) {
+	ctx, span := tracing.Start(ctx, "CaseTypeRepository.UpdateCaseTypeImage")
+	result, err := r.repo.UpdateCaseTypeImage(ctx, ID, path)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedCaseTypeRepository) DeleteCaseTypeImage(ctx context.Context, ID domain.CaseTypeImageID) error {
+	ctx, span := tracing.Start(ctx, "CaseTypeRepository.DeleteCaseTypeImage")
+	err := r.repo.DeleteCaseTypeImage(ctx, ID)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedCaseTypeRepository) RestoreCaseTypeImage(
+	ctx context.Context, ID domain.CaseTypeImageID,
+) (domain.CaseTypeImage, error) {
+	ctx, span := tracing.Start(ctx, "CaseTypeRepository.RestoreCaseTypeImage")
+	result, err := r.repo.RestoreCaseTypeImage(ctx, ID)
+	tracing.End(span, err)
+	return result, err
+}
@@ -0,0 +1,70 @@
+package tracedrepo
+
+import (
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
+)
+
+type TracedPhoneBrandRepository struct {
+	repo ports.PhoneBrandRepository
+}
+
+func NewTracedPhoneBrandRepository(repo ports.PhoneBrandRepository) *TracedPhoneBrandRepository {
+	return &TracedPhoneBrandRepository{repo: repo}
+}
+
+func (r *TracedPhoneBrandRepository) ListAllBrands(ctx context.Context) []domain.PhoneBrand {
+	ctx, span := tracing.Start(ctx, "PhoneBrandRepository.ListAllBrands")
+	defer span.End()
+	return r.repo.ListAllBrands(ctx)
+}
+
+func (r *TracedPhoneBrandRepository) UpdateBrand(ctx context.Context, brand domain.PhoneBrand, renameTo string) error {
+	ctx, span := tracing.Start(ctx, "PhoneBrandRepository.UpdateBrand")
+	err := r.repo.UpdateBrand(ctx, brand, renameTo)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedPhoneBrandRepository) CreateBrand(ctx context.Context, brand domain.PhoneBrand) error {
+	ctx, span := tracing.Start(ctx, "PhoneBrandRepository.CreateBrand")
+	err := r.repo.CreateBrand(ctx, brand)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedPhoneBrandRepository) ListBrandReferences(
+	ctx context.Context, brand domain.PhoneBrand,
+) []domain.PhoneBrandReference {
+	ctx, span := tracing.Start(ctx, "PhoneBrandRepository.ListBrandReferences")
+	defer span.End()
+	return r.repo.ListBrandReferences(ctx, brand)
+}
+
+func (r *TracedPhoneBrandRepository) GetBrandReferenceByID(
+	ctx context.Context, ID domain.PhoneBrandReferenceID,
+) (domain.PhoneBrandReference, bool) {
+	ctx, span := tracing.Start(ctx, "PhoneBrandRepository.GetBrandReferenceByID")
+	defer span.End()
+	return r.repo.GetBrandReferenceByID(ctx, ID)
+}
+
+func (r *TracedPhoneBrandRepository) UpdateBrandReference(
+	ctx context.Context, ID domain.PhoneBrandReferenceID, name string,
+) (domain.PhoneBrandReference, error) {
+	ctx, span := tracing.Start(ctx, "PhoneBrandRepository.UpdateBrandReference")
+	result, err := r.repo.UpdateBrandReference(ctx, ID, name)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedPhoneBrandRepository) CreateBrandReference(
+	ctx context.Context, name string, brand domain.PhoneBrand,
+) (domain.PhoneBrandReference, error) {
+	ctx, span := tracing.Start(ctx, "PhoneBrandRepository.CreateBrandReference")
+	result, err := r.repo.CreateBrandReference(ctx, name, brand)
+	tracing.End(span, err)
+	return result, err
+}
@@ -0,0 +1,124 @@
+package tracedrepo
+
+import (
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/Rhymond/go-money"
+	"time"
+)
+
+type TracedPhoneCaseRepository struct {
+	repo ports.PhoneCaseRepository
+}
+
+func NewTracedPhoneCaseRepository(repo ports.PhoneCaseRepository) *TracedPhoneCaseRepository {
+	return &TracedPhoneCaseRepository{repo: repo}
+}
+
+func (r *TracedPhoneCaseRepository) ListPhoneCases(
+	ctx context.Context, filters map[string]string, limit int, offset int,
+) ([]domain.PhoneCase, int) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.ListPhoneCases")
+	defer span.End()
+	return r.repo.ListPhoneCases(ctx, filters, limit, offset)
+}
+
+func (r *TracedPhoneCaseRepository) GetPhoneCaseByID(
+	ctx context.Context, id domain.PhoneCaseID,
+) (domain.PhoneCase, bool) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.GetPhoneCaseByID")
+	defer span.End()
+	return r.repo.GetPhoneCaseByID(ctx, id)
+}
+
+func (r *TracedPhoneCaseRepository) CreatePhoneCase(
+	ctx context.Context, price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus, phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID, createdBy users.UserID,
+) (domain.PhoneCase, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.CreatePhoneCase")
+	result, err := r.repo.CreatePhoneCase(ctx, price, scaffoldImgPath, inventoryStatus, phoneBrandRefID, caseTypeID, createdBy)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedPhoneCaseRepository) UpdatePhoneCase(
+	ctx context.Context, ID domain.PhoneCaseID, version int, price money.Money, scaffoldImgPath string, inventoryStatus domain.InventoryStatus, phoneBrandRefID domain.PhoneBrandReferenceID, caseTypeID domain.CaseTypeID,
+) (domain.PhoneCase, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.UpdatePhoneCase")
+	result, err := r.repo.UpdatePhoneCase(ctx, ID, version, price, scaffoldImgPath, inventoryStatus, phoneBrandRefID, caseTypeID)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedPhoneCaseRepository) DeletePhoneCase(ctx context.Context, ID domain.PhoneCaseID, version int) error {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.DeletePhoneCase")
+	err := r.repo.DeletePhoneCase(ctx, ID, version)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedPhoneCaseRepository) RestorePhoneCase(
+	ctx context.Context, ID domain.PhoneCaseID, version int,
+) (domain.PhoneCase, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.RestorePhoneCase")
+	result, err := r.repo.RestorePhoneCase(ctx, ID, version)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedPhoneCaseRepository) AttachDiscount(
+	ctx context.Context, caseID domain.PhoneCaseID, discountID domain.DiscountID,
+) error {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.AttachDiscount")
+	err := r.repo.AttachDiscount(ctx, caseID, discountID)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedPhoneCaseRepository) ListAllDiscounts(ctx context.Context, includeDeleted bool) []domain.Discount {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.ListAllDiscounts")
+	defer span.End()
+	return r.repo.ListAllDiscounts(ctx, includeDeleted)
+}
+
+func (r *TracedPhoneCaseRepository) GetDiscountByID(ctx context.Context, ID domain.DiscountID) (domain.Discount, bool) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.GetDiscountByID")
+	defer span.End()
+	return r.repo.GetDiscountByID(ctx, ID)
+}
+
+func (r *TracedPhoneCaseRepository) CreateDiscount(
+	ctx context.Context, name string, rate int, validUntil time.Time, createdBy users.UserID,
+) (domain.Discount, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.CreateDiscount")
+	result, err := r.repo.CreateDiscount(ctx, name, rate, validUntil, createdBy)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedPhoneCaseRepository) UpdateDiscount(
+	ctx context.Context, ID domain.DiscountID, version int, rate int, validUntil time.Time,
+) (domain.Discount, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.UpdateDiscount")
+	result, err := r.repo.UpdateDiscount(ctx, ID, version, rate, validUntil)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedPhoneCaseRepository) DeleteDiscount(ctx context.Context, ID domain.DiscountID, version int) error {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.DeleteDiscount")
+	err := r.repo.DeleteDiscount(ctx, ID, version)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedPhoneCaseRepository) RestoreDiscount(
+	ctx context.Context, ID domain.DiscountID, version int,
+) (domain.Discount, error) {
+	ctx, span := tracing.Start(ctx, "PhoneCaseRepository.RestoreDiscount")
+	result, err := r.repo.RestoreDiscount(ctx, ID, version)
+	tracing.End(span, err)
+	return result, err
+}
@@ -0,0 +1,217 @@
+package snapshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const FileName = "snapshot.json"
+
+var Corrupted = errors.New("snapshot is corrupted")
+
+// Store is anything that can be written to and restored from a snapshot,
+// RLock must take the same lock the writes of the store take
+type Store interface {
+	RLock()
+	RUnlock()
+	// MarshalSnapshot is called with the read lock held
+	MarshalSnapshot() ([]byte, error)
+	// UnmarshalSnapshot must replace the whole content of the store
+	UnmarshalSnapshot(data []byte) error
+}
+
+// file is the content of the snapshot file, the checksum is the sha256 of the
+// compact json encoding of the stores
+type file struct {
+	SavedAt  time.Time                  `json:"saved_at"`
+	Checksum string                     `json:"checksum"`
+	Stores   map[string]json.RawMessage `json:"stores"`
+}
+
+// Snapshotter writes every registered store to a single file. Save holds the
+// read lock of every store while it marshals them, so the stores are always
+// restored from the same point in time. The locks are taken in the order the
+// stores were registered, stores that are locked together somewhere else, like
+// in a unit of work, must be registered in that same order
+type Snapshotter struct {
+	mu     sync.Mutex
+	dir    string
+	names  []string
+	stores map[string]Store
+}
+
+func New(dir string) *Snapshotter {
+	return &Snapshotter{dir: dir, stores: make(map[string]Store)}
+}
+
+func (s *Snapshotter) Path() string {
+	return filepath.Join(s.dir, FileName)
+}
+
+func (s *Snapshotter) Register(name string, store Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.stores[name]; !ok {
+		s.names = append(s.names, name)
+	}
+	s.stores[name] = store
+}
+
+// Load restores the registered stores from the snapshot file, it returns false
+// when there is no snapshot yet. Stores missing from the file keep their content
+func (s *Snapshotter) Load() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := os.ReadFile(s.Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var f file
+	if err := json.Unmarshal(content, &f); err != nil {
+		return false, fmt.Errorf("%w: %s: %v", Corrupted, s.Path(), err)
+	}
+	sum, err := checksum(f.Stores)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s: %v", Corrupted, s.Path(), err)
+	}
+	if sum != f.Checksum {
+		return false, fmt.Errorf("%w: %s: checksum does not match its content", Corrupted, s.Path())
+	}
+
+	for name, store := range s.stores {
+		data, ok := f.Stores[name]
+		if !ok {
+			continue
+		}
+		if err := store.UnmarshalSnapshot(data); err != nil {
+			return false, fmt.Errorf("error restoring %s: %w", name, err)
+		}
+	}
+	return true, nil
+}
+
+// Save writes the registered stores to a temporary file and renames it over the
+// snapshot, so a crash never leaves a partially written snapshot behind
+func (s *Snapshotter) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.marshal()
+	if err != nil {
+		return err
+	}
+	sum, err := checksum(f.Stores)
+	if err != nil {
+		return err
+	}
+	f.Checksum = sum
+
+	content, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(s.dir, s.Path(), content)
+}
+
+// marshal encodes every store while all of them are locked, the file is
+// written after the locks are released
+func (s *Snapshotter) marshal() (file, error) {
+	for _, name := range s.names {
+		store := s.stores[name]
+		store.RLock()
+		defer store.RUnlock()
+	}
+
+	f := file{SavedAt: time.Now().UTC(), Stores: make(map[string]json.RawMessage, len(s.stores))}
+	for _, name := range s.names {
+		data, err := s.stores[name].MarshalSnapshot()
+		if err != nil {
+			return file{}, fmt.Errorf("error saving %s: %w", name, err)
+		}
+		f.Stores[name] = data
+	}
+	return f, nil
+}
+
+// Run saves the stores every interval and once more when the context is done,
+// it returns after that last save
+func (s *Snapshotter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Save(); err != nil {
+				slog.Error("error saving snapshot", "error", err)
+			}
+		case <-ctx.Done():
+			if err := s.Save(); err != nil {
+				slog.Error("error saving snapshot", "error", err)
+				return
+			}
+			slog.Info("snapshot saved", "path", s.Path())
+			return
+		}
+	}
+}
+
+// checksum does not depend on the indentation of the file, the stores are
+// encoded compact and with sorted keys
+func checksum(stores map[string]json.RawMessage) (string, error) {
+	data, err := json.Marshal(stores)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func writeAtomic(dir string, path string, content []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+FileName+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// the rename is only durable once the directory is synced
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,224 @@
+package snapshot_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/products/domain"
+	productsrepo "github.com/CrissAlvarezH/fundart-api/internal/products/infrastructure/memoryrepo"
+	"github.com/CrissAlvarezH/fundart-api/internal/snapshot"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/memoryrepo"
+	"github.com/Rhymond/go-money"
+)
+
+type memoryBackend struct {
+	users     *memoryrepo.MemoryUserRepository
+	addresses *memoryrepo.MemoryAddressRepository
+	audit     *memoryrepo.MemoryAuditLogRepository
+	store     *productsrepo.MemoryStore
+}
+
+func newMemoryBackend(dir string) (memoryBackend, *snapshot.Snapshotter) {
+	b := memoryBackend{
+		users:     memoryrepo.NewMemoryUserRepository(nil),
+		addresses: memoryrepo.NewMemoryAddressRepository(nil),
+		audit:     memoryrepo.NewMemoryAuditLogRepository(nil),
+		store:     productsrepo.NewMemoryStore(nil, nil, nil, nil, nil),
+	}
+	s := snapshot.New(dir)
+	s.Register("users", b.users)
+	s.Register("addresses", b.addresses)
+	s.Register("audit", b.audit)
+	s.Register("products", b.store)
+	return b, s
+}
+
+func TestSnapshotter_SaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	b, s := newMemoryBackend(dir)
+
+	user, _ := b.users.Add(ctx, "Cristian", "cristian@email.com", "123_encrypt", "320684398", true, nil)
+	b.addresses.Add(ctx, user.ID, "Cordoba", "Monteria", "Cll 1", "320684398", "Cristian")
+	removed, _ := b.addresses.Add(ctx, user.ID, "Cordoba", "Monteria", "Cll 2", "320684398", "Cristian")
+	b.addresses.Delete(ctx, user.ID, removed.ID, ports.AnyVersion)
+	b.audit.Append(ctx, users.AuditEvent{Type: "user.login", ActorID: user.ID, Details: map[string]string{"ip": "127.0.0.1"}})
+
+	brands := productsrepo.NewMemoryPhoneBrandRepository(b.store)
+	caseTypes := productsrepo.NewMemoryCaseTypeRepository(b.store)
+	cases := productsrepo.NewMemoryPhoneCaseRepository(b.store)
+	brands.CreateBrand(ctx, "Apple")
+	ref, _ := brands.CreateBrandReference(ctx, "iPhone 15", "Apple")
+	caseType, _ := caseTypes.CreateCaseType(ctx, "Silicone", "/icon.png")
+	caseTypes.CreateCaseTypeImage(ctx, caseType.ID, "/silicone.png", 1)
+	phoneCase, _ := cases.CreatePhoneCase(
+		ctx, *money.New(45000, money.COP), "/scaffold.png", domain.PhoneCaseAvailable, ref.ID, caseType.ID, user.ID,
+	)
+
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, rs := newMemoryBackend(dir)
+	loaded, err := rs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded {
+		t.Fatal("expected the snapshot to be loaded")
+	}
+
+	if u, ok := restored.users.GetByEmail(ctx, "cristian@email.com"); !ok || u.ID != user.ID {
+		t.Errorf("user not restored, got %+v", u)
+	}
+	if password, _ := restored.users.GetPassword(ctx, user.ID); password != "123_encrypt" {
+		t.Errorf("expected password to be restored, got %q", password)
+	}
+	if l := restored.addresses.List(ctx, user.ID); len(l) != 1 || l[0].Address != "Cll 1" {
+		t.Errorf("unexpected addresses %+v", l)
+	}
+	if events := restored.audit.All(); len(events) != 1 || events[0].Details["ip"] != "127.0.0.1" {
+		t.Errorf("unexpected audit events %+v", events)
+	}
+
+	restoredCases := productsrepo.NewMemoryPhoneCaseRepository(restored.store)
+	got, ok := restoredCases.GetPhoneCaseByID(ctx, phoneCase.ID)
+	if !ok {
+		t.Fatal("phone case not restored")
+	}
+	if got.PhoneBrandReference.Name != "iPhone 15" || got.CaseType.Name != "Silicone" {
+		t.Errorf("phone case relations not restored, got %+v", got)
+	}
+	if len(got.CaseType.Images) != 1 || got.CaseType.Images[0].Path != "/silicone.png" {
+		t.Errorf("case type images not restored, got %+v", got.CaseType.Images)
+	}
+
+	// IDs of deleted rows are not handed out again
+	added, _ := restored.addresses.Add(ctx, user.ID, "Cordoba", "Monteria", "Cll 3", "320684398", "Cristian")
+	if added.ID <= removed.ID {
+		t.Errorf("expected a new address ID after %d, got %d", removed.ID, added.ID)
+	}
+}
+
+// lockedStore checks the other stores are locked while it's marshalled
+type lockedStore struct {
+	sync.RWMutex
+	others   []*lockedStore
+	unlocked bool
+}
+
+func (s *lockedStore) MarshalSnapshot() ([]byte, error) {
+	for _, other := range s.others {
+		if other.TryLock() {
+			other.Unlock()
+			s.unlocked = true
+		}
+	}
+	return []byte("{}"), nil
+}
+
+func (s *lockedStore) UnmarshalSnapshot(data []byte) error {
+	return nil
+}
+
+// the stores changed together, like the ones of a unit of work, must be
+// marshalled at the same point in time
+func TestSnapshotter_SaveLocksEveryStore(t *testing.T) {
+	first, second := &lockedStore{}, &lockedStore{}
+	first.others, second.others = []*lockedStore{second}, []*lockedStore{first}
+
+	s := snapshot.New(t.TempDir())
+	s.Register("first", first)
+	s.Register("second", second)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if first.unlocked || second.unlocked {
+		t.Error("expected every store to be locked while the snapshot is marshalled")
+	}
+	if !first.TryLock() || !second.TryLock() {
+		t.Error("expected the stores to be unlocked after the save")
+	}
+}
+
+func TestSnapshotter_LoadWithoutSnapshot(t *testing.T) {
+	_, s := newMemoryBackend(t.TempDir())
+
+	loaded, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded {
+		t.Error("expected nothing to be loaded")
+	}
+}
+
+func TestSnapshotter_DetectsCorruption(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	b, s := newMemoryBackend(dir)
+	b.users.Add(ctx, "Cristian", "cristian@email.com", "123_encrypt", "320684398", true, nil)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(s.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"tampered content": strings.Replace(string(content), "Cristian", "Mallory", 1),
+		"truncated file":   string(content[:len(content)/2]),
+	}
+	for name, corrupted := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := os.WriteFile(s.Path(), []byte(corrupted), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			restored, rs := newMemoryBackend(dir)
+			if _, err := rs.Load(); !errors.Is(err, snapshot.Corrupted) {
+				t.Errorf("expected %v, got %v", snapshot.Corrupted, err)
+			}
+			if len(restored.users.All()) != 0 {
+				t.Error("corrupted snapshot must not be restored")
+			}
+		})
+	}
+}
+
+func TestSnapshotter_RunSavesOnShutdown(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	b, s := newMemoryBackend(dir)
+	b.users.Add(ctx, "Cristian", "cristian@email.com", "123_encrypt", "320684398", true, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, time.Hour)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != snapshot.FileName {
+		t.Errorf("expected only %s in the data directory, got %v", snapshot.FileName, entries)
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshot.FileName)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,96 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/CrissAlvarezH/fundart-api/internal/migrations"
+	"time"
+)
+
+// DB is a database with the dialect it speaks, the repositories write their
+// queries once with $n placeholders and ask the dialect for what differs
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Open opens the database of the dialect, dsn is a connection string for
+// postgres and the path of the database file for sqlite
+func Open(dialect migrations.Dialect, dsn string) (*DB, error) {
+	var d Dialect
+	switch dialect {
+	case migrations.Postgres:
+		d = postgresDialect{}
+	case migrations.SQLite:
+		d = sqliteDialect{}
+	default:
+		return nil, fmt.Errorf("unknown database backend %q", dialect)
+	}
+
+	driver, dsn := d.driver(dsn)
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{DB: db, Dialect: d}, nil
+}
+
+func PrepareStatements(db *sql.DB, queries map[**sql.Stmt]string) error {
+	for stmt, query := range queries {
+		prepared, err := db.Prepare(query)
+		if err != nil {
+			return err
+		}
+		*stmt = prepared
+	}
+	return nil
+}
+
+// BindStatements replaces every statement with its version for tx
+func BindStatements(tx *sql.Tx, stmts map[**sql.Stmt]string) {
+	for stmt := range stmts {
+		*stmt = tx.Stmt(*stmt)
+	}
+}
+
+// InTx runs fn in its own transaction, or in tx when the repository is bound to
+// a unit of work, then a savepoint undoes the partial change if fn fails
+func InTx(ctx context.Context, db *sql.DB, tx *sql.Tx, fn func(tx *sql.Tx) error) error {
+	if tx == nil {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT repository_change"); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT repository_change")
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT repository_change")
+	return err
+}
+
+type RowScanner interface {
+	Scan(dest ...any) error
+}
+
+// NullTime stores the zero time as NULL, times are written in UTC so sqlite,
+// which stores them as text, can compare them
+func NullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
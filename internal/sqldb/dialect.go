@@ -0,0 +1,131 @@
+package sqldb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CrissAlvarezH/fundart-api/internal/migrations"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect is what differs between the databases, everything else is written
+// in the sql both of them understand
+type Dialect interface {
+	Name() migrations.Dialect
+	// List is the argument of a list column or of a condition of InList
+	List(values any) any
+	// ScanList is the scan destination of a list column of strings
+	ScanList(dest *[]string) any
+	// InList is the condition of column being one of the values in the list
+	// bound to param
+	InList(column string, param string) string
+	IsUniqueViolation(err error) bool
+	IsForeignKeyViolation(err error) bool
+
+	driver(dsn string) (string, string)
+}
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// types maps postgres arrays to go slices, database/sql doesn't support them
+var types = pgtype.NewMap()
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() migrations.Dialect {
+	return migrations.Postgres
+}
+
+func (postgresDialect) List(values any) any {
+	return values
+}
+
+func (postgresDialect) ScanList(dest *[]string) any {
+	return types.SQLScanner(dest)
+}
+
+func (postgresDialect) InList(column string, param string) string {
+	return fmt.Sprintf("%s = ANY(%s)", column, param)
+}
+
+func (postgresDialect) IsUniqueViolation(err error) bool {
+	return isPgError(err, uniqueViolation)
+}
+
+func (postgresDialect) IsForeignKeyViolation(err error) bool {
+	return isPgError(err, foreignKeyViolation)
+}
+
+func (postgresDialect) driver(dsn string) (string, string) {
+	return "pgx", dsn
+}
+
+func isPgError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+// sqlite has no array type, lists are stored as json arrays
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() migrations.Dialect {
+	return migrations.SQLite
+}
+
+func (sqliteDialect) List(values any) any {
+	content, _ := json.Marshal(values)
+	if string(content) == "null" {
+		return "[]"
+	}
+	return string(content)
+}
+
+func (sqliteDialect) ScanList(dest *[]string) any {
+	return jsonList{dest}
+}
+
+func (sqliteDialect) InList(column string, param string) string {
+	return fmt.Sprintf("%s IN (SELECT value FROM json_each(%s))", column, param)
+}
+
+func (sqliteDialect) IsUniqueViolation(err error) bool {
+	return isSQLiteError(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE) ||
+		isSQLiteError(err, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+}
+
+func (sqliteDialect) IsForeignKeyViolation(err error) bool {
+	return isSQLiteError(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY)
+}
+
+// the database is opened in WAL mode, write transactions take the lock on
+// begin so concurrent requests wait instead of failing
+func (sqliteDialect) driver(path string) (string, string) {
+	return "sqlite", "file:" + path +
+		"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+}
+
+func isSQLiteError(err error, code int) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == code
+}
+
+type jsonList struct {
+	dest *[]string
+}
+
+func (l jsonList) Scan(src any) error {
+	switch content := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(content), l.dest)
+	case []byte:
+		return json.Unmarshal(content, l.dest)
+	}
+	return fmt.Errorf("cannot scan %T into a list", src)
+}
@@ -0,0 +1,58 @@
+package sqldbtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/migrations"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb"
+)
+
+const dropTables = `DROP TABLE IF EXISTS
+	phone_cases, discounts, case_type_images, case_types, phone_brand_references, phone_brands,
+	audit_events, addresses, users, schema_migrations CASCADE`
+
+// Run runs test once per dialect, the repositories are the same for every
+// database so they are tested on all of them
+func Run(t *testing.T, test func(t *testing.T, dialect migrations.Dialect)) {
+	for _, dialect := range migrations.Dialects {
+		t.Run(string(dialect), func(t *testing.T) {
+			test(t, dialect)
+		})
+	}
+}
+
+// Open returns an empty database with every migration applied, postgres uses
+// the database in POSTGRES_TEST_DSN and is skipped when it isn't set, except on
+// CI where it must be tested. The tests of every package share that database,
+// run them with go test -p 1
+func Open(t *testing.T, dialect migrations.Dialect) *sqldb.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	if dialect == migrations.Postgres {
+		dsn = os.Getenv("POSTGRES_TEST_DSN")
+		if dsn == "" && os.Getenv("CI") != "" {
+			t.Fatal("POSTGRES_TEST_DSN must be set on CI")
+		}
+		if dsn == "" {
+			t.Skip("POSTGRES_TEST_DSN not set")
+		}
+	}
+
+	db, err := sqldb.Open(dialect, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if dialect == migrations.Postgres {
+		if _, err := db.Exec(dropTables); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := migrations.Apply(db.DB, dialect); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
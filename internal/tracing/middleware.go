@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"fmt"
+	"github.com/CrissAlvarezH/fundart-api/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+const TraceIDHeader = "X-Trace-ID"
+
+// Middleware starts a server span for every request, continuing the trace of
+// the traceparent header when the caller sends one. It must run after
+// common.RequestID, the trace and span ids are added to the logger of the
+// request context and the trace id is sent back in the X-Trace-ID header, so
+// an error response can be found in the traces
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		name := c.Request.Method + " " + route
+		if route == "" {
+			name = c.Request.Method
+		}
+		ctx, span := Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(c.Request.URL.Path),
+			semconv.ClientAddress(c.ClientIP()),
+		))
+		defer span.End()
+
+		spanCtx := span.SpanContext()
+		if id := c.GetString("request_id"); id != "" {
+			span.SetAttributes(attribute.String("request_id", id))
+		}
+		ctx = logging.With(ctx, "trace_id", spanCtx.TraceID().String(), "span_id", spanCtx.SpanID().String())
+		c.Header(TraceIDHeader, spanCtx.TraceID().String())
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("status %d", status))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/common"
+	"github.com/CrissAlvarezH/fundart-api/internal/logging"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID  = "00f067aa0ba902b7"
+)
+
+// record installs a tracer provider that keeps the ended spans in memory
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func serve(traceparent string) (*httptest.ResponseRecorder, string) {
+	var out bytes.Buffer
+	router := gin.New()
+	router.Use(common.RequestID(logging.New(&out, slog.LevelInfo)), tracing.Middleware())
+	router.GET("/cases/:id", func(c *gin.Context) {
+		ctx, span := tracing.Start(c.Request.Context(), "PhoneCaseService.GetPhoneCaseByID")
+		logging.FromContext(ctx).Info("from the service")
+		tracing.End(span, errors.New("database is down"))
+		c.Status(http.StatusInternalServerError)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/cases/7", nil)
+	if traceparent != "" {
+		req.Header.Set("traceparent", traceparent)
+	}
+	router.ServeHTTP(w, req)
+	return w, out.String()
+}
+
+func TestMiddleware_ContinuesTheCallerTrace(t *testing.T) {
+	recorder := record(t)
+	w, logs := serve("00-" + parentTraceID + "-" + parentSpanID + "-01")
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected the service and the request spans, got %d", len(spans))
+	}
+	service, request := spans[0], spans[1]
+
+	if request.Name() != "GET /cases/:id" {
+		t.Errorf("unexpected request span name %q", request.Name())
+	}
+	if request.SpanContext().TraceID().String() != parentTraceID || request.Parent().SpanID().String() != parentSpanID {
+		t.Errorf("request span is not a child of the traceparent, parent %v", request.Parent())
+	}
+	if request.Status().Code != codes.Error {
+		t.Error("a 5xx response must mark the request span as failed")
+	}
+	if service.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Error("service span is not a child of the request span")
+	}
+	if service.Status().Code != codes.Error || len(service.Events()) == 0 {
+		t.Error("the error of the service span was not recorded")
+	}
+
+	if got := w.Header().Get(tracing.TraceIDHeader); got != parentTraceID {
+		t.Errorf("expected the trace id %s in the response, got %q", parentTraceID, got)
+	}
+	var entry map[string]any
+	json.Unmarshal([]byte(strings.TrimSpace(logs)), &entry)
+	if entry["trace_id"] != parentTraceID || entry["span_id"] != request.SpanContext().SpanID().String() {
+		t.Errorf("trace ids missing from the log %v", entry)
+	}
+}
+
+func TestMiddleware_StartsATrace(t *testing.T) {
+	recorder := record(t)
+	w, _ := serve("")
+
+	spans := recorder.Ended()
+	request := spans[len(spans)-1]
+	if request.Parent().IsValid() {
+		t.Errorf("expected a root span, got parent %v", request.Parent())
+	}
+	if got := w.Header().Get(tracing.TraceIDHeader); got == "" || got != request.SpanContext().TraceID().String() {
+		t.Errorf("expected the new trace id in the response, got %q", got)
+	}
+}
@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
+
+// AuditLogRepository is append-only, events can't be updated or deleted.
+// List supports the filters 'type', 'actor_id', 'target_id', 'from' and 'to'
+// (RFC3339 dates), the events are sorted from newest to oldest
+type AuditLogRepository interface {
+	Append(ctx context.Context, event users.AuditEvent) (users.AuditEvent, error)
+	List(ctx context.Context, filters map[string]string, limit int, offset int) ([]users.AuditEvent, int)
+}
@@ -1,12 +1,18 @@
 package ports
 
 import (
-	"errors"
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"time"
 )
 
 var (
-	InvalidToken = errors.New("invalid token")
+	InvalidToken                = apperror.New(apperror.Unauthorized, "invalid_token", "invalid token")
+	ImpersonationNotAllowed     = apperror.New(apperror.Forbidden, "impersonation_not_allowed", "impersonation not allowed")
+	ForbiddenWhileImpersonating = apperror.New(apperror.Forbidden, "forbidden_while_impersonating", "action not allowed while impersonating a user")
+	PasswordTooLong             = apperror.New(apperror.Invalid, "password_too_long", "password is too long")
+	ScopesChangeNotAllowed      = apperror.New(apperror.Forbidden, "scopes_change_not_allowed", "only admins can change the scopes of a user")
 )
 
 type Token struct {
@@ -15,11 +21,15 @@ type Token struct {
 }
 
 type PasswordManager interface {
+	// Encrypt fails with PasswordTooLong when the password can't be hashed whole
 	Encrypt(rawPassword string) (string, error)
 	Verify(rawPassword string, encryptedPassword string) (bool, error)
 }
 
 type JWTManager interface {
 	Create(user users.User) (Token, error)
-	Verify(accessToken string) (users.User, error)
+	// CreateImpersonation creates a short-lived access token of user with an 'act' claim
+	// that identifies the actor, Verify returns the user with its ImpersonatorID set
+	CreateImpersonation(user users.User, actor users.User, expiresIn time.Duration) (Token, error)
+	Verify(ctx context.Context, accessToken string) (users.User, error)
 }
@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
+
+var (
+	InvalidAvatarType = apperror.New(apperror.UnsupportedMedia, "invalid_avatar_type", "avatar must be a jpeg or png image")
+	AvatarTooLarge    = apperror.New(apperror.TooLarge, "avatar_too_large", "avatar exceeds the max size allowed")
+)
+
+type AvatarStorage interface {
+	Save(ID users.UserID, content []byte) (string, error)
+	Delete(ID users.UserID) error
+}
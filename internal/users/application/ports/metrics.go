@@ -0,0 +1,29 @@
+package ports
+
+type LoginOutcome string
+
+const (
+	LoginSucceeded       LoginOutcome = "success"
+	LoginUnknownEmail    LoginOutcome = "unknown_email"
+	LoginInvalidPassword LoginOutcome = "invalid_password"
+	LoginError           LoginOutcome = "error"
+)
+
+var LoginOutcomes = []LoginOutcome{LoginSucceeded, LoginUnknownEmail, LoginInvalidPassword, LoginError}
+
+type VerificationCodePurpose string
+
+const (
+	VerifyAccountCode   VerificationCodePurpose = "verify_account"
+	RecoverPasswordCode VerificationCodePurpose = "recover_password"
+)
+
+var VerificationCodePurposes = []VerificationCodePurpose{VerifyAccountCode, RecoverPasswordCode}
+
+// Metrics counts the business events of the users, the implementations must
+// be safe for concurrent use
+type Metrics interface {
+	UserRegistered()
+	LoginAttempted(outcome LoginOutcome)
+	VerificationCodeSent(purpose VerificationCodePurpose)
+}
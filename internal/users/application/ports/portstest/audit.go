@@ -0,0 +1,134 @@
+package portstest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
+
+func eventIDs(events []users.AuditEvent) []users.AuditEventID {
+	IDs := make([]users.AuditEventID, 0, len(events))
+	for _, e := range events {
+		IDs = append(IDs, e.ID)
+	}
+	return IDs
+}
+
+func AuditLogRepository(t *testing.T, newBackend NewBackend) {
+	ctx := context.Background()
+	t.Run("append", func(t *testing.T) {
+		repo := newBackend(t).Audit
+
+		before := time.Now().Add(-time.Second)
+		first, err := repo.Append(ctx, users.AuditEvent{
+			Type: users.AuditLoginSucceeded, ActorID: 1, IP: "127.0.0.1", Details: map[string]string{"via": "api"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.ID == 0 || first.CreatedAt.Before(before) {
+			t.Errorf("expected an ID and the current time, got %+v", first)
+		}
+
+		createdAt := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+		second, err := repo.Append(ctx, users.AuditEvent{Type: users.AuditLoginFailed, CreatedAt: createdAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second.ID <= first.ID {
+			t.Errorf("expected an ID after %d, got %d", first.ID, second.ID)
+		}
+		if !second.CreatedAt.Equal(createdAt) {
+			t.Errorf("expected the given date %v, got %v", createdAt, second.CreatedAt)
+		}
+
+		// events are listed from newest to oldest
+		events, total := repo.List(ctx, nil, 10, 0)
+		if total != 2 {
+			t.Fatalf("expected 2 events, got %d", total)
+		}
+		assertIDs(t, []users.AuditEventID{second.ID, first.ID}, eventIDs(events))
+		if events[1].IP != "127.0.0.1" || events[1].Details["via"] != "api" {
+			t.Errorf("unexpected event %+v", events[1])
+		}
+	})
+
+	t.Run("list pagination", func(t *testing.T) {
+		repo := newBackend(t).Audit
+		var newestFirst []users.AuditEventID
+		for i := 0; i < 5; i++ {
+			e, err := repo.Append(ctx, users.AuditEvent{Type: users.AuditLoginSucceeded, ActorID: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			newestFirst = append([]users.AuditEventID{e.ID}, newestFirst...)
+		}
+
+		cases := []struct {
+			name          string
+			limit, offset int
+			expected      []users.AuditEventID
+		}{
+			{"first page", 2, 0, newestFirst[0:2]},
+			{"last partial page", 6, 4, newestFirst[4:]},
+			{"page after the end", 10, 5, nil},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				events, total := repo.List(ctx, map[string]string{}, c.limit, c.offset)
+				if total != len(newestFirst) {
+					t.Errorf("expected total %d, got %d", len(newestFirst), total)
+				}
+				assertIDs(t, c.expected, eventIDs(events))
+			})
+		}
+	})
+
+	t.Run("list filters", func(t *testing.T) {
+		repo := newBackend(t).Audit
+		add := func(event users.AuditEvent) users.AuditEventID {
+			t.Helper()
+			e, err := repo.Append(ctx, event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return e.ID
+		}
+		old := add(users.AuditEvent{
+			Type: users.AuditLoginSucceeded, ActorID: 1, CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		changed := add(users.AuditEvent{
+			Type: users.AuditScopesChanged, ActorID: 1, TargetID: 2, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		login := add(users.AuditEvent{
+			Type: users.AuditLoginSucceeded, ActorID: 2, CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+
+		cases := []struct {
+			name     string
+			filters  map[string]string
+			expected []users.AuditEventID
+		}{
+			{"type", map[string]string{"type": string(users.AuditLoginSucceeded)}, []users.AuditEventID{login, old}},
+			{"actor", map[string]string{"actor_id": "1"}, []users.AuditEventID{changed, old}},
+			{"target", map[string]string{"target_id": "2"}, []users.AuditEventID{changed}},
+			{"from is inclusive", map[string]string{"from": "2024-01-01T00:00:00Z"}, []users.AuditEventID{login, changed}},
+			{"to is inclusive", map[string]string{"to": "2024-01-01T00:00:00Z"}, []users.AuditEventID{changed, old}},
+			{"every filter must match", map[string]string{"actor_id": "1", "type": string(users.AuditLoginSucceeded)}, []users.AuditEventID{old}},
+			{"empty filters are ignored", map[string]string{"type": "", "actor_id": ""}, []users.AuditEventID{login, changed, old}},
+			{"invalid filters are ignored", map[string]string{"actor_id": "one", "from": "yesterday"}, []users.AuditEventID{login, changed, old}},
+			{"no match", map[string]string{"actor_id": "3"}, nil},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				events, total := repo.List(ctx, c.filters, 10, 0)
+				if total != len(c.expected) {
+					t.Errorf("expected total %d, got %d", len(c.expected), total)
+				}
+				assertIDs(t, c.expected, eventIDs(events))
+			})
+		}
+	})
+}
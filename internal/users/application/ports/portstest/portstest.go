@@ -0,0 +1,27 @@
+package portstest
+
+import (
+	"testing"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+)
+
+// Backend are the ports implemented by a storage backend, the unit of work must
+// change the same data the repositories read
+type Backend struct {
+	ports.Repositories
+	UnitOfWork ports.UnitOfWork
+}
+
+// NewBackend returns an empty backend, it's called once per test so the tests
+// don't see the data of each other
+type NewBackend func(t *testing.T) Backend
+
+// Run runs the contract of every port against the backend, each backend must
+// pass the same tests
+func Run(t *testing.T, newBackend NewBackend) {
+	t.Run("UserRepository", func(t *testing.T) { UserRepository(t, newBackend) })
+	t.Run("AddressRepository", func(t *testing.T) { AddressRepository(t, newBackend) })
+	t.Run("AuditLogRepository", func(t *testing.T) { AuditLogRepository(t, newBackend) })
+	t.Run("UnitOfWork", func(t *testing.T) { UnitOfWork(t, newBackend) })
+}
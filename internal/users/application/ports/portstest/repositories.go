@@ -0,0 +1,530 @@
+package portstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
+
+const missingID = 9999
+
+func addUser(ctx context.Context, t *testing.T, repo ports.UserRepository, name string, email string, phone string) users.User {
+	t.Helper()
+	user, err := repo.Add(ctx, name, email, "123_encrypt", phone, true, []users.ScopeName{users.USERS_READ})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func userIDs(list []users.User) []users.UserID {
+	IDs := make([]users.UserID, 0, len(list))
+	for _, u := range list {
+		IDs = append(IDs, u.ID)
+	}
+	return IDs
+}
+
+func UserRepository(t *testing.T, newBackend NewBackend) {
+	ctx := context.Background()
+	t.Run("add and get", func(t *testing.T) {
+		repo := newBackend(t).Users
+		user := addUser(ctx, t, repo, "Cristian", "Cristian@email.com", "320684398")
+		if user.ID == 0 || user.Version != 1 || !user.IsActive {
+			t.Errorf("unexpected added user %+v", user)
+		}
+		if user.Preferences.Language != users.LanguageSpanish {
+			t.Errorf("expected default language, got %q", user.Preferences.Language)
+		}
+
+		found, ok := repo.GetByID(ctx, user.ID)
+		if !ok || found.Name != "Cristian" || found.Email != "Cristian@email.com" || found.Phone != "320684398" {
+			t.Errorf("unexpected user by ID %+v", found)
+		}
+		if len(found.Scopes) != 1 || found.Scopes[0] != users.USERS_READ {
+			t.Errorf("unexpected scopes %v", found.Scopes)
+		}
+		if found, ok := repo.GetByEmail(ctx, "cristian@EMAIL.com"); !ok || found.ID != user.ID {
+			t.Error("email lookup must be case insensitive")
+		}
+		if password, ok := repo.GetPassword(ctx, user.ID); !ok || password != "123_encrypt" {
+			t.Errorf("unexpected password %q", password)
+		}
+	})
+
+	t.Run("duplicated email", func(t *testing.T) {
+		repo := newBackend(t).Users
+		user := addUser(ctx, t, repo, "Cristian", "cristian@email.com", "1")
+		other := addUser(ctx, t, repo, "Juan", "juan@email.com", "2")
+
+		if _, err := repo.Add(ctx, "Other", "CRISTIAN@email.com", "pass", "3", true, nil); !errors.Is(err, ports.EmailAlreadyExists) {
+			t.Errorf("expected EmailAlreadyExists, got %v", err)
+		}
+		_, err := repo.Update(ctx, other.ID, ports.AnyVersion, "Juan", "Cristian@Email.com", "2", nil)
+		if !errors.Is(err, ports.EmailAlreadyExists) {
+			t.Errorf("expected EmailAlreadyExists, got %v", err)
+		}
+		// a user can keep its own email
+		if _, err := repo.Update(ctx, user.ID, ports.AnyVersion, "Cristian", "CRISTIAN@email.com", "1", nil); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := newBackend(t).Users
+
+		if _, ok := repo.GetByID(ctx, missingID); ok {
+			t.Error("GetByID of a missing user")
+		}
+		if _, ok := repo.GetByEmail(ctx, "missing@email.com"); ok {
+			t.Error("GetByEmail of a missing user")
+		}
+		if _, ok := repo.GetPendingByEmail(ctx, "missing@email.com"); ok {
+			t.Error("GetPendingByEmail of a missing user")
+		}
+		if _, ok := repo.GetPassword(ctx, missingID); ok {
+			t.Error("GetPassword of a missing user")
+		}
+		if repo.Activate(ctx, missingID) {
+			t.Error("Activate of a missing user")
+		}
+
+		_, err := repo.Update(ctx, missingID, ports.AnyVersion, "x", "x@email.com", "1", nil)
+		errs := map[string]error{
+			"Update":                      err,
+			"ChangePassword":              repo.ChangePassword(ctx, missingID, "pass"),
+			"UpdateAvatar":                repo.UpdateAvatar(ctx, missingID, "/avatar.png"),
+			"UpdatePreferences":           repo.UpdatePreferences(ctx, missingID, users.DefaultPreferences()),
+			"Deactivate":                  repo.Deactivate(ctx, missingID, ports.AnyVersion),
+			"SaveAccountVerificationCode": repo.SaveAccountVerificationCode(ctx, missingID, "1234"),
+			"SaveRecoveryPasswordCode":    repo.SaveRecoveryPasswordCode(ctx, missingID, "1234"),
+		}
+		for name, err := range errs {
+			if !errors.Is(err, ports.UserDoesNotExists) {
+				t.Errorf("%s: expected UserDoesNotExists, got %v", name, err)
+			}
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		repo := newBackend(t).Users
+		user := addUser(ctx, t, repo, "Cristian", "cristian@email.com", "1")
+
+		updated, err := repo.Update(
+			ctx, user.ID, user.Version, "Cristian A", "cristian.a@email.com", "2", []users.ScopeName{users.USERS_WRITE},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Version != user.Version+1 {
+			t.Errorf("expected version %d, got %d", user.Version+1, updated.Version)
+		}
+		found, _ := repo.GetByID(ctx, user.ID)
+		if found.Name != "Cristian A" || found.Email != "cristian.a@email.com" || found.Phone != "2" {
+			t.Errorf("unexpected updated user %+v", found)
+		}
+		if len(found.Scopes) != 1 || found.Scopes[0] != users.USERS_WRITE {
+			t.Errorf("unexpected scopes %v", found.Scopes)
+		}
+		if _, ok := repo.GetByEmail(ctx, "cristian@email.com"); ok {
+			t.Error("the old email must not find the user")
+		}
+
+		_, err = repo.Update(ctx, user.ID, user.Version, "Stale", "cristian.a@email.com", "2", nil)
+		if !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+	})
+
+	t.Run("password, avatar and preferences", func(t *testing.T) {
+		repo := newBackend(t).Users
+		user := addUser(ctx, t, repo, "Cristian", "cristian@email.com", "1")
+
+		if err := repo.ChangePassword(ctx, user.ID, "456_encrypt"); err != nil {
+			t.Fatal(err)
+		}
+		if password, _ := repo.GetPassword(ctx, user.ID); password != "456_encrypt" {
+			t.Errorf("expected the new password, got %q", password)
+		}
+
+		if err := repo.UpdateAvatar(ctx, user.ID, "/avatars/1.png"); err != nil {
+			t.Fatal(err)
+		}
+		preferences := users.Preferences{
+			Language:             users.LanguageEnglish,
+			NotificationChannels: []users.NotificationChannel{users.NotificationSMS},
+		}
+		if err := repo.UpdatePreferences(ctx, user.ID, preferences); err != nil {
+			t.Fatal(err)
+		}
+
+		found, _ := repo.GetByID(ctx, user.ID)
+		if found.AvatarURL != "/avatars/1.png" {
+			t.Errorf("unexpected avatar %q", found.AvatarURL)
+		}
+		if found.Preferences.Language != users.LanguageEnglish || len(found.Preferences.NotificationChannels) != 1 ||
+			found.Preferences.NotificationChannels[0] != users.NotificationSMS {
+			t.Errorf("unexpected preferences %+v", found.Preferences)
+		}
+		if found.Version != user.Version+2 {
+			t.Errorf("avatar and preferences are changes of the user, expected version %d, got %d",
+				user.Version+2, found.Version)
+		}
+	})
+
+	t.Run("inactive users are hidden", func(t *testing.T) {
+		repo := newBackend(t).Users
+		user := addUser(ctx, t, repo, "Cristian", "cristian@email.com", "1")
+		inactive, err := repo.Add(ctx, "Juan", "juan@email.com", "pass", "2", false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := repo.GetByID(ctx, inactive.ID); ok {
+			t.Error("inactive user found by ID")
+		}
+		if err := repo.Deactivate(ctx, user.ID, user.Version); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := repo.GetByEmail(ctx, "cristian@email.com"); ok {
+			t.Error("deactivated user found by email")
+		}
+		if _, ok := repo.GetPassword(ctx, user.ID); ok {
+			t.Error("password of a deactivated user")
+		}
+		if list, total := repo.List(ctx, nil, 10, 0); total != 0 || len(list) != 0 {
+			t.Errorf("inactive users must not be listed, got %d (total %d)", len(list), total)
+		}
+
+		if !repo.Activate(ctx, inactive.ID) {
+			t.Fatal("expected the user to be activated")
+		}
+		if found, ok := repo.GetByID(ctx, inactive.ID); !ok || !found.IsActive {
+			t.Error("activated user not found")
+		}
+	})
+
+	t.Run("verification codes", func(t *testing.T) {
+		repo := newBackend(t).Users
+		user := addUser(ctx, t, repo, "Cristian", "cristian@email.com", "1")
+
+		if repo.ValidateAccountVerificationCode(ctx, user.ID, "") || repo.ValidateRecoveryPasswordCode(ctx, user.ID, "") {
+			t.Error("an empty code must not be valid when no code was saved")
+		}
+		if err := repo.SaveAccountVerificationCode(ctx, user.ID, "1234"); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.SaveRecoveryPasswordCode(ctx, user.ID, "5678"); err != nil {
+			t.Fatal(err)
+		}
+		if !repo.ValidateAccountVerificationCode(ctx, user.ID, "1234") || repo.ValidateAccountVerificationCode(ctx, user.ID, "5678") {
+			t.Error("unexpected account verification code result")
+		}
+		if !repo.ValidateRecoveryPasswordCode(ctx, user.ID, "5678") || repo.ValidateRecoveryPasswordCode(ctx, user.ID, "1234") {
+			t.Error("unexpected recovery password code result")
+		}
+		if repo.ValidateAccountVerificationCode(ctx, missingID, "1234") {
+			t.Error("code of a missing user")
+		}
+	})
+
+	t.Run("pending users", func(t *testing.T) {
+		repo := newBackend(t).Users
+		pending, err := repo.Add(ctx, "Juan", "juan@email.com", "pass", "2", false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := repo.GetPendingByEmail(ctx, "juan@email.com"); ok {
+			t.Error("user without verification code is pending")
+		}
+		if err := repo.SaveAccountVerificationCode(ctx, pending.ID, "1234"); err != nil {
+			t.Fatal(err)
+		}
+		if found, ok := repo.GetPendingByEmail(ctx, "JUAN@email.com"); !ok || found.ID != pending.ID {
+			t.Errorf("expected pending user %d, got %v %v", pending.ID, found.ID, ok)
+		}
+
+		// the activation uses up the code, so it can't activate the user again
+		if !repo.Activate(ctx, pending.ID) {
+			t.Fatal("expected the user to be activated")
+		}
+		if _, ok := repo.GetPendingByEmail(ctx, "juan@email.com"); ok {
+			t.Error("active user is pending")
+		}
+		if repo.ValidateAccountVerificationCode(ctx, pending.ID, "1234") {
+			t.Error("code still valid after the activation")
+		}
+
+		// the deactivated users are not pending, even with a code
+		user := addUser(ctx, t, repo, "Cristian", "cristian@email.com", "1")
+		if err := repo.SaveAccountVerificationCode(ctx, user.ID, "5678"); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Deactivate(ctx, user.ID, ports.AnyVersion); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := repo.GetPendingByEmail(ctx, "cristian@email.com"); ok {
+			t.Error("deactivated user is pending")
+		}
+		if repo.ValidateAccountVerificationCode(ctx, user.ID, "5678") {
+			t.Error("code of a deactivated user still valid")
+		}
+	})
+
+	t.Run("list pagination", func(t *testing.T) {
+		repo := newBackend(t).Users
+		var added []users.UserID
+		for i := 0; i < 5; i++ {
+			added = append(added, addUser(ctx, t, repo, "user", fmt.Sprintf("user%d@email.com", i), "1").ID)
+		}
+
+		// limit is the end of the page, not its size
+		cases := []struct {
+			name          string
+			limit, offset int
+			expected      []users.UserID
+		}{
+			{"first page", 2, 0, added[0:2]},
+			{"middle page", 4, 2, added[2:4]},
+			{"last partial page", 6, 4, added[4:]},
+			{"whole list", 5, 0, added},
+			{"page after the end", 10, 5, nil},
+			{"page far after the end", 20, 10, nil},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				list, total := repo.List(ctx, map[string]string{}, c.limit, c.offset)
+				if total != len(added) {
+					t.Errorf("expected total %d, got %d", len(added), total)
+				}
+				assertIDs(t, c.expected, userIDs(list))
+			})
+		}
+	})
+
+	t.Run("list filters", func(t *testing.T) {
+		repo := newBackend(t).Users
+		cristian := addUser(ctx, t, repo, "Cristian", "Cristian@email.com", "320")
+		juan := addUser(ctx, t, repo, "Juan", "juan@email.com", "320")
+		other := addUser(ctx, t, repo, "Juan", "other@email.com", "310")
+
+		cases := []struct {
+			name     string
+			filters  map[string]string
+			expected []users.UserID
+		}{
+			{"no filters", nil, []users.UserID{cristian.ID, juan.ID, other.ID}},
+			{"name", map[string]string{"name": "Juan"}, []users.UserID{juan.ID, other.ID}},
+			{"email is case insensitive", map[string]string{"email": "cristian@EMAIL.com"}, []users.UserID{cristian.ID}},
+			{"phone", map[string]string{"phone": "320"}, []users.UserID{cristian.ID, juan.ID}},
+			{"every filter must match", map[string]string{"name": "Juan", "phone": "320"}, []users.UserID{juan.ID}},
+			{"empty filters are ignored", map[string]string{"name": "", "phone": "310"}, []users.UserID{other.ID}},
+			{"unknown filters are ignored", map[string]string{"color": "red"}, []users.UserID{cristian.ID, juan.ID, other.ID}},
+			{"no match", map[string]string{"name": "Pedro"}, nil},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				list, total := repo.List(ctx, c.filters, 10, 0)
+				if total != len(c.expected) {
+					t.Errorf("expected total %d, got %d", len(c.expected), total)
+				}
+				assertIDs(t, c.expected, userIDs(list))
+			})
+		}
+	})
+}
+
+func AddressRepository(t *testing.T, newBackend NewBackend) {
+	ctx := context.Background()
+	add := func(t *testing.T, repo ports.AddressRepository, userID users.UserID, address string) users.Address {
+		t.Helper()
+		a, err := repo.Add(ctx, userID, "Cordoba", "Monteria", address, "320684398", "Cristian")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	t.Run("add, get and list", func(t *testing.T) {
+		backend := newBackend(t)
+		repo := backend.Addresses
+		owner := addUser(ctx, t, backend.Users, "Owner", "owner@email.com", "1")
+		other := addUser(ctx, t, backend.Users, "Other", "other@email.com", "2")
+
+		if _, ok := repo.GetDefault(ctx, owner.ID); ok {
+			t.Error("a user without addresses has no default address")
+		}
+		first := add(t, repo, owner.ID, "Calle 1")
+		second := add(t, repo, owner.ID, "Calle 2")
+		if !first.IsDefault || second.IsDefault {
+			t.Error("only the first address of a user is its default address")
+		}
+		if first.Version != 1 {
+			t.Errorf("expected version 1, got %d", first.Version)
+		}
+		// the first address of another user is its default one too
+		if otherAddress := add(t, repo, other.ID, "Calle 3"); !otherAddress.IsDefault {
+			t.Error("the first address of another user must be its default address")
+		}
+
+		found, ok := repo.GetByID(ctx, owner.ID, second.ID)
+		if !ok || found.Address != "Calle 2" || found.City != "Monteria" || found.ReceiverName != "Cristian" {
+			t.Errorf("unexpected address %+v", found)
+		}
+		if def, ok := repo.GetDefault(ctx, owner.ID); !ok || def.ID != first.ID {
+			t.Errorf("expected default address %d, got %d", first.ID, def.ID)
+		}
+
+		list := repo.List(ctx, owner.ID)
+		if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+			t.Errorf("expected the addresses of the owner in order, got %+v", list)
+		}
+		if list := repo.List(ctx, missingID); len(list) != 0 {
+			t.Errorf("expected no addresses, got %+v", list)
+		}
+	})
+
+	t.Run("addresses of another user do not exist", func(t *testing.T) {
+		backend := newBackend(t)
+		repo := backend.Addresses
+		owner := addUser(ctx, t, backend.Users, "Owner", "owner@email.com", "1")
+		other := addUser(ctx, t, backend.Users, "Other", "other@email.com", "2")
+		address := add(t, repo, owner.ID, "Calle 1")
+
+		if _, ok := repo.GetByID(ctx, other.ID, address.ID); ok {
+			t.Error("address visible to another user")
+		}
+		_, updateErr := repo.Update(ctx, other.ID, address.ID, ports.AnyVersion, "x", "x", "x", "x", "x")
+		_, setDefaultErr := repo.SetDefault(ctx, other.ID, address.ID, ports.AnyVersion)
+		_, missingErr := repo.Update(ctx, owner.ID, missingID, ports.AnyVersion, "x", "x", "x", "x", "x")
+		errs := map[string]error{
+			"Update":         updateErr,
+			"SetDefault":     setDefaultErr,
+			"Delete":         repo.Delete(ctx, other.ID, address.ID, ports.AnyVersion),
+			"Update missing": missingErr,
+			"Delete missing": repo.Delete(ctx, owner.ID, missingID, ports.AnyVersion),
+		}
+		for name, err := range errs {
+			if !errors.Is(err, ports.AddressDoesNotExists) {
+				t.Errorf("%s: expected AddressDoesNotExists, got %v", name, err)
+			}
+		}
+		if found, _ := repo.GetByID(ctx, owner.ID, address.ID); found.Address != "Calle 1" || found.Version != address.Version {
+			t.Error("address changed by another user")
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		backend := newBackend(t)
+		repo := backend.Addresses
+		owner := addUser(ctx, t, backend.Users, "Owner", "owner@email.com", "1")
+		address := add(t, repo, owner.ID, "Calle 1")
+
+		updated, err := repo.Update(ctx, owner.ID, address.ID, address.Version, "Antioquia", "Medellin", "Calle 2", "310", "Juan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Department != "Antioquia" || updated.City != "Medellin" || updated.Address != "Calle 2" ||
+			updated.ReceiverPhone != "310" || updated.ReceiverName != "Juan" || !updated.IsDefault {
+			t.Errorf("unexpected updated address %+v", updated)
+		}
+		if updated.Version != address.Version+1 {
+			t.Errorf("expected version %d, got %d", address.Version+1, updated.Version)
+		}
+
+		_, err = repo.Update(ctx, owner.ID, address.ID, address.Version, "Cordoba", "Monteria", "Calle 1", "320", "Cristian")
+		if !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+	})
+
+	t.Run("set default", func(t *testing.T) {
+		backend := newBackend(t)
+		repo := backend.Addresses
+		owner := addUser(ctx, t, backend.Users, "Owner", "owner@email.com", "1")
+		first := add(t, repo, owner.ID, "Calle 1")
+		second := add(t, repo, owner.ID, "Calle 2")
+
+		def, err := repo.SetDefault(ctx, owner.ID, second.ID, second.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if def.ID != second.ID || !def.IsDefault || def.Version != second.Version+1 {
+			t.Errorf("unexpected default address %+v", def)
+		}
+		if found, _ := repo.GetByID(ctx, owner.ID, first.ID); found.IsDefault || found.Version != first.Version+1 {
+			t.Errorf("the previous default address must be unset, got %+v", found)
+		}
+
+		// setting the default address again doesn't change it
+		again, err := repo.SetDefault(ctx, owner.ID, second.ID, def.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.Version != def.Version {
+			t.Errorf("expected version %d, got %d", def.Version, again.Version)
+		}
+		if _, err := repo.SetDefault(ctx, owner.ID, first.ID, first.Version); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		backend := newBackend(t)
+		repo := backend.Addresses
+		owner := addUser(ctx, t, backend.Users, "Owner", "owner@email.com", "1")
+		first := add(t, repo, owner.ID, "Calle 1")
+		second := add(t, repo, owner.ID, "Calle 2")
+		third := add(t, repo, owner.ID, "Calle 3")
+
+		if err := repo.Delete(ctx, owner.ID, second.ID, second.Version+1); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if err := repo.Delete(ctx, owner.ID, second.ID, second.Version); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := repo.GetByID(ctx, owner.ID, second.ID); ok {
+			t.Error("deleted address found")
+		}
+		if def, _ := repo.GetDefault(ctx, owner.ID); def.ID != first.ID {
+			t.Error("deleting another address must not change the default address")
+		}
+
+		// the oldest address takes the place of a deleted default address
+		if err := repo.Delete(ctx, owner.ID, first.ID, first.Version); err != nil {
+			t.Fatal(err)
+		}
+		def, ok := repo.GetDefault(ctx, owner.ID)
+		if !ok || def.ID != third.ID {
+			t.Errorf("expected default address %d, got %d", third.ID, def.ID)
+		}
+
+		if err := repo.Delete(ctx, owner.ID, third.ID, def.Version); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := repo.GetDefault(ctx, owner.ID); ok {
+			t.Error("a user without addresses has no default address")
+		}
+		if err := repo.Delete(ctx, owner.ID, third.ID, ports.AnyVersion); !errors.Is(err, ports.AddressDoesNotExists) {
+			t.Errorf("expected AddressDoesNotExists, got %v", err)
+		}
+	})
+}
+
+func assertIDs[ID comparable](t *testing.T, expected []ID, got []ID) {
+	t.Helper()
+	if len(expected) != len(got) {
+		t.Errorf("expected %v, got %v", expected, got)
+		return
+	}
+	for i := range expected {
+		if expected[i] != got[i] {
+			t.Errorf("expected %v, got %v", expected, got)
+			return
+		}
+	}
+}
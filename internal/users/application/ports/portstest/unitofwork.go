@@ -0,0 +1,96 @@
+package portstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
+
+func UnitOfWork(t *testing.T, newBackend NewBackend) {
+	ctx := context.Background()
+	register := func(repos ports.Repositories) (users.User, error) {
+		user, err := repos.Users.Add(ctx, "Juan", "juan@email.com", "333_encrypt", "320", true, nil)
+		if err != nil {
+			return users.User{}, err
+		}
+		if _, err := repos.Addresses.Add(ctx, user.ID, "Cordoba", "Monteria", "Cll 1", "320", "Juan"); err != nil {
+			return users.User{}, err
+		}
+		_, err = repos.Audit.Append(ctx, users.AuditEvent{Type: users.AuditAddressCreated, ActorID: user.ID})
+		return user, err
+	}
+
+	t.Run("failure discards every change", func(t *testing.T) {
+		backend := newBackend(t)
+		failure := errors.New("email not sent")
+
+		var userID users.UserID
+		err := backend.UnitOfWork.Do(ctx, func(ctx context.Context, repos ports.Repositories) error {
+			user, err := register(repos)
+			if err != nil {
+				return err
+			}
+			userID = user.ID
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("expected %v, got %v", failure, err)
+		}
+
+		if _, ok := backend.Users.GetByEmail(ctx, "juan@email.com"); ok {
+			t.Error("user of a failed unit of work was kept")
+		}
+		if list := backend.Addresses.List(ctx, userID); len(list) != 0 {
+			t.Error("address of a failed unit of work was kept")
+		}
+		if _, total := backend.Audit.List(ctx, nil, 10, 0); total != 0 {
+			t.Error("audit event of a failed unit of work was kept")
+		}
+	})
+
+	t.Run("success keeps every change", func(t *testing.T) {
+		backend := newBackend(t)
+
+		var userID users.UserID
+		err := backend.UnitOfWork.Do(ctx, func(ctx context.Context, repos ports.Repositories) error {
+			user, err := register(repos)
+			userID = user.ID
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if user, ok := backend.Users.GetByEmail(ctx, "juan@email.com"); !ok || user.ID != userID {
+			t.Error("user of the unit of work not found")
+		}
+		if list := backend.Addresses.List(ctx, userID); len(list) != 1 || !list[0].IsDefault {
+			t.Errorf("unexpected addresses %+v", list)
+		}
+		if _, total := backend.Audit.List(ctx, nil, 10, 0); total != 1 {
+			t.Errorf("expected 1 audit event, got %d", total)
+		}
+	})
+
+	t.Run("failed change inside a successful unit of work", func(t *testing.T) {
+		backend := newBackend(t)
+		addUser(ctx, t, backend.Users, "Cristian", "cristian@email.com", "1")
+
+		err := backend.UnitOfWork.Do(ctx, func(ctx context.Context, repos ports.Repositories) error {
+			if _, err := repos.Users.Add(ctx, "Other", "cristian@email.com", "pass", "2", true, nil); !errors.Is(err, ports.EmailAlreadyExists) {
+				t.Errorf("expected EmailAlreadyExists, got %v", err)
+			}
+			_, err := register(repos)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, total := backend.Users.List(ctx, nil, 10, 0); total != 2 {
+			t.Errorf("expected 2 users, got %d", total)
+		}
+	})
+}
@@ -1,47 +1,69 @@
 package ports
 
 import (
-	"errors"
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
 )
 
 var (
-	UserDoesNotExists    = errors.New("user does not exists")
-	AddressDoesNotExists = errors.New("address does not exists")
-	InvalidCredentials   = errors.New("invalid credentials")
+	UserDoesNotExists    = apperror.New(apperror.NotFound, "user_not_found", "user does not exists")
+	EmailAlreadyExists   = apperror.New(apperror.Conflict, "email_already_exists", "email already exists")
+	AddressDoesNotExists = apperror.New(apperror.NotFound, "address_not_found", "address does not exists")
+	InvalidCredentials   = apperror.New(apperror.Invalid, "invalid_credentials", "invalid credentials")
+	VersionMismatch      = apperror.New(apperror.PreconditionFailed, "version_mismatch", "resource was modified by another request")
+
+	InvalidLanguage            = apperror.New(apperror.Invalid, "invalid_language", "invalid language")
+	InvalidNotificationChannel = apperror.New(apperror.Invalid, "invalid_notification_channel", "invalid notification channel")
 )
 
+// AnyVersion skips the version check of a write, any other version must match
+// the current one or the write fails with VersionMismatch
+const AnyVersion = 0
+
 type UserRepository interface {
-	List(filters map[string]string, limit int, offset int) ([]users.User, int)
-	GetByID(ID users.UserID) (users.User, bool)
-	GetByEmail(email string) (users.User, bool)
-	GetPassword(ID users.UserID) (string, bool)
+	List(ctx context.Context, filters map[string]string, limit int, offset int) ([]users.User, int)
+	GetByID(ctx context.Context, ID users.UserID) (users.User, bool)
+	GetByEmail(ctx context.Context, email string) (users.User, bool)
+	// GetPendingByEmail finds the inactive user of email that has not validated
+	// its verification code yet, the deactivated users are not pending
+	GetPendingByEmail(ctx context.Context, email string) (users.User, bool)
+	GetPassword(ctx context.Context, ID users.UserID) (string, bool)
 	Add(
-		name string, email string, password string, phone string, isActive bool, scopes []users.ScopeName,
+		ctx context.Context, name string, email string,
+		password string, phone string, isActive bool, scopes []users.ScopeName,
 	) (users.User, error)
 	Update(
-		ID users.UserID, name string, email string, phone string, scopes []users.ScopeName,
+		ctx context.Context, ID users.UserID, version int,
+		name string, email string, phone string, scopes []users.ScopeName,
 	) (users.User, error)
-	ChangePassword(ID users.UserID, newPassword string) error
-	Deactivate(ID users.UserID) error
-	Activate(ID users.UserID) bool
+	ChangePassword(ctx context.Context, ID users.UserID, newPassword string) error
+	UpdateAvatar(ctx context.Context, ID users.UserID, avatarURL string) error
+	UpdatePreferences(ctx context.Context, ID users.UserID, preferences users.Preferences) error
+	Deactivate(ctx context.Context, ID users.UserID, version int) error
+	Activate(ctx context.Context, ID users.UserID) bool
 
-	SaveAccountVerificationCode(ID users.UserID, code string) error
-	ValidateAccountVerificationCode(ID users.UserID, code string) bool
+	SaveAccountVerificationCode(ctx context.Context, ID users.UserID, code string) error
+	ValidateAccountVerificationCode(ctx context.Context, ID users.UserID, code string) bool
 
-	SaveRecoveryPasswordCode(ID users.UserID, code string) error
-	ValidateRecoveryPasswordCode(ID users.UserID, code string) bool
+	SaveRecoveryPasswordCode(ctx context.Context, ID users.UserID, code string) error
+	ValidateRecoveryPasswordCode(ctx context.Context, ID users.UserID, code string) bool
 }
 
+// AddressRepository operations are scoped to the user that owns the address,
+// an address of another user must be reported as AddressDoesNotExists
 type AddressRepository interface {
-	List(ID users.UserID) []users.Address
+	List(ctx context.Context, userID users.UserID) []users.Address
+	GetByID(ctx context.Context, userID users.UserID, ID users.AddressID) (users.Address, bool)
+	GetDefault(ctx context.Context, userID users.UserID) (users.Address, bool)
 	Add(
-		userID users.UserID, department string, city string, address string,
-		receiverPhone string, receiverName string,
+		ctx context.Context, userID users.UserID, department string,
+		city string, address string, receiverPhone string, receiverName string,
 	) (users.Address, error)
 	Update(
-		ID users.AddressID, department string, city string, address string,
-		receiverPhone string, receiverName string,
+		ctx context.Context, userID users.UserID, ID users.AddressID, version int,
+		department string, city string, address string, receiverPhone string, receiverName string,
 	) (users.Address, error)
-	Delete(ID users.AddressID) error
+	SetDefault(ctx context.Context, userID users.UserID, ID users.AddressID, version int) (users.Address, error)
+	Delete(ctx context.Context, userID users.UserID, ID users.AddressID, version int) error
 }
@@ -0,0 +1,19 @@
+package ports
+
+import "context"
+
+// Repositories are the repositories available inside a unit of work
+type Repositories struct {
+	Users     UserRepository
+	Addresses AddressRepository
+	Audit     AuditLogRepository
+}
+
+// UnitOfWork runs fn with repositories that share one transaction, the changes
+// are committed when fn returns nil and discarded when it returns an error.
+// Inside fn only the given repositories and ctx must be used, fn runs while
+// the repositories are locked, so external calls like sending an email must
+// be made after Do returns
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
@@ -1,14 +1,18 @@
 package ports
 
-import "errors"
+import (
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
 
 var (
-	InvalidValidationCode = errors.New("invalid validation code")
+	InvalidValidationCode = apperror.New(apperror.Invalid, "invalid_verification_code", "invalid validation code")
+	UserAlreadyActive     = apperror.New(apperror.Conflict, "user_already_active", "user is already active")
 )
 
 type MessageProvider string
 
 type VerificationCodeManager interface {
-	SendEmailToVerifyAccount(code string, email string) error
-	SendEmailToRecoverPassword(code string, email string) error
+	SendEmailToVerifyAccount(code string, email string, language users.Language) error
+	SendEmailToRecoverPassword(code string, email string, language users.Language) error
 }
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/logging"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"strconv"
+	"time"
+)
+
+// audit never fails the operation that is being audited, errors are only logged
+func (s *UserService) audit(
+	ctx context.Context, actor users.Actor, eventType users.AuditEventType,
+	targetID users.UserID, details map[string]string,
+) {
+	_, err := s.auditRepo.Append(ctx, users.AuditEvent{
+		Type:           eventType,
+		ActorID:        actor.UserID,
+		ImpersonatorID: actor.ImpersonatorID,
+		TargetID:       targetID,
+		IP:             actor.IP,
+		UserAgent:      actor.UserAgent,
+		Details:        details,
+		CreatedAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error(
+			"error saving audit event", "event", eventType, "target_id", targetID, "error", err,
+		)
+	}
+}
+
+func (s *UserService) AuditImpersonatedRequest(
+	ctx context.Context, actor users.Actor, method string, path string, status int,
+) {
+	ctx, span := tracing.Start(ctx, "UserService.AuditImpersonatedRequest")
+	defer span.End()
+
+	s.audit(ctx, actor, users.AuditImpersonatedRequest, actor.UserID, map[string]string{
+		"method": method,
+		"path":   path,
+		"status": strconv.Itoa(status),
+	})
+}
+
+func (s *UserService) ListAuditEvents(
+	ctx context.Context, filters map[string]string, limit int, offset int,
+) ([]users.AuditEvent, int) {
+	ctx, span := tracing.Start(ctx, "UserService.ListAuditEvents")
+	defer span.End()
+
+	return s.auditRepo.List(ctx, filters, limit, offset)
+}
@@ -1,25 +1,41 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"log/slog"
 	"math/rand"
+	"net/http"
 	"strconv"
+	"time"
 )
 
+const MaxAvatarSize = 5 << 20 // 5 MB
+const ImpersonationTokenDuration = 15 * time.Minute
+
+var allowedAvatarTypes = []string{"image/jpeg", "image/png"}
+
 type UserService struct {
 	repo                    ports.UserRepository
 	addressRepo             ports.AddressRepository
 	verificationCodeManager ports.VerificationCodeManager
 	passwordManager         ports.PasswordManager
 	jwtManager              ports.JWTManager
+	avatarStorage           ports.AvatarStorage
+	auditRepo               ports.AuditLogRepository
+	unitOfWork              ports.UnitOfWork
+	metrics                 ports.Metrics
 }
 
 func NewUserService(
 	repo ports.UserRepository, addressRepo ports.AddressRepository,
 	verificationCodeManager ports.VerificationCodeManager,
 	passwordManager ports.PasswordManager, jwtManager ports.JWTManager,
+	avatarStorage ports.AvatarStorage, auditRepo ports.AuditLogRepository,
+	unitOfWork ports.UnitOfWork, metrics ports.Metrics,
 ) UserService {
 	return UserService{
 		repo:                    repo,
@@ -27,69 +43,211 @@ func NewUserService(
 		verificationCodeManager: verificationCodeManager,
 		passwordManager:         passwordManager,
 		jwtManager:              jwtManager,
+		avatarStorage:           avatarStorage,
+		auditRepo:               auditRepo,
+		unitOfWork:              unitOfWork,
+		metrics:                 metrics,
 	}
 }
 
 func (s *UserService) List(
-	filters map[string]string, limit int, offset int,
+	ctx context.Context, filters map[string]string, limit int, offset int,
 ) ([]users.User, int) {
-	return s.repo.List(filters, limit, offset)
+	ctx, span := tracing.Start(ctx, "UserService.List")
+	defer span.End()
+
+	return s.repo.List(ctx, filters, limit, offset)
 }
 
-func (s *UserService) GetByID(ID users.UserID) (users.User, bool) {
-	user, ok := s.repo.GetByID(ID)
+func (s *UserService) GetByID(ctx context.Context, ID users.UserID) (users.User, bool) {
+	ctx, span := tracing.Start(ctx, "UserService.GetByID")
+	defer span.End()
+
+	user, ok := s.repo.GetByID(ctx, ID)
 	if ok {
-		user.Addresses = s.addressRepo.List(ID)
+		user.Addresses = s.addressRepo.List(ctx, ID)
 	}
 	return user, ok
 }
 
-func (s *UserService) Login(email string, password string) (ports.Token, error) {
-	user, ok := s.repo.GetByEmail(email)
+func (s *UserService) Login(
+	ctx context.Context, actor users.Actor, email string, password string,
+) (ports.Token, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Login")
+	defer span.End()
+
+	user, ok := s.repo.GetByEmail(ctx, email)
 	if !ok {
+		s.metrics.LoginAttempted(ports.LoginUnknownEmail)
+		s.audit(ctx, actor, users.AuditLoginFailed, 0, map[string]string{"email": email})
 		return ports.Token{}, ports.InvalidCredentials
 	}
 
-	encryptedPassword, ok := s.repo.GetPassword(user.ID)
+	encryptedPassword, ok := s.repo.GetPassword(ctx, user.ID)
 	if !ok {
+		s.metrics.LoginAttempted(ports.LoginInvalidPassword)
+		s.audit(ctx, actor, users.AuditLoginFailed, user.ID, map[string]string{"email": email})
 		return ports.Token{}, ports.InvalidCredentials
 	}
 
 	ok, err := s.passwordManager.Verify(password, encryptedPassword)
 	if err != nil || !ok {
+		s.metrics.LoginAttempted(ports.LoginInvalidPassword)
+		s.audit(ctx, actor, users.AuditLoginFailed, user.ID, map[string]string{"email": email})
 		return ports.Token{}, ports.InvalidCredentials
 	}
 
 	token, err := s.jwtManager.Create(user)
 	if err != nil || !ok {
+		s.metrics.LoginAttempted(ports.LoginError)
+		return ports.Token{}, errors.New("error to create JWT")
+	}
+
+	s.metrics.LoginAttempted(ports.LoginSucceeded)
+	s.audit(ctx, actor, users.AuditLoginSucceeded, user.ID, nil)
+	return token, nil
+}
+
+func (s *UserService) Impersonate(
+	ctx context.Context, actor users.Actor, targetID users.UserID,
+) (ports.Token, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Impersonate")
+	defer span.End()
+
+	if actor.ImpersonatorID != 0 || actor.UserID == targetID {
+		return ports.Token{}, ports.ImpersonationNotAllowed
+	}
+
+	actorUser, ok := s.repo.GetByID(ctx, actor.UserID)
+	if !ok || !actorUser.HasScope(users.USERS_IMPERSONATE) {
+		return ports.Token{}, ports.ImpersonationNotAllowed
+	}
+	target, ok := s.repo.GetByID(ctx, targetID)
+	if !ok {
+		return ports.Token{}, ports.UserDoesNotExists
+	}
+	// the token has the scopes of the target, so an actor can't gain scopes
+	// by impersonating a user with more permissions
+	if !actorUser.HasAllScopes(target.Scopes...) {
+		return ports.Token{}, ports.ImpersonationNotAllowed
+	}
+
+	token, err := s.jwtManager.CreateImpersonation(target, actorUser, ImpersonationTokenDuration)
+	if err != nil {
 		return ports.Token{}, errors.New("error to create JWT")
 	}
 
+	s.audit(ctx, actor, users.AuditImpersonationStarted, targetID, nil)
 	return token, nil
 }
 
-func (s *UserService) ValidateJWT(accessToken string) (users.User, error) {
-	return s.jwtManager.Verify(accessToken)
+func (s *UserService) ValidateJWT(ctx context.Context, accessToken string) (users.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ValidateJWT")
+	defer span.End()
+
+	return s.jwtManager.Verify(ctx, accessToken)
 }
 
 func (s *UserService) Add(
-	name string, email string, password string, phone string, isActive bool, scopes []users.ScopeName,
+	ctx context.Context, name string, email string,
+	password string, phone string, isActive bool, scopes []users.ScopeName,
 ) (users.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Add")
+	defer span.End()
+
 	hashPassword, err := s.passwordManager.Encrypt(password)
 	if err != nil {
 		return users.User{}, err
 	}
-	return s.repo.Add(name, email, hashPassword, phone, isActive, scopes)
+	return s.repo.Add(ctx, name, email, hashPassword, phone, isActive, scopes)
+}
+
+// Register adds an inactive user and sends it the code to verify the account,
+// the user is not kept when the code can't be sent
+func (s *UserService) Register(
+	ctx context.Context, name string, email string, password string, phone string,
+) (users.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Register")
+	defer span.End()
+
+	hashPassword, err := s.passwordManager.Encrypt(password)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	var user users.User
+	var code string
+	err = s.unitOfWork.Do(ctx, func(ctx context.Context, repos ports.Repositories) error {
+		var err error
+		user, err = repos.Users.Add(ctx, name, email, hashPassword, phone, false, []users.ScopeName{})
+		if err != nil {
+			return err
+		}
+		code, err = saveAccountVerificationCode(ctx, repos.Users, user.ID)
+		return err
+	})
+	if err != nil {
+		return users.User{}, err
+	}
+
+	s.metrics.UserRegistered()
+
+	// the email is sent once the user is committed, when it fails the user
+	// stays inactive and can ask for a new code with ResendAccountVerificationCode
+	if err := s.sendAccountVerificationEmail(user, code); err != nil {
+		span.RecordError(err)
+		slog.ErrorContext(ctx, "account verification email not sent", "user_id", user.ID, "error", err)
+	}
+	return user, nil
 }
 
 func (s *UserService) Update(
-	ID users.UserID, name string, email string, phone string, scopes []users.ScopeName,
+	ctx context.Context, actor users.Actor, ID users.UserID,
+	version int, name string, email string, phone string, scopes []users.ScopeName,
 ) (users.User, error) {
-	return s.repo.Update(ID, name, email, phone, scopes)
+	ctx, span := tracing.Start(ctx, "UserService.Update")
+	defer span.End()
+
+	current, ok := s.repo.GetByID(ctx, ID)
+	if !ok {
+		return users.User{}, ports.UserDoesNotExists
+	}
+	if !users.SameScopes(current.Scopes, scopes) {
+		if actor.ImpersonatorID != 0 {
+			return users.User{}, ports.ForbiddenWhileImpersonating
+		}
+		// the users can update their own profile, but only the admins can
+		// change the scopes, even their own ones
+		if admin, ok := s.repo.GetByID(ctx, actor.UserID); !ok || !admin.HasScope(users.USERS_WRITE, users.USERS_DELETE) {
+			return users.User{}, ports.ScopesChangeNotAllowed
+		}
+	}
+
+	user, err := s.repo.Update(ctx, ID, version, name, email, phone, scopes)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	if !users.SameScopes(current.Scopes, user.Scopes) {
+		s.audit(ctx, actor, users.AuditScopesChanged, ID, map[string]string{
+			"from": users.JoinScopes(current.Scopes),
+			"to":   users.JoinScopes(user.Scopes),
+		})
+	}
+	return user, nil
 }
 
-func (s *UserService) ChangePassword(ID users.UserID, currentPassword string, newPassword string) error {
-	encryptedPassword, ok := s.repo.GetPassword(ID)
+func (s *UserService) ChangePassword(
+	ctx context.Context, actor users.Actor, ID users.UserID, currentPassword string, newPassword string,
+) error {
+	ctx, span := tracing.Start(ctx, "UserService.ChangePassword")
+	defer span.End()
+
+	if actor.ImpersonatorID != 0 {
+		return ports.ForbiddenWhileImpersonating
+	}
+
+	encryptedPassword, ok := s.repo.GetPassword(ctx, ID)
 	if !ok {
 		return ports.UserDoesNotExists
 	}
@@ -105,89 +263,291 @@ func (s *UserService) ChangePassword(ID users.UserID, currentPassword string, ne
 	if err != nil {
 		return err
 	}
-	err = s.repo.ChangePassword(ID, newPass)
+	err = s.repo.ChangePassword(ctx, ID, newPass)
 	if err != nil {
 		return err
 	}
 
+	s.audit(ctx, actor, users.AuditPasswordChanged, ID, nil)
 	return nil
 }
 
-func (s *UserService) Deactivate(ID users.UserID) error {
-	return s.repo.Deactivate(ID)
+func (s *UserService) UpdateAvatar(
+	ctx context.Context, actor users.Actor, ID users.UserID, content []byte,
+) (users.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateAvatar")
+	defer span.End()
+
+	if len(content) > MaxAvatarSize {
+		return users.User{}, ports.AvatarTooLarge
+	}
+
+	contentType := http.DetectContentType(content)
+	isAllowed := false
+	for _, t := range allowedAvatarTypes {
+		if contentType == t {
+			isAllowed = true
+			break
+		}
+	}
+	if !isAllowed {
+		return users.User{}, ports.InvalidAvatarType
+	}
+
+	if _, ok := s.repo.GetByID(ctx, ID); !ok {
+		return users.User{}, ports.UserDoesNotExists
+	}
+
+	avatarURL, err := s.avatarStorage.Save(ID, content)
+	if err != nil {
+		return users.User{}, err
+	}
+	if err := s.repo.UpdateAvatar(ctx, ID, avatarURL); err != nil {
+		return users.User{}, err
+	}
+
+	s.audit(ctx, actor, users.AuditAvatarUpdated, ID, nil)
+	user, _ := s.GetByID(ctx, ID)
+	return user, nil
 }
 
-func (s *UserService) ListAddresses(id users.UserID) []users.Address {
-	return s.addressRepo.List(id)
+// Deactivate checks the version before the avatar is removed, so a stale
+// request doesn't leave the user without its avatar
+func (s *UserService) Deactivate(
+	ctx context.Context, actor users.Actor, ID users.UserID, version int,
+) error {
+	ctx, span := tracing.Start(ctx, "UserService.Deactivate")
+	defer span.End()
+
+	user, ok := s.repo.GetByID(ctx, ID)
+	if err := s.repo.Deactivate(ctx, ID, version); err != nil {
+		return err
+	}
+
+	if ok && user.AvatarURL != "" {
+		if err := s.avatarStorage.Delete(ID); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateAvatar(ctx, ID, ""); err != nil {
+			return err
+		}
+	}
+
+	s.audit(ctx, actor, users.AuditUserDeactivated, ID, nil)
+	return nil
+}
+
+func (s *UserService) GetPreferences(ctx context.Context, ID users.UserID) (users.Preferences, bool) {
+	ctx, span := tracing.Start(ctx, "UserService.GetPreferences")
+	defer span.End()
+
+	user, ok := s.repo.GetByID(ctx, ID)
+	if !ok {
+		return users.Preferences{}, false
+	}
+	return user.Preferences, true
+}
+
+func (s *UserService) UpdatePreferences(
+	ctx context.Context, ID users.UserID, language users.Language,
+	marketingEmail bool, marketingSMS bool, channels []users.NotificationChannel,
+) (users.Preferences, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdatePreferences")
+	defer span.End()
+
+	if !language.IsValid() {
+		return users.Preferences{}, ports.InvalidLanguage
+	}
+	for _, c := range channels {
+		if !c.IsValid() {
+			return users.Preferences{}, ports.InvalidNotificationChannel
+		}
+	}
+
+	user, ok := s.repo.GetByID(ctx, ID)
+	if !ok {
+		return users.Preferences{}, ports.UserDoesNotExists
+	}
+
+	// consent timestamps only change when the user grants or revokes the consent
+	now := time.Now().UTC()
+	preferences := user.Preferences
+	if preferences.MarketingEmail.Granted != marketingEmail || preferences.MarketingEmail.UpdatedAt.IsZero() {
+		preferences.MarketingEmail = users.Consent{Granted: marketingEmail, UpdatedAt: now}
+	}
+	if preferences.MarketingSMS.Granted != marketingSMS || preferences.MarketingSMS.UpdatedAt.IsZero() {
+		preferences.MarketingSMS = users.Consent{Granted: marketingSMS, UpdatedAt: now}
+	}
+	preferences.Language = language
+	preferences.NotificationChannels = channels
+
+	if err := s.repo.UpdatePreferences(ctx, ID, preferences); err != nil {
+		return users.Preferences{}, err
+	}
+	return preferences, nil
+}
+
+func (s *UserService) ListAddresses(ctx context.Context, id users.UserID) []users.Address {
+	ctx, span := tracing.Start(ctx, "UserService.ListAddresses")
+	defer span.End()
+
+	return s.addressRepo.List(ctx, id)
 }
 
 func (s *UserService) AddAddress(
-	ID users.UserID, department string, city string, address string, receiverPhone string,
-	receiverName string,
+	ctx context.Context, actor users.Actor, ID users.UserID, department string,
+	city string, address string, receiverPhone string, receiverName string,
 ) (users.Address, error) {
-	createdAddress, err := s.addressRepo.Add(ID, department, city, address, receiverPhone, receiverName)
+	ctx, span := tracing.Start(ctx, "UserService.AddAddress")
+	defer span.End()
+
+	createdAddress, err := s.addressRepo.Add(ctx, ID, department, city, address, receiverPhone, receiverName)
 	if err != nil {
 		return users.Address{}, err
 	}
 
+	s.audit(ctx, actor, users.AuditAddressCreated, ID, addressDetails(createdAddress.ID))
 	return createdAddress, nil
 }
 
-func (s *UserService) DeleteAddress(addressID users.AddressID) error {
-	err := s.addressRepo.Delete(addressID)
+func (s *UserService) GetDefaultAddress(ctx context.Context, userID users.UserID) (users.Address, bool) {
+	ctx, span := tracing.Start(ctx, "UserService.GetDefaultAddress")
+	defer span.End()
+
+	return s.addressRepo.GetDefault(ctx, userID)
+}
+
+func (s *UserService) DeleteAddress(
+	ctx context.Context, actor users.Actor, userID users.UserID, addressID users.AddressID, version int,
+) error {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteAddress")
+	defer span.End()
+
+	err := s.addressRepo.Delete(ctx, userID, addressID, version)
 	if err != nil {
 		return err
 	}
+
+	s.audit(ctx, actor, users.AuditAddressDeleted, userID, addressDetails(addressID))
 	return nil
 }
 
 func (s *UserService) UpdateAddress(
-	addressID users.AddressID, department string, city string, address string,
-	receiverPhone string, receiverName string,
+	ctx context.Context, actor users.Actor, userID users.UserID, addressID users.AddressID,
+	version int, department string, city string, address string, receiverPhone string, receiverName string,
 ) (users.Address, error) {
-	return s.addressRepo.Update(addressID, department, city, address, receiverPhone, receiverName)
+	ctx, span := tracing.Start(ctx, "UserService.UpdateAddress")
+	defer span.End()
+
+	updated, err := s.addressRepo.Update(
+		ctx, userID, addressID, version, department, city, address, receiverPhone, receiverName,
+	)
+	if err != nil {
+		return users.Address{}, err
+	}
+
+	s.audit(ctx, actor, users.AuditAddressUpdated, userID, addressDetails(addressID))
+	return updated, nil
+}
+
+func addressDetails(ID users.AddressID) map[string]string {
+	return map[string]string{"address_id": strconv.Itoa(int(ID))}
 }
 
-func (s *UserService) SendAccountVerificationCode(user users.User) error {
+func (s *UserService) SetDefaultAddress(
+	ctx context.Context, actor users.Actor, userID users.UserID, addressID users.AddressID, version int,
+) (users.Address, error) {
+	ctx, span := tracing.Start(ctx, "UserService.SetDefaultAddress")
+	defer span.End()
+
+	address, err := s.addressRepo.SetDefault(ctx, userID, addressID, version)
+	if err != nil {
+		return users.Address{}, err
+	}
+
+	s.audit(ctx, actor, users.AuditDefaultAddressSet, userID, addressDetails(addressID))
+	return address, nil
+}
+
+func (s *UserService) SendAccountVerificationCode(ctx context.Context, user users.User) error {
+	ctx, span := tracing.Start(ctx, "UserService.SendAccountVerificationCode")
+	defer span.End()
+
+	code, err := saveAccountVerificationCode(ctx, s.repo, user.ID)
+	if err != nil {
+		return err
+	}
+	return s.sendAccountVerificationEmail(user, code)
+}
+
+// ResendAccountVerificationCode sends a new code to the inactive user of email
+func (s *UserService) ResendAccountVerificationCode(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ResendAccountVerificationCode")
+	defer span.End()
+
+	user, ok := s.repo.GetPendingByEmail(ctx, email)
+	if !ok {
+		if _, active := s.repo.GetByEmail(ctx, email); active {
+			return ports.UserAlreadyActive
+		}
+		return ports.UserDoesNotExists
+	}
+	return s.SendAccountVerificationCode(ctx, user)
+}
+
+func saveAccountVerificationCode(ctx context.Context, repo ports.UserRepository, ID users.UserID) (string, error) {
 	codeRangeMin := 1000
 	codeRangeMax := 9999
 	code := strconv.Itoa(rand.Intn(codeRangeMax-codeRangeMin) + codeRangeMin)
 
-	err := s.verificationCodeManager.SendEmailToVerifyAccount(code, user.Email)
+	if err := repo.SaveAccountVerificationCode(ctx, ID, code); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+func (s *UserService) sendAccountVerificationEmail(user users.User, code string) error {
+	err := s.verificationCodeManager.SendEmailToVerifyAccount(code, user.Email, user.Preferences.Language)
 	if err != nil {
 		return err
 	}
-
-	err = s.repo.SaveAccountVerificationCode(user.ID, code)
-	return err
+	s.metrics.VerificationCodeSent(ports.VerifyAccountCode)
+	return nil
 }
 
-func (s *UserService) ValidateAccountVerificationCode(ID users.UserID, code string) bool {
-	isValid := s.repo.ValidateAccountVerificationCode(ID, code)
+func (s *UserService) ValidateAccountVerificationCode(ctx context.Context, ID users.UserID, code string) bool {
+	ctx, span := tracing.Start(ctx, "UserService.ValidateAccountVerificationCode")
+	defer span.End()
+
+	isValid := s.repo.ValidateAccountVerificationCode(ctx, ID, code)
 
 	if isValid {
-		ok := s.repo.Activate(ID)
+		ok := s.repo.Activate(ctx, ID)
 		return ok
 	}
 	return false
 }
 
-func (s *UserService) SendRecoveryPasswordRequest(email string) error {
+func (s *UserService) SendRecoveryPasswordRequest(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "UserService.SendRecoveryPasswordRequest")
+	defer span.End()
+
 	codeRangeMin := 100000000
 	codeRangeMax := 999999999
 	code := strconv.Itoa(rand.Intn(codeRangeMax-codeRangeMin) + codeRangeMin)
 
-	user, ok := s.repo.GetByEmail(email)
+	user, ok := s.repo.GetByEmail(ctx, email)
 	if !ok {
 		return ports.UserDoesNotExists
 	}
 
-	err := s.verificationCodeManager.SendEmailToRecoverPassword(code, email)
+	err := s.verificationCodeManager.SendEmailToRecoverPassword(code, email, user.Preferences.Language)
 	if err != nil {
 		return err
 	}
+	s.metrics.VerificationCodeSent(ports.RecoverPasswordCode)
 
-	err = s.repo.SaveRecoveryPasswordCode(user.ID, code)
+	err = s.repo.SaveRecoveryPasswordCode(ctx, user.ID, code)
 	if err != nil {
 		return err
 	}
@@ -195,12 +555,17 @@ func (s *UserService) SendRecoveryPasswordRequest(email string) error {
 	return nil
 }
 
-func (s *UserService) RecoveryPassword(email string, newPassword string, code string) error {
-	user, ok := s.repo.GetByEmail(email)
+func (s *UserService) RecoveryPassword(
+	ctx context.Context, actor users.Actor, email string, newPassword string, code string,
+) error {
+	ctx, span := tracing.Start(ctx, "UserService.RecoveryPassword")
+	defer span.End()
+
+	user, ok := s.repo.GetByEmail(ctx, email)
 	if !ok {
 		return ports.UserDoesNotExists
 	}
-	ok = s.repo.ValidateRecoveryPasswordCode(user.ID, code)
+	ok = s.repo.ValidateRecoveryPasswordCode(ctx, user.ID, code)
 	if !ok {
 		return ports.InvalidValidationCode
 	}
@@ -209,10 +574,11 @@ func (s *UserService) RecoveryPassword(email string, newPassword string, code st
 	if err != nil {
 		return err
 	}
-	err = s.repo.ChangePassword(user.ID, encryptedPassword)
+	err = s.repo.ChangePassword(ctx, user.ID, encryptedPassword)
 	if err != nil {
 		return err
 	}
 
+	s.audit(ctx, actor, users.AuditPasswordRecovered, user.ID, nil)
 	return nil
 }
@@ -0,0 +1,43 @@
+package users
+
+import "time"
+
+type AuditEventID int
+type AuditEventType string
+
+const (
+	AuditLoginSucceeded    AuditEventType = "login_succeeded"
+	AuditLoginFailed       AuditEventType = "login_failed"
+	AuditPasswordChanged   AuditEventType = "password_changed"
+	AuditPasswordRecovered AuditEventType = "password_recovered"
+	AuditScopesChanged     AuditEventType = "scopes_changed"
+	AuditUserDeactivated   AuditEventType = "user_deactivated"
+	AuditAvatarUpdated     AuditEventType = "avatar_updated"
+	AuditAddressCreated    AuditEventType = "address_created"
+	AuditAddressUpdated    AuditEventType = "address_updated"
+	AuditAddressDeleted    AuditEventType = "address_deleted"
+	AuditDefaultAddressSet AuditEventType = "default_address_set"
+
+	AuditImpersonationStarted AuditEventType = "impersonation_started"
+	AuditImpersonatedRequest  AuditEventType = "impersonated_request"
+)
+
+// Actor is who performs an action, UserID is zero when the actor is anonymous
+type Actor struct {
+	UserID         UserID
+	ImpersonatorID UserID
+	IP             string
+	UserAgent      string
+}
+
+type AuditEvent struct {
+	ID             AuditEventID
+	Type           AuditEventType
+	ActorID        UserID
+	ImpersonatorID UserID
+	TargetID       UserID
+	IP             string
+	UserAgent      string
+	Details        map[string]string
+	CreatedAt      time.Time
+}
@@ -0,0 +1,43 @@
+package users
+
+import "time"
+
+type Language string
+type NotificationChannel string
+
+const (
+	LanguageSpanish Language = "es"
+	LanguageEnglish Language = "en"
+
+	NotificationEmail NotificationChannel = "email"
+	NotificationSMS   NotificationChannel = "sms"
+)
+
+func (l Language) IsValid() bool {
+	return l == LanguageSpanish || l == LanguageEnglish
+}
+
+func (c NotificationChannel) IsValid() bool {
+	return c == NotificationEmail || c == NotificationSMS
+}
+
+// Consent keeps when the user granted or revoked it, it's the legal proof
+// required to send marketing messages
+type Consent struct {
+	Granted   bool
+	UpdatedAt time.Time
+}
+
+type Preferences struct {
+	Language             Language
+	MarketingEmail       Consent
+	MarketingSMS         Consent
+	NotificationChannels []NotificationChannel
+}
+
+func DefaultPreferences() Preferences {
+	return Preferences{
+		Language:             LanguageSpanish,
+		NotificationChannels: []NotificationChannel{NotificationEmail},
+	}
+}
@@ -1,9 +1,15 @@
 package users
 
 const (
-	USERS_READ   ScopeName = "users:read"
-	USERS_WRITE  ScopeName = "users:write"
-	USERS_DELETE ScopeName = "users:delete"
+	USERS_READ        ScopeName = "users:read"
+	USERS_WRITE       ScopeName = "users:write"
+	USERS_DELETE      ScopeName = "users:delete"
+	USERS_IMPERSONATE ScopeName = "users:impersonate"
+
+	AUDIT_READ ScopeName = "audit:read"
+
+	// PRODUCTS_ADMIN allows to see and restore the deleted products
+	PRODUCTS_ADMIN ScopeName = "products:admin"
 
 	// TODO create other scopes about cases and other resources
 )
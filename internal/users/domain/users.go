@@ -1,19 +1,34 @@
 package users
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type UserID int
 type ScopeName string
 
 type User struct {
-	ID        UserID
-	Name      string
-	Email     string
-	Phone     string
-	IsActive  bool
-	CreatedAt time.Time
-	Addresses []Address
-	Scopes    []ScopeName
+	ID          UserID
+	Name        string
+	Email       string
+	Phone       string
+	AvatarURL   string
+	IsActive    bool
+	CreatedAt   time.Time
+	Addresses   []Address
+	Scopes      []ScopeName
+	Preferences Preferences
+	// Version is increased on every change of the user, it starts at 1
+	Version int
+
+	// ImpersonatorID is the user (support agent) acting as this user, it's
+	// only set when the user was authenticated with an impersonation token
+	ImpersonatorID UserID
+}
+
+func (u *User) IsImpersonated() bool {
+	return u.ImpersonatorID != 0
 }
 
 func (u *User) HasScope(scopes ...ScopeName) bool {
@@ -27,6 +42,16 @@ func (u *User) HasScope(scopes ...ScopeName) bool {
 	return false
 }
 
+// HasAllScopes reports whether the user has every one of the scopes
+func (u *User) HasAllScopes(scopes ...ScopeName) bool {
+	for _, s := range scopes {
+		if !u.HasScope(s) {
+			return false
+		}
+	}
+	return true
+}
+
 type AddressID int
 
 type Address struct {
@@ -36,4 +61,33 @@ type Address struct {
 	Address       string
 	ReceiverPhone string
 	ReceiverName  string
+	IsDefault     bool
+	Version       int
+}
+
+func SameScopes(a []ScopeName, b []ScopeName) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, s := range a {
+		found := false
+		for _, o := range b {
+			if s == o {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func JoinScopes(scopes []ScopeName) string {
+	names := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		names = append(names, string(s))
+	}
+	return strings.Join(names, ",")
 }
@@ -1,9 +1,12 @@
 package infrastructure
 
 import (
+	"context"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type MockPasswordManager struct {
@@ -36,12 +39,39 @@ func (m *MockJWTManager) Create(user users.User) (ports.Token, error) {
 	}, nil
 }
 
-func (m *MockJWTManager) Verify(accessToken string) (users.User, error) {
-	email := strings.Split(accessToken, "___")[0]
-	user, ok := m.userRepo.GetByEmail(email)
+func (m *MockJWTManager) CreateImpersonation(
+	user users.User, actor users.User, expiresIn time.Duration,
+) (ports.Token, error) {
+	expiresAt := time.Now().Add(expiresIn).Unix()
+	return ports.Token{
+		AccessToken: user.Email + "___jwt___act=" + strconv.Itoa(int(actor.ID)) +
+			"___exp=" + strconv.FormatInt(expiresAt, 10),
+	}, nil
+}
+
+func (m *MockJWTManager) Verify(ctx context.Context, accessToken string) (users.User, error) {
+	parts := strings.Split(accessToken, "___")
+	email := parts[0]
+	user, ok := m.userRepo.GetByEmail(ctx, email)
 	if !ok {
 		return users.User{}, ports.InvalidToken
 	}
 
+	for _, claim := range parts[1:] {
+		if strings.HasPrefix(claim, "act=") {
+			actorID, err := strconv.Atoi(strings.TrimPrefix(claim, "act="))
+			if err != nil {
+				return users.User{}, ports.InvalidToken
+			}
+			user.ImpersonatorID = users.UserID(actorID)
+		}
+		if strings.HasPrefix(claim, "exp=") {
+			expiresAt, err := strconv.ParseInt(strings.TrimPrefix(claim, "exp="), 10, 64)
+			if err != nil || time.Now().Unix() > expiresAt {
+				return users.User{}, ports.InvalidToken
+			}
+		}
+	}
+
 	return user, nil
 }
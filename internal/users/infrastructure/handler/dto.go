@@ -1,13 +1,18 @@
 package handler
 
-import users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+import (
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"time"
+)
 
 type ListUserDTO struct {
-	ID     users.UserID      `json:"id"`
-	Name   string            `json:"name"`
-	Email  string            `json:"email"`
-	Phone  string            `json:"phone"`
-	Scopes []users.ScopeName `json:"scopes"`
+	ID        users.UserID      `json:"id"`
+	Name      string            `json:"name"`
+	Email     string            `json:"email"`
+	Phone     string            `json:"phone"`
+	AvatarURL string            `json:"avatar_url"`
+	Scopes    []users.ScopeName `json:"scopes"`
+	Version   int               `json:"version"`
 }
 
 type RetrieveUserDTO struct {
@@ -15,8 +20,10 @@ type RetrieveUserDTO struct {
 	Name      string            `json:"name"`
 	Email     string            `json:"email"`
 	Phone     string            `json:"phone"`
+	AvatarURL string            `json:"avatar_url"`
 	Scopes    []users.ScopeName `json:"scopes"`
 	Addresses []ListAddressDTO  `json:"addresses"`
+	Version   int               `json:"version"`
 }
 
 type RegisterUserDTO struct {
@@ -35,6 +42,10 @@ type ValidateVerificationCodeDTO struct {
 	Code string `json:"code" binding:"required"`
 }
 
+type ResendVerificationCodeDTO struct {
+	Email string `json:"email" binding:"required"`
+}
+
 type RequestRecoveryPasswordDTO struct {
 	Email string `json:"email" binding:"required"`
 }
@@ -64,6 +75,8 @@ type ListAddressDTO struct {
 	Address       string          `json:"address"`
 	ReceiverPhone string          `json:"receiver_phone"`
 	ReceiverName  string          `json:"receiver_name"`
+	IsDefault     bool            `json:"is_default"`
+	Version       int             `json:"version"`
 }
 
 type CreateAddressDTO struct {
@@ -73,3 +86,31 @@ type CreateAddressDTO struct {
 	ReceiverPhone string `json:"receiver_phone"`
 	ReceiverName  string `json:"receiver_name"`
 }
+
+type PreferencesDTO struct {
+	Language                users.Language              `json:"language"`
+	MarketingEmailConsent   bool                        `json:"marketing_email_consent"`
+	MarketingEmailConsentAt *time.Time                  `json:"marketing_email_consent_at"`
+	MarketingSMSConsent     bool                        `json:"marketing_sms_consent"`
+	MarketingSMSConsentAt   *time.Time                  `json:"marketing_sms_consent_at"`
+	NotificationChannels    []users.NotificationChannel `json:"notification_channels"`
+}
+
+type UpdatePreferencesDTO struct {
+	Language              string   `json:"language" binding:"required,oneof=es en"`
+	MarketingEmailConsent bool     `json:"marketing_email_consent"`
+	MarketingSMSConsent   bool     `json:"marketing_sms_consent"`
+	NotificationChannels  []string `json:"notification_channels" binding:"required,min=1,dive,oneof=email sms"`
+}
+
+type AuditEventDTO struct {
+	ID             users.AuditEventID   `json:"id"`
+	Type           users.AuditEventType `json:"type"`
+	ActorID        users.UserID         `json:"actor_id"`
+	ImpersonatorID users.UserID         `json:"impersonator_id"`
+	TargetID       users.UserID         `json:"target_id"`
+	IP             string               `json:"ip"`
+	UserAgent      string               `json:"user_agent"`
+	Details        map[string]string    `json:"details"`
+	CreatedAt      time.Time            `json:"created_at"`
+}
@@ -2,14 +2,17 @@ package handler
 
 import (
 	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/apperror"
 	"github.com/CrissAlvarezH/fundart-api/internal/common"
+	"github.com/CrissAlvarezH/fundart-api/internal/i18n"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/application/services"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
 	"github.com/gin-gonic/gin"
-	"log"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type UserHandler struct {
@@ -28,10 +31,15 @@ func (h *UserHandler) AddRoutes(g *gin.RouterGroup) {
 
 	g.POST("/users/login", h.Login)
 
+	g.POST("/users/verification-code/request", h.ResendAccountVerificationCode)
 	g.POST("/users/:id/verification-code/", h.ValidateAccountVerificationCode)
 	g.PUT("/users/:id/", common.ValidOr(ScopeUserWrite, IsSameUser), h.Update)
 	g.DELETE("/users/:id/", common.ValidOr(ScopeUserDelete, IsSameUser), h.Delete)
-	g.PUT("/users/:id/password/", common.Valid(IsSameUser), h.ChangePassword)
+	g.PUT("/users/:id/password/", common.Valid(IsSameUser), common.Valid(NotImpersonating), h.ChangePassword)
+	g.POST("/users/:id/impersonate/", common.Valid(ScopeUserImpersonate), common.Valid(NotImpersonating), h.Impersonate)
+	g.PUT("/users/:id/avatar/", common.ValidOr(ScopeUserWrite, IsSameUser), h.UpdateAvatar)
+	g.GET("/users/:id/preferences", common.ValidOr(ScopeUserRead, IsSameUser), h.GetPreferences)
+	g.PUT("/users/:id/preferences/", common.Valid(IsSameUser), h.UpdatePreferences)
 
 	// recovery password
 	g.POST("/users/recovery-password/request", h.RequestRecoveryPassword)
@@ -42,17 +50,21 @@ func (h *UserHandler) AddRoutes(g *gin.RouterGroup) {
 	g.POST("/users/:id/addresses/", common.Valid(IsSameUser), h.AddAddress)
 	g.PUT("/users/:id/addresses/:address_id/", common.Valid(IsSameUser), h.UpdateAddress)
 	g.DELETE("/users/:id/addresses/:address_id/", common.Valid(IsSameUser), h.DeleteAddress)
+	g.PUT("/users/:id/addresses/:address_id/default/", common.Valid(IsSameUser), h.SetDefaultAddress)
+
+	// audit
+	g.GET("/audit-events", common.Valid(ScopeAuditRead), h.ListAuditEvents)
 }
 
 func (h *UserHandler) List(c *gin.Context) {
 	pageParams, err := common.GetPaginationParams(c.Request.URL.Query())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	userResult, userCount := h.service.List(
-		pageParams.Filters, pageParams.Limit, pageParams.Offset,
+		c.Request.Context(), pageParams.Filters, pageParams.Limit, pageParams.Offset,
 	)
 
 	usersDTO := make([]ListUserDTO, 0, len(userResult))
@@ -69,28 +81,29 @@ func (h *UserHandler) List(c *gin.Context) {
 func (h *UserHandler) GetByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not valid number"})
+		c.Error(apperror.InvalidNumber("id"))
 		return
 	}
-	user, ok := h.service.GetByID(users.UserID(id))
+	user, ok := h.service.GetByID(c.Request.Context(), users.UserID(id))
 	if !ok {
-		c.Status(http.StatusNotFound)
+		c.Error(ports.UserDoesNotExists)
 		return
 	}
 
+	common.SetETag(c, user.Version)
 	c.JSON(http.StatusOK, MapToRetrieveUserDTO(user))
 }
 
 func (h *UserHandler) Login(c *gin.Context) {
 	var body LoginUserDTO
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
-	token, err := h.service.Login(body.Email, body.Password)
+	token, err := h.service.Login(c.Request.Context(), actorFromContext(c), body.Email, body.Password)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -102,157 +115,296 @@ func (h *UserHandler) Login(c *gin.Context) {
 
 func (h *UserHandler) Register(c *gin.Context) {
 	var body RegisterUserDTO
-	if err := c.BindJSON(&body); err != nil {
-		log.Println(err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	user, err := h.service.Add(
-		body.Name, body.Email, body.Password, body.Phone,
-		false, []users.ScopeName{},
-	)
-	if err != nil {
-		log.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
-	err = h.service.SendAccountVerificationCode(user)
+	user, err := h.service.Register(c.Request.Context(), body.Name, body.Email, body.Password, body.Phone)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	common.SetETag(c, user.Version)
 	c.JSON(http.StatusCreated, MapToRetrieveUserDTO(user))
 }
 
 func (h *UserHandler) ValidateAccountVerificationCode(c *gin.Context) {
 	var body ValidateVerificationCodeDTO
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not a valid number"})
+		c.Error(apperror.InvalidNumber("id"))
 		return
 	}
 
-	userWasActivated := h.service.ValidateAccountVerificationCode(users.UserID(userID), body.Code)
+	userWasActivated := h.service.ValidateAccountVerificationCode(c.Request.Context(), users.UserID(userID), body.Code)
 
 	if !userWasActivated {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		c.Error(ports.InvalidValidationCode)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"details": i18n.T(c, "user_activated")})
+}
+
+func (h *UserHandler) ResendAccountVerificationCode(c *gin.Context) {
+	var body ResendVerificationCodeDTO
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	err := h.service.ResendAccountVerificationCode(c.Request.Context(), body.Email)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"details": "User was activated successfully"})
+	c.JSON(http.StatusOK, gin.H{"details": i18n.T(c, "verification_sent")})
 }
 
 func (h *UserHandler) RequestRecoveryPassword(c *gin.Context) {
 	var body RequestRecoveryPasswordDTO
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
-	err := h.service.SendRecoveryPasswordRequest(body.Email)
+	err := h.service.SendRecoveryPasswordRequest(c.Request.Context(), body.Email)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if errors.Is(err, ports.UserDoesNotExists) {
-			status = http.StatusNotFound
-		}
-
-		c.JSON(status, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"details": "message sent successfully"})
+	c.JSON(http.StatusOK, gin.H{"details": i18n.T(c, "recovery_code_sent")})
 }
 
 func (h *UserHandler) RecoveryPassword(c *gin.Context) {
 	var body RecoveryPasswordDTO
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
-	err := h.service.RecoveryPassword(body.Email, body.NewPassword, body.Code)
+	err := h.service.RecoveryPassword(
+		c.Request.Context(), actorFromContext(c), body.Email, body.NewPassword, body.Code,
+	)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if errors.Is(err, ports.InvalidValidationCode) {
-			status = http.StatusBadRequest
-		}
-		c.JSON(status, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"details": "recovery password successfully"})
+	c.JSON(http.StatusOK, gin.H{"details": i18n.T(c, "password_recovered")})
 }
 
 func (h *UserHandler) Update(c *gin.Context) {
 	var body UpdateUserDTO
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not a valid number"})
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
 		return
 	}
 
 	user, err := h.service.Update(
-		users.UserID(userID), body.Name, body.Email, body.Phone, body.Scopes,
+		c.Request.Context(), actorFromContext(c), users.UserID(userID), version, body.Name, body.Email,
+		body.Phone, body.Scopes,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	common.SetETag(c, user.Version)
 	c.JSON(http.StatusOK, MapToRetrieveUserDTO(user))
 }
 
 func (h *UserHandler) ChangePassword(c *gin.Context) {
 	var body ChangePasswordDTO
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not a valid number"})
+		c.Error(apperror.InvalidNumber("id"))
 		return
 	}
 
-	err = h.service.ChangePassword(users.UserID(userID), body.CurrentPassword, body.NewPassword)
+	err = h.service.ChangePassword(
+		c.Request.Context(), actorFromContext(c), users.UserID(userID), body.CurrentPassword, body.NewPassword,
+	)
 	if err != nil {
-		status := http.StatusInternalServerError
-		message := err.Error()
-		if errors.Is(err, ports.InvalidCredentials) || errors.Is(err, ports.UserDoesNotExists) {
-			status = http.StatusBadRequest
-			message = "invalid credentials"
+		if errors.Is(err, ports.UserDoesNotExists) {
+			err = ports.InvalidCredentials
 		}
-		c.JSON(status, gin.H{"error": message})
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"details": i18n.T(c, "password_changed")})
+}
+
+func (h *UserHandler) Impersonate(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	token, err := h.service.Impersonate(c.Request.Context(), actorFromContext(c), users.UserID(userID))
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"details": "password changed successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token.AccessToken,
+		"expires_in":   int(services.ImpersonationTokenDuration.Seconds()),
+	})
+}
+
+// AuditImpersonatedRequests records on the audit log every request made with
+// an impersonation token, it must be registered before the routes
+func (h *UserHandler) AuditImpersonatedRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		actor := actorFromContext(c)
+		if actor.ImpersonatorID == 0 {
+			return
+		}
+
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		h.service.AuditImpersonatedRequest(c.Request.Context(), actor, c.Request.Method, path, c.Writer.Status())
+	}
+}
+
+func (h *UserHandler) UpdateAvatar(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		c.Error(apperror.InvalidField("avatar", "required", "'avatar' file is required"))
+		return
+	}
+	if fileHeader.Size > services.MaxAvatarSize {
+		c.Error(ports.AvatarTooLarge)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.Error(apperror.InvalidField("avatar", "file", "error reading 'avatar' file"))
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, services.MaxAvatarSize+1))
+	if err != nil {
+		c.Error(apperror.InvalidField("avatar", "file", "error reading 'avatar' file"))
+		return
+	}
+
+	user, err := h.service.UpdateAvatar(c.Request.Context(), actorFromContext(c), users.UserID(userID), content)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	common.SetETag(c, user.Version)
+	c.JSON(http.StatusOK, MapToRetrieveUserDTO(user))
+}
+
+func (h *UserHandler) GetPreferences(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	preferences, ok := h.service.GetPreferences(c.Request.Context(), users.UserID(userID))
+	if !ok {
+		c.Error(ports.UserDoesNotExists)
+		return
+	}
+
+	c.JSON(http.StatusOK, MapToPreferencesDTO(preferences))
+}
+
+func (h *UserHandler) UpdatePreferences(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	var body UpdatePreferencesDTO
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	channels := make([]users.NotificationChannel, 0, len(body.NotificationChannels))
+	for _, ch := range body.NotificationChannels {
+		channels = append(channels, users.NotificationChannel(ch))
+	}
+
+	preferences, err := h.service.UpdatePreferences(
+		c.Request.Context(), users.UserID(userID), users.Language(body.Language),
+		body.MarketingEmailConsent, body.MarketingSMSConsent, channels,
+	)
+	if errors.Is(err, ports.InvalidLanguage) {
+		err = apperror.AsField("language", err)
+	} else if errors.Is(err, ports.InvalidNotificationChannel) {
+		err = apperror.AsField("notification_channels", err)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MapToPreferencesDTO(preferences))
 }
 
 func (h *UserHandler) Delete(c *gin.Context) {
 	ID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not a valid number"})
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
 		return
 	}
 
-	err = h.service.Deactivate(users.UserID(ID))
+	err = h.service.Deactivate(c.Request.Context(), actorFromContext(c), users.UserID(ID), version)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -262,11 +414,11 @@ func (h *UserHandler) Delete(c *gin.Context) {
 func (h *UserHandler) ListAddresses(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not a valid number"})
+		c.Error(apperror.InvalidNumber("id"))
 		return
 	}
 
-	addresses := h.service.ListAddresses(users.UserID(userID))
+	addresses := h.service.ListAddresses(c.Request.Context(), users.UserID(userID))
 	addressesDTO := MapToListAddressesDTO(addresses)
 	c.JSON(http.StatusOK, addressesDTO)
 }
@@ -274,26 +426,26 @@ func (h *UserHandler) ListAddresses(c *gin.Context) {
 func (h *UserHandler) AddAddress(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not a valid number"})
+		c.Error(apperror.InvalidNumber("id"))
 		return
 	}
 
 	var body CreateAddressDTO
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	_, err = h.service.AddAddress(
-		users.UserID(userID), body.Department, body.City,
+		c.Request.Context(), actorFromContext(c), users.UserID(userID), body.Department, body.City,
 		body.Address, body.ReceiverPhone, body.ReceiverName,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	addresses := h.service.ListAddresses(users.UserID(userID))
+	addresses := h.service.ListAddresses(c.Request.Context(), users.UserID(userID))
 	addressesDTO := MapToListAddressesDTO(addresses)
 	c.JSON(http.StatusCreated, addressesDTO)
 }
@@ -301,36 +453,40 @@ func (h *UserHandler) AddAddress(c *gin.Context) {
 func (h *UserHandler) UpdateAddress(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not a valid number"})
+		c.Error(apperror.InvalidNumber("id"))
 		return
 	}
 
 	addressID, err := strconv.Atoi(c.Param("address_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "address id is not a valid number"})
+		c.Error(apperror.InvalidNumber("address_id"))
+		return
+	}
+
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
 		return
 	}
 
 	var body CreateAddressDTO
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
-	_, err = h.service.UpdateAddress(
-		users.AddressID(addressID), body.Department, body.City,
-		body.Address, body.ReceiverPhone, body.ReceiverName,
+	updated, err := h.service.UpdateAddress(
+		c.Request.Context(), actorFromContext(c), users.UserID(userID), users.AddressID(addressID), version,
+		body.Department, body.City, body.Address, body.ReceiverPhone, body.ReceiverName,
 	)
 	if err != nil {
-		statusError := http.StatusInternalServerError
-		if errors.Is(err, ports.AddressDoesNotExists) {
-			statusError = http.StatusNotFound
-		}
-		c.JSON(statusError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	addresses := h.service.ListAddresses(users.UserID(userID))
+	// the body lists every address, the ETag is the one of the updated address
+	common.SetETag(c, updated.Version)
+
+	addresses := h.service.ListAddresses(c.Request.Context(), users.UserID(userID))
 	addressesDTO := MapToListAddressesDTO(addresses)
 	c.JSON(http.StatusOK, addressesDTO)
 }
@@ -338,27 +494,92 @@ func (h *UserHandler) UpdateAddress(c *gin.Context) {
 func (h *UserHandler) DeleteAddress(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id is not a valid number"})
+		c.Error(apperror.InvalidNumber("id"))
 		return
 	}
 
 	addressID, err := strconv.Atoi(c.Param("address_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "address id is not a valid number"})
+		c.Error(apperror.InvalidNumber("address_id"))
 		return
 	}
 
-	err = h.service.DeleteAddress(users.AddressID(addressID))
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	err = h.service.DeleteAddress(
+		c.Request.Context(), actorFromContext(c), users.UserID(userID), users.AddressID(addressID), version,
+	)
 	if err != nil {
-		statusError := http.StatusInternalServerError
-		if errors.Is(err, ports.AddressDoesNotExists) {
-			statusError = http.StatusNotFound
-		}
-		c.JSON(statusError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	addresses := h.service.ListAddresses(users.UserID(userID))
+	addresses := h.service.ListAddresses(c.Request.Context(), users.UserID(userID))
 	addressesDTO := MapToListAddressesDTO(addresses)
 	c.JSON(http.StatusOK, addressesDTO)
 }
+
+func (h *UserHandler) SetDefaultAddress(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("id"))
+		return
+	}
+
+	addressID, err := strconv.Atoi(c.Param("address_id"))
+	if err != nil {
+		c.Error(apperror.InvalidNumber("address_id"))
+		return
+	}
+
+	version, ok := common.RequireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	address, err := h.service.SetDefaultAddress(
+		c.Request.Context(), actorFromContext(c), users.UserID(userID), users.AddressID(addressID), version,
+	)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	common.SetETag(c, address.Version)
+
+	addresses := h.service.ListAddresses(c.Request.Context(), users.UserID(userID))
+	addressesDTO := MapToListAddressesDTO(addresses)
+	c.JSON(http.StatusOK, addressesDTO)
+}
+
+func (h *UserHandler) ListAuditEvents(c *gin.Context) {
+	pageParams, err := common.GetPaginationParams(c.Request.URL.Query())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	for _, dateFilter := range []string{"from", "to"} {
+		if value, ok := pageParams.Filters[dateFilter]; ok {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				c.Error(apperror.InvalidField(dateFilter, "rfc3339", "'"+dateFilter+"' must be a RFC3339 date"))
+				return
+			}
+		}
+	}
+
+	events, count := h.service.ListAuditEvents(c.Request.Context(), pageParams.Filters, pageParams.Limit, pageParams.Offset)
+
+	eventsDTO := make([]AuditEventDTO, 0, len(events))
+	for _, e := range events {
+		eventsDTO = append(eventsDTO, MapToAuditEventDTO(e))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pagination": common.PaginationJson(count, pageParams.Page, pageParams.PageSize),
+		"result":     eventsDTO,
+	})
+}
@@ -2,15 +2,22 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/CrissAlvarezH/fundart-api/internal/common"
+	"github.com/CrissAlvarezH/fundart-api/internal/metrics"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/application/services"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/handler"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/memoryrepo"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/notifications"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/storage"
 	"github.com/gin-gonic/gin"
+	"image"
+	"image/png"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -22,7 +29,7 @@ func createMockUserService(
 	userData []memoryrepo.MemoryUser, addressData []memoryrepo.MemoryAddress,
 ) (
 	services.UserService, notifications.MockVerificationCodeManager,
-	memoryrepo.MemoryUserRepository, infrastructure.MockJWTManager,
+	*memoryrepo.MemoryUserRepository, infrastructure.MockJWTManager,
 ) {
 	userMemoRepo := memoryrepo.NewMemoryUserRepository(userData)
 
@@ -30,12 +37,16 @@ func createMockUserService(
 	mockPassManager := infrastructure.NewMockPasswordManager()
 	mockVerifyCode := notifications.NewMockVerificationCodeManager()
 	mockJWTManager := infrastructure.NewMockJWTManager(userMemoRepo)
+	mockAvatarStorage := storage.NewMockAvatarStorage()
+
+	auditMemoRepo := memoryrepo.NewMemoryAuditLogRepository(make([]users.AuditEvent, 0))
 
 	userService := services.NewUserService(
 		userMemoRepo, addressMemoRepo, mockVerifyCode,
-		mockPassManager, mockJWTManager,
+		mockPassManager, mockJWTManager, mockAvatarStorage, auditMemoRepo,
+		memoryrepo.NewMemoryUnitOfWork(userMemoRepo, addressMemoRepo, auditMemoRepo), metrics.New(),
 	)
-	return userService, *mockVerifyCode, *userMemoRepo, *mockJWTManager
+	return userService, *mockVerifyCode, userMemoRepo, *mockJWTManager
 }
 
 func TestUserHandler_List(t *testing.T) {
@@ -80,7 +91,7 @@ func TestUserHandler_List(t *testing.T) {
 
 	router := gin.New()
 	router.Use(common.Auth(&jwt))
-	apiV1Routes := router.Group("/api/v1")
+	apiV1Routes := router.Group("/api/v1", common.Problems())
 	userHandler.AddRoutes(apiV1Routes)
 
 	// TESTING PAGINATION DATA
@@ -173,7 +184,7 @@ func TestUserHandler_GetByID(t *testing.T) {
 
 	router := gin.New()
 	router.Use(common.Auth(&jwt))
-	apiV1Routes := router.Group("/api/v1")
+	apiV1Routes := router.Group("/api/v1", common.Problems())
 	userHandler.AddRoutes(apiV1Routes)
 
 	// TESTING HAPPY PATH
@@ -208,6 +219,10 @@ func TestUserHandler_GetByID(t *testing.T) {
 	if w.Code != http.StatusNotFound {
 		t.Error("incorrect status code:", w.Code, "expected not found")
 	}
+	var problem common.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.Code != "user_not_found" {
+		t.Error("expected the user_not_found problem, body:", w.Body.String())
+	}
 }
 
 func TestUserHandler_Login(t *testing.T) {
@@ -228,7 +243,7 @@ func TestUserHandler_Login(t *testing.T) {
 
 	router := gin.New()
 	router.Use(common.Auth(&jwt))
-	apiV1Routes := router.Group("/api/v1")
+	apiV1Routes := router.Group("/api/v1", common.Problems())
 	userHandler.AddRoutes(apiV1Routes)
 
 	// TEST HAPPY PATH
@@ -266,6 +281,70 @@ func TestUserHandler_Login(t *testing.T) {
 	}
 }
 
+func TestUserHandler_Register_EmailFailure(t *testing.T) {
+	userRepo := memoryrepo.NewMemoryUserRepository(nil)
+	addressRepo := memoryrepo.NewMemoryAddressRepository(nil)
+	auditRepo := memoryrepo.NewMemoryAuditLogRepository(nil)
+	verifyCode := notifications.NewMockVerificationCodeManager()
+	jwt := infrastructure.NewMockJWTManager(userRepo)
+	service := services.NewUserService(
+		userRepo, addressRepo, verifyCode, infrastructure.NewMockPasswordManager(), jwt,
+		storage.NewMockAvatarStorage(), auditRepo,
+		memoryrepo.NewMemoryUnitOfWork(userRepo, addressRepo, auditRepo), metrics.New(),
+	)
+
+	router := gin.New()
+	router.Use(common.Auth(jwt))
+	userHandler := handler.NewUserHandler(service)
+	userHandler.AddRoutes(router.Group("/api/v1", common.Problems()))
+
+	post := func(path string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1"+path, bytes.NewReader([]byte(body)))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	resend := func() int {
+		return post("/users/verification-code/request", `{"email": "juan@email.com"}`).Code
+	}
+
+	// the user is kept inactive when the email fails, so it isn't lost
+	verifyCode.Err = errors.New("smtp server unavailable")
+	w := post("/users", `{"name": "Juan", "email": "juan@email.com", "phone": "3207846634", "password": "333333"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatal("register with failing email response code:", w.Code, "expected:", http.StatusCreated)
+	}
+	user, ok := userRepo.GetPendingByEmail(context.Background(), "juan@email.com")
+	if !ok {
+		t.Fatal("user was not kept inactive after the verification email failed:", user)
+	}
+	if code := resend(); code != http.StatusInternalServerError {
+		t.Error("resend with failing email response code:", code, "expected:", http.StatusInternalServerError)
+	}
+
+	// a new code can be requested once the email works again
+	verifyCode.Err = nil
+	if code := resend(); code != http.StatusOK {
+		t.Fatal("resend response code:", code, "expected:", http.StatusOK)
+	}
+	code, ok := verifyCode.AccountCodes["juan@email.com"]
+	if !ok {
+		t.Fatal("verification code was not sent")
+	}
+	path := "/users/" + strconv.Itoa(int(user.ID)) + "/verification-code/"
+	if w := post(path, `{"code": "`+code+`"}`); w.Code != http.StatusOK {
+		t.Fatal("verify response code:", w.Code, "expected:", http.StatusOK, w.Body.String())
+	}
+
+	// active and unknown users can't ask for a code
+	if code := resend(); code != http.StatusConflict {
+		t.Error("resend to active user response code:", code, "expected:", http.StatusConflict)
+	}
+	if w := post("/users/verification-code/request", `{"email": "nobody@email.com"}`); w.Code != http.StatusNotFound {
+		t.Error("resend to unknown user response code:", w.Code, "expected:", http.StatusNotFound)
+	}
+}
+
 func TestUserHandler_Register_And_VerifyAccount(t *testing.T) {
 	service, verifyCodeManager, _, jwt := createMockUserService(
 		make([]memoryrepo.MemoryUser, 0), make([]memoryrepo.MemoryAddress, 0),
@@ -274,7 +353,7 @@ func TestUserHandler_Register_And_VerifyAccount(t *testing.T) {
 
 	router := gin.New()
 	router.Use(common.Auth(&jwt))
-	apiV1Routes := router.Group("/api/v1")
+	apiV1Routes := router.Group("/api/v1", common.Problems())
 	userHandler.AddRoutes(apiV1Routes)
 
 	reqBodyRaw := `
@@ -346,7 +425,7 @@ func TestUserHandler_Request_And_RecoveryPassword(t *testing.T) {
 
 	router := gin.New()
 	router.Use(common.Auth(&jwt))
-	apiV1Routes := router.Group("/api/v1")
+	apiV1Routes := router.Group("/api/v1", common.Problems())
 	userHandler.AddRoutes(apiV1Routes)
 
 	// REQUEST RECOVERY PASSWORD
@@ -426,7 +505,7 @@ func TestUserHandler_Update(t *testing.T) {
 
 	router := gin.New()
 	router.Use(common.Auth(&jwt))
-	apiV1Routes := router.Group("/api/v1")
+	apiV1Routes := router.Group("/api/v1", common.Problems())
 	userHandler.AddRoutes(apiV1Routes)
 
 	// UPDATE USER
@@ -443,6 +522,7 @@ func TestUserHandler_Update(t *testing.T) {
 		reqBody,
 	)
 	req.Header.Set("authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+	req.Header.Set("If-Match", "*")
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -453,7 +533,7 @@ func TestUserHandler_Update(t *testing.T) {
 	}
 
 	// VALIDATE CHANGES IN USER REPOSITORY
-	userInRepo := userRepo.Users[0]
+	userInRepo := userRepo.All()[0]
 	if userInRepo.Email != newEmail {
 		t.Error("email updated incorrect:", userInRepo.Email, "expected:", newEmail)
 	}
@@ -462,6 +542,70 @@ func TestUserHandler_Update(t *testing.T) {
 	}
 }
 
+func TestUserHandler_Update_Scopes(t *testing.T) {
+	customer := memoryrepo.MemoryUser{
+		ID: 1, Name: "Cristian", Email: "cristian@email.com", Password: "23456_encrypt", IsActive: true,
+	}
+	admin := memoryrepo.MemoryUser{
+		ID: 2, Name: "Admin", Email: "admin@email.com", Password: "23456_encrypt", IsActive: true,
+		Scopes: []users.ScopeName{users.USERS_WRITE},
+	}
+	userService, _, userRepo, jwt := createMockUserService(
+		[]memoryrepo.MemoryUser{customer, admin}, make([]memoryrepo.MemoryAddress, 0),
+	)
+	userHandler := handler.NewUserHandler(userService)
+
+	router := gin.New()
+	router.Use(common.Auth(&jwt))
+	apiV1Routes := router.Group("/api/v1", common.Problems())
+	userHandler.AddRoutes(apiV1Routes)
+
+	update := func(token string, scopes string) *httptest.ResponseRecorder {
+		reqBody := bytes.NewReader([]byte(`{
+			"name": "Cristian",
+			"email": "cristian@email.com",
+			"scopes": ` + scopes + `
+		}`))
+		req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/"+strconv.Itoa(int(customer.ID))+"/", reqBody)
+		req.Header.Set("authorization", "Bearer "+token+"___jwt") // mock jwt
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	scopesOf := func(ID users.UserID) []users.ScopeName {
+		user, _ := userRepo.GetByID(context.Background(), ID)
+		return user.Scopes
+	}
+
+	// the user can't give itself scopes by updating its profile
+	w := update(customer.Email, `["users:impersonate", "audit:read"]`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("self escalation: expected status 403, got %d: %s", w.Code, w.Body)
+	}
+	if scopes := scopesOf(customer.ID); len(scopes) != 0 {
+		t.Errorf("the user got the scopes %v", scopes)
+	}
+
+	// but it can still update its profile when the scopes don't change
+	if w := update(customer.Email, `[]`); w.Code != http.StatusOK {
+		t.Errorf("update without scopes: expected status 200, got %d: %s", w.Code, w.Body)
+	}
+
+	w = update(admin.Email, `["audit:read"]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin: expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if scopes := scopesOf(customer.ID); len(scopes) != 1 || scopes[0] != users.AUDIT_READ {
+		t.Errorf("expected the scopes given by the admin, got %v", scopes)
+	}
+
+	// nor remove them once it has them
+	if w := update(customer.Email, `[]`); w.Code != http.StatusForbidden {
+		t.Errorf("remove scopes: expected status 403, got %d: %s", w.Code, w.Body)
+	}
+}
+
 func TestUserHandler_ChangePassword(t *testing.T) {
 	cristianUser := memoryrepo.MemoryUser{
 		ID:        1,
@@ -480,7 +624,7 @@ func TestUserHandler_ChangePassword(t *testing.T) {
 
 	router := gin.New()
 	router.Use(common.Auth(&jwt))
-	apiV1Routes := router.Group("/api/v1")
+	apiV1Routes := router.Group("/api/v1", common.Problems())
 	userHandler.AddRoutes(apiV1Routes)
 
 	// UPDATE USER
@@ -550,11 +694,12 @@ func TestUserHandler_Delete(t *testing.T) {
 
 	router := gin.New()
 	router.Use(common.Auth(&jwt))
-	apiV1Routes := router.Group("/api/v1")
+	apiV1Routes := router.Group("/api/v1", common.Problems())
 	userHandler.AddRoutes(apiV1Routes)
 
 	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/users/"+strconv.Itoa(int(cristianUser.ID))+"/", nil)
 	req.Header.Set("authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+	req.Header.Set("If-Match", "*")
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -563,14 +708,96 @@ func TestUserHandler_Delete(t *testing.T) {
 		return
 	}
 
-	userInRepo := userRepo.Users[0]
+	userInRepo := userRepo.All()[0]
 	if userInRepo.IsActive {
 		t.Error("user was not deleted")
-		t.Log("users in repo:", userRepo.Users)
+		t.Log("users in repo:", userRepo.All())
+	}
+}
+
+func TestUserHandler_Preconditions(t *testing.T) {
+	ctx := context.Background()
+	cristianUser := memoryrepo.MemoryUser{
+		ID:       1,
+		Name:     "Cristian",
+		Email:    "cristian@email.com",
+		Password: "23456_encrypt",
+		IsActive: true,
+	}
+	addresses := []memoryrepo.MemoryAddress{
+		{ID: 1, Department: "cordoba", City: "monteria", Address: "cr 1", IsDefault: true, UserID: 1},
+	}
+	userService, _, _, jwt := createMockUserService([]memoryrepo.MemoryUser{cristianUser}, addresses)
+	userHandler := handler.NewUserHandler(userService)
+
+	router := gin.New()
+	router.Use(common.Auth(&jwt))
+	apiV1Routes := router.Group("/api/v1", common.Problems())
+	userHandler.AddRoutes(apiV1Routes)
+
+	send := func(method string, path string, ifMatch string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	userBody := `{"name": "Cristian Alvarez", "email": "cristian@email.com", "scopes": []}`
+
+	w := send(http.MethodGet, "/api/v1/users/1", "", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag != `"1"` {
+		t.Fatal("get user status code:", w.Code, "etag:", etag)
+	}
+
+	if w := send(http.MethodPut, "/api/v1/users/1/", "", userBody); w.Code != http.StatusPreconditionRequired {
+		t.Error("update without If-Match status code:", w.Code, "expected:", http.StatusPreconditionRequired)
+	}
+	if w := send(http.MethodPut, "/api/v1/users/1/", "W/"+etag, userBody); w.Code != http.StatusBadRequest {
+		t.Error("update with a weak ETag status code:", w.Code, "expected:", http.StatusBadRequest)
+	}
+
+	w = send(http.MethodPut, "/api/v1/users/1/", etag, userBody)
+	if w.Code != http.StatusOK {
+		t.Fatal("update with the current ETag status code:", w.Code, "body:", w.Body.String())
+	}
+	if newETag := w.Header().Get("ETag"); newETag != `"2"` {
+		t.Error("update ETag:", newETag, `expected: "2"`)
+	}
+
+	// the first ETag is stale after the update
+	if w := send(http.MethodPut, "/api/v1/users/1/", etag, userBody); w.Code != http.StatusPreconditionFailed {
+		t.Error("update with a stale ETag status code:", w.Code, "expected:", http.StatusPreconditionFailed)
+	}
+	if user, _ := userService.GetByID(ctx, 1); user.Name != "Cristian Alvarez" || user.Version != 2 {
+		t.Error("stale update was applied, user:", user.Name, "version:", user.Version)
+	}
+
+	addressBody := `{"department": "antioquia", "city": "medellin", "address": "cr 2"}`
+	if w := send(http.MethodPut, "/api/v1/users/1/addresses/1/", "", addressBody); w.Code != http.StatusPreconditionRequired {
+		t.Error("update address without If-Match status code:", w.Code, "expected:", http.StatusPreconditionRequired)
+	}
+	w = send(http.MethodPut, "/api/v1/users/1/addresses/1/", `"1"`, addressBody)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Error("update address status code:", w.Code, "etag:", w.Header().Get("ETag"))
+	}
+	if w := send(http.MethodDelete, "/api/v1/users/1/addresses/1/", `"1"`, ""); w.Code != http.StatusPreconditionFailed {
+		t.Error("delete address with a stale ETag status code:", w.Code, "expected:", http.StatusPreconditionFailed)
+	}
+
+	if w := send(http.MethodDelete, "/api/v1/users/1/", etag, ""); w.Code != http.StatusPreconditionFailed {
+		t.Error("delete user with a stale ETag status code:", w.Code, "expected:", http.StatusPreconditionFailed)
+	}
+	if w := send(http.MethodDelete, "/api/v1/users/1/", `"2"`, ""); w.Code != http.StatusNoContent {
+		t.Error("delete user with the current ETag status code:", w.Code, "expected:", http.StatusNoContent)
 	}
 }
 
 func TestUserHandler_List_Add_Update_And_DeleteAddress(t *testing.T) {
+	ctx := context.Background()
 	cristianUser := memoryrepo.MemoryUser{
 		ID:        1,
 		Name:      "Cristian",
@@ -589,13 +816,13 @@ func TestUserHandler_List_Add_Update_And_DeleteAddress(t *testing.T) {
 
 	router := gin.New()
 	router.Use(common.Auth(&jwt))
-	apiV1Routes := router.Group("/api/v1")
+	apiV1Routes := router.Group("/api/v1", common.Problems())
 	userHandler.AddRoutes(apiV1Routes)
 
 	// CHECK THERE AREN'T ANY ADDRESS
-	if len(userService.ListAddresses(cristianUser.ID)) != 0 {
+	if len(userService.ListAddresses(ctx, cristianUser.ID)) != 0 {
 		t.Error("address must be empty")
-		t.Log("addresses:", userService.ListAddresses(cristianUser.ID))
+		t.Log("addresses:", userService.ListAddresses(ctx, cristianUser.ID))
 		return
 	}
 
@@ -619,10 +846,10 @@ func TestUserHandler_List_Add_Update_And_DeleteAddress(t *testing.T) {
 		t.Log("body:", w.Body.String())
 		return
 	}
-	firstAddress := userService.ListAddresses(cristianUser.ID)[0]
-	if len(userService.ListAddresses(cristianUser.ID)) != 1 {
+	firstAddress := userService.ListAddresses(ctx, cristianUser.ID)[0]
+	if len(userService.ListAddresses(ctx, cristianUser.ID)) != 1 {
 		t.Error("address must one address")
-		t.Log("addresses:", userService.ListAddresses(cristianUser.ID))
+		t.Log("addresses:", userService.ListAddresses(ctx, cristianUser.ID))
 		return
 	}
 
@@ -643,6 +870,7 @@ func TestUserHandler_List_Add_Update_And_DeleteAddress(t *testing.T) {
 		reqBody,
 	)
 	req.Header.Set("authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+	req.Header.Set("If-Match", "*")
 	w = httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -651,7 +879,7 @@ func TestUserHandler_List_Add_Update_And_DeleteAddress(t *testing.T) {
 		t.Log("body:", w.Body.String())
 		return
 	}
-	firstAddress = userService.ListAddresses(cristianUser.ID)[0]
+	firstAddress = userService.ListAddresses(ctx, cristianUser.ID)[0]
 	if firstAddress.Department != newDep || firstAddress.Address != newAddress {
 		t.Error("address was not updated, id:", firstAddressID)
 		t.Log("res body:", w.Body.String())
@@ -678,9 +906,9 @@ func TestUserHandler_List_Add_Update_And_DeleteAddress(t *testing.T) {
 		t.Log("body:", w.Body.String())
 		return
 	}
-	if len(userService.ListAddresses(cristianUser.ID)) != 2 {
+	if len(userService.ListAddresses(ctx, cristianUser.ID)) != 2 {
 		t.Error("address must one address")
-		t.Log("addresses:", userService.ListAddresses(cristianUser.ID))
+		t.Log("addresses:", userService.ListAddresses(ctx, cristianUser.ID))
 		return
 	}
 
@@ -710,3 +938,568 @@ func TestUserHandler_List_Add_Update_And_DeleteAddress(t *testing.T) {
 		return
 	}
 }
+
+func TestUserHandler_Address_Ownership_And_Default(t *testing.T) {
+	ctx := context.Background()
+	cristianUser := memoryrepo.MemoryUser{
+		ID:        1,
+		Name:      "Cristian",
+		Email:     "cristian@email.com",
+		Password:  "23456_encrypt",
+		Phone:     "320684398",
+		IsActive:  true,
+		CreatedAt: time.Time{},
+		Addresses: nil,
+		Scopes:    nil,
+	}
+	joseUser := memoryrepo.MemoryUser{
+		ID:        2,
+		Name:      "Jose",
+		Email:     "jose@email.com",
+		Password:  "11111_encrypt",
+		Phone:     "3203454398",
+		IsActive:  true,
+		CreatedAt: time.Time{},
+		Addresses: nil,
+		Scopes:    nil,
+	}
+	addresses := []memoryrepo.MemoryAddress{
+		{ID: 1, Department: "cordoba", City: "monteria", Address: "cll 1", IsDefault: true, UserID: cristianUser.ID},
+		{ID: 2, Department: "cordoba", City: "monteria", Address: "cll 2", IsDefault: false, UserID: cristianUser.ID},
+		{ID: 3, Department: "antioquia", City: "medellin", Address: "cll 3", IsDefault: true, UserID: joseUser.ID},
+	}
+	userData := []memoryrepo.MemoryUser{cristianUser, joseUser}
+	userService, _, _, jwt := createMockUserService(userData, addresses)
+	userHandler := handler.NewUserHandler(userService)
+
+	router := gin.New()
+	router.Use(common.Auth(&jwt))
+	apiV1Routes := router.Group("/api/v1", common.Problems())
+	userHandler.AddRoutes(apiV1Routes)
+
+	// UPDATE AN ADDRESS OF ANOTHER USER
+	reqBody := bytes.NewReader([]byte(`{
+		"department": "hacked",
+		"city": "hacked",
+		"address": "hacked",
+		"receiver_name": "hacked",
+		"receiver_phone": "hacked"
+	}`))
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/2/addresses/1/", reqBody)
+	req.Header.Set("Authorization", "Bearer "+joseUser.Email+"___jwt") // mock jwt
+	req.Header.Set("If-Match", "*")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Error("update address of another user status code:", w.Code, "expected:", http.StatusNotFound)
+		return
+	}
+	if userService.ListAddresses(ctx, cristianUser.ID)[0].Department != "cordoba" {
+		t.Error("address of another user was updated")
+		return
+	}
+
+	// DELETE AN ADDRESS OF ANOTHER USER
+	req, _ = http.NewRequest(http.MethodDelete, "/api/v1/users/2/addresses/2/", nil)
+	req.Header.Set("Authorization", "Bearer "+joseUser.Email+"___jwt") // mock jwt
+	req.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Error("delete address of another user status code:", w.Code, "expected:", http.StatusNotFound)
+		return
+	}
+	if len(userService.ListAddresses(ctx, cristianUser.ID)) != 2 {
+		t.Error("address of another user was deleted")
+		return
+	}
+
+	// SET DEFAULT ADDRESS
+	req, _ = http.NewRequest(http.MethodPut, "/api/v1/users/1/addresses/2/default/", nil)
+	req.Header.Set("Authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+	req.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Error("set default address status code:", w.Code, "expected:", http.StatusOK)
+		t.Log("body:", w.Body.String())
+		return
+	}
+	defaultAddress, ok := userService.GetDefaultAddress(ctx, cristianUser.ID)
+	if !ok || defaultAddress.ID != 2 {
+		t.Error("incorrect default address:", defaultAddress.ID, "expected: 2")
+		return
+	}
+	for _, a := range userService.ListAddresses(ctx, cristianUser.ID) {
+		if a.ID != 2 && a.IsDefault {
+			t.Error("more than one default address, id:", a.ID)
+		}
+	}
+
+	// SET DEFAULT ADDRESS OF ANOTHER USER
+	req, _ = http.NewRequest(http.MethodPut, "/api/v1/users/1/addresses/3/default/", nil)
+	req.Header.Set("Authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+	req.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Error("set default address of another user status code:", w.Code, "expected:", http.StatusNotFound)
+	}
+}
+
+func TestUserHandler_UpdateAvatar(t *testing.T) {
+	cristianUser := memoryrepo.MemoryUser{
+		ID:        1,
+		Name:      "Cristian",
+		Email:     "cristian@email.com",
+		Password:  "23456_encrypt",
+		Phone:     "320684398",
+		IsActive:  true,
+		CreatedAt: time.Time{},
+		Addresses: nil,
+		Scopes:    nil,
+	}
+	userData := []memoryrepo.MemoryUser{cristianUser}
+	userService, _, userRepo, jwt := createMockUserService(userData, make([]memoryrepo.MemoryAddress, 0))
+	userHandler := handler.NewUserHandler(userService)
+
+	router := gin.New()
+	router.Use(common.Auth(&jwt))
+	apiV1Routes := router.Group("/api/v1", common.Problems())
+	userHandler.AddRoutes(apiV1Routes)
+
+	avatarRequest := func(content []byte) *http.Request {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("avatar", "avatar.png")
+		part.Write(content)
+		writer.Close()
+
+		req, _ := http.NewRequest(
+			http.MethodPut, "/api/v1/users/"+strconv.Itoa(int(cristianUser.ID))+"/avatar/", body,
+		)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+		return req
+	}
+
+	// UPLOAD A FILE THAT IS NOT AN IMAGE
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, avatarRequest([]byte("this is not an image")))
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Error("upload invalid avatar status code:", w.Code, "expected:", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// UPLOAD A VALID IMAGE
+	img := &bytes.Buffer{}
+	png.Encode(img, image.NewRGBA(image.Rect(0, 0, 20, 10)))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, avatarRequest(img.Bytes()))
+
+	if w.Code != http.StatusOK {
+		t.Error("upload avatar status code:", w.Code, "expected:", http.StatusOK)
+		t.Log("body:", w.Body.String())
+		return
+	}
+
+	resBody := make(map[string]interface{})
+	if err := json.Unmarshal(w.Body.Bytes(), &resBody); err != nil {
+		t.Error("error parsing upload avatar response:", err)
+		return
+	}
+	if resBody["avatar_url"] == "" || resBody["avatar_url"] != userRepo.All()[0].AvatarURL {
+		t.Error("incorrect avatar url:", resBody["avatar_url"], "expected:", userRepo.All()[0].AvatarURL)
+	}
+}
+
+func TestUserHandler_Update_And_GetPreferences(t *testing.T) {
+	ctx := context.Background()
+	cristianUser := memoryrepo.MemoryUser{
+		ID:        1,
+		Name:      "Cristian",
+		Email:     "cristian@email.com",
+		Password:  "23456_encrypt",
+		Phone:     "320684398",
+		IsActive:  true,
+		CreatedAt: time.Time{},
+		Addresses: nil,
+		Scopes:    nil,
+	}
+	userData := []memoryrepo.MemoryUser{cristianUser}
+	userService, verifyCodeManager, _, jwt := createMockUserService(userData, make([]memoryrepo.MemoryAddress, 0))
+	userHandler := handler.NewUserHandler(userService)
+
+	router := gin.New()
+	router.Use(common.Auth(&jwt))
+	apiV1Routes := router.Group("/api/v1", common.Problems())
+	userHandler.AddRoutes(apiV1Routes)
+
+	// UPDATE PREFERENCES
+	reqBody := bytes.NewReader([]byte(`{
+		"language": "en",
+		"marketing_email_consent": true,
+		"marketing_sms_consent": false,
+		"notification_channels": ["email", "sms"]
+	}`))
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/1/preferences/", reqBody)
+	req.Header.Set("Authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Error("update preferences status code:", w.Code, "expected:", http.StatusOK)
+		t.Log("body:", w.Body.String())
+		return
+	}
+
+	// GET PREFERENCES
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/users/1/preferences", nil)
+	req.Header.Set("Authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Error("get preferences status code:", w.Code, "expected:", http.StatusOK)
+		return
+	}
+
+	resBody := make(map[string]interface{})
+	if err := json.Unmarshal(w.Body.Bytes(), &resBody); err != nil {
+		t.Error("error parsing preferences response:", err)
+		return
+	}
+	if resBody["language"] != "en" {
+		t.Error("incorrect language:", resBody["language"], "expected: en")
+	}
+	if resBody["marketing_email_consent"] != true || resBody["marketing_email_consent_at"] == nil {
+		t.Error("marketing email consent was not registered, body:", w.Body.String())
+	}
+	if len(resBody["notification_channels"].([]interface{})) != 2 {
+		t.Error("incorrect notification channels:", resBody["notification_channels"])
+	}
+
+	// INVALID LANGUAGE
+	reqBody = bytes.NewReader([]byte(`{"language": "fr", "notification_channels": ["email"]}`))
+	req, _ = http.NewRequest(http.MethodPut, "/api/v1/users/1/preferences/", reqBody)
+	req.Header.Set("Authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("invalid language status code:", w.Code, "expected:", http.StatusBadRequest)
+	}
+
+	// MESSAGES ARE SENT IN THE USER LANGUAGE
+	if err := userService.SendRecoveryPasswordRequest(ctx, cristianUser.Email); err != nil {
+		t.Error("error sending recovery password request:", err)
+		return
+	}
+	expected := "Your password recovery code is: " + verifyCodeManager.PassCodes[cristianUser.Email]
+	if verifyCodeManager.Messages[cristianUser.Email] != expected {
+		t.Error("incorrect message:", verifyCodeManager.Messages[cristianUser.Email], "expected:", expected)
+	}
+}
+
+func TestUserHandler_ListAuditEvents(t *testing.T) {
+	cristianUser := memoryrepo.MemoryUser{
+		ID:        1,
+		Name:      "Cristian",
+		Email:     "cristian@email.com",
+		Password:  "23456_encrypt",
+		Phone:     "320684398",
+		IsActive:  true,
+		CreatedAt: time.Time{},
+		Addresses: nil,
+		Scopes:    nil,
+	}
+	adminUser := memoryrepo.MemoryUser{
+		ID:        2,
+		Name:      "Admin",
+		Email:     "admin@email.com",
+		Password:  "11111_encrypt",
+		Phone:     "3203454398",
+		IsActive:  true,
+		CreatedAt: time.Time{},
+		Addresses: nil,
+		Scopes:    []users.ScopeName{users.USERS_WRITE, users.AUDIT_READ},
+	}
+	userData := []memoryrepo.MemoryUser{cristianUser, adminUser}
+	addresses := []memoryrepo.MemoryAddress{
+		{ID: 1, Department: "cordoba", City: "monteria", Address: "cll 1", IsDefault: true, UserID: cristianUser.ID},
+		{ID: 2, Department: "cordoba", City: "monteria", Address: "cll 2", UserID: cristianUser.ID},
+	}
+	userService, _, _, jwt := createMockUserService(userData, addresses)
+	userHandler := handler.NewUserHandler(userService)
+
+	router := gin.New()
+	router.Use(common.Auth(&jwt))
+	apiV1Routes := router.Group("/api/v1", common.Problems())
+	userHandler.AddRoutes(apiV1Routes)
+
+	// FAILED AND SUCCESSFUL LOGIN
+	for _, password := range []string{"wrong_pass", "23456"} {
+		reqBody := bytes.NewReader([]byte(`{"email": "cristian@email.com", "password": "` + password + `"}`))
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/users/login", reqBody)
+		req.Header.Set("User-Agent", "test-agent")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+	}
+
+	// SCOPES CHANGED BY THE ADMIN
+	reqBody := bytes.NewReader([]byte(`{
+		"name": "Cristian",
+		"email": "cristian@email.com",
+		"scopes": ["users:read"]
+	}`))
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/1/", reqBody)
+	req.Header.Set("Authorization", "Bearer "+adminUser.Email+"___jwt") // mock jwt
+	req.Header.Set("If-Match", "*")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Error("update scopes status code:", w.Code, "expected:", http.StatusOK)
+		return
+	}
+
+	// AVATAR UPDATED BY THE ADMIN
+	img := &bytes.Buffer{}
+	png.Encode(img, image.NewRGBA(image.Rect(0, 0, 20, 10)))
+	avatarBody := &bytes.Buffer{}
+	writer := multipart.NewWriter(avatarBody)
+	part, _ := writer.CreateFormFile("avatar", "avatar.png")
+	part.Write(img.Bytes())
+	writer.Close()
+	req, _ = http.NewRequest(http.MethodPut, "/api/v1/users/1/avatar/", avatarBody)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+adminUser.Email+"___jwt") // mock jwt
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Error("update avatar status code:", w.Code, "expected:", http.StatusOK)
+		return
+	}
+
+	// DEFAULT ADDRESS SET
+	req, _ = http.NewRequest(http.MethodPut, "/api/v1/users/1/addresses/2/default/", nil)
+	req.Header.Set("Authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+	req.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Error("set default address status code:", w.Code, "expected:", http.StatusOK)
+		return
+	}
+
+	// LIST WITHOUT PERMISSIONS
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/audit-events", nil)
+	req.Header.Set("Authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("list audit events without scope status code:", w.Code, "expected:", http.StatusForbidden)
+		return
+	}
+
+	// LIST FILTERED BY TYPE
+	expectedCounts := map[users.AuditEventType]int{
+		users.AuditLoginFailed:       1,
+		users.AuditLoginSucceeded:    1,
+		users.AuditScopesChanged:     1,
+		users.AuditAvatarUpdated:     1,
+		users.AuditDefaultAddressSet: 1,
+	}
+	for eventType, expected := range expectedCounts {
+		req, _ = http.NewRequest(http.MethodGet, "/api/v1/audit-events?type="+string(eventType), nil)
+		req.Header.Set("Authorization", "Bearer "+adminUser.Email+"___jwt") // mock jwt
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Error("list audit events status code:", w.Code, "expected:", http.StatusOK)
+			return
+		}
+
+		resBody := make(map[string]interface{})
+		if err := json.Unmarshal(w.Body.Bytes(), &resBody); err != nil {
+			t.Error("error parsing audit events response:", err)
+			return
+		}
+		result := resBody["result"].([]interface{})
+		if len(result) != expected {
+			t.Error("incorrect amount of", eventType, "events:", len(result), "expected:", expected)
+			continue
+		}
+
+		event := result[0].(map[string]interface{})
+		if event["target_id"] != float64(cristianUser.ID) {
+			t.Error("incorrect target of", eventType, "event:", event["target_id"])
+		}
+		if eventType == users.AuditScopesChanged && event["actor_id"] != float64(adminUser.ID) {
+			t.Error("incorrect actor of scopes changed event:", event["actor_id"])
+		}
+		if eventType == users.AuditAvatarUpdated && event["actor_id"] != float64(adminUser.ID) {
+			t.Error("incorrect actor of avatar updated event:", event["actor_id"])
+		}
+		if eventType == users.AuditDefaultAddressSet && event["details"].(map[string]interface{})["address_id"] != "2" {
+			t.Error("incorrect details of default address set event:", event["details"])
+		}
+		if eventType == users.AuditLoginFailed && event["user_agent"] != "test-agent" {
+			t.Error("incorrect user agent of login failed event:", event["user_agent"])
+		}
+	}
+}
+
+func TestUserHandler_Impersonate(t *testing.T) {
+	ctx := context.Background()
+	cristianUser := memoryrepo.MemoryUser{
+		ID:        1,
+		Name:      "Cristian",
+		Email:     "cristian@email.com",
+		Password:  "23456_encrypt",
+		Phone:     "320684398",
+		IsActive:  true,
+		CreatedAt: time.Time{},
+		Addresses: nil,
+		Scopes:    nil,
+	}
+	supportUser := memoryrepo.MemoryUser{
+		ID:        2,
+		Name:      "Support",
+		Email:     "support@email.com",
+		Password:  "11111_encrypt",
+		Phone:     "3203454398",
+		IsActive:  true,
+		CreatedAt: time.Time{},
+		Addresses: nil,
+		Scopes:    []users.ScopeName{users.USERS_IMPERSONATE, users.AUDIT_READ},
+	}
+	adminUser := memoryrepo.MemoryUser{
+		ID:        3,
+		Name:      "Admin",
+		Email:     "admin@email.com",
+		Password:  "22222_encrypt",
+		Phone:     "3203454399",
+		IsActive:  true,
+		CreatedAt: time.Time{},
+		Addresses: nil,
+		Scopes: []users.ScopeName{
+			users.USERS_READ, users.USERS_WRITE, users.USERS_DELETE, users.USERS_IMPERSONATE, users.AUDIT_READ,
+		},
+	}
+	userData := []memoryrepo.MemoryUser{cristianUser, supportUser, adminUser}
+	userService, _, _, jwt := createMockUserService(userData, make([]memoryrepo.MemoryAddress, 0))
+	userHandler := handler.NewUserHandler(userService)
+
+	router := gin.New()
+	router.Use(common.Auth(&jwt))
+	apiV1Routes := router.Group("/api/v1")
+	apiV1Routes.Use(userHandler.AuditImpersonatedRequests(), common.Problems())
+	userHandler.AddRoutes(apiV1Routes)
+
+	// USER WITHOUT SCOPE CAN'T IMPERSONATE
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users/2/impersonate/", nil)
+	req.Header.Set("Authorization", "Bearer "+cristianUser.Email+"___jwt") // mock jwt
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("impersonate without scope status code:", w.Code, "expected:", http.StatusForbidden)
+		return
+	}
+
+	// SUPPORT AGENT CAN'T IMPERSONATE A USER WITH MORE SCOPES
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/users/3/impersonate/", nil)
+	req.Header.Set("Authorization", "Bearer "+supportUser.Email+"___jwt") // mock jwt
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var problem common.Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	if w.Code != http.StatusForbidden || problem.Code != "impersonation_not_allowed" {
+		t.Error("impersonate admin status code:", w.Code, "expected:", http.StatusForbidden, "body:", w.Body.String())
+		return
+	}
+
+	// IMPERSONATE
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/users/1/impersonate/", nil)
+	req.Header.Set("Authorization", "Bearer "+supportUser.Email+"___jwt") // mock jwt
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Error("impersonate status code:", w.Code, "expected:", http.StatusOK)
+		t.Log("body:", w.Body.String())
+		return
+	}
+
+	resBody := make(map[string]interface{})
+	if err := json.Unmarshal(w.Body.Bytes(), &resBody); err != nil {
+		t.Error("error parsing impersonate response:", err)
+		return
+	}
+	impersonationToken := resBody["access_token"].(string)
+
+	// SEE THE API AS THE USER
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+	req.Header.Set("Authorization", "Bearer "+impersonationToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Error("get user while impersonating status code:", w.Code, "expected:", http.StatusOK)
+		return
+	}
+
+	// CAN'T CHANGE THE PASSWORD
+	reqBody := bytes.NewReader([]byte(`{"current_password": "23456", "new_password": "888888"}`))
+	req, _ = http.NewRequest(http.MethodPut, "/api/v1/users/1/password/", reqBody)
+	req.Header.Set("Authorization", "Bearer "+impersonationToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("change password while impersonating status code:", w.Code, "expected:", http.StatusForbidden)
+		return
+	}
+
+	// CAN'T CHANGE THE SCOPES
+	reqBody = bytes.NewReader([]byte(`{
+		"name": "Cristian",
+		"email": "cristian@email.com",
+		"scopes": ["users:delete"]
+	}`))
+	req, _ = http.NewRequest(http.MethodPut, "/api/v1/users/1/", reqBody)
+	req.Header.Set("Authorization", "Bearer "+impersonationToken)
+	req.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("change scopes while impersonating status code:", w.Code, "expected:", http.StatusForbidden)
+		return
+	}
+
+	// REQUESTS WHILE IMPERSONATING ARE AUDITED
+	events, count := userService.ListAuditEvents(
+		ctx, map[string]string{"type": string(users.AuditImpersonatedRequest)}, 10, 0,
+	)
+	if count != 3 {
+		t.Error("incorrect amount of impersonated requests audited:", count, "expected: 3")
+		return
+	}
+	for _, e := range events {
+		if e.ImpersonatorID != supportUser.ID || e.ActorID != cristianUser.ID {
+			t.Error("incorrect impersonated request event:", e)
+		}
+	}
+}
@@ -1,14 +1,19 @@
 package handler
 
-import users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+import (
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"time"
+)
 
 func MapToListUserDTO(user users.User) ListUserDTO {
 	return ListUserDTO{
-		ID:     user.ID,
-		Name:   user.Name,
-		Email:  user.Email,
-		Phone:  user.Phone,
-		Scopes: user.Scopes,
+		ID:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		AvatarURL: user.AvatarURL,
+		Scopes:    user.Scopes,
+		Version:   user.Version,
 	}
 }
 
@@ -18,8 +23,10 @@ func MapToRetrieveUserDTO(user users.User) RetrieveUserDTO {
 		Name:      user.Name,
 		Email:     user.Email,
 		Phone:     user.Phone,
+		AvatarURL: user.AvatarURL,
 		Scopes:    user.Scopes,
 		Addresses: MapToListAddressesDTO(user.Addresses),
+		Version:   user.Version,
 	}
 }
 
@@ -31,20 +38,47 @@ func MapToListAddressDTO(address users.Address) ListAddressDTO {
 		Address:       address.Address,
 		ReceiverPhone: address.ReceiverPhone,
 		ReceiverName:  address.ReceiverName,
+		IsDefault:     address.IsDefault,
+		Version:       address.Version,
 	}
 }
 
 func MapToListAddressesDTO(address []users.Address) []ListAddressDTO {
 	dtos := make([]ListAddressDTO, 0, len(address))
 	for _, a := range address {
-		dtos = append(dtos, ListAddressDTO{
-			ID:            a.ID,
-			Department:    a.Department,
-			City:          a.City,
-			Address:       a.Address,
-			ReceiverPhone: a.ReceiverPhone,
-			ReceiverName:  a.ReceiverName,
-		})
+		dtos = append(dtos, MapToListAddressDTO(a))
 	}
 	return dtos
 }
+
+func MapToPreferencesDTO(preferences users.Preferences) PreferencesDTO {
+	consentTime := func(c users.Consent) *time.Time {
+		if c.UpdatedAt.IsZero() {
+			return nil
+		}
+		return &c.UpdatedAt
+	}
+
+	return PreferencesDTO{
+		Language:                preferences.Language,
+		MarketingEmailConsent:   preferences.MarketingEmail.Granted,
+		MarketingEmailConsentAt: consentTime(preferences.MarketingEmail),
+		MarketingSMSConsent:     preferences.MarketingSMS.Granted,
+		MarketingSMSConsentAt:   consentTime(preferences.MarketingSMS),
+		NotificationChannels:    preferences.NotificationChannels,
+	}
+}
+
+func MapToAuditEventDTO(event users.AuditEvent) AuditEventDTO {
+	return AuditEventDTO{
+		ID:             event.ID,
+		Type:           event.Type,
+		ActorID:        event.ActorID,
+		ImpersonatorID: event.ImpersonatorID,
+		TargetID:       event.TargetID,
+		IP:             event.IP,
+		UserAgent:      event.UserAgent,
+		Details:        event.Details,
+		CreatedAt:      event.CreatedAt,
+	}
+}
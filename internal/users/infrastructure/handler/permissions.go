@@ -2,6 +2,7 @@ package handler
 
 import (
 	"github.com/CrissAlvarezH/fundart-api/internal/common"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
 	"github.com/gin-gonic/gin"
 	"strconv"
@@ -22,6 +23,15 @@ func IsSameUser(user users.User, isAnonymous bool, c *gin.Context) (bool, string
 	return true, ""
 }
 
+func actorFromContext(c *gin.Context) users.Actor {
+	actor := users.Actor{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	if user, ok := c.Get("user"); ok {
+		actor.UserID = user.(users.User).ID
+		actor.ImpersonatorID = user.(users.User).ImpersonatorID
+	}
+	return actor
+}
+
 func ValidateScopes(scopes ...users.ScopeName) common.ScopeValidatorFunc {
 	return func(user users.User, isAnonymous bool, c *gin.Context) (bool, string) {
 		if isAnonymous {
@@ -48,3 +58,18 @@ func ScopeUserWrite(user users.User, isAnonymous bool, c *gin.Context) (bool, st
 func ScopeUserDelete(user users.User, isAnonymous bool, c *gin.Context) (bool, string) {
 	return ValidateScopes(users.USERS_DELETE)(user, isAnonymous, c)
 }
+
+func ScopeAuditRead(user users.User, isAnonymous bool, c *gin.Context) (bool, string) {
+	return ValidateScopes(users.AUDIT_READ)(user, isAnonymous, c)
+}
+
+func ScopeUserImpersonate(user users.User, isAnonymous bool, c *gin.Context) (bool, string) {
+	return ValidateScopes(users.USERS_IMPERSONATE)(user, isAnonymous, c)
+}
+
+func NotImpersonating(user users.User, isAnonymous bool, c *gin.Context) (bool, string) {
+	if user.IsImpersonated() {
+		return false, ports.ForbiddenWhileImpersonating.Error()
+	}
+	return true, ""
+}
@@ -0,0 +1,108 @@
+package infrastructure
+
+import (
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"strconv"
+	"time"
+)
+
+const (
+	accessToken  = "access"
+	refreshToken = "refresh"
+)
+
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Type string `json:"typ"`
+	// Actor is the user impersonating the subject
+	Actor users.UserID `json:"act,omitempty"`
+}
+
+// HMACJWTManager signs the tokens with HS256, the user is loaded again on
+// Verify so changes of its scopes apply to the tokens already issued
+type HMACJWTManager struct {
+	userRepo   ports.UserRepository
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewHMACJWTManager(
+	userRepo ports.UserRepository, secret string, accessTTL time.Duration, refreshTTL time.Duration,
+) *HMACJWTManager {
+	return &HMACJWTManager{
+		userRepo:   userRepo,
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+func (m *HMACJWTManager) Create(user users.User) (ports.Token, error) {
+	now := time.Now()
+	access, err := m.sign(newClaims(user, accessToken, now, m.accessTTL))
+	if err != nil {
+		return ports.Token{}, err
+	}
+	refresh, err := m.sign(newClaims(user, refreshToken, now, m.refreshTTL))
+	if err != nil {
+		return ports.Token{}, err
+	}
+	return ports.Token{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+func (m *HMACJWTManager) CreateImpersonation(
+	user users.User, actor users.User, expiresIn time.Duration,
+) (ports.Token, error) {
+	claims := newClaims(user, accessToken, time.Now(), expiresIn)
+	claims.Actor = actor.ID
+	access, err := m.sign(claims)
+	return ports.Token{AccessToken: access}, err
+}
+
+// Verify only accepts access tokens signed with HS256, the alg of the header
+// is not trusted so a token signed with other algorithm, or none, is rejected.
+// exp is required and nbf and iat are checked when present
+func (m *HMACJWTManager) Verify(ctx context.Context, token string) (users.User, error) {
+	var claims jwtClaims
+	_, err := jwt.ParseWithClaims(
+		token, &claims,
+		func(*jwt.Token) (any, error) { return m.secret, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil || claims.Type != accessToken {
+		return users.User{}, ports.InvalidToken
+	}
+
+	ID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return users.User{}, ports.InvalidToken
+	}
+	user, ok := m.userRepo.GetByID(ctx, users.UserID(ID))
+	if !ok {
+		return users.User{}, ports.InvalidToken
+	}
+	user.ImpersonatorID = claims.Actor
+	return user, nil
+}
+
+func (m *HMACJWTManager) sign(claims jwtClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+func newClaims(user users.User, tokenType string, now time.Time, ttl time.Duration) jwtClaims {
+	return jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(int(user.ID)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Type: tokenType,
+	}
+}
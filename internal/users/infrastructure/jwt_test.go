@@ -0,0 +1,146 @@
+package infrastructure_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/memoryrepo"
+	"github.com/golang-jwt/jwt/v5"
+	"strings"
+	"testing"
+	"time"
+)
+
+const secret = "a-secret-of-at-least-32-characters"
+
+func TestHMACJWTManager(t *testing.T) {
+	ctx := context.Background()
+	repo := memoryrepo.NewMemoryUserRepository([]memoryrepo.MemoryUser{
+		{ID: 1, Email: "cristian@email.com", IsActive: true, Scopes: []users.ScopeName{users.USERS_READ}},
+		{ID: 2, Email: "yuli@email.com", IsActive: true},
+	})
+	manager := infrastructure.NewHMACJWTManager(repo, secret, time.Minute, time.Hour)
+	cristian, _ := repo.GetByID(ctx, 1)
+	yuli, _ := repo.GetByID(ctx, 2)
+
+	token, err := manager.Create(cristian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := manager.Verify(ctx, token.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != cristian.ID || !user.HasScope(users.USERS_READ) || user.ImpersonatorID != 0 {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	impersonation, _ := manager.CreateImpersonation(yuli, cristian, time.Minute)
+	if user, err := manager.Verify(ctx, impersonation.AccessToken); err != nil || user.ID != yuli.ID || user.ImpersonatorID != cristian.ID {
+		t.Errorf("unexpected impersonated user %+v, %v", user, err)
+	}
+
+	expired, _ := manager.CreateImpersonation(yuli, cristian, -time.Minute)
+	other, _ := infrastructure.NewHMACJWTManager(repo, secret+"-other", time.Minute, time.Hour).Create(cristian)
+	parts := strings.Split(token.AccessToken, ".")
+	tampered := parts[0] + "." + strings.TrimSuffix(parts[1], "Q") + "R." + parts[2]
+	unsigned := parts[0] + "." + parts[1] + "."
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"refresh token", token.RefreshToken},
+		{"expired token", expired.AccessToken},
+		{"signed with other secret", other.AccessToken},
+		{"tampered payload", tampered},
+		{"without signature", unsigned},
+		{"mock token", "cristian@email.com___jwt"},
+		{"empty", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := manager.Verify(ctx, c.token); !errors.Is(err, ports.InvalidToken) {
+				t.Errorf("expected InvalidToken, got %v", err)
+			}
+		})
+	}
+}
+
+// TestHMACJWTManager_RejectsForgedTokens signs the tokens by hand to check
+// what Verify accepts beyond the tokens of the manager
+func TestHMACJWTManager_RejectsForgedTokens(t *testing.T) {
+	ctx := context.Background()
+	repo := memoryrepo.NewMemoryUserRepository([]memoryrepo.MemoryUser{
+		{ID: 1, Email: "cristian@email.com", IsActive: true},
+	})
+	manager := infrastructure.NewHMACJWTManager(repo, secret, time.Minute, time.Hour)
+
+	now := time.Now()
+	claims := func(changes jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{"sub": "1", "typ": "access", "iat": now.Unix(), "exp": now.Add(time.Minute).Unix()}
+		for k, v := range changes {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+	sign := func(method jwt.SigningMethod, key any, c jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(method, c).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	valid := sign(jwt.SigningMethodHS256, []byte(secret), claims(nil))
+	if user, err := manager.Verify(ctx, valid); err != nil || user.ID != 1 {
+		t.Fatalf("expected the token signed by hand to be valid, got %+v, %v", user, err)
+	}
+
+	// a header that claims other algorithm but is signed with HS256 and the
+	// secret, like the key of an asymmetric algorithm confused with the secret
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	unsigned := header + "." + strings.Split(valid, ".")[1]
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	confused := unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	parts := strings.Split(valid, ".")
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	signature[0] ^= 0xff
+	tamperedSignature := parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"alg none", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims(nil))},
+		{"alg HS512 with the secret", sign(jwt.SigningMethodHS512, []byte(secret), claims(nil))},
+		{"alg RS256 signed with the secret", confused},
+		{"tampered signature", tamperedSignature},
+		{"expired", sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"exp": now.Add(-time.Second).Unix()}))},
+		{"without exp", sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"exp": nil}))},
+		{"not valid yet", sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"nbf": now.Add(time.Minute).Unix()}))},
+		{"issued in the future", sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"iat": now.Add(time.Minute).Unix()}))},
+		{"refresh type", sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"typ": "refresh"}))},
+		{"without type", sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"typ": nil}))},
+		{"unknown user", sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"sub": "99"}))},
+		{"subject not a number", sign(jwt.SigningMethodHS256, []byte(secret), claims(jwt.MapClaims{"sub": "cristian@email.com"}))},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := manager.Verify(ctx, c.token); !errors.Is(err, ports.InvalidToken) {
+				t.Errorf("expected InvalidToken, got %v", err)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package memoryrepo
 
 import (
+	"context"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"sync"
 )
 
 type MemoryAddress struct {
@@ -12,7 +14,9 @@ type MemoryAddress struct {
 	Address       string
 	ReceiverPhone string
 	ReceiverName  string
+	IsDefault     bool
 	UserID        users.UserID
+	Version       int
 }
 
 func mapToMemoryAddress(address users.Address, userID users.UserID) MemoryAddress {
@@ -23,7 +27,9 @@ func mapToMemoryAddress(address users.Address, userID users.UserID) MemoryAddres
 		Address:       address.Address,
 		ReceiverPhone: address.ReceiverPhone,
 		ReceiverName:  address.ReceiverName,
+		IsDefault:     address.IsDefault,
 		UserID:        userID,
+		Version:       address.Version,
 	}
 }
 
@@ -35,77 +41,240 @@ func mapToAddress(memoAddress MemoryAddress) users.Address {
 		Address:       memoAddress.Address,
 		ReceiverPhone: memoAddress.ReceiverPhone,
 		ReceiverName:  memoAddress.ReceiverName,
+		IsDefault:     memoAddress.IsDefault,
+		Version:       memoAddress.Version,
 	}
 }
 
+// MemoryAddressRepository is safe for concurrent use, addresses are indexed by
+// ID and by owner and IDs are never reused after an address is deleted
 type MemoryAddressRepository struct {
-	Addresses []MemoryAddress
+	mu     sync.RWMutex
+	byID   map[users.AddressID]*MemoryAddress
+	byUser map[users.UserID][]users.AddressID
+	lastID users.AddressID
 }
 
 func NewMemoryAddressRepository(addresses []MemoryAddress) *MemoryAddressRepository {
-	return &MemoryAddressRepository{Addresses: addresses}
+	r := &MemoryAddressRepository{
+		byID:   make(map[users.AddressID]*MemoryAddress, len(addresses)),
+		byUser: make(map[users.UserID][]users.AddressID),
+	}
+	for _, a := range addresses {
+		if _, ok := r.byID[a.ID]; ok {
+			continue
+		}
+		r.insert(a)
+	}
+	return r
 }
 
-func (r *MemoryAddressRepository) List(userID users.UserID) []users.Address {
-	var addresses = make([]users.Address, 0)
-	for _, a := range r.Addresses {
-		if a.UserID == userID {
-			addresses = append(addresses, mapToAddress(a))
+// All returns a copy of every stored address
+func (r *MemoryAddressRepository) All() []MemoryAddress {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]MemoryAddress, 0, len(r.byID))
+	for ID := users.AddressID(1); ID <= r.lastID; ID++ {
+		if a, ok := r.byID[ID]; ok {
+			result = append(result, *a)
 		}
 	}
+	return result
+}
+
+func (r *MemoryAddressRepository) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.byID)
+}
+
+func (r *MemoryAddressRepository) List(ctx context.Context, userID users.UserID) []users.Address {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var addresses = make([]users.Address, 0, len(r.byUser[userID]))
+	for _, ID := range r.byUser[userID] {
+		addresses = append(addresses, mapToAddress(*r.byID[ID]))
+	}
 	return addresses
 }
 
+func (r *MemoryAddressRepository) GetByID(
+	ctx context.Context, userID users.UserID, ID users.AddressID,
+) (users.Address, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.get(userID, ID)
+	if !ok {
+		return users.Address{}, false
+	}
+	return mapToAddress(*a), true
+}
+
+func (r *MemoryAddressRepository) GetDefault(ctx context.Context, userID users.UserID) (users.Address, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.getDefault(userID)
+	if !ok {
+		return users.Address{}, false
+	}
+	return mapToAddress(*a), true
+}
+
 func (r *MemoryAddressRepository) Add(
-	userID users.UserID, department string, city string, address string, receiverPhone string, receiverName string,
+	ctx context.Context, userID users.UserID, department string,
+	city string, address string, receiverPhone string, receiverName string,
 ) (users.Address, error) {
-	lastAddressID := 0
-	if len(r.Addresses) > 0 {
-		lastAddressID = int(r.Addresses[len(r.Addresses)-1].ID)
-	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the first address of a user is its default address
+	_, hasDefault := r.getDefault(userID)
 
 	newAddress := users.Address{
-		ID:            users.AddressID(lastAddressID + 1),
+		ID:            r.lastID + 1,
 		Department:    department,
 		City:          city,
 		Address:       address,
 		ReceiverPhone: receiverPhone,
 		ReceiverName:  receiverName,
+		IsDefault:     !hasDefault,
+		Version:       1,
 	}
-	r.Addresses = append(r.Addresses, mapToMemoryAddress(newAddress, userID))
+	r.insert(mapToMemoryAddress(newAddress, userID))
 	return newAddress, nil
 }
 
 func (r *MemoryAddressRepository) Update(
-	ID users.AddressID, department string, city string, address string,
-	receiverPhone string, receiverName string,
+	ctx context.Context, userID users.UserID, ID users.AddressID, version int,
+	department string, city string, address string, receiverPhone string, receiverName string,
 ) (users.Address, error) {
-	for i, u := range r.Addresses {
-		if u.ID == ID {
-			r.Addresses[i].Department = department
-			r.Addresses[i].City = city
-			r.Addresses[i].Address = address
-			r.Addresses[i].ReceiverName = receiverPhone
-			r.Addresses[i].ReceiverPhone = receiverName
-			return mapToAddress(r.Addresses[i]), nil
-		}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.get(userID, ID)
+	if !ok {
+		return users.Address{}, ports.AddressDoesNotExists
+	}
+	if !matchVersion(a.Version, version) {
+		return users.Address{}, ports.VersionMismatch
 	}
-	return users.Address{}, ports.AddressDoesNotExists
+	a.Department = department
+	a.City = city
+	a.Address = address
+	a.ReceiverPhone = receiverPhone
+	a.ReceiverName = receiverName
+	a.Version++
+	return mapToAddress(*a), nil
 }
 
-func (r *MemoryAddressRepository) Delete(ID users.AddressID) error {
-	found := false
-	filtered := make([]MemoryAddress, 0, len(r.Addresses))
-	for _, a := range r.Addresses {
-		if a.ID != ID {
-			filtered = append(filtered, a)
-			found = true
-		}
+func (r *MemoryAddressRepository) SetDefault(
+	ctx context.Context, userID users.UserID, ID users.AddressID, version int,
+) (users.Address, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	defaultAddress, ok := r.get(userID, ID)
+	if !ok {
+		return users.Address{}, ports.AddressDoesNotExists
+	}
+	if !matchVersion(defaultAddress.Version, version) {
+		return users.Address{}, ports.VersionMismatch
 	}
 
-	r.Addresses = filtered
-	if !found {
+	for _, addressID := range r.byUser[userID] {
+		r.byID[addressID].setDefault(addressID == ID)
+	}
+	return mapToAddress(*defaultAddress), nil
+}
+
+func (r *MemoryAddressRepository) Delete(
+	ctx context.Context, userID users.UserID, ID users.AddressID, version int,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted, ok := r.get(userID, ID)
+	if !ok {
 		return ports.AddressDoesNotExists
 	}
+	if !matchVersion(deleted.Version, version) {
+		return ports.VersionMismatch
+	}
+
+	delete(r.byID, ID)
+	remaining := make([]users.AddressID, 0, len(r.byUser[userID]))
+	for _, addressID := range r.byUser[userID] {
+		if addressID != ID {
+			remaining = append(remaining, addressID)
+		}
+	}
+	r.byUser[userID] = remaining
+
+	// another address of the user takes the place of the deleted default address
+	if deleted.IsDefault && len(remaining) > 0 {
+		r.byID[remaining[0]].setDefault(true)
+	}
 	return nil
 }
+
+// setDefault only increases the version when the flag changes
+func (a *MemoryAddress) setDefault(isDefault bool) {
+	if a.IsDefault != isDefault {
+		a.IsDefault = isDefault
+		a.Version++
+	}
+}
+
+// the methods below must be called with the lock held
+
+func (r *MemoryAddressRepository) insert(a MemoryAddress) {
+	if a.Version == 0 {
+		a.Version = 1
+	}
+	r.byID[a.ID] = &a
+	r.byUser[a.UserID] = append(r.byUser[a.UserID], a.ID)
+	if a.ID > r.lastID {
+		r.lastID = a.ID
+	}
+}
+
+func (r *MemoryAddressRepository) clone() *MemoryAddressRepository {
+	c := &MemoryAddressRepository{
+		byID:   make(map[users.AddressID]*MemoryAddress, len(r.byID)),
+		byUser: make(map[users.UserID][]users.AddressID, len(r.byUser)),
+		lastID: r.lastID,
+	}
+	for ID, a := range r.byID {
+		a := *a
+		c.byID[ID] = &a
+	}
+	for userID, IDs := range r.byUser {
+		c.byUser[userID] = append([]users.AddressID(nil), IDs...)
+	}
+	return c
+}
+
+func (r *MemoryAddressRepository) replace(c *MemoryAddressRepository) {
+	r.byID, r.byUser, r.lastID = c.byID, c.byUser, c.lastID
+}
+
+func (r *MemoryAddressRepository) get(userID users.UserID, ID users.AddressID) (*MemoryAddress, bool) {
+	a, ok := r.byID[ID]
+	if !ok || a.UserID != userID {
+		return nil, false
+	}
+	return a, true
+}
+
+func (r *MemoryAddressRepository) getDefault(userID users.UserID) (*MemoryAddress, bool) {
+	for _, ID := range r.byUser[userID] {
+		if r.byID[ID].IsDefault {
+			return r.byID[ID], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,105 @@
+package memoryrepo
+
+import (
+	"context"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryAuditLogRepository is safe for concurrent use
+type MemoryAuditLogRepository struct {
+	mu     sync.RWMutex
+	events []users.AuditEvent
+	lastID users.AuditEventID
+}
+
+func NewMemoryAuditLogRepository(events []users.AuditEvent) *MemoryAuditLogRepository {
+	r := &MemoryAuditLogRepository{events: events}
+	for _, e := range events {
+		if e.ID > r.lastID {
+			r.lastID = e.ID
+		}
+	}
+	return r
+}
+
+// All returns a copy of every stored event, oldest first
+func (r *MemoryAuditLogRepository) All() []users.AuditEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]users.AuditEvent(nil), r.events...)
+}
+
+func (r *MemoryAuditLogRepository) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.events)
+}
+
+func (r *MemoryAuditLogRepository) Append(ctx context.Context, event users.AuditEvent) (users.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	event.ID = r.lastID
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+	r.events = append(r.events, event)
+	return event, nil
+}
+
+func (r *MemoryAuditLogRepository) List(
+	ctx context.Context, filters map[string]string, limit int, offset int,
+) ([]users.AuditEvent, int) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	filtered := make([]users.AuditEvent, 0, len(r.events))
+	for i := len(r.events) - 1; i >= 0; i-- {
+		if matchAuditFilters(r.events[i], filters) {
+			filtered = append(filtered, r.events[i])
+		}
+	}
+
+	data := make([]users.AuditEvent, 0, len(filtered))
+	if len(filtered) >= limit && len(filtered) >= offset {
+		data = filtered[offset:limit]
+	} else if len(filtered) >= offset && len(filtered) < limit {
+		data = filtered[offset:]
+	}
+	return data, len(filtered)
+}
+
+// empty and invalid filters are ignored like in the sql repositories
+func matchAuditFilters(e users.AuditEvent, filters map[string]string) bool {
+	if eventType := filters["type"]; eventType != "" && string(e.Type) != eventType {
+		return false
+	}
+	if actorID, err := strconv.Atoi(filters["actor_id"]); err == nil && int(e.ActorID) != actorID {
+		return false
+	}
+	if targetID, err := strconv.Atoi(filters["target_id"]); err == nil && int(e.TargetID) != targetID {
+		return false
+	}
+	if from, err := time.Parse(time.RFC3339, filters["from"]); err == nil && e.CreatedAt.Before(from) {
+		return false
+	}
+	if to, err := time.Parse(time.RFC3339, filters["to"]); err == nil && e.CreatedAt.After(to) {
+		return false
+	}
+	return true
+}
+
+// clone and replace are used by the unit of work, they must be called with the
+// lock held
+func (r *MemoryAuditLogRepository) clone() *MemoryAuditLogRepository {
+	return &MemoryAuditLogRepository{events: append([]users.AuditEvent(nil), r.events...), lastID: r.lastID}
+}
+
+func (r *MemoryAuditLogRepository) replace(c *MemoryAuditLogRepository) {
+	r.events, r.lastID = c.events, c.lastID
+}
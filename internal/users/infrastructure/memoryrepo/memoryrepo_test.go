@@ -0,0 +1,243 @@
+package memoryrepo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports/portstest"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/memoryrepo"
+)
+
+const workers = 50
+
+// run with go test -race, the stress tests only check invariants, the race
+// detector reports unsynchronized access
+func TestMemoryUserRepository_ConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	repo := memoryrepo.NewMemoryUserRepository(nil)
+
+	var wg sync.WaitGroup
+	IDs := make(chan users.UserID, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			email := fmt.Sprintf("user%d@email.com", i)
+			user, err := repo.Add(ctx, "user", email, "pass", "1", true, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			IDs <- user.ID
+
+			repo.Update(ctx, user.ID, ports.AnyVersion, "renamed", email, "2", nil)
+			repo.SaveAccountVerificationCode(ctx, user.ID, "1234")
+			repo.GetByEmail(ctx, email)
+			repo.List(ctx, map[string]string{}, 10, 0)
+		}(i)
+	}
+	wg.Wait()
+	close(IDs)
+
+	seen := make(map[users.UserID]bool)
+	for ID := range IDs {
+		if seen[ID] {
+			t.Errorf("ID %d was assigned twice", ID)
+		}
+		seen[ID] = true
+	}
+	if _, total := repo.List(ctx, map[string]string{}, workers, 0); total != workers {
+		t.Errorf("expected %d users, got %d", workers, total)
+	}
+}
+
+func TestMemoryUserRepository_DuplicatedEmail(t *testing.T) {
+	ctx := context.Background()
+	repo := memoryrepo.NewMemoryUserRepository(nil)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	added := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.Add(ctx, "user", "Same@email.com", "pass", "1", true, nil); err == nil {
+				mu.Lock()
+				added++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if added != 1 {
+		t.Errorf("the email should be registered once, registered %d times", added)
+	}
+	if _, ok := repo.GetByEmail(ctx, "same@EMAIL.com"); !ok {
+		t.Error("email lookups should ignore case")
+	}
+}
+
+func TestMemoryAddressRepository_ConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	repo := memoryrepo.NewMemoryAddressRepository(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(userID users.UserID) {
+			defer wg.Done()
+			repo.Add(ctx, userID, "Cordoba", "Monteria", "Calle 1", "320", "user")
+			second, _ := repo.Add(ctx, userID, "Cordoba", "Monteria", "Calle 2", "320", "user")
+			repo.SetDefault(ctx, userID, second.ID, ports.AnyVersion)
+			repo.Delete(ctx, userID, second.ID, ports.AnyVersion)
+			repo.List(ctx, userID)
+		}(users.UserID(i % 5))
+	}
+	wg.Wait()
+
+	for userID := users.UserID(0); userID < 5; userID++ {
+		defaults := 0
+		for _, a := range repo.List(ctx, userID) {
+			if a.IsDefault {
+				defaults++
+			}
+		}
+		if defaults != 1 {
+			t.Errorf("user %d should have one default address, has %d", userID, defaults)
+		}
+	}
+
+	// IDs are not reused after deleting the last address
+	all := repo.All()
+	last := all[len(all)-1]
+	repo.Delete(ctx, last.UserID, last.ID, ports.AnyVersion)
+	created, _ := repo.Add(ctx, last.UserID, "Cordoba", "Monteria", "Calle 3", "320", "user")
+	if created.ID != users.AddressID(2*workers+1) {
+		t.Errorf("expected ID %d, got %d", 2*workers+1, created.ID)
+	}
+}
+
+func TestMemoryAuditLogRepository_ConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	repo := memoryrepo.NewMemoryAuditLogRepository(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo.Append(ctx, users.AuditEvent{Type: users.AuditLoginSucceeded})
+			repo.List(ctx, map[string]string{}, 10, 0)
+		}()
+	}
+	wg.Wait()
+
+	if _, total := repo.List(ctx, map[string]string{}, 10, 0); total != workers {
+		t.Errorf("expected %d events, got %d", workers, total)
+	}
+}
+
+func TestMemoryUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	userRepo := memoryrepo.NewMemoryUserRepository(nil)
+	addressRepo := memoryrepo.NewMemoryAddressRepository(nil)
+	auditRepo := memoryrepo.NewMemoryAuditLogRepository(nil)
+	uow := memoryrepo.NewMemoryUnitOfWork(userRepo, addressRepo, auditRepo)
+
+	failure := errors.New("email not sent")
+	err := uow.Do(ctx, func(ctx context.Context, repos ports.Repositories) error {
+		user, _ := repos.Users.Add(ctx, "Juan", "juan@email.com", "333_encrypt", "3207846634", true, nil)
+		repos.Addresses.Add(ctx, user.ID, "Cordoba", "Monteria", "Cll 1", "320", "Juan")
+		repos.Audit.Append(ctx, users.AuditEvent{Type: users.AuditLoginSucceeded, ActorID: user.ID})
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+	if len(userRepo.All()) != 0 || len(addressRepo.All()) != 0 || len(auditRepo.All()) != 0 {
+		t.Error("changes of a failed unit of work were kept")
+	}
+
+	// units of work running at the same time are serialized
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := uow.Do(ctx, func(ctx context.Context, repos ports.Repositories) error {
+				user, err := repos.Users.Add(ctx, "user", fmt.Sprintf("user%d@email.com", i), "", "", true, nil)
+				if err != nil {
+					return err
+				}
+				_, err = repos.Addresses.Add(ctx, user.ID, "Cordoba", "Monteria", "Cll 1", "320", "user")
+				return err
+			})
+			if err != nil {
+				t.Error(err)
+			}
+			userRepo.List(ctx, map[string]string{}, 10, 0)
+		}(i)
+	}
+	wg.Wait()
+
+	if len(userRepo.All()) != workers || len(addressRepo.All()) != workers {
+		t.Errorf("expected %d users and addresses, got %d and %d", workers, len(userRepo.All()), len(addressRepo.All()))
+	}
+	for _, u := range userRepo.All() {
+		if _, ok := addressRepo.GetDefault(ctx, u.ID); !ok {
+			t.Errorf("user %d has no default address", u.ID)
+		}
+	}
+}
+
+func TestMemoryRepositories_Versions(t *testing.T) {
+	ctx := context.Background()
+	userRepo := memoryrepo.NewMemoryUserRepository(nil)
+	addressRepo := memoryrepo.NewMemoryAddressRepository(nil)
+
+	user, _ := userRepo.Add(ctx, "Cristian", "cristian@email.com", "pass", "1", true, nil)
+	updated, err := userRepo.Update(ctx, user.ID, user.Version, "Cristian A", user.Email, "1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != user.Version+1 {
+		t.Errorf("expected version %d, got %d", user.Version+1, updated.Version)
+	}
+	if _, err := userRepo.Update(ctx, user.ID, user.Version, "Stale", user.Email, "1", nil); !errors.Is(err, ports.VersionMismatch) {
+		t.Errorf("expected VersionMismatch, got %v", err)
+	}
+	if found, _ := userRepo.GetByID(ctx, user.ID); found.Name != "Cristian A" {
+		t.Errorf("stale update was applied, got %q", found.Name)
+	}
+	if err := userRepo.Deactivate(ctx, 9999, 1); !errors.Is(err, ports.UserDoesNotExists) {
+		t.Errorf("expected UserDoesNotExists, got %v", err)
+	}
+
+	first, _ := addressRepo.Add(ctx, user.ID, "Cordoba", "Monteria", "Calle 1", "320", "Cristian")
+	second, _ := addressRepo.Add(ctx, user.ID, "Cordoba", "Monteria", "Calle 2", "320", "Cristian")
+	if _, err := addressRepo.SetDefault(ctx, user.ID, second.ID, second.Version); err != nil {
+		t.Fatal(err)
+	}
+	if err := addressRepo.Delete(ctx, user.ID, first.ID, first.Version); !errors.Is(err, ports.VersionMismatch) {
+		t.Errorf("expected VersionMismatch, got %v", err)
+	}
+}
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	portstest.Run(t, func(t *testing.T) portstest.Backend {
+		userRepo := memoryrepo.NewMemoryUserRepository(nil)
+		addressRepo := memoryrepo.NewMemoryAddressRepository(nil)
+		auditRepo := memoryrepo.NewMemoryAuditLogRepository(nil)
+		return portstest.Backend{
+			Repositories: ports.Repositories{Users: userRepo, Addresses: addressRepo, Audit: auditRepo},
+			UnitOfWork:   memoryrepo.NewMemoryUnitOfWork(userRepo, addressRepo, auditRepo),
+		}
+	})
+}
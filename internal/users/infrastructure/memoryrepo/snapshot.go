@@ -0,0 +1,140 @@
+package memoryrepo
+
+import (
+	"encoding/json"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"strings"
+)
+
+// the repositories implement snapshot.Store so their content can be
+// snapshotted, the ID sequences are kept so IDs of removed rows
+// are not reused after a restore
+
+type userSnapshot struct {
+	LastID users.UserID
+	Users  []MemoryUser
+}
+
+func (r *MemoryUserRepository) RLock() {
+	r.mu.RLock()
+}
+
+func (r *MemoryUserRepository) RUnlock() {
+	r.mu.RUnlock()
+}
+
+func (r *MemoryUserRepository) MarshalSnapshot() ([]byte, error) {
+	snapshot := userSnapshot{LastID: r.lastID, Users: make([]MemoryUser, 0, len(r.ids))}
+	for _, ID := range r.ids {
+		snapshot.Users = append(snapshot.Users, *r.byID[ID])
+	}
+	return json.Marshal(snapshot)
+}
+
+// UnmarshalSnapshot replaces the content of the repository
+func (r *MemoryUserRepository) UnmarshalSnapshot(data []byte) error {
+	var snapshot userSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.byID = make(map[users.UserID]*MemoryUser, len(snapshot.Users))
+	r.byEmail = make(map[string]users.UserID, len(snapshot.Users))
+	r.ids = make([]users.UserID, 0, len(snapshot.Users))
+	r.lastID = snapshot.LastID
+	for _, u := range snapshot.Users {
+		if _, ok := r.byID[u.ID]; ok {
+			continue
+		}
+		if _, ok := r.byEmail[strings.ToLower(u.Email)]; ok {
+			continue
+		}
+		r.insert(u)
+	}
+	return nil
+}
+
+type addressSnapshot struct {
+	LastID    users.AddressID
+	Addresses []MemoryAddress
+}
+
+func (r *MemoryAddressRepository) RLock() {
+	r.mu.RLock()
+}
+
+func (r *MemoryAddressRepository) RUnlock() {
+	r.mu.RUnlock()
+}
+
+func (r *MemoryAddressRepository) MarshalSnapshot() ([]byte, error) {
+	snapshot := addressSnapshot{LastID: r.lastID, Addresses: make([]MemoryAddress, 0, len(r.byID))}
+	for ID := users.AddressID(1); ID <= r.lastID; ID++ {
+		if a, ok := r.byID[ID]; ok {
+			snapshot.Addresses = append(snapshot.Addresses, *a)
+		}
+	}
+	return json.Marshal(snapshot)
+}
+
+// UnmarshalSnapshot replaces the content of the repository
+func (r *MemoryAddressRepository) UnmarshalSnapshot(data []byte) error {
+	var snapshot addressSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.byID = make(map[users.AddressID]*MemoryAddress, len(snapshot.Addresses))
+	r.byUser = make(map[users.UserID][]users.AddressID)
+	r.lastID = snapshot.LastID
+	for _, a := range snapshot.Addresses {
+		if _, ok := r.byID[a.ID]; ok {
+			continue
+		}
+		r.insert(a)
+	}
+	return nil
+}
+
+type auditSnapshot struct {
+	LastID users.AuditEventID
+	Events []users.AuditEvent
+}
+
+func (r *MemoryAuditLogRepository) RLock() {
+	r.mu.RLock()
+}
+
+func (r *MemoryAuditLogRepository) RUnlock() {
+	r.mu.RUnlock()
+}
+
+func (r *MemoryAuditLogRepository) MarshalSnapshot() ([]byte, error) {
+	return json.Marshal(auditSnapshot{LastID: r.lastID, Events: r.events})
+}
+
+// UnmarshalSnapshot replaces the content of the repository
+func (r *MemoryAuditLogRepository) UnmarshalSnapshot(data []byte) error {
+	var snapshot auditSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = snapshot.Events
+	r.lastID = snapshot.LastID
+	for _, e := range r.events {
+		if e.ID > r.lastID {
+			r.lastID = e.ID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package memoryrepo
+
+import (
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+)
+
+// MemoryUnitOfWork gives fn copies of the repositories and swaps them in when fn
+// succeeds. The repositories stay locked while fn runs, so units of work are
+// serialized and other calls wait for them, like a sql write transaction. fn
+// must only use the repositories it's given, the locked ones would deadlock
+type MemoryUnitOfWork struct {
+	users     *MemoryUserRepository
+	addresses *MemoryAddressRepository
+	audit     *MemoryAuditLogRepository
+}
+
+func NewMemoryUnitOfWork(
+	users *MemoryUserRepository, addresses *MemoryAddressRepository, audit *MemoryAuditLogRepository,
+) *MemoryUnitOfWork {
+	return &MemoryUnitOfWork{users: users, addresses: addresses, audit: audit}
+}
+
+func (u *MemoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos ports.Repositories) error) error {
+	// always locked in the same order
+	u.users.mu.Lock()
+	defer u.users.mu.Unlock()
+	u.addresses.mu.Lock()
+	defer u.addresses.mu.Unlock()
+	u.audit.mu.Lock()
+	defer u.audit.mu.Unlock()
+
+	users, addresses, audit := u.users.clone(), u.addresses.clone(), u.audit.clone()
+	err := fn(ctx, ports.Repositories{Users: users, Addresses: addresses, Audit: audit})
+	if err != nil {
+		return err
+	}
+
+	u.users.replace(users)
+	u.addresses.replace(addresses)
+	u.audit.replace(audit)
+	return nil
+}
@@ -1,8 +1,11 @@
 package memoryrepo
 
 import (
+	"context"
 	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
 	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -12,83 +15,118 @@ type MemoryUser struct {
 	Email                string
 	Password             string
 	Phone                string
+	AvatarURL            string
 	IsActive             bool
 	CreatedAt            time.Time
 	Addresses            []users.Address
 	Scopes               []users.ScopeName
+	Preferences          users.Preferences
 	VerificationCode     string
 	RecoveryPasswordCode string
+	Version              int
 }
 
 func mapToMemoryUser(user users.User, password string) MemoryUser {
 	return MemoryUser{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		Password:  password,
-		Phone:     user.Phone,
-		IsActive:  user.IsActive,
-		CreatedAt: user.CreatedAt,
-		Addresses: user.Addresses,
-		Scopes:    user.Scopes,
+		ID:          user.ID,
+		Name:        user.Name,
+		Email:       user.Email,
+		Password:    password,
+		Phone:       user.Phone,
+		AvatarURL:   user.AvatarURL,
+		IsActive:    user.IsActive,
+		CreatedAt:   user.CreatedAt,
+		Addresses:   user.Addresses,
+		Scopes:      user.Scopes,
+		Preferences: user.Preferences,
+		Version:     user.Version,
 	}
 }
 
 func mapToUser(memoryUser MemoryUser) users.User {
+	preferences := memoryUser.Preferences
+	if preferences.Language == "" {
+		preferences = users.DefaultPreferences()
+	}
+
 	return users.User{
-		ID:        memoryUser.ID,
-		Name:      memoryUser.Name,
-		Email:     memoryUser.Email,
-		Phone:     memoryUser.Phone,
-		IsActive:  memoryUser.IsActive,
-		CreatedAt: memoryUser.CreatedAt,
-		Addresses: memoryUser.Addresses,
-		Scopes:    memoryUser.Scopes,
+		ID:          memoryUser.ID,
+		Name:        memoryUser.Name,
+		Email:       memoryUser.Email,
+		Phone:       memoryUser.Phone,
+		AvatarURL:   memoryUser.AvatarURL,
+		IsActive:    memoryUser.IsActive,
+		CreatedAt:   memoryUser.CreatedAt,
+		Addresses:   memoryUser.Addresses,
+		Scopes:      memoryUser.Scopes,
+		Preferences: preferences,
+		Version:     memoryUser.Version,
 	}
 }
 
+// MemoryUserRepository is safe for concurrent use, users are indexed by ID and
+// by lowercased email
 type MemoryUserRepository struct {
-	Users []MemoryUser
+	mu      sync.RWMutex
+	byID    map[users.UserID]*MemoryUser
+	byEmail map[string]users.UserID
+	ids     []users.UserID
+	lastID  users.UserID
 }
 
-func NewMemoryUserRepository(users []MemoryUser) *MemoryUserRepository {
-	return &MemoryUserRepository{Users: users}
+// NewMemoryUserRepository indexes the given users, when two of them share an
+// ID or an email only the first one is kept
+func NewMemoryUserRepository(data []MemoryUser) *MemoryUserRepository {
+	r := &MemoryUserRepository{
+		byID:    make(map[users.UserID]*MemoryUser, len(data)),
+		byEmail: make(map[string]users.UserID, len(data)),
+		ids:     make([]users.UserID, 0, len(data)),
+	}
+	for _, u := range data {
+		if _, ok := r.byID[u.ID]; ok {
+			continue
+		}
+		if _, ok := r.byEmail[strings.ToLower(u.Email)]; ok {
+			continue
+		}
+		r.insert(u)
+	}
+	return r
+}
+
+// All returns a copy of every stored user, deactivated ones included
+func (r *MemoryUserRepository) All() []MemoryUser {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]MemoryUser, 0, len(r.ids))
+	for _, ID := range r.ids {
+		result = append(result, *r.byID[ID])
+	}
+	return result
+}
+
+func (r *MemoryUserRepository) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.byID)
 }
 
 func (r *MemoryUserRepository) List(
-	filters map[string]string, limit int, offset int,
+	ctx context.Context, filters map[string]string, limit int, offset int,
 ) ([]users.User, int) {
-	filtered := make([]MemoryUser, 0, len(r.Users))
-	if len(filters) == 0 {
-		filtered = r.Users
-	} else {
-		for _, v := range r.Users {
-			name, ok := filters["name"]
-			if ok && v.Name == name {
-				filtered = append(filtered, v)
-			}
-
-			email, ok := filters["email"]
-			if ok && v.Email == email {
-				filtered = append(filtered, v)
-			}
-
-			phone, ok := filters["phone"]
-			if ok && v.Phone == phone {
-				filtered = append(filtered, v)
-			}
-		}
-	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	onlyActives := make([]MemoryUser, 0, len(filtered))
-	for _, u := range filtered {
-		if u.IsActive {
-			onlyActives = append(onlyActives, u)
+	filtered := make([]*MemoryUser, 0, len(r.ids))
+	for _, ID := range r.ids {
+		u := r.byID[ID]
+		if u.IsActive && matchUserFilters(u, filters) {
+			filtered = append(filtered, u)
 		}
 	}
-	filtered = onlyActives
 
-	data := make([]MemoryUser, 0, len(filtered))
+	data := make([]*MemoryUser, 0, len(filtered))
 	if len(filtered) >= limit && len(filtered) >= offset {
 		data = filtered[offset:limit]
 	} else if len(filtered) >= offset && len(filtered) < limit {
@@ -97,159 +135,242 @@ func (r *MemoryUserRepository) List(
 
 	result := make([]users.User, 0, len(data))
 	for _, u := range data {
-		result = append(result, mapToUser(u))
+		result = append(result, mapToUser(*u))
 	}
 	return result, len(filtered)
 }
 
-func (r *MemoryUserRepository) GetByID(ID users.UserID) (users.User, bool) {
-	for _, u := range r.Users {
-		if u.ID == ID {
-			if u.IsActive {
-				return mapToUser(u), true
-			}
-			break
-		}
+func (r *MemoryUserRepository) GetByID(ctx context.Context, ID users.UserID) (users.User, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.byID[ID]
+	if !ok || !u.IsActive {
+		return users.User{}, false
 	}
-	return users.User{}, false
+	return mapToUser(*u), true
 }
 
-func (r *MemoryUserRepository) GetByEmail(email string) (users.User, bool) {
-	for _, u := range r.Users {
-		if u.Email == email {
-			if u.IsActive {
-				return mapToUser(u), true
-			}
-			break
-		}
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (users.User, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ID, ok := r.byEmail[strings.ToLower(email)]
+	if !ok || !r.byID[ID].IsActive {
+		return users.User{}, false
 	}
-	return users.User{}, false
+	return mapToUser(*r.byID[ID]), true
 }
 
-func (r *MemoryUserRepository) GetPassword(ID users.UserID) (string, bool) {
-	for _, u := range r.Users {
-		if u.ID == ID {
-			if u.IsActive {
-				return u.Password, true
-			}
-			break
-		}
+func (r *MemoryUserRepository) GetPendingByEmail(ctx context.Context, email string) (users.User, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ID, ok := r.byEmail[strings.ToLower(email)]
+	if !ok || r.byID[ID].IsActive || r.byID[ID].VerificationCode == "" {
+		return users.User{}, false
 	}
-	return "", false
+	return mapToUser(*r.byID[ID]), true
+}
+
+func (r *MemoryUserRepository) GetPassword(ctx context.Context, ID users.UserID) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.byID[ID]
+	if !ok || !u.IsActive {
+		return "", false
+	}
+	return u.Password, true
 }
 
 func (r *MemoryUserRepository) Add(
-	name string, email string, password string, phone string, isActive bool, scopes []users.ScopeName,
+	ctx context.Context, name string, email string,
+	password string, phone string, isActive bool, scopes []users.ScopeName,
 ) (users.User, error) {
-	lastUserID := users.UserID(0)
-	if len(r.Users) > 0 {
-		lastUserID = r.Users[len(r.Users)-1].ID
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.byEmail[strings.ToLower(email)]; ok {
+		return users.User{}, ports.EmailAlreadyExists
 	}
+
 	newUser := users.User{
-		ID:       lastUserID + 1,
-		Name:     name,
-		Email:    email,
-		Phone:    phone,
-		IsActive: isActive,
-		Scopes:   scopes,
-	}
-	r.Users = append(r.Users, mapToMemoryUser(newUser, password))
+		ID:          r.lastID + 1,
+		Name:        name,
+		Email:       email,
+		Phone:       phone,
+		IsActive:    isActive,
+		Scopes:      scopes,
+		Preferences: users.DefaultPreferences(),
+		Version:     1,
+	}
+	r.insert(mapToMemoryUser(newUser, password))
 	return newUser, nil
 }
 
 func (r *MemoryUserRepository) Update(
-	ID users.UserID, name string, email string, phone string, scopes []users.ScopeName,
+	ctx context.Context, ID users.UserID, version int,
+	name string, email string, phone string, scopes []users.ScopeName,
 ) (users.User, error) {
-	for i, u := range r.Users {
-		if u.ID == ID {
-			r.Users[i].Name = name
-			r.Users[i].Email = email
-			r.Users[i].Phone = phone
-			r.Users[i].Scopes = scopes
-			return mapToUser(r.Users[i]), nil
-		}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.byID[ID]
+	if !ok {
+		return users.User{}, ports.UserDoesNotExists
+	}
+	if !matchVersion(u.Version, version) {
+		return users.User{}, ports.VersionMismatch
 	}
-	return users.User{}, ports.UserDoesNotExists
+	if ownerID, ok := r.byEmail[strings.ToLower(email)]; ok && ownerID != ID {
+		return users.User{}, ports.EmailAlreadyExists
+	}
+
+	delete(r.byEmail, strings.ToLower(u.Email))
+	r.byEmail[strings.ToLower(email)] = ID
+	u.Name = name
+	u.Email = email
+	u.Phone = phone
+	u.Scopes = scopes
+	u.Version++
+	return mapToUser(*u), nil
 }
 
-func (r *MemoryUserRepository) ChangePassword(ID users.UserID, newPassword string) error {
-	for i, u := range r.Users {
-		if u.ID == ID {
-			r.Users[i].Password = newPassword
-			return nil
-		}
-	}
-	return ports.UserDoesNotExists
+func (r *MemoryUserRepository) ChangePassword(ctx context.Context, ID users.UserID, newPassword string) error {
+	return r.modify(ID, func(u *MemoryUser) { u.Password = newPassword })
 }
 
-func (r *MemoryUserRepository) Deactivate(ID users.UserID) error {
-	for i, u := range r.Users {
-		if u.ID == ID {
-			r.Users[i].IsActive = false
-			return nil
-		}
-	}
-	return ports.UserDoesNotExists
+func (r *MemoryUserRepository) UpdateAvatar(ctx context.Context, ID users.UserID, avatarURL string) error {
+	return r.modifyVersion(ID, ports.AnyVersion, func(u *MemoryUser) { u.AvatarURL = avatarURL })
 }
 
-func (r *MemoryUserRepository) Activate(ID users.UserID) bool {
-	for i, u := range r.Users {
-		if u.ID == ID {
-			r.Users[i].IsActive = true
-			return true
-		}
-	}
-	return false
+func (r *MemoryUserRepository) UpdatePreferences(
+	ctx context.Context, ID users.UserID, preferences users.Preferences,
+) error {
+	return r.modifyVersion(ID, ports.AnyVersion, func(u *MemoryUser) { u.Preferences = preferences })
 }
 
-func (r *MemoryUserRepository) SaveAccountVerificationCode(ID users.UserID, code string) error {
-	found := false
-	for i, u := range r.Users {
-		if u.ID == ID {
-			r.Users[i].VerificationCode = code
-			found = true
-			break
-		}
-	}
+func (r *MemoryUserRepository) Deactivate(ctx context.Context, ID users.UserID, version int) error {
+	return r.modifyVersion(ID, version, func(u *MemoryUser) {
+		u.IsActive = false
+		u.VerificationCode = ""
+	})
+}
 
-	if !found {
-		return ports.UserDoesNotExists
-	}
+func (r *MemoryUserRepository) Activate(ctx context.Context, ID users.UserID) bool {
+	return r.modifyVersion(ID, ports.AnyVersion, func(u *MemoryUser) {
+		u.IsActive = true
+		u.VerificationCode = ""
+	}) == nil
+}
 
-	return nil
+func (r *MemoryUserRepository) SaveAccountVerificationCode(ctx context.Context, ID users.UserID, code string) error {
+	return r.modify(ID, func(u *MemoryUser) { u.VerificationCode = code })
 }
 
-func (r *MemoryUserRepository) ValidateAccountVerificationCode(ID users.UserID, code string) bool {
-	for _, u := range r.Users {
-		if u.ID == ID {
-			return u.VerificationCode == code
-		}
+func (r *MemoryUserRepository) ValidateAccountVerificationCode(ctx context.Context, ID users.UserID, code string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.byID[ID]
+	return ok && u.VerificationCode != "" && u.VerificationCode == code
+}
+
+func (r *MemoryUserRepository) SaveRecoveryPasswordCode(ctx context.Context, ID users.UserID, code string) error {
+	return r.modify(ID, func(u *MemoryUser) { u.RecoveryPasswordCode = code })
+}
+
+func (r *MemoryUserRepository) ValidateRecoveryPasswordCode(ctx context.Context, ID users.UserID, code string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.byID[ID]
+	return ok && u.RecoveryPasswordCode != "" && u.RecoveryPasswordCode == code
+}
+
+// insert must be called with the lock held or before the repository is shared,
+// users without a version start at the first one
+func (r *MemoryUserRepository) insert(u MemoryUser) {
+	if u.Version == 0 {
+		u.Version = 1
+	}
+	r.byID[u.ID] = &u
+	r.byEmail[strings.ToLower(u.Email)] = u.ID
+	r.ids = append(r.ids, u.ID)
+	if u.ID > r.lastID {
+		r.lastID = u.ID
 	}
-	return false
 }
 
-func (r *MemoryUserRepository) SaveRecoveryPasswordCode(ID users.UserID, code string) error {
-	found := false
-	for i, u := range r.Users {
-		if u.ID == ID {
-			r.Users[i].RecoveryPasswordCode = code
-			found = true
-			break
-		}
+// clone and replace are used by the unit of work, they must be called with the
+// lock held
+func (r *MemoryUserRepository) clone() *MemoryUserRepository {
+	c := &MemoryUserRepository{
+		byID:    make(map[users.UserID]*MemoryUser, len(r.byID)),
+		byEmail: make(map[string]users.UserID, len(r.byEmail)),
+		ids:     append([]users.UserID(nil), r.ids...),
+		lastID:  r.lastID,
+	}
+	for ID, u := range r.byID {
+		u := *u
+		c.byID[ID] = &u
+	}
+	for email, ID := range r.byEmail {
+		c.byEmail[email] = ID
 	}
+	return c
+}
 
-	if !found {
+func (r *MemoryUserRepository) replace(c *MemoryUserRepository) {
+	r.byID, r.byEmail, r.ids, r.lastID = c.byID, c.byEmail, c.ids, c.lastID
+}
+
+func (r *MemoryUserRepository) modify(ID users.UserID, change func(u *MemoryUser)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.byID[ID]
+	if !ok {
 		return ports.UserDoesNotExists
 	}
+	change(u)
+	return nil
+}
 
+// modifyVersion is modify for changes of the user itself, they must match the
+// given version and increase it
+func (r *MemoryUserRepository) modifyVersion(ID users.UserID, version int, change func(u *MemoryUser)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.byID[ID]
+	if !ok {
+		return ports.UserDoesNotExists
+	}
+	if !matchVersion(u.Version, version) {
+		return ports.VersionMismatch
+	}
+	change(u)
+	u.Version++
 	return nil
 }
 
-func (r *MemoryUserRepository) ValidateRecoveryPasswordCode(ID users.UserID, code string) bool {
-	for _, u := range r.Users {
-		if u.ID == ID {
-			return u.RecoveryPasswordCode == code
-		}
+func matchVersion(current int, expected int) bool {
+	return expected == ports.AnyVersion || current == expected
+}
+
+// every given filter must match, empty filters are ignored like in the sql
+// repositories
+func matchUserFilters(u *MemoryUser, filters map[string]string) bool {
+	if name := filters["name"]; name != "" && u.Name != name {
+		return false
+	}
+	if email := filters["email"]; email != "" && !strings.EqualFold(u.Email, email) {
+		return false
+	}
+	if phone := filters["phone"]; phone != "" && u.Phone != phone {
+		return false
 	}
-	return false
+	return true
 }
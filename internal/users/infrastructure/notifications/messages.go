@@ -0,0 +1,41 @@
+package notifications
+
+import (
+	"fmt"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
+
+type message struct {
+	Subject string
+	Body    string
+}
+
+var verifyAccountMessages = map[users.Language]message{
+	users.LanguageSpanish: {
+		Subject: "Verifica tu cuenta de Fundart",
+		Body:    "Tu código de verificación es: %s",
+	},
+	users.LanguageEnglish: {
+		Subject: "Verify your Fundart account",
+		Body:    "Your verification code is: %s",
+	},
+}
+
+var recoverPasswordMessages = map[users.Language]message{
+	users.LanguageSpanish: {
+		Subject: "Recupera tu contraseña de Fundart",
+		Body:    "Tu código para recuperar la contraseña es: %s",
+	},
+	users.LanguageEnglish: {
+		Subject: "Recover your Fundart password",
+		Body:    "Your password recovery code is: %s",
+	},
+}
+
+func buildMessage(templates map[users.Language]message, language users.Language, code string) message {
+	m, ok := templates[language]
+	if !ok {
+		m = templates[users.LanguageSpanish]
+	}
+	return message{Subject: m.Subject, Body: fmt.Sprintf(m.Body, code)}
+}
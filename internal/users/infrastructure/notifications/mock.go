@@ -1,29 +1,54 @@
 package notifications
 
 import (
-	"log"
+	"context"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"log/slog"
 )
 
 type MockVerificationCodeManager struct {
 	AccountCodes map[string]string
 	PassCodes    map[string]string
+	Messages     map[string]string
+	// Err is returned by every send when it is set
+	Err error
 }
 
 func NewMockVerificationCodeManager() *MockVerificationCodeManager {
 	return &MockVerificationCodeManager{
 		AccountCodes: make(map[string]string),
 		PassCodes:    make(map[string]string),
+		Messages:     make(map[string]string),
 	}
 }
 
-func (m *MockVerificationCodeManager) SendEmailToVerifyAccount(code string, email string) error {
+func (m *MockVerificationCodeManager) SendEmailToVerifyAccount(
+	code string, email string, language users.Language,
+) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	msg := buildMessage(verifyAccountMessages, language, code)
 	m.AccountCodes[email] = code
-	log.Println("Send verification account code:", code, "to:", email)
+	m.Messages[email] = msg.Body
+	slog.Info("email not sent, smtp is not configured", "to", email, "subject", msg.Subject, "body", msg.Body)
 	return nil
 }
 
-func (m *MockVerificationCodeManager) SendEmailToRecoverPassword(code string, email string) error {
+func (m *MockVerificationCodeManager) SendEmailToRecoverPassword(
+	code string, email string, language users.Language,
+) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	msg := buildMessage(recoverPasswordMessages, language, code)
 	m.PassCodes[email] = code
-	log.Println("Send recover password code:", code, "to:", email)
+	m.Messages[email] = msg.Body
+	slog.Info("email not sent, smtp is not configured", "to", email, "subject", msg.Subject, "body", msg.Body)
 	return nil
 }
+
+// Check fails with Err too
+func (m *MockVerificationCodeManager) Check(ctx context.Context) error {
+	return m.Err
+}
@@ -0,0 +1,82 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+// SMTPVerificationCodeManager sends the codes by email, the server must support
+// STARTTLS when a username is set because net/smtp refuses plain auth without it
+type SMTPVerificationCodeManager struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPVerificationCodeManager(
+	host string, port int, username string, password string, from string,
+) *SMTPVerificationCodeManager {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPVerificationCodeManager{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (m *SMTPVerificationCodeManager) SendEmailToVerifyAccount(
+	code string, email string, language users.Language,
+) error {
+	return m.send(email, buildMessage(verifyAccountMessages, language, code))
+}
+
+func (m *SMTPVerificationCodeManager) SendEmailToRecoverPassword(
+	code string, email string, language users.Language,
+) error {
+	return m.send(email, buildMessage(recoverPasswordMessages, language, code))
+}
+
+// Check connects to the server and waits for its greeting
+func (m *SMTPVerificationCodeManager) Check(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(m.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	return client.Quit()
+}
+
+func (m *SMTPVerificationCodeManager) send(to string, msg message) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid email address %q", to)
+	}
+
+	content := "From: " + m.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + msg.Body + "\r\n"
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(content)); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return nil
+}
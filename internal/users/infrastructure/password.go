@@ -0,0 +1,43 @@
+package infrastructure
+
+import (
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxPasswordLength is the max number of bytes bcrypt hashes, the rest of a
+// longer password would be ignored
+const MaxPasswordLength = 72
+
+// BcryptPasswordManager keeps the cost inside the hash, so the passwords hashed
+// with a previous cost are still verified
+type BcryptPasswordManager struct {
+	cost int
+}
+
+func NewBcryptPasswordManager(cost int) *BcryptPasswordManager {
+	return &BcryptPasswordManager{cost: cost}
+}
+
+func (p *BcryptPasswordManager) Encrypt(rawPassword string) (string, error) {
+	if len(rawPassword) > MaxPasswordLength {
+		return "", ports.PasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(rawPassword), p.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (p *BcryptPasswordManager) Verify(rawPassword string, encryptedPassword string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encryptedPassword), []byte(rawPassword))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
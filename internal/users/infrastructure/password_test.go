@@ -0,0 +1,43 @@
+package infrastructure_test
+
+import (
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+func TestBcryptPasswordManager(t *testing.T) {
+	manager := infrastructure.NewBcryptPasswordManager(bcrypt.MinCost)
+
+	hash, err := manager.Encrypt("23456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(hash, "23456") {
+		t.Errorf("the hash must not contain the password, got %q", hash)
+	}
+	if ok, err := manager.Verify("23456", hash); err != nil || !ok {
+		t.Errorf("expected the password to match, got %v %v", ok, err)
+	}
+	if ok, err := manager.Verify("65432", hash); err != nil || ok {
+		t.Errorf("expected a wrong password to not match, got %v %v", ok, err)
+	}
+
+	// the hashes of another cost are still verified
+	other, _ := infrastructure.NewBcryptPasswordManager(bcrypt.MinCost + 1).Encrypt("23456")
+	if ok, _ := manager.Verify("23456", other); !ok {
+		t.Error("expected a hash of another cost to match")
+	}
+
+	if _, err := manager.Verify("23456", "23456_encrypt"); err == nil {
+		t.Error("expected an error verifying a hash that is not bcrypt")
+	}
+	// the length is in bytes, not characters
+	long := strings.Repeat("ñ", infrastructure.MaxPasswordLength/2+1)
+	if _, err := manager.Encrypt(long); !errors.Is(err, ports.PasswordTooLong) {
+		t.Errorf("expected %v, got %v", ports.PasswordTooLong, err)
+	}
+}
@@ -1,4 +0,0 @@
-package postgresrepo
-
-type PgUserRepository struct {
-}
\ No newline at end of file
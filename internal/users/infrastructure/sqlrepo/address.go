@@ -0,0 +1,206 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
+
+const addressColumns = `id, department, city, address, receiver_phone, receiver_name, is_default, version`
+
+const (
+	listAddressesQuery     = `SELECT ` + addressColumns + ` FROM addresses WHERE user_id = $1 ORDER BY id`
+	getAddressQuery        = `SELECT ` + addressColumns + ` FROM addresses WHERE user_id = $1 AND id = $2`
+	getDefaultAddressQuery = `SELECT ` + addressColumns + ` FROM addresses WHERE user_id = $1 AND is_default`
+	addAddressQuery        = `
+		INSERT INTO addresses (user_id, department, city, address, receiver_phone, receiver_name, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, NOT EXISTS (
+			SELECT 1 FROM addresses WHERE user_id = $1 AND is_default
+		))
+		RETURNING ` + addressColumns
+	// a version of 0 matches any version, see ports.AnyVersion
+	updateAddressQuery = `
+		UPDATE addresses SET
+			department = $3, city = $4, address = $5, receiver_phone = $6, receiver_name = $7,
+			version = version + 1
+		WHERE user_id = $1 AND id = $2 AND ($8 = 0 OR version = $8)
+		RETURNING ` + addressColumns
+	unsetDefaultAddressQuery = `
+		UPDATE addresses SET is_default = FALSE, version = version + 1
+		WHERE user_id = $1 AND is_default AND id <> $2`
+	setDefaultAddressQuery = `
+		UPDATE addresses SET
+			is_default = TRUE, version = CASE WHEN is_default THEN version ELSE version + 1 END
+		WHERE user_id = $1 AND id = $2 AND ($3 = 0 OR version = $3)
+		RETURNING ` + addressColumns
+	deleteAddressQuery = `
+		DELETE FROM addresses WHERE user_id = $1 AND id = $2 AND ($3 = 0 OR version = $3)
+		RETURNING is_default`
+	// the oldest address takes the place of a deleted default address
+	promoteDefaultAddressQuery = `
+		UPDATE addresses SET is_default = TRUE, version = version + 1
+		WHERE id = (SELECT min(id) FROM addresses WHERE user_id = $1)`
+)
+
+type SQLAddressRepository struct {
+	db             *sqldb.DB
+	tx             *sql.Tx
+	list           *sql.Stmt
+	get            *sql.Stmt
+	getDefault     *sql.Stmt
+	add            *sql.Stmt
+	update         *sql.Stmt
+	unsetDefault   *sql.Stmt
+	setDefault     *sql.Stmt
+	delete         *sql.Stmt
+	promoteDefault *sql.Stmt
+}
+
+func NewSQLAddressRepository(db *sqldb.DB) (*SQLAddressRepository, error) {
+	r := &SQLAddressRepository{db: db}
+	err := sqldb.PrepareStatements(db.DB, r.statements())
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *SQLAddressRepository) statements() map[**sql.Stmt]string {
+	return map[**sql.Stmt]string{
+		&r.list:           listAddressesQuery,
+		&r.get:            getAddressQuery,
+		&r.getDefault:     getDefaultAddressQuery,
+		&r.add:            addAddressQuery,
+		&r.update:         updateAddressQuery,
+		&r.unsetDefault:   unsetDefaultAddressQuery,
+		&r.setDefault:     setDefaultAddressQuery,
+		&r.delete:         deleteAddressQuery,
+		&r.promoteDefault: promoteDefaultAddressQuery,
+	}
+}
+
+// bind returns a copy of the repository that runs in tx
+func (r *SQLAddressRepository) bind(tx *sql.Tx) *SQLAddressRepository {
+	bound := *r
+	bound.tx = tx
+	sqldb.BindStatements(tx, bound.statements())
+	return &bound
+}
+
+func (r *SQLAddressRepository) List(ctx context.Context, userID users.UserID) []users.Address {
+	addresses := make([]users.Address, 0)
+	rows, err := r.list.QueryContext(ctx, userID)
+	if err != nil {
+		return addresses
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		address, err := scanAddress(rows)
+		if err != nil {
+			return addresses
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+func (r *SQLAddressRepository) GetByID(
+	ctx context.Context, userID users.UserID, ID users.AddressID,
+) (users.Address, bool) {
+	address, err := scanAddress(r.get.QueryRowContext(ctx, userID, ID))
+	if err != nil {
+		return users.Address{}, false
+	}
+	return address, true
+}
+
+func (r *SQLAddressRepository) GetDefault(ctx context.Context, userID users.UserID) (users.Address, bool) {
+	address, err := scanAddress(r.getDefault.QueryRowContext(ctx, userID))
+	if err != nil {
+		return users.Address{}, false
+	}
+	return address, true
+}
+
+func (r *SQLAddressRepository) Add(
+	ctx context.Context, userID users.UserID, department string,
+	city string, address string, receiverPhone string, receiverName string,
+) (users.Address, error) {
+	created, err := scanAddress(r.add.QueryRowContext(ctx, userID, department, city, address, receiverPhone, receiverName))
+	if r.db.Dialect.IsForeignKeyViolation(err) {
+		return users.Address{}, ports.UserDoesNotExists
+	}
+	return created, err
+}
+
+func (r *SQLAddressRepository) Update(
+	ctx context.Context, userID users.UserID, ID users.AddressID, version int,
+	department string, city string, address string, receiverPhone string, receiverName string,
+) (users.Address, error) {
+	updated, err := scanAddress(
+		r.update.QueryRowContext(ctx, userID, ID, department, city, address, receiverPhone, receiverName, version),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return users.Address{}, r.notChanged(ctx, userID, ID)
+	}
+	return updated, err
+}
+
+func (r *SQLAddressRepository) SetDefault(
+	ctx context.Context, userID users.UserID, ID users.AddressID, version int,
+) (users.Address, error) {
+	var address users.Address
+	err := sqldb.InTx(ctx, r.db.DB, r.tx, func(tx *sql.Tx) error {
+		if _, err := tx.Stmt(r.unsetDefault).ExecContext(ctx, userID, ID); err != nil {
+			return err
+		}
+		var err error
+		address, err = scanAddress(tx.Stmt(r.setDefault).QueryRowContext(ctx, userID, ID, version))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return users.Address{}, r.notChanged(ctx, userID, ID)
+	}
+	return address, err
+}
+
+func (r *SQLAddressRepository) Delete(
+	ctx context.Context, userID users.UserID, ID users.AddressID, version int,
+) error {
+	err := sqldb.InTx(ctx, r.db.DB, r.tx, func(tx *sql.Tx) error {
+		var wasDefault bool
+		if err := tx.Stmt(r.delete).QueryRowContext(ctx, userID, ID, version).Scan(&wasDefault); err != nil {
+			return err
+		}
+		if wasDefault {
+			if _, err := tx.Stmt(r.promoteDefault).ExecContext(ctx, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.notChanged(ctx, userID, ID)
+	}
+	return err
+}
+
+// notChanged tells why a write guarded by a version did not change the address
+func (r *SQLAddressRepository) notChanged(ctx context.Context, userID users.UserID, ID users.AddressID) error {
+	if _, ok := r.GetByID(ctx, userID, ID); !ok {
+		return ports.AddressDoesNotExists
+	}
+	return ports.VersionMismatch
+}
+
+func scanAddress(row sqldb.RowScanner) (users.Address, error) {
+	var a users.Address
+	err := row.Scan(
+		&a.ID, &a.Department, &a.City, &a.Address, &a.ReceiverPhone, &a.ReceiverName, &a.IsDefault, &a.Version,
+	)
+	return a, err
+}
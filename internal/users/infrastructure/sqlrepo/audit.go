@@ -0,0 +1,146 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"strconv"
+	"time"
+)
+
+const auditEventColumns = `id, type, actor_id, impersonator_id, target_id, ip, user_agent, details, created_at`
+
+// empty filters are ignored, so the list queries can be prepared. The
+// comparisons go first so postgres takes the type of the parameters from them
+const auditFiltersCondition = `
+	($1 = '' OR type = $1)
+	AND (actor_id = $2 OR $2 IS NULL)
+	AND (target_id = $3 OR $3 IS NULL)
+	AND (created_at >= $4 OR $4 IS NULL)
+	AND (created_at <= $5 OR $5 IS NULL)
+`
+
+const (
+	appendAuditEventQuery = `
+		INSERT INTO audit_events (type, actor_id, impersonator_id, target_id, ip, user_agent, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + auditEventColumns
+	listAuditEventsQuery = `SELECT ` + auditEventColumns + ` FROM audit_events WHERE ` + auditFiltersCondition +
+		` ORDER BY id DESC LIMIT $6 OFFSET $7`
+	countAuditEventsQuery = `SELECT count(*) FROM audit_events WHERE ` + auditFiltersCondition
+)
+
+type SQLAuditLogRepository struct {
+	append *sql.Stmt
+	list   *sql.Stmt
+	count  *sql.Stmt
+}
+
+func NewSQLAuditLogRepository(db *sqldb.DB) (*SQLAuditLogRepository, error) {
+	r := &SQLAuditLogRepository{}
+	err := sqldb.PrepareStatements(db.DB, r.statements())
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *SQLAuditLogRepository) statements() map[**sql.Stmt]string {
+	return map[**sql.Stmt]string{
+		&r.append: appendAuditEventQuery,
+		&r.list:   listAuditEventsQuery,
+		&r.count:  countAuditEventsQuery,
+	}
+}
+
+// bind returns a copy of the repository that runs in tx
+func (r *SQLAuditLogRepository) bind(tx *sql.Tx) *SQLAuditLogRepository {
+	bound := *r
+	sqldb.BindStatements(tx, bound.statements())
+	return &bound
+}
+
+func (r *SQLAuditLogRepository) Append(ctx context.Context, event users.AuditEvent) (users.AuditEvent, error) {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return users.AuditEvent{}, err
+	}
+	if event.Details == nil {
+		details = []byte("{}")
+	}
+	createdAt := event.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	return scanAuditEvent(r.append.QueryRowContext(ctx,
+		string(event.Type), event.ActorID, event.ImpersonatorID, event.TargetID,
+		event.IP, event.UserAgent, string(details), createdAt.UTC(),
+	))
+}
+
+func (r *SQLAuditLogRepository) List(
+	ctx context.Context, filters map[string]string, limit int, offset int,
+) ([]users.AuditEvent, int) {
+	events := make([]users.AuditEvent, 0)
+	// sqlite stores dates as text, they are only comparable with values
+	// written by the driver in the same format
+	args := []any{
+		filters["type"], nullInt(filters["actor_id"]), nullInt(filters["target_id"]),
+		nullRFC3339(filters["from"]), nullRFC3339(filters["to"]),
+	}
+
+	var total int
+	if err := r.count.QueryRowContext(ctx, args...).Scan(&total); err != nil {
+		return events, 0
+	}
+
+	rows, err := r.list.QueryContext(ctx, append(args, limit-offset, offset)...)
+	if err != nil {
+		return events, total
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return events, total
+		}
+		events = append(events, event)
+	}
+	return events, total
+}
+
+func scanAuditEvent(row sqldb.RowScanner) (users.AuditEvent, error) {
+	var e users.AuditEvent
+	var eventType, details string
+
+	err := row.Scan(
+		&e.ID, &eventType, &e.ActorID, &e.ImpersonatorID, &e.TargetID,
+		&e.IP, &e.UserAgent, &details, &e.CreatedAt,
+	)
+	if err != nil {
+		return users.AuditEvent{}, err
+	}
+
+	e.Type = users.AuditEventType(eventType)
+	if err := json.Unmarshal([]byte(details), &e.Details); err != nil {
+		return users.AuditEvent{}, err
+	}
+	return e, nil
+}
+
+func nullInt(value string) sql.NullInt64 {
+	n, err := strconv.ParseInt(value, 10, 64)
+	return sql.NullInt64{Int64: n, Valid: err == nil}
+}
+
+func nullRFC3339(value string) sql.NullTime {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}
+	}
+	return sqldb.NullTime(t)
+}
@@ -0,0 +1,295 @@
+package sqlrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CrissAlvarezH/fundart-api/internal/migrations"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb/sqldbtest"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports/portstest"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/sqlrepo"
+)
+
+func TestSQLUserRepository(t *testing.T) {
+	sqldbtest.Run(t, func(t *testing.T, dialect migrations.Dialect) {
+		ctx := context.Background()
+		db := sqldbtest.Open(t, dialect)
+		repo, err := sqlrepo.NewSQLUserRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		user, err := repo.Add(ctx, "Cristian", "cristian@email.com", "pass", "320684398", true, []users.ScopeName{users.USERS_READ})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = repo.Add(ctx, "Other", "CRISTIAN@email.com", "pass", "1", true, nil)
+		if !errors.Is(err, ports.EmailAlreadyExists) {
+			t.Errorf("expected EmailAlreadyExists, got %v", err)
+		}
+
+		found, ok := repo.GetByEmail(ctx, "Cristian@Email.com")
+		if !ok || found.ID != user.ID {
+			t.Errorf("expected user %d by email, got %v %v", user.ID, found.ID, ok)
+		}
+		if len(found.Scopes) != 1 || found.Scopes[0] != users.USERS_READ {
+			t.Errorf("unexpected scopes %v", found.Scopes)
+		}
+
+		if _, err := repo.Update(ctx, 9999, ports.AnyVersion, "x", "x@email.com", "1", nil); !errors.Is(err, ports.UserDoesNotExists) {
+			t.Errorf("expected UserDoesNotExists, got %v", err)
+		}
+
+		if err := repo.SaveAccountVerificationCode(ctx, user.ID, "1234"); err != nil {
+			t.Fatal(err)
+		}
+		if !repo.ValidateAccountVerificationCode(ctx, user.ID, "1234") || repo.ValidateAccountVerificationCode(ctx, user.ID, "0000") {
+			t.Error("unexpected verification code validation result")
+		}
+
+		list, total := repo.List(ctx, map[string]string{"name": "Cristian"}, 10, 0)
+		if total != 1 || len(list) != 1 {
+			t.Errorf("expected 1 user, got %d (total %d)", len(list), total)
+		}
+
+		if err := repo.Deactivate(ctx, user.ID, ports.AnyVersion); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := repo.GetByID(ctx, user.ID); ok {
+			t.Error("deactivated user should not be found")
+		}
+	})
+}
+
+func TestSQLAddressRepository(t *testing.T) {
+	sqldbtest.Run(t, func(t *testing.T, dialect migrations.Dialect) {
+		ctx := context.Background()
+		db := sqldbtest.Open(t, dialect)
+		userRepo, err := sqlrepo.NewSQLUserRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, err := sqlrepo.NewSQLAddressRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		owner, _ := userRepo.Add(ctx, "Owner", "owner@email.com", "pass", "1", true, nil)
+		other, _ := userRepo.Add(ctx, "Other", "other@email.com", "pass", "2", true, nil)
+
+		first, err := repo.Add(ctx, owner.ID, "Cordoba", "Monteria", "Calle 1", "320", "Owner")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !first.IsDefault {
+			t.Error("first address should be the default one")
+		}
+		second, _ := repo.Add(ctx, owner.ID, "Cordoba", "Monteria", "Calle 2", "320", "Owner")
+
+		if _, ok := repo.GetByID(ctx, other.ID, first.ID); ok {
+			t.Error("address should not be visible to another user")
+		}
+		if err := repo.Delete(ctx, other.ID, first.ID, ports.AnyVersion); !errors.Is(err, ports.AddressDoesNotExists) {
+			t.Errorf("expected AddressDoesNotExists, got %v", err)
+		}
+
+		if _, err := repo.SetDefault(ctx, owner.ID, second.ID, ports.AnyVersion); err != nil {
+			t.Fatal(err)
+		}
+		if def, _ := repo.GetDefault(ctx, owner.ID); def.ID != second.ID {
+			t.Errorf("expected default address %d, got %d", second.ID, def.ID)
+		}
+
+		if err := repo.Delete(ctx, owner.ID, second.ID, ports.AnyVersion); err != nil {
+			t.Fatal(err)
+		}
+		if def, ok := repo.GetDefault(ctx, owner.ID); !ok || def.ID != first.ID {
+			t.Error("remaining address should become the default one")
+		}
+	})
+}
+
+func TestSQLAuditLogRepository(t *testing.T) {
+	sqldbtest.Run(t, func(t *testing.T, dialect migrations.Dialect) {
+		ctx := context.Background()
+		db := sqldbtest.Open(t, dialect)
+		repo, err := sqlrepo.NewSQLAuditLogRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		old := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		if _, err := repo.Append(ctx, users.AuditEvent{Type: users.AuditLoginSucceeded, ActorID: 1, CreatedAt: old}); err != nil {
+			t.Fatal(err)
+		}
+		event, err := repo.Append(ctx, users.AuditEvent{
+			Type: users.AuditPasswordChanged, ActorID: 1, TargetID: 2, Details: map[string]string{"via": "api"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		events, total := repo.List(ctx, map[string]string{"actor_id": "1", "from": "2024-01-01T00:00:00Z"}, 10, 0)
+		if total != 1 || len(events) != 1 || events[0].ID != event.ID {
+			t.Fatalf("expected only the recent event, got %+v (total %d)", events, total)
+		}
+		if events[0].Details["via"] != "api" {
+			t.Errorf("unexpected details %v", events[0].Details)
+		}
+
+		if _, err := db.Exec(`DELETE FROM audit_events`); err == nil {
+			t.Error("audit events should be append-only")
+		}
+	})
+}
+
+func TestSQLUnitOfWork(t *testing.T) {
+	sqldbtest.Run(t, func(t *testing.T, dialect migrations.Dialect) {
+		ctx := context.Background()
+		db := sqldbtest.Open(t, dialect)
+		userRepo, err := sqlrepo.NewSQLUserRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addressRepo, err := sqlrepo.NewSQLAddressRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		auditRepo, err := sqlrepo.NewSQLAuditLogRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uow := sqlrepo.NewSQLUnitOfWork(db, userRepo, addressRepo, auditRepo)
+
+		failure := errors.New("email not sent")
+		err = uow.Do(ctx, func(ctx context.Context, repos ports.Repositories) error {
+			user, err := repos.Users.Add(ctx, "Juan", "juan@email.com", "333_encrypt", "3207846634", true, nil)
+			if err != nil {
+				return err
+			}
+			if _, err := repos.Addresses.Add(ctx, user.ID, "Cordoba", "Monteria", "Cll 1", "320", "Juan"); err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("expected %v, got %v", failure, err)
+		}
+		if _, ok := userRepo.GetByEmail(ctx, "juan@email.com"); ok {
+			t.Error("user of a failed unit of work was kept")
+		}
+
+		var userID users.UserID
+		err = uow.Do(ctx, func(ctx context.Context, repos ports.Repositories) error {
+			user, err := repos.Users.Add(ctx, "Juan", "juan@email.com", "333_encrypt", "3207846634", true, nil)
+			if err != nil {
+				return err
+			}
+			userID = user.ID
+			address, err := repos.Addresses.Add(ctx, user.ID, "Cordoba", "Monteria", "Cll 1", "320", "Juan")
+			if err != nil {
+				return err
+			}
+			// a failed change inside the unit of work is undone by its savepoint
+			// and does not abort the transaction
+			if err := repos.Addresses.Delete(ctx, user.ID, address.ID+100, ports.AnyVersion); !errors.Is(err, ports.AddressDoesNotExists) {
+				t.Errorf("expected AddressDoesNotExists, got %v", err)
+			}
+			_, err = repos.Addresses.SetDefault(ctx, user.ID, address.ID, ports.AnyVersion)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := userRepo.GetByEmail(ctx, "juan@email.com"); !ok {
+			t.Error("user of a committed unit of work not found")
+		}
+		if _, ok := addressRepo.GetDefault(ctx, userID); !ok {
+			t.Error("default address of a committed unit of work not found")
+		}
+	})
+}
+
+func TestSQLRepositories_Versions(t *testing.T) {
+	sqldbtest.Run(t, func(t *testing.T, dialect migrations.Dialect) {
+		ctx := context.Background()
+		db := sqldbtest.Open(t, dialect)
+		userRepo, err := sqlrepo.NewSQLUserRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addressRepo, err := sqlrepo.NewSQLAddressRepository(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		user, _ := userRepo.Add(ctx, "Cristian", "cristian@email.com", "pass", "1", true, nil)
+		if user.Version != 1 {
+			t.Errorf("expected version 1, got %d", user.Version)
+		}
+		updated, err := userRepo.Update(ctx, user.ID, user.Version, "Cristian A", user.Email, "1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Version != 2 {
+			t.Errorf("expected version 2, got %d", updated.Version)
+		}
+		if _, err := userRepo.Update(ctx, user.ID, user.Version, "Stale", user.Email, "1", nil); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if err := userRepo.Deactivate(ctx, user.ID, user.Version); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if err := userRepo.Deactivate(ctx, 9999, 1); !errors.Is(err, ports.UserDoesNotExists) {
+			t.Errorf("expected UserDoesNotExists, got %v", err)
+		}
+
+		first, _ := addressRepo.Add(ctx, user.ID, "Cordoba", "Monteria", "Calle 1", "320", "Cristian")
+		second, _ := addressRepo.Add(ctx, user.ID, "Cordoba", "Monteria", "Calle 2", "320", "Cristian")
+		if _, err := addressRepo.SetDefault(ctx, user.ID, second.ID, second.Version+1); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if def, _ := addressRepo.GetDefault(ctx, user.ID); def.ID != first.ID || def.Version != first.Version {
+			t.Error("a rejected SetDefault must not change the default address")
+		}
+		if _, err := addressRepo.SetDefault(ctx, user.ID, second.ID, second.Version); err != nil {
+			t.Fatal(err)
+		}
+		// unsetting the previous default is a change of that address too
+		if err := addressRepo.Delete(ctx, user.ID, first.ID, first.Version); !errors.Is(err, ports.VersionMismatch) {
+			t.Errorf("expected VersionMismatch, got %v", err)
+		}
+		if err := addressRepo.Delete(ctx, user.ID, 9999, 1); !errors.Is(err, ports.AddressDoesNotExists) {
+			t.Errorf("expected AddressDoesNotExists, got %v", err)
+		}
+	})
+}
+
+func TestSQLRepositoryContract(t *testing.T) {
+	sqldbtest.Run(t, func(t *testing.T, dialect migrations.Dialect) {
+		portstest.Run(t, func(t *testing.T) portstest.Backend {
+			db := sqldbtest.Open(t, dialect)
+			userRepo, err := sqlrepo.NewSQLUserRepository(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addressRepo, err := sqlrepo.NewSQLAddressRepository(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			auditRepo, err := sqlrepo.NewSQLAuditLogRepository(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return portstest.Backend{
+				Repositories: ports.Repositories{Users: userRepo, Addresses: addressRepo, Audit: auditRepo},
+				UnitOfWork:   sqlrepo.NewSQLUnitOfWork(db, userRepo, addressRepo, auditRepo),
+			}
+		})
+	})
+}
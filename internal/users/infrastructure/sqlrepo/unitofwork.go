@@ -0,0 +1,38 @@
+package sqlrepo
+
+import (
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+)
+
+type SQLUnitOfWork struct {
+	db        *sqldb.DB
+	users     *SQLUserRepository
+	addresses *SQLAddressRepository
+	audit     *SQLAuditLogRepository
+}
+
+func NewSQLUnitOfWork(
+	db *sqldb.DB, users *SQLUserRepository, addresses *SQLAddressRepository, audit *SQLAuditLogRepository,
+) *SQLUnitOfWork {
+	return &SQLUnitOfWork{db: db, users: users, addresses: addresses, audit: audit}
+}
+
+func (u *SQLUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos ports.Repositories) error) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(ctx, ports.Repositories{
+		Users:     u.users.bind(tx),
+		Addresses: u.addresses.bind(tx),
+		Audit:     u.audit.bind(tx),
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,329 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/sqldb"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"time"
+)
+
+const userColumns = `
+	id, name, email, phone, avatar_url, is_active, scopes, created_at, language,
+	marketing_email_consent, marketing_email_consent_at, marketing_sms_consent,
+	marketing_sms_consent_at, notification_channels, version
+`
+
+// empty filters are ignored, so the list queries can be prepared
+const userFiltersCondition = `
+	is_active
+	AND ($1 = '' OR name = $1)
+	AND ($2 = '' OR lower(email) = lower($2))
+	AND ($3 = '' OR phone = $3)
+`
+
+const (
+	listUsersQuery = `SELECT ` + userColumns + ` FROM users WHERE ` + userFiltersCondition +
+		` ORDER BY id LIMIT $4 OFFSET $5`
+	countUsersQuery      = `SELECT count(*) FROM users WHERE ` + userFiltersCondition
+	getUserByIDQuery     = `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND is_active`
+	getUserByEmailQuery  = `SELECT ` + userColumns + ` FROM users WHERE lower(email) = lower($1) AND is_active`
+	getPendingUserQuery  = `SELECT ` + userColumns + ` FROM users WHERE lower(email) = lower($1) AND NOT is_active AND verification_code <> ''`
+	getUserPasswordQuery = `SELECT password FROM users WHERE id = $1 AND is_active`
+	addUserQuery         = `
+		INSERT INTO users (name, email, password, phone, is_active, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + userColumns
+	// a version of 0 matches any version, see ports.AnyVersion
+	updateUserQuery = `
+		UPDATE users SET name = $2, email = $3, phone = $4, scopes = $5, version = version + 1
+		WHERE id = $1 AND ($6 = 0 OR version = $6)
+		RETURNING ` + userColumns
+	changePasswordQuery    = `UPDATE users SET password = $2 WHERE id = $1`
+	updateAvatarQuery      = `UPDATE users SET avatar_url = $2, version = version + 1 WHERE id = $1`
+	updatePreferencesQuery = `
+		UPDATE users SET
+			language = $2, marketing_email_consent = $3, marketing_email_consent_at = $4,
+			marketing_sms_consent = $5, marketing_sms_consent_at = $6, notification_channels = $7,
+			version = version + 1
+		WHERE id = $1`
+	deactivateUserQuery = `
+		UPDATE users SET is_active = FALSE, verification_code = '', version = version + 1
+		WHERE id = $1 AND ($2 = 0 OR version = $2)`
+	activateUserQuery          = `UPDATE users SET is_active = TRUE, verification_code = '', version = version + 1 WHERE id = $1`
+	userExistsQuery            = `SELECT count(*) FROM users WHERE id = $1`
+	saveVerificationCodeQuery  = `UPDATE users SET verification_code = $2 WHERE id = $1`
+	checkVerificationCodeQuery = `SELECT count(*) FROM users WHERE id = $1 AND verification_code <> '' AND verification_code = $2`
+	saveRecoveryPassCodeQuery  = `UPDATE users SET recovery_password_code = $2 WHERE id = $1`
+	checkRecoveryPassCodeQuery = `SELECT count(*) FROM users WHERE id = $1 AND recovery_password_code <> '' AND recovery_password_code = $2`
+)
+
+type SQLUserRepository struct {
+	dialect               sqldb.Dialect
+	list                  *sql.Stmt
+	count                 *sql.Stmt
+	getByID               *sql.Stmt
+	getByEmail            *sql.Stmt
+	getPending            *sql.Stmt
+	getPassword           *sql.Stmt
+	add                   *sql.Stmt
+	update                *sql.Stmt
+	changePassword        *sql.Stmt
+	updateAvatar          *sql.Stmt
+	updatePreferences     *sql.Stmt
+	deactivate            *sql.Stmt
+	activate              *sql.Stmt
+	saveVerificationCode  *sql.Stmt
+	checkVerificationCode *sql.Stmt
+	saveRecoveryPassCode  *sql.Stmt
+	checkRecoveryPassCode *sql.Stmt
+	exists                *sql.Stmt
+}
+
+func NewSQLUserRepository(db *sqldb.DB) (*SQLUserRepository, error) {
+	r := &SQLUserRepository{dialect: db.Dialect}
+	err := sqldb.PrepareStatements(db.DB, r.statements())
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *SQLUserRepository) statements() map[**sql.Stmt]string {
+	return map[**sql.Stmt]string{
+		&r.list:                  listUsersQuery,
+		&r.count:                 countUsersQuery,
+		&r.getByID:               getUserByIDQuery,
+		&r.getByEmail:            getUserByEmailQuery,
+		&r.getPending:            getPendingUserQuery,
+		&r.getPassword:           getUserPasswordQuery,
+		&r.add:                   addUserQuery,
+		&r.update:                updateUserQuery,
+		&r.changePassword:        changePasswordQuery,
+		&r.updateAvatar:          updateAvatarQuery,
+		&r.updatePreferences:     updatePreferencesQuery,
+		&r.deactivate:            deactivateUserQuery,
+		&r.activate:              activateUserQuery,
+		&r.saveVerificationCode:  saveVerificationCodeQuery,
+		&r.checkVerificationCode: checkVerificationCodeQuery,
+		&r.saveRecoveryPassCode:  saveRecoveryPassCodeQuery,
+		&r.checkRecoveryPassCode: checkRecoveryPassCodeQuery,
+		&r.exists:                userExistsQuery,
+	}
+}
+
+// bind returns a copy of the repository that runs in tx
+func (r *SQLUserRepository) bind(tx *sql.Tx) *SQLUserRepository {
+	bound := *r
+	sqldb.BindStatements(tx, bound.statements())
+	return &bound
+}
+
+func (r *SQLUserRepository) List(
+	ctx context.Context, filters map[string]string, limit int, offset int,
+) ([]users.User, int) {
+	result := make([]users.User, 0)
+
+	var total int
+	err := r.count.QueryRowContext(ctx, filters["name"], filters["email"], filters["phone"]).Scan(&total)
+	if err != nil {
+		return result, 0
+	}
+
+	rows, err := r.list.QueryContext(ctx, filters["name"], filters["email"], filters["phone"], limit-offset, offset)
+	if err != nil {
+		return result, total
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := r.scanUser(rows)
+		if err != nil {
+			return result, total
+		}
+		result = append(result, user)
+	}
+	return result, total
+}
+
+func (r *SQLUserRepository) GetByID(ctx context.Context, ID users.UserID) (users.User, bool) {
+	user, err := r.scanUser(r.getByID.QueryRowContext(ctx, ID))
+	if err != nil {
+		return users.User{}, false
+	}
+	return user, true
+}
+
+func (r *SQLUserRepository) GetByEmail(ctx context.Context, email string) (users.User, bool) {
+	user, err := r.scanUser(r.getByEmail.QueryRowContext(ctx, email))
+	if err != nil {
+		return users.User{}, false
+	}
+	return user, true
+}
+
+func (r *SQLUserRepository) GetPendingByEmail(ctx context.Context, email string) (users.User, bool) {
+	user, err := r.scanUser(r.getPending.QueryRowContext(ctx, email))
+	if err != nil {
+		return users.User{}, false
+	}
+	return user, true
+}
+
+func (r *SQLUserRepository) GetPassword(ctx context.Context, ID users.UserID) (string, bool) {
+	var password string
+	if err := r.getPassword.QueryRowContext(ctx, ID).Scan(&password); err != nil {
+		return "", false
+	}
+	return password, true
+}
+
+func (r *SQLUserRepository) Add(
+	ctx context.Context, name string, email string,
+	password string, phone string, isActive bool, scopes []users.ScopeName,
+) (users.User, error) {
+	user, err := r.scanUser(r.add.QueryRowContext(ctx,
+		name, email, password, phone, isActive, r.dialect.List(names(scopes)), time.Now().UTC(),
+	))
+	if r.dialect.IsUniqueViolation(err) {
+		return users.User{}, ports.EmailAlreadyExists
+	}
+	return user, err
+}
+
+func (r *SQLUserRepository) Update(
+	ctx context.Context, ID users.UserID, version int,
+	name string, email string, phone string, scopes []users.ScopeName,
+) (users.User, error) {
+	user, err := r.scanUser(r.update.QueryRowContext(ctx, ID, name, email, phone, r.dialect.List(names(scopes)), version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return users.User{}, r.notChanged(ctx, ID)
+	}
+	if r.dialect.IsUniqueViolation(err) {
+		return users.User{}, ports.EmailAlreadyExists
+	}
+	return user, err
+}
+
+func (r *SQLUserRepository) ChangePassword(ctx context.Context, ID users.UserID, newPassword string) error {
+	return execAffectingUser(ctx, r.changePassword, ID, newPassword)
+}
+
+func (r *SQLUserRepository) UpdateAvatar(ctx context.Context, ID users.UserID, avatarURL string) error {
+	return execAffectingUser(ctx, r.updateAvatar, ID, avatarURL)
+}
+
+func (r *SQLUserRepository) UpdatePreferences(
+	ctx context.Context, ID users.UserID, preferences users.Preferences,
+) error {
+	return execAffectingUser(
+		ctx, r.updatePreferences, ID, string(preferences.Language),
+		preferences.MarketingEmail.Granted, sqldb.NullTime(preferences.MarketingEmail.UpdatedAt),
+		preferences.MarketingSMS.Granted, sqldb.NullTime(preferences.MarketingSMS.UpdatedAt),
+		r.dialect.List(names(preferences.NotificationChannels)),
+	)
+}
+
+func (r *SQLUserRepository) Deactivate(ctx context.Context, ID users.UserID, version int) error {
+	err := execAffectingUser(ctx, r.deactivate, ID, version)
+	if errors.Is(err, ports.UserDoesNotExists) {
+		return r.notChanged(ctx, ID)
+	}
+	return err
+}
+
+func (r *SQLUserRepository) Activate(ctx context.Context, ID users.UserID) bool {
+	return execAffectingUser(ctx, r.activate, ID) == nil
+}
+
+func (r *SQLUserRepository) SaveAccountVerificationCode(ctx context.Context, ID users.UserID, code string) error {
+	return execAffectingUser(ctx, r.saveVerificationCode, ID, code)
+}
+
+func (r *SQLUserRepository) ValidateAccountVerificationCode(ctx context.Context, ID users.UserID, code string) bool {
+	var count int
+	err := r.checkVerificationCode.QueryRowContext(ctx, ID, code).Scan(&count)
+	return err == nil && count == 1
+}
+
+func (r *SQLUserRepository) SaveRecoveryPasswordCode(ctx context.Context, ID users.UserID, code string) error {
+	return execAffectingUser(ctx, r.saveRecoveryPassCode, ID, code)
+}
+
+func (r *SQLUserRepository) ValidateRecoveryPasswordCode(ctx context.Context, ID users.UserID, code string) bool {
+	var count int
+	err := r.checkRecoveryPassCode.QueryRowContext(ctx, ID, code).Scan(&count)
+	return err == nil && count == 1
+}
+
+// notChanged tells why a write guarded by a version did not change the user
+func (r *SQLUserRepository) notChanged(ctx context.Context, ID users.UserID) error {
+	var count int
+	if err := r.exists.QueryRowContext(ctx, ID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ports.UserDoesNotExists
+	}
+	return ports.VersionMismatch
+}
+
+func execAffectingUser(ctx context.Context, stmt *sql.Stmt, ID users.UserID, args ...any) error {
+	result, err := stmt.ExecContext(ctx, append([]any{ID}, args...)...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ports.UserDoesNotExists
+	}
+	return nil
+}
+
+func (r *SQLUserRepository) scanUser(row sqldb.RowScanner) (users.User, error) {
+	var user users.User
+	var scopes, channels []string
+	var language string
+	var emailConsentAt, smsConsentAt sql.NullTime
+
+	err := row.Scan(
+		&user.ID, &user.Name, &user.Email, &user.Phone, &user.AvatarURL, &user.IsActive,
+		r.dialect.ScanList(&scopes), &user.CreatedAt, &language,
+		&user.Preferences.MarketingEmail.Granted, &emailConsentAt,
+		&user.Preferences.MarketingSMS.Granted, &smsConsentAt,
+		r.dialect.ScanList(&channels), &user.Version,
+	)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	user.Scopes = fromNames[users.ScopeName](scopes)
+	user.Preferences.Language = users.Language(language)
+	user.Preferences.MarketingEmail.UpdatedAt = emailConsentAt.Time
+	user.Preferences.MarketingSMS.UpdatedAt = smsConsentAt.Time
+	user.Preferences.NotificationChannels = fromNames[users.NotificationChannel](channels)
+	return user, nil
+}
+
+// names is the list of strings stored in a list column, it's never nil since
+// the columns are not nullable
+func names[T ~string](values []T) []string {
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		list = append(list, string(v))
+	}
+	return list
+}
+
+func fromNames[T ~string](list []string) []T {
+	values := make([]T, 0, len(list))
+	for _, v := range list {
+		values = append(values, T(v))
+	}
+	return values
+}
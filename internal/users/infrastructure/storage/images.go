@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"golang.org/x/image/draw"
+	"image"
+)
+
+// squareRect is the centered square of the bounds of an image
+func squareRect(b image.Rectangle) image.Rectangle {
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	return image.Rect(x0, y0, x0+side, y0+side)
+}
+
+// resize scales the sr square of src to size x size
+func resize(src image.Image, sr image.Rectangle, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, sr, draw.Src, nil)
+	return dst
+}
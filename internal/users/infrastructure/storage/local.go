@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// AvatarSizes are the standard sizes (in pixels) of the square avatars stored,
+// the url returned on Save points to the "medium" size
+var AvatarSizes = map[string]int{
+	"small":  64,
+	"medium": 256,
+	"large":  512,
+}
+
+type LocalAvatarStorage struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalAvatarStorage(dir string, baseURL string) *LocalAvatarStorage {
+	return &LocalAvatarStorage{dir: dir, baseURL: baseURL}
+}
+
+// MaxAvatarDimension is the max width and height (in pixels) of the avatars, a
+// small file can declare a huge image that takes gigabytes once decoded
+const MaxAvatarDimension = 4096
+
+func (s *LocalAvatarStorage) Save(ID users.UserID, content []byte) (string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ports.InvalidAvatarType, err)
+	}
+	if config.Width > MaxAvatarDimension || config.Height > MaxAvatarDimension {
+		return "", fmt.Errorf("%w: %dx%d pixels", ports.AvatarTooLarge, config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ports.InvalidAvatarType, err)
+	}
+	square := squareRect(img.Bounds())
+
+	userDir := filepath.Join(s.dir, strconv.Itoa(int(ID)))
+	if err := os.MkdirAll(userDir, 0o755); err != nil {
+		return "", err
+	}
+
+	for name, size := range AvatarSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resize(img, square, size), &jpeg.Options{Quality: 85}); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(userDir, name+".jpg"), buf.Bytes(), 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	// the version query param avoids clients keep showing a cached old avatar
+	url := fmt.Sprintf("%s/%d/medium.jpg?v=%d", s.baseURL, ID, time.Now().Unix())
+	return url, nil
+}
+
+func (s *LocalAvatarStorage) Delete(ID users.UserID) error {
+	return os.RemoveAll(filepath.Join(s.dir, strconv.Itoa(int(ID))))
+}
+
+// Check fails when the avatars can't be written to the directory
+func (s *LocalAvatarStorage) Check(ctx context.Context) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(s.dir, ".check-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,116 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/storage"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 30, B: 30, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLocalAvatarStorage_Save(t *testing.T) {
+	dir := t.TempDir()
+	s := storage.NewLocalAvatarStorage(dir, "/media/avatars")
+
+	url, err := s.Save(7, encodePNG(t, 300, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(url, "/media/avatars/7/medium.jpg?v=") {
+		t.Errorf("unexpected url %s", url)
+	}
+
+	for name, size := range storage.AvatarSizes {
+		file, err := os.Open(filepath.Join(dir, "7", name+".jpg"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := jpeg.Decode(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+			t.Errorf("%s: expected %dx%d, got %dx%d", name, size, size, b.Dx(), b.Dy())
+		}
+		// the color of the source is kept by the scaling
+		r, g, _, _ := img.At(size/2, size/2).RGBA()
+		if r>>8 < 180 || g>>8 > 60 {
+			t.Errorf("%s: unexpected color %d %d", name, r>>8, g>>8)
+		}
+	}
+
+	if err := s.Delete(7); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "7")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the avatars to be removed, got %v", err)
+	}
+}
+
+func TestLocalAvatarStorage_SaveInvalidImage(t *testing.T) {
+	s := storage.NewLocalAvatarStorage(t.TempDir(), "/media/avatars")
+
+	content := encodePNG(t, 10, 10)
+	cases := map[string][]byte{
+		"not an image": []byte("not an image"),
+		"truncated":    content[:len(content)/2],
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.Save(7, content); !errors.Is(err, ports.InvalidAvatarType) {
+				t.Errorf("expected %v, got %v", ports.InvalidAvatarType, err)
+			}
+		})
+	}
+}
+
+// the dimensions are checked before the image is decoded
+func TestLocalAvatarStorage_SaveTooLarge(t *testing.T) {
+	dir := t.TempDir()
+	s := storage.NewLocalAvatarStorage(dir, "/media/avatars")
+
+	_, err := s.Save(7, encodePNG(t, storage.MaxAvatarDimension+1, 1))
+	if !errors.Is(err, ports.AvatarTooLarge) {
+		t.Errorf("expected %v, got %v", ports.AvatarTooLarge, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "7")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("no avatar must be stored")
+	}
+}
+
+func TestLocalAvatarStorage_Check(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "avatars")
+	s := storage.NewLocalAvatarStorage(dir, "/media/avatars")
+
+	if err := s.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected no files left by the check, got %v", entries)
+	}
+}
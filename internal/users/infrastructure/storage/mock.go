@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
+
+type MockAvatarStorage struct {
+	Avatars map[users.UserID][]byte
+}
+
+func NewMockAvatarStorage() *MockAvatarStorage {
+	return &MockAvatarStorage{Avatars: make(map[users.UserID][]byte)}
+}
+
+func (s *MockAvatarStorage) Save(ID users.UserID, content []byte) (string, error) {
+	s.Avatars[ID] = content
+	return fmt.Sprintf("/media/avatars/%d/medium.jpg", ID), nil
+}
+
+func (s *MockAvatarStorage) Delete(ID users.UserID) error {
+	delete(s.Avatars, ID)
+	return nil
+}
+
+func (s *MockAvatarStorage) Check(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,72 @@
+package tracedrepo
+
+import (
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
+
+type TracedAddressRepository struct {
+	repo ports.AddressRepository
+}
+
+func NewTracedAddressRepository(repo ports.AddressRepository) *TracedAddressRepository {
+	return &TracedAddressRepository{repo: repo}
+}
+
+func (r *TracedAddressRepository) List(ctx context.Context, userID users.UserID) []users.Address {
+	ctx, span := tracing.Start(ctx, "AddressRepository.List")
+	defer span.End()
+	return r.repo.List(ctx, userID)
+}
+
+func (r *TracedAddressRepository) GetByID(
+	ctx context.Context, userID users.UserID, ID users.AddressID,
+) (users.Address, bool) {
+	ctx, span := tracing.Start(ctx, "AddressRepository.GetByID")
+	defer span.End()
+	return r.repo.GetByID(ctx, userID, ID)
+}
+
+func (r *TracedAddressRepository) GetDefault(ctx context.Context, userID users.UserID) (users.Address, bool) {
+	ctx, span := tracing.Start(ctx, "AddressRepository.GetDefault")
+	defer span.End()
+	return r.repo.GetDefault(ctx, userID)
+}
+
+func (r *TracedAddressRepository) Add(
+	ctx context.Context, userID users.UserID, department string, city string, address string, receiverPhone string, receiverName string,
+) (users.Address, error) {
+	ctx, span := tracing.Start(ctx, "AddressRepository.Add")
+	result, err := r.repo.Add(ctx, userID, department, city, address, receiverPhone, receiverName)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedAddressRepository) Update(
+	ctx context.Context, userID users.UserID, ID users.AddressID, version int, department string, city string, address string, receiverPhone string, receiverName string,
+) (users.Address, error) {
+	ctx, span := tracing.Start(ctx, "AddressRepository.Update")
+	result, err := r.repo.Update(ctx, userID, ID, version, department, city, address, receiverPhone, receiverName)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedAddressRepository) SetDefault(
+	ctx context.Context, userID users.UserID, ID users.AddressID, version int,
+) (users.Address, error) {
+	ctx, span := tracing.Start(ctx, "AddressRepository.SetDefault")
+	result, err := r.repo.SetDefault(ctx, userID, ID, version)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedAddressRepository) Delete(
+	ctx context.Context, userID users.UserID, ID users.AddressID, version int,
+) error {
+	ctx, span := tracing.Start(ctx, "AddressRepository.Delete")
+	err := r.repo.Delete(ctx, userID, ID, version)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,31 @@
+package tracedrepo
+
+import (
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
+
+type TracedAuditLogRepository struct {
+	repo ports.AuditLogRepository
+}
+
+func NewTracedAuditLogRepository(repo ports.AuditLogRepository) *TracedAuditLogRepository {
+	return &TracedAuditLogRepository{repo: repo}
+}
+
+func (r *TracedAuditLogRepository) Append(ctx context.Context, event users.AuditEvent) (users.AuditEvent, error) {
+	ctx, span := tracing.Start(ctx, "AuditLogRepository.Append")
+	result, err := r.repo.Append(ctx, event)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedAuditLogRepository) List(
+	ctx context.Context, filters map[string]string, limit int, offset int,
+) ([]users.AuditEvent, int) {
+	ctx, span := tracing.Start(ctx, "AuditLogRepository.List")
+	defer span.End()
+	return r.repo.List(ctx, filters, limit, offset)
+}
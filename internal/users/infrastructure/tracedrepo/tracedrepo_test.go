@@ -0,0 +1,61 @@
+package tracedrepo_test
+
+import (
+	"context"
+	"errors"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/memoryrepo"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/infrastructure/tracedrepo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestTracedUnitOfWork(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	userRepo := memoryrepo.NewMemoryUserRepository(nil)
+	addressRepo := memoryrepo.NewMemoryAddressRepository(nil)
+	auditRepo := memoryrepo.NewMemoryAuditLogRepository(make([]users.AuditEvent, 0))
+	uow := tracedrepo.NewTracedUnitOfWork(memoryrepo.NewMemoryUnitOfWork(userRepo, addressRepo, auditRepo))
+
+	ctx := context.Background()
+	err := uow.Do(ctx, func(ctx context.Context, repos ports.Repositories) error {
+		user, err := repos.Users.Add(ctx, "Juan", "juan@email.com", "pass", "320", true, nil)
+		if err != nil {
+			return err
+		}
+		return repos.Addresses.Delete(ctx, user.ID, 99, ports.AnyVersion)
+	})
+	if !errors.Is(err, ports.AddressDoesNotExists) {
+		t.Fatalf("expected AddressDoesNotExists, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	names := make([]string, 0, len(spans))
+	for _, s := range spans {
+		names = append(names, s.Name())
+	}
+	if len(spans) != 3 {
+		t.Fatalf("expected the unit of work and two repository spans, got %v", names)
+	}
+	add, del, do := spans[0], spans[1], spans[2]
+	if add.Name() != "UserRepository.Add" || del.Name() != "AddressRepository.Delete" || do.Name() != "UnitOfWork.Do" {
+		t.Fatalf("unexpected spans %v", names)
+	}
+	if add.Parent().SpanID() != do.SpanContext().SpanID() || del.Parent().SpanID() != do.SpanContext().SpanID() {
+		t.Error("the repository spans must be children of the unit of work")
+	}
+	if add.Status().Code == codes.Error {
+		t.Error("a successful call must not be marked as failed")
+	}
+	if del.Status().Code != codes.Error || do.Status().Code != codes.Error {
+		t.Error("the error must be recorded in the repository and unit of work spans")
+	}
+}
@@ -0,0 +1,35 @@
+package tracedrepo
+
+import (
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+)
+
+// TracedUnitOfWork creates a span for the whole unit of work, the calls to the
+// repositories inside it are children of that span
+type TracedUnitOfWork struct {
+	uow ports.UnitOfWork
+}
+
+func NewTracedUnitOfWork(uow ports.UnitOfWork) *TracedUnitOfWork {
+	return &TracedUnitOfWork{uow: uow}
+}
+
+func (u *TracedUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos ports.Repositories) error) error {
+	ctx, span := tracing.Start(ctx, "UnitOfWork.Do")
+	err := u.uow.Do(ctx, func(ctx context.Context, repos ports.Repositories) error {
+		return fn(ctx, Wrap(repos))
+	})
+	tracing.End(span, err)
+	return err
+}
+
+// Wrap returns the repositories traced
+func Wrap(repos ports.Repositories) ports.Repositories {
+	return ports.Repositories{
+		Users:     NewTracedUserRepository(repos.Users),
+		Addresses: NewTracedAddressRepository(repos.Addresses),
+		Audit:     NewTracedAuditLogRepository(repos.Audit),
+	}
+}
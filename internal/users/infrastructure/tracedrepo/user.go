@@ -0,0 +1,130 @@
+package tracedrepo
+
+import (
+	"context"
+	"github.com/CrissAlvarezH/fundart-api/internal/tracing"
+	"github.com/CrissAlvarezH/fundart-api/internal/users/application/ports"
+	users "github.com/CrissAlvarezH/fundart-api/internal/users/domain"
+)
+
+// TracedUserRepository creates a span for every call to the wrapped repository, the
+// other traced repositories work the same
+type TracedUserRepository struct {
+	repo ports.UserRepository
+}
+
+func NewTracedUserRepository(repo ports.UserRepository) *TracedUserRepository {
+	return &TracedUserRepository{repo: repo}
+}
+
+func (r *TracedUserRepository) List(
+	ctx context.Context, filters map[string]string, limit int, offset int,
+) ([]users.User, int) {
+	ctx, span := tracing.Start(ctx, "UserRepository.List")
+	defer span.End()
+	return r.repo.List(ctx, filters, limit, offset)
+}
+
+func (r *TracedUserRepository) GetByID(ctx context.Context, ID users.UserID) (users.User, bool) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetByID")
+	defer span.End()
+	return r.repo.GetByID(ctx, ID)
+}
+
+func (r *TracedUserRepository) GetByEmail(ctx context.Context, email string) (users.User, bool) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetByEmail")
+	defer span.End()
+	return r.repo.GetByEmail(ctx, email)
+}
+
+func (r *TracedUserRepository) GetPendingByEmail(ctx context.Context, email string) (users.User, bool) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetPendingByEmail")
+	defer span.End()
+	return r.repo.GetPendingByEmail(ctx, email)
+}
+
+func (r *TracedUserRepository) GetPassword(ctx context.Context, ID users.UserID) (string, bool) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetPassword")
+	defer span.End()
+	return r.repo.GetPassword(ctx, ID)
+}
+
+func (r *TracedUserRepository) Add(
+	ctx context.Context, name string, email string, password string, phone string, isActive bool, scopes []users.ScopeName,
+) (users.User, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.Add")
+	result, err := r.repo.Add(ctx, name, email, password, phone, isActive, scopes)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedUserRepository) Update(
+	ctx context.Context, ID users.UserID, version int, name string, email string, phone string, scopes []users.ScopeName,
+) (users.User, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.Update")
+	result, err := r.repo.Update(ctx, ID, version, name, email, phone, scopes)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *TracedUserRepository) ChangePassword(ctx context.Context, ID users.UserID, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.ChangePassword")
+	err := r.repo.ChangePassword(ctx, ID, newPassword)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedUserRepository) UpdateAvatar(ctx context.Context, ID users.UserID, avatarURL string) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.UpdateAvatar")
+	err := r.repo.UpdateAvatar(ctx, ID, avatarURL)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedUserRepository) UpdatePreferences(
+	ctx context.Context, ID users.UserID, preferences users.Preferences,
+) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.UpdatePreferences")
+	err := r.repo.UpdatePreferences(ctx, ID, preferences)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedUserRepository) Deactivate(ctx context.Context, ID users.UserID, version int) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.Deactivate")
+	err := r.repo.Deactivate(ctx, ID, version)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedUserRepository) Activate(ctx context.Context, ID users.UserID) bool {
+	ctx, span := tracing.Start(ctx, "UserRepository.Activate")
+	defer span.End()
+	return r.repo.Activate(ctx, ID)
+}
+
+func (r *TracedUserRepository) SaveAccountVerificationCode(ctx context.Context, ID users.UserID, code string) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.SaveAccountVerificationCode")
+	err := r.repo.SaveAccountVerificationCode(ctx, ID, code)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedUserRepository) ValidateAccountVerificationCode(ctx context.Context, ID users.UserID, code string) bool {
+	ctx, span := tracing.Start(ctx, "UserRepository.ValidateAccountVerificationCode")
+	defer span.End()
+	return r.repo.ValidateAccountVerificationCode(ctx, ID, code)
+}
+
+func (r *TracedUserRepository) SaveRecoveryPasswordCode(ctx context.Context, ID users.UserID, code string) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.SaveRecoveryPasswordCode")
+	err := r.repo.SaveRecoveryPasswordCode(ctx, ID, code)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *TracedUserRepository) ValidateRecoveryPasswordCode(ctx context.Context, ID users.UserID, code string) bool {
+	ctx, span := tracing.Start(ctx, "UserRepository.ValidateRecoveryPasswordCode")
+	defer span.End()
+	return r.repo.ValidateRecoveryPasswordCode(ctx, ID, code)
+}